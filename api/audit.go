@@ -0,0 +1,26 @@
+package api
+
+import (
+	"time"
+)
+
+// An AuditRecord describes a call to an RPC that changes the state of the
+// deployment. The daemon appends a record for every such call to its audit log.
+type AuditRecord struct {
+	Time time.Time
+
+	// The full name of the RPC, e.g. "/API/Deploy".
+	Method string
+
+	// The role and SHA256 fingerprint of the caller's TLS certificate. Both are
+	// empty if the caller didn't present a certificate, e.g. because it
+	// connected over the daemon's Unix socket.
+	Role        string
+	Certificate string
+
+	// A human readable summary of the request.
+	Request string
+
+	// The error returned by the RPC, or "ok" if it succeeded.
+	Result string
+}
@@ -56,6 +56,22 @@ type Client interface {
 	// QueryImages retrieves the image information tracked by the Quilt daemon.
 	QueryImages() ([]db.Image, error)
 
+	// QueryScalingGroups retrieves the autoscaling groups tracked by the Quilt
+	// daemon.
+	QueryScalingGroups() ([]db.ScalingGroup, error)
+
+	// QueryTraffic retrieves the traffic sent between the endpoints of the
+	// connection graph, as tracked by the Quilt daemon.
+	QueryTraffic() ([]db.Traffic, error)
+
+	// QueryHealth retrieves the health of each subsystem of the Quilt daemon,
+	// or, if a host is given, of the minion on that host.
+	QueryHealth(string) ([]pb.HealthCheck, error)
+
+	// QueryAudit retrieves the daemon's audit log of calls that changed the
+	// deployment. Only defined on the daemon.
+	QueryAudit() ([]api.AuditRecord, error)
+
 	// Deploy makes a request to the Quilt daemon to deploy the given deployment.
 	// Only defined on the daemon.
 	Deploy(deployment string) error
@@ -148,6 +164,18 @@ func query(pbClient pb.APIClient, table db.TableType) (interface{}, error) {
 			return nil, err
 		}
 		return images, nil
+	case db.ScalingGroupTable:
+		var groups []db.ScalingGroup
+		if err := json.Unmarshal(replyBytes, &groups); err != nil {
+			return nil, err
+		}
+		return groups, nil
+	case db.TrafficTable:
+		var traffic []db.Traffic
+		if err := json.Unmarshal(replyBytes, &traffic); err != nil {
+			return nil, err
+		}
+		return traffic, nil
 	default:
 		panic(fmt.Sprintf("unsupported table type: %s", table))
 	}
@@ -259,6 +287,59 @@ func (c clientImpl) QueryImages() ([]db.Image, error) {
 	return rows.([]db.Image), nil
 }
 
+// QueryScalingGroups retrieves the autoscaling groups tracked by the Quilt daemon.
+func (c clientImpl) QueryScalingGroups() ([]db.ScalingGroup, error) {
+	rows, err := query(c.pbClient, db.ScalingGroupTable)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows.([]db.ScalingGroup), nil
+}
+
+// QueryTraffic retrieves the traffic sent between the endpoints of the connection
+// graph, as tracked by the Quilt daemon.
+func (c clientImpl) QueryTraffic() ([]db.Traffic, error) {
+	rows, err := query(c.pbClient, db.TrafficTable)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows.([]db.Traffic), nil
+}
+
+// QueryHealth retrieves the health of each subsystem of the Quilt daemon, or, if
+// `host` is non-empty, of the minion on that host.
+func (c clientImpl) QueryHealth(host string) ([]pb.HealthCheck, error) {
+	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
+	reply, err := c.pbClient.Health(ctx, &pb.HealthRequest{Host: host})
+	if err != nil {
+		return nil, err
+	}
+
+	var checks []pb.HealthCheck
+	for _, check := range reply.Checks {
+		checks = append(checks, *check)
+	}
+	return checks, nil
+}
+
+// QueryAudit retrieves the daemon's audit log of calls that changed the
+// deployment.
+func (c clientImpl) QueryAudit() ([]api.AuditRecord, error) {
+	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
+	reply, err := c.pbClient.QueryAudit(ctx, &pb.AuditRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	var records []api.AuditRecord
+	if err := json.Unmarshal([]byte(reply.Records), &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
 // Deploy makes a request to the Quilt daemon to deploy the given deployment.
 func (c clientImpl) Deploy(deployment string) error {
 	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
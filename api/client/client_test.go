@@ -3,11 +3,13 @@ package client
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 
+	"github.com/quilt/quilt/api"
 	"github.com/quilt/quilt/api/pb"
 	"github.com/quilt/quilt/db"
 )
@@ -41,6 +43,20 @@ func (c mockAPIClient) QueryMinionCounters(ctx context.Context, in *pb.
 	return &pb.CountersReply{}, nil
 }
 
+func (c mockAPIClient) QueryAudit(ctx context.Context, in *pb.AuditRequest,
+	opts ...grpc.CallOption) (*pb.AuditReply, error) {
+
+	return &pb.AuditReply{Records: c.mockResponse}, c.mockError
+}
+
+func (c mockAPIClient) Health(ctx context.Context, in *pb.HealthRequest,
+	opts ...grpc.CallOption) (*pb.HealthReply, error) {
+
+	return &pb.HealthReply{Checks: []*pb.HealthCheck{
+		{Name: "engine", Healthy: true},
+	}}, c.mockError
+}
+
 func (c mockAPIClient) Version(ctx context.Context, in *pb.VersionRequest,
 	opts ...grpc.CallOption) (*pb.VersionReply, error) {
 
@@ -109,6 +125,52 @@ func TestUnmarshalImage(t *testing.T) {
 	}, res)
 }
 
+func TestUnmarshalScalingGroup(t *testing.T) {
+	t.Parallel()
+
+	apiClient := mockAPIClient{
+		mockResponse: `[{"ID":1,"BlueprintID":"foo","Min":1,"Max":3,` +
+			`"Size":2,"LastEvent":"grew to 2"}]`,
+	}
+	c := clientImpl{pbClient: apiClient}
+	res, err := c.QueryScalingGroups()
+	assert.NoError(t, err)
+	assert.Equal(t, []db.ScalingGroup{{ID: 1, BlueprintID: "foo", Min: 1,
+		Max: 3, Size: 2, LastEvent: "grew to 2"}}, res)
+}
+
+func TestUnmarshalTraffic(t *testing.T) {
+	t.Parallel()
+
+	apiClient := mockAPIClient{
+		mockResponse: `[{"From":"web","To":"db","Packets":2,"Bytes":100}]`,
+	}
+	c := clientImpl{pbClient: apiClient}
+	res, err := c.QueryTraffic()
+	assert.NoError(t, err)
+	assert.Equal(t, []db.Traffic{
+		{From: "web", To: "db", Packets: 2, Bytes: 100},
+	}, res)
+}
+
+func TestUnmarshalAudit(t *testing.T) {
+	t.Parallel()
+
+	apiClient := mockAPIClient{
+		mockResponse: `[{"Time":"2017-11-01T12:00:00Z","Method":"/API/Deploy",` +
+			`"Role":"deployer","Result":"ok"}]`,
+	}
+	c := clientImpl{pbClient: apiClient}
+	res, err := c.QueryAudit()
+	assert.NoError(t, err)
+	assert.Equal(t, []api.AuditRecord{{
+		Time:   time.Date(2017, 11, 1, 12, 0, 0, 0, time.UTC),
+		Method: "/API/Deploy",
+		Role:   "deployer",
+		Result: "ok",
+	}}, res)
+}
+
 func TestUnmarshalError(t *testing.T) {
 	t.Parallel()
 
@@ -1,6 +1,7 @@
 // Code generated by mockery v1.0.0
 package mocks
 
+import api "github.com/quilt/quilt/api"
 import db "github.com/quilt/quilt/db"
 import mock "github.com/stretchr/testify/mock"
 import pb "github.com/quilt/quilt/api/pb"
@@ -245,6 +246,98 @@ func (_m *Client) QueryMinionCounters(_a0 string) ([]pb.Counter, error) {
 	return r0, r1
 }
 
+// QueryHealth provides a mock function with given fields: _a0
+func (_m *Client) QueryHealth(_a0 string) ([]pb.HealthCheck, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []pb.HealthCheck
+	if rf, ok := ret.Get(0).(func(string) []pb.HealthCheck); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]pb.HealthCheck)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// QueryAudit provides a mock function with given fields:
+func (_m *Client) QueryAudit() ([]api.AuditRecord, error) {
+	ret := _m.Called()
+
+	var r0 []api.AuditRecord
+	if rf, ok := ret.Get(0).(func() []api.AuditRecord); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]api.AuditRecord)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// QueryScalingGroups provides a mock function with given fields:
+func (_m *Client) QueryScalingGroups() ([]db.ScalingGroup, error) {
+	ret := _m.Called()
+
+	var r0 []db.ScalingGroup
+	if rf, ok := ret.Get(0).(func() []db.ScalingGroup); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db.ScalingGroup)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// QueryTraffic provides a mock function with given fields:
+func (_m *Client) QueryTraffic() ([]db.Traffic, error) {
+	ret := _m.Called()
+
+	var r0 []db.Traffic
+	if rf, ok := ret.Get(0).(func() []db.Traffic); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db.Traffic)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Version provides a mock function with given fields:
 func (_m *Client) Version() (string, error) {
 	ret := _m.Called()
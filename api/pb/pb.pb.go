@@ -18,6 +18,11 @@ It has these top-level messages:
 	MinionCountersRequest
 	CountersReply
 	Counter
+	AuditRequest
+	AuditReply
+	HealthRequest
+	HealthReply
+	HealthCheck
 */
 package pb
 
@@ -201,6 +206,96 @@ func (m *Counter) GetPrevValue() uint64 {
 	return 0
 }
 
+type AuditRequest struct {
+}
+
+func (m *AuditRequest) Reset()                    { *m = AuditRequest{} }
+func (m *AuditRequest) String() string            { return proto.CompactTextString(m) }
+func (*AuditRequest) ProtoMessage()               {}
+func (*AuditRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+type AuditReply struct {
+	Records string `protobuf:"bytes,1,opt,name=Records" json:"Records,omitempty"`
+}
+
+func (m *AuditReply) Reset()                    { *m = AuditReply{} }
+func (m *AuditReply) String() string            { return proto.CompactTextString(m) }
+func (*AuditReply) ProtoMessage()               {}
+func (*AuditReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *AuditReply) GetRecords() string {
+	if m != nil {
+		return m.Records
+	}
+	return ""
+}
+
+// If Host is set, the daemon returns the health of the minion on that host.
+// Otherwise, the server returns its own health.
+type HealthRequest struct {
+	Host string `protobuf:"bytes,1,opt,name=Host" json:"Host,omitempty"`
+}
+
+func (m *HealthRequest) Reset()                    { *m = HealthRequest{} }
+func (m *HealthRequest) String() string            { return proto.CompactTextString(m) }
+func (*HealthRequest) ProtoMessage()               {}
+func (*HealthRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *HealthRequest) GetHost() string {
+	if m != nil {
+		return m.Host
+	}
+	return ""
+}
+
+type HealthReply struct {
+	Checks []*HealthCheck `protobuf:"bytes,1,rep,name=checks" json:"checks,omitempty"`
+}
+
+func (m *HealthReply) Reset()                    { *m = HealthReply{} }
+func (m *HealthReply) String() string            { return proto.CompactTextString(m) }
+func (*HealthReply) ProtoMessage()               {}
+func (*HealthReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *HealthReply) GetChecks() []*HealthCheck {
+	if m != nil {
+		return m.Checks
+	}
+	return nil
+}
+
+type HealthCheck struct {
+	Name    string `protobuf:"bytes,1,opt,name=Name" json:"Name,omitempty"`
+	Healthy bool   `protobuf:"varint,2,opt,name=Healthy" json:"Healthy,omitempty"`
+	Detail  string `protobuf:"bytes,3,opt,name=Detail" json:"Detail,omitempty"`
+}
+
+func (m *HealthCheck) Reset()                    { *m = HealthCheck{} }
+func (m *HealthCheck) String() string            { return proto.CompactTextString(m) }
+func (*HealthCheck) ProtoMessage()               {}
+func (*HealthCheck) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *HealthCheck) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *HealthCheck) GetHealthy() bool {
+	if m != nil {
+		return m.Healthy
+	}
+	return false
+}
+
+func (m *HealthCheck) GetDetail() string {
+	if m != nil {
+		return m.Detail
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*DBQuery)(nil), "DBQuery")
 	proto.RegisterType((*QueryReply)(nil), "QueryReply")
@@ -212,6 +307,11 @@ func init() {
 	proto.RegisterType((*MinionCountersRequest)(nil), "MinionCountersRequest")
 	proto.RegisterType((*CountersReply)(nil), "CountersReply")
 	proto.RegisterType((*Counter)(nil), "Counter")
+	proto.RegisterType((*AuditRequest)(nil), "AuditRequest")
+	proto.RegisterType((*AuditReply)(nil), "AuditReply")
+	proto.RegisterType((*HealthRequest)(nil), "HealthRequest")
+	proto.RegisterType((*HealthReply)(nil), "HealthReply")
+	proto.RegisterType((*HealthCheck)(nil), "HealthCheck")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -229,9 +329,11 @@ type APIClient interface {
 	Query(ctx context.Context, in *DBQuery, opts ...grpc.CallOption) (*QueryReply, error)
 	Version(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*VersionReply, error)
 	QueryCounters(ctx context.Context, in *CountersRequest, opts ...grpc.CallOption) (*CountersReply, error)
+	Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthReply, error)
 	// Only defined on the daemon.
 	Deploy(ctx context.Context, in *DeployRequest, opts ...grpc.CallOption) (*DeployReply, error)
 	QueryMinionCounters(ctx context.Context, in *MinionCountersRequest, opts ...grpc.CallOption) (*CountersReply, error)
+	QueryAudit(ctx context.Context, in *AuditRequest, opts ...grpc.CallOption) (*AuditReply, error)
 }
 
 type aPIClient struct {
@@ -269,6 +371,15 @@ func (c *aPIClient) QueryCounters(ctx context.Context, in *CountersRequest, opts
 	return out, nil
 }
 
+func (c *aPIClient) Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthReply, error) {
+	out := new(HealthReply)
+	err := grpc.Invoke(ctx, "/API/Health", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *aPIClient) Deploy(ctx context.Context, in *DeployRequest, opts ...grpc.CallOption) (*DeployReply, error) {
 	out := new(DeployReply)
 	err := grpc.Invoke(ctx, "/API/Deploy", in, out, c.cc, opts...)
@@ -287,6 +398,15 @@ func (c *aPIClient) QueryMinionCounters(ctx context.Context, in *MinionCountersR
 	return out, nil
 }
 
+func (c *aPIClient) QueryAudit(ctx context.Context, in *AuditRequest, opts ...grpc.CallOption) (*AuditReply, error) {
+	out := new(AuditReply)
+	err := grpc.Invoke(ctx, "/API/QueryAudit", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for API service
 
 type APIServer interface {
@@ -294,9 +414,11 @@ type APIServer interface {
 	Query(context.Context, *DBQuery) (*QueryReply, error)
 	Version(context.Context, *VersionRequest) (*VersionReply, error)
 	QueryCounters(context.Context, *CountersRequest) (*CountersReply, error)
+	Health(context.Context, *HealthRequest) (*HealthReply, error)
 	// Only defined on the daemon.
 	Deploy(context.Context, *DeployRequest) (*DeployReply, error)
 	QueryMinionCounters(context.Context, *MinionCountersRequest) (*CountersReply, error)
+	QueryAudit(context.Context, *AuditRequest) (*AuditReply, error)
 }
 
 func RegisterAPIServer(s *grpc.Server, srv APIServer) {
@@ -357,6 +479,24 @@ func _API_QueryCounters_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _API_Health_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HealthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).Health(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/Health",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).Health(ctx, req.(*HealthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _API_Deploy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(DeployRequest)
 	if err := dec(in); err != nil {
@@ -393,6 +533,24 @@ func _API_QueryMinionCounters_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _API_QueryAudit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuditRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).QueryAudit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/QueryAudit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).QueryAudit(ctx, req.(*AuditRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _API_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "API",
 	HandlerType: (*APIServer)(nil),
@@ -409,6 +567,10 @@ var _API_serviceDesc = grpc.ServiceDesc{
 			MethodName: "QueryCounters",
 			Handler:    _API_QueryCounters_Handler,
 		},
+		{
+			MethodName: "Health",
+			Handler:    _API_Health_Handler,
+		},
 		{
 			MethodName: "Deploy",
 			Handler:    _API_Deploy_Handler,
@@ -417,6 +579,10 @@ var _API_serviceDesc = grpc.ServiceDesc{
 			MethodName: "QueryMinionCounters",
 			Handler:    _API_QueryMinionCounters_Handler,
 		},
+		{
+			MethodName: "QueryAudit",
+			Handler:    _API_QueryAudit_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pb/pb.proto",
@@ -425,28 +591,36 @@ var _API_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("pb/pb.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 364 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x6c, 0x52, 0x4b, 0x4b, 0xc3, 0x40,
-	0x10, 0x4e, 0x4c, 0x9f, 0x93, 0xa6, 0xad, 0xe3, 0x83, 0x10, 0x44, 0xc3, 0xe2, 0x21, 0x50, 0xd8,
-	0x42, 0x8b, 0x67, 0xd1, 0xf6, 0xa0, 0x07, 0xa5, 0x06, 0xe9, 0xbd, 0x29, 0x8b, 0x14, 0xd3, 0x6c,
-	0xcc, 0x43, 0xc8, 0xff, 0xf6, 0x07, 0x48, 0x76, 0x37, 0x6d, 0x53, 0xbd, 0xcd, 0x7c, 0xdf, 0x3c,
-	0x76, 0xbe, 0x6f, 0xc1, 0x8c, 0x83, 0x71, 0x1c, 0xd0, 0x38, 0xe1, 0x19, 0x27, 0x37, 0xd0, 0x9e,
-	0x3f, 0xbe, 0xe5, 0x2c, 0x29, 0xf0, 0x1c, 0x9a, 0xef, 0xab, 0x20, 0x64, 0xb6, 0xee, 0xea, 0x5e,
-	0xd7, 0x97, 0x09, 0x99, 0x00, 0x08, 0xda, 0x67, 0x71, 0x58, 0xe0, 0x2d, 0x58, 0x02, 0x9e, 0xf1,
-	0x28, 0x63, 0x51, 0x96, 0xaa, 0xda, 0x3a, 0x48, 0xc6, 0x60, 0xcd, 0x59, 0x1c, 0xf2, 0xc2, 0x67,
-	0x5f, 0x39, 0x4b, 0x33, 0xbc, 0x06, 0x90, 0xc0, 0x96, 0x45, 0x99, 0xea, 0x39, 0x40, 0x88, 0x05,
-	0x66, 0xd5, 0x10, 0x87, 0x05, 0x19, 0x42, 0x7f, 0xc9, 0x92, 0x74, 0xc3, 0x23, 0x35, 0x80, 0x78,
-	0xd0, 0xdb, 0x21, 0xe5, 0x3b, 0x6c, 0x68, 0xab, 0x5c, 0x4d, 0xab, 0x52, 0x72, 0x0a, 0x83, 0x19,
-	0xcf, 0xa3, 0x8c, 0x25, 0x69, 0xd5, 0x3c, 0x82, 0x8b, 0x97, 0x4d, 0xb4, 0xe1, 0xd1, 0x11, 0x81,
-	0x08, 0x8d, 0x27, 0x9e, 0x56, 0x0f, 0x12, 0x31, 0xb9, 0x03, 0x6b, 0x5f, 0x26, 0x4f, 0xee, 0xac,
-	0x15, 0x60, 0xeb, 0xae, 0xe1, 0x99, 0x93, 0x0e, 0x55, 0x15, 0xfe, 0x8e, 0x21, 0x6b, 0x68, 0x2b,
-	0x10, 0x87, 0x60, 0x2c, 0x3e, 0x3f, 0xd4, 0xd0, 0x32, 0x2c, 0xf7, 0xbc, 0xae, 0xb6, 0xcc, 0x3e,
-	0x91, 0x7b, 0xca, 0xb8, 0x54, 0x7b, 0xb9, 0x0a, 0x73, 0x66, 0x1b, 0xae, 0xee, 0x35, 0x7c, 0x99,
-	0xe0, 0x15, 0x74, 0x17, 0x09, 0xfb, 0x96, 0x4c, 0x43, 0x30, 0x7b, 0x60, 0xf2, 0xa3, 0x83, 0xf1,
-	0xb0, 0x78, 0x46, 0x17, 0x9a, 0xd2, 0xb2, 0x0e, 0x55, 0xe6, 0x39, 0x26, 0xdd, 0xbb, 0x44, 0x34,
-	0x1c, 0xed, 0xf4, 0xc1, 0x01, 0xad, 0x6b, 0xe9, 0x58, 0xf4, 0x50, 0x4a, 0xa2, 0xe1, 0x14, 0x2c,
-	0xd1, 0x5c, 0xdd, 0x8d, 0x43, 0x7a, 0xa4, 0x94, 0xd3, 0xa7, 0x35, 0x51, 0x88, 0x86, 0x1e, 0xb4,
-	0xa4, 0x65, 0xd8, 0xa7, 0x35, 0xb3, 0x9d, 0x1e, 0x3d, 0xf4, 0x52, 0xc3, 0x7b, 0x38, 0x13, 0xe3,
-	0xeb, 0x1e, 0xe0, 0x25, 0xfd, 0xd7, 0x94, 0xbf, 0xab, 0x82, 0x96, 0xf8, 0xaa, 0xd3, 0xdf, 0x00,
-	0x00, 0x00, 0xff, 0xff, 0x89, 0xf3, 0xd8, 0x96, 0xb9, 0x02, 0x00, 0x00,
+	// 481 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x53, 0x4d, 0x6f, 0xd3, 0x40,
+	0x10, 0xb5, 0x9b, 0x34, 0x1f, 0xe3, 0x8f, 0x86, 0x01, 0x2a, 0xcb, 0x42, 0x10, 0x2d, 0x08, 0x59,
+	0x54, 0xda, 0x4a, 0x89, 0x38, 0xa3, 0x92, 0x1c, 0xca, 0x01, 0x14, 0x0c, 0xea, 0xdd, 0x71, 0x57,
+	0xd4, 0xaa, 0xeb, 0x35, 0xf6, 0x1a, 0xc9, 0x7f, 0x91, 0x5f, 0x85, 0xbc, 0x1f, 0x89, 0x5d, 0x55,
+	0xdc, 0xf6, 0xbd, 0x99, 0xd9, 0x9d, 0x37, 0xf3, 0x16, 0x9c, 0x72, 0x7f, 0x59, 0xee, 0x69, 0x59,
+	0x71, 0xc1, 0xc9, 0x1b, 0x98, 0x6e, 0x3f, 0x7f, 0x6f, 0x58, 0xd5, 0xe2, 0x0b, 0x38, 0xfd, 0x99,
+	0xec, 0x73, 0x16, 0xd8, 0x4b, 0x3b, 0x9a, 0xc7, 0x0a, 0x90, 0x15, 0x80, 0x0c, 0xc7, 0xac, 0xcc,
+	0x5b, 0x7c, 0x07, 0x9e, 0xa4, 0x37, 0xbc, 0x10, 0xac, 0x10, 0xb5, 0xce, 0x1d, 0x92, 0xe4, 0x12,
+	0xbc, 0x2d, 0x2b, 0x73, 0xde, 0xc6, 0xec, 0x77, 0xc3, 0x6a, 0x81, 0xaf, 0x01, 0x14, 0xf1, 0xc0,
+	0x0a, 0xa1, 0x6b, 0x7a, 0x0c, 0xf1, 0xc0, 0x31, 0x05, 0x65, 0xde, 0x92, 0x05, 0xf8, 0x37, 0xac,
+	0xaa, 0x33, 0x5e, 0xe8, 0x0b, 0x48, 0x04, 0xee, 0x81, 0xe9, 0xfa, 0x08, 0x60, 0xaa, 0xb1, 0xbe,
+	0xcd, 0x40, 0xf2, 0x0c, 0xce, 0x36, 0xbc, 0x29, 0x04, 0xab, 0x6a, 0x53, 0x7c, 0x01, 0x2f, 0xbf,
+	0x66, 0x45, 0xc6, 0x8b, 0x47, 0x01, 0x44, 0x18, 0x5f, 0xf3, 0xda, 0x34, 0x24, 0xcf, 0xe4, 0x23,
+	0x78, 0xc7, 0x34, 0x25, 0x79, 0x96, 0x6a, 0x22, 0xb0, 0x97, 0xa3, 0xc8, 0x59, 0xcd, 0xa8, 0xce,
+	0x88, 0x0f, 0x11, 0x92, 0xc2, 0x54, 0x93, 0xb8, 0x80, 0xd1, 0xee, 0xfe, 0x97, 0xbe, 0xb4, 0x3b,
+	0x76, 0xef, 0x7c, 0x4b, 0x1e, 0x58, 0x70, 0xa2, 0xde, 0xe9, 0xce, 0xdd, 0xb4, 0x6f, 0x92, 0xbc,
+	0x61, 0xc1, 0x68, 0x69, 0x47, 0xe3, 0x58, 0x01, 0x7c, 0x05, 0xf3, 0x5d, 0xc5, 0xfe, 0xa8, 0xc8,
+	0x58, 0x46, 0x8e, 0x04, 0xf1, 0xc1, 0xbd, 0x6a, 0x6e, 0x33, 0x61, 0x84, 0xbd, 0x07, 0xd0, 0x58,
+	0xcf, 0x24, 0x66, 0x29, 0xaf, 0x6e, 0xcd, 0x56, 0x0c, 0x24, 0x6f, 0xc1, 0xbb, 0x66, 0x49, 0x2e,
+	0xee, 0xfe, 0x27, 0x7c, 0x0d, 0x8e, 0x49, 0x52, 0xb2, 0x27, 0xe9, 0x1d, 0x4b, 0xef, 0x8d, 0x68,
+	0x97, 0xaa, 0xe8, 0xa6, 0x23, 0x63, 0x1d, 0x23, 0x3f, 0xc0, 0xe9, 0xd1, 0x07, 0xa1, 0x76, 0x4f,
+	0x68, 0x00, 0x53, 0x95, 0xd2, 0x4a, 0xfd, 0xb3, 0xd8, 0x40, 0x3c, 0x87, 0xc9, 0x96, 0x89, 0x24,
+	0xcb, 0xe5, 0x0c, 0xe6, 0xb1, 0x46, 0xab, 0xbf, 0x27, 0x30, 0xba, 0xda, 0x7d, 0xc1, 0x25, 0x9c,
+	0x2a, 0x67, 0xce, 0xa8, 0xf6, 0x68, 0xe8, 0xd0, 0xa3, 0x19, 0x89, 0x85, 0x17, 0x07, 0x1b, 0xe0,
+	0x19, 0x1d, 0x5a, 0x26, 0xf4, 0x68, 0xdf, 0x31, 0xc4, 0xc2, 0x35, 0x78, 0xb2, 0xd8, 0xac, 0x17,
+	0x17, 0xf4, 0x91, 0x21, 0x42, 0x9f, 0x0e, 0x76, 0x4f, 0x2c, 0x8c, 0x60, 0xa2, 0xda, 0x45, 0x9f,
+	0x0e, 0x66, 0x18, 0xba, 0xb4, 0x37, 0x2e, 0x95, 0xa9, 0x3c, 0x8c, 0x3e, 0x1d, 0xb8, 0x3f, 0x74,
+	0x69, 0xdf, 0xdc, 0x16, 0x7e, 0x82, 0xe7, 0xb2, 0x91, 0xa1, 0x29, 0xf1, 0x9c, 0x3e, 0xe9, 0xd2,
+	0x27, 0x9a, 0xfa, 0xa0, 0xff, 0xa4, 0x5c, 0x3e, 0x7a, 0xb4, 0x6f, 0x8a, 0xd0, 0xa1, 0x47, 0x4f,
+	0x10, 0x6b, 0x3f, 0x91, 0xff, 0x7c, 0xfd, 0x6f, 0x00, 0x06, 0x9c, 0x60, 0x00, 0xf6, 0x03, 0x00,
+	0x00,
 }
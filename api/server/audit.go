@@ -0,0 +1,143 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/quilt/quilt/api"
+	"github.com/quilt/quilt/api/pb"
+	"github.com/quilt/quilt/blueprint"
+	"github.com/quilt/quilt/connection/auth"
+	"github.com/quilt/quilt/util"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// The RPCs that change the state of the deployment, and so must be audited, along
+// with functions that summarize their requests.
+var auditedRPCs = map[string]func(interface{}) string{
+	"/API/Deploy": summarizeDeploy,
+}
+
+// An auditLog appends a record of each call to an audited RPC to a file, one JSON
+// object per line. The file is only ever appended to.
+type auditLog struct {
+	path string
+
+	sync.Mutex
+}
+
+func newAuditLog(path string) (*auditLog, error) {
+	if err := util.AppFs.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	return &auditLog{path: path}, nil
+}
+
+// intercept is a grpc.UnaryServerInterceptor that records the calls to audited
+// RPCs.
+func (l *auditLog) intercept(ctx context.Context, req interface{},
+	info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+	summarize, ok := auditedRPCs[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	record := api.AuditRecord{
+		Time:    now(),
+		Method:  info.FullMethod,
+		Request: summarize(req),
+		Result:  "ok",
+	}
+
+	if cert, err := auth.PeerCert(ctx); err == nil {
+		record.Role = string(auth.CertRole(cert))
+		record.Certificate = fmt.Sprintf("%x", sha256.Sum256(cert.Raw))
+	}
+
+	reply, err := handler(ctx, req)
+	if err != nil {
+		record.Result = err.Error()
+	}
+
+	if writeErr := l.append(record); writeErr != nil {
+		log.WithError(writeErr).WithField("record", record).Error(
+			"Failed to write audit record")
+	}
+	return reply, err
+}
+
+func (l *auditLog) append(record api.AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	f, err := util.AppFs.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY,
+		0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// read returns every record in the log, oldest first.
+func (l *auditLog) read() ([]api.AuditRecord, error) {
+	l.Lock()
+	contents, err := util.ReadFile(l.path)
+	l.Unlock()
+
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var records []api.AuditRecord
+	for i, line := range bytes.Split([]byte(contents), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+
+		var record api.AuditRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, fmt.Errorf("line %d: %s", i+1, err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func summarizeDeploy(req interface{}) string {
+	deployReq, ok := req.(*pb.DeployRequest)
+	if !ok {
+		return ""
+	}
+
+	bp, err := blueprint.FromJSON(deployReq.Deployment)
+	if err != nil {
+		return "invalid blueprint"
+	}
+
+	return fmt.Sprintf("namespace %q: %d machines, %d containers",
+		bp.Namespace, len(bp.Machines), len(bp.Containers))
+}
+
+// Stored in a variable so that it may be mocked out in unit tests.
+var now = time.Now
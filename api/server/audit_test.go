@@ -0,0 +1,126 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	"github.com/quilt/quilt/api"
+	"github.com/quilt/quilt/api/pb"
+	"github.com/quilt/quilt/connection/auth"
+	"github.com/quilt/quilt/util"
+)
+
+func TestAuditIntercept(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+	defer func() { now = time.Now }()
+
+	timestamp := time.Date(2017, 11, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return timestamp }
+
+	audit, err := newAuditLog("/audit/audit.log")
+	assert.NoError(t, err)
+
+	var handlerErr error
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "reply", handlerErr
+	}
+	call := func(ctx context.Context, method string, req interface{}) (
+		interface{}, error) {
+		return audit.intercept(ctx, req,
+			&grpc.UnaryServerInfo{FullMethod: method}, handler)
+	}
+
+	// Calls that don't change the deployment aren't audited.
+	reply, err := call(context.Background(), "/API/Query", &pb.DBQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, "reply", reply)
+
+	records, err := audit.read()
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+
+	// Local calls don't have a certificate.
+	deployReq := &pb.DeployRequest{Deployment: `{"Namespace": "ns",
+		"Machines": [{"Provider": "Amazon"}]}`}
+	reply, err = call(context.Background(), "/API/Deploy", deployReq)
+	assert.NoError(t, err)
+	assert.Equal(t, "reply", reply)
+
+	cert := &x509.Certificate{Raw: []byte("cert"),
+		Subject: auth.Subject(auth.ReadOnly)}
+	handlerErr = assert.AnError
+	_, err = call(peerContext(cert), "/API/Deploy",
+		&pb.DeployRequest{Deployment: "bad"})
+	assert.Equal(t, assert.AnError, err)
+
+	records, err = audit.read()
+	assert.NoError(t, err)
+	assert.Equal(t, []api.AuditRecord{{
+		Time:    timestamp,
+		Method:  "/API/Deploy",
+		Request: `namespace "ns": 1 machines, 0 containers`,
+		Result:  "ok",
+	}, {
+		Time:        timestamp,
+		Method:      "/API/Deploy",
+		Role:        "read-only",
+		Certificate: fmt.Sprintf("%x", sha256.Sum256([]byte("cert"))),
+		Request:     "invalid blueprint",
+		Result:      assert.AnError.Error(),
+	}}, records)
+}
+
+func TestQueryAudit(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	s := server{runningOnDaemon: true}
+	_, err := s.QueryAudit(nil, nil)
+	assert.EqualError(t, err, "the audit log is disabled")
+
+	s.audit, err = newAuditLog("/audit.log")
+	assert.NoError(t, err)
+
+	reply, err := s.QueryAudit(nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "null", reply.Records)
+
+	record := api.AuditRecord{
+		Time:   time.Date(2017, 11, 1, 12, 0, 0, 0, time.UTC),
+		Method: "/API/Deploy",
+		Result: "ok",
+	}
+	assert.NoError(t, s.audit.append(record))
+
+	reply, err = s.QueryAudit(nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"Time":"2017-11-01T12:00:00Z","Method":"/API/Deploy",`+
+		`"Role":"","Certificate":"","Request":"","Result":"ok"}]`,
+		reply.Records)
+
+	// Corrupt records are reported rather than skipped.
+	assert.NoError(t, util.AppFs.Remove("/audit.log"))
+	assert.NoError(t, afero.WriteFile(util.AppFs, "/audit.log",
+		[]byte("{}\nbad\n"), 0600))
+	_, err = s.QueryAudit(nil, nil)
+	assert.EqualError(t, err,
+		"line 2: invalid character 'b' looking for beginning of value")
+}
+
+func peerContext(cert *x509.Certificate) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+		}},
+	})
+}
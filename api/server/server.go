@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
 	"sync"
@@ -14,19 +15,36 @@ import (
 	"github.com/quilt/quilt/api/pb"
 	"github.com/quilt/quilt/blueprint"
 	"github.com/quilt/quilt/connection"
-	"github.com/quilt/quilt/connection/credentials"
+	"github.com/quilt/quilt/connection/auth"
+	"github.com/quilt/quilt/connection/credentials/tls"
 	"github.com/quilt/quilt/counter"
 	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/health"
 	"github.com/quilt/quilt/version"
 
 	"github.com/docker/distribution/reference"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc"
 
 	log "github.com/Sirupsen/logrus"
 )
 
 var errDaemonOnlyRPC = errors.New("only defined on the daemon")
 
+var readRoles = []auth.Role{auth.ReadOnly, auth.Deployer, auth.Admin}
+
+// The roles allowed to call each RPC over TLS. Listeners without TLS are secured
+// some other way, such as by Unix permissions, so they may call every RPC.
+var policy = auth.Policy{
+	"/API/Query":               readRoles,
+	"/API/Version":             readRoles,
+	"/API/QueryCounters":       readRoles,
+	"/API/QueryMinionCounters": readRoles,
+	"/API/Health":              readRoles,
+	"/API/Deploy":              {auth.Deployer, auth.Admin},
+	"/API/QueryAudit":          {auth.Admin},
+}
+
 type server struct {
 	conn db.Conn
 
@@ -38,49 +56,124 @@ type server struct {
 
 	// The credentials to use while connecting to clients in the cluster.
 	clientCreds connection.Credentials
+
+	// The audit log of calls that change the deployment. Only set on the daemon.
+	audit *auditLog
+}
+
+// A Listener is an address that the API is served on, along with the credentials
+// that secure connections to it.
+type Listener struct {
+	Address string
+	Creds   connection.Credentials
 }
 
 // Run starts a server that responds to connections from the CLI. It runs on both
 // the daemon and on the minion. The server provides various client-relevant
 // methods, such as starting deployments, and querying the state of the system.
 // This is in contrast to the minion server (minion/pb/pb.proto), which facilitates
-// the actual deployment.
-func Run(conn db.Conn, listenAddr string, runningOnDaemon bool,
-	creds connection.Credentials) error {
-	proto, addr, err := api.ParseListenAddress(listenAddr)
-	if err != nil {
-		return err
+// the actual deployment. The server listens on each of `listeners`, and secures
+// each according to the listener's credentials. `clientCreds` are used when
+// proxying queries to the cluster. If `auditPath` is set, calls that change the
+// deployment are recorded in the audit log at that path.
+func Run(conn db.Conn, listeners []Listener, runningOnDaemon bool,
+	clientCreds connection.Credentials, auditPath string) error {
+
+	type listener struct {
+		proto, addr string
+		creds       connection.Credentials
+	}
+	var parsed []listener
+	for _, l := range listeners {
+		proto, addr, err := api.ParseListenAddress(l.Address)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, listener{proto, addr, l.Creds})
 	}
 
-	// Don't enforce TLS on inbound local connections. This way, users don't
-	// need to supply credentials when making local connections to the daemon
-	// (e.g. when running a spec). Instead, local connections should be secured
-	// using Unix permissions. Connections to the cluster (i.e. proxied
-	// connections) still need to use the proper credentials.
-	serverCreds := creds
-	if proto == "unix" {
-		serverCreds = credentials.Insecure{}
+	var audit *auditLog
+	if auditPath != "" {
+		var err error
+		if audit, err = newAuditLog(auditPath); err != nil {
+			return fmt.Errorf("audit log: %s", err)
+		}
 	}
 
-	sock, s := connection.Server(proto, addr, serverCreds.ServerOpts())
+	apiServer := server{conn, runningOnDaemon, clientCreds, audit}
 
-	// Cleanup the socket if we're interrupted.
+	// Cleanup the sockets if we're interrupted.
+	var socksLock sync.Mutex
+	var socks []net.Listener
 	sigc := make(chan os.Signal, 1)
 	signal.Notify(sigc, os.Interrupt, os.Kill, syscall.SIGTERM, syscall.SIGHUP)
 	go func(c chan os.Signal) {
 		sig := <-c
 		log.Printf("Caught signal %s: shutting down.\n", sig)
-		sock.Close()
+		socksLock.Lock()
+		for _, sock := range socks {
+			sock.Close()
+		}
 		os.Exit(0)
 	}(sigc)
 
-	apiServer := server{conn, runningOnDaemon, creds}
-	pb.RegisterAPIServer(s, apiServer)
-	s.Serve(sock)
+	var wg sync.WaitGroup
+	for _, l := range parsed {
+		wg.Add(1)
+		go func(l listener) {
+			defer wg.Done()
+
+			opts := serverOpts(l.proto, l.creds, audit)
+			sock, s := connection.Server(l.proto, l.addr, opts)
+
+			socksLock.Lock()
+			socks = append(socks, sock)
+			socksLock.Unlock()
+
+			log.WithFields(log.Fields{
+				"proto": l.proto,
+				"addr":  l.addr,
+			}).Info("Serving the API")
+			pb.RegisterAPIServer(s, apiServer)
+			s.Serve(sock)
+		}(l)
+	}
+	wg.Wait()
 
 	return nil
 }
 
+// serverOpts returns the options for a server listening on `proto` and secured
+// by `creds`. Calls that change the deployment are recorded in `audit`, if it's
+// set.
+func serverOpts(proto string, creds connection.Credentials,
+	audit *auditLog) []grpc.ServerOption {
+
+	// Audit calls before authorizing them, so that denied calls are recorded as
+	// well.
+	var interceptors []grpc.UnaryServerInterceptor
+	if audit != nil {
+		interceptors = append(interceptors, audit.intercept)
+	}
+
+	// Listeners without TLS, such as a Unix socket secured by its permissions,
+	// have no client certificate to take a role from, so they may call every
+	// RPC.
+	if _, isTLS := creds.(tls.TLS); isTLS {
+		interceptors = append(interceptors, policy.Intercept)
+	} else if proto != "unix" {
+		log.Warn("Serving the API over TCP without TLS, so any client " +
+			"that can reach it may call every RPC")
+	}
+
+	opts := creds.ServerOpts()
+	if len(interceptors) > 0 {
+		opts = append(opts, grpc.UnaryInterceptor(
+			connection.ChainUnaryInterceptors(interceptors...)))
+	}
+	return opts
+}
+
 // Query runs in two modes: daemon, or local. If in local mode, Query simply
 // returns the requested table from its local database. If in daemon mode,
 // Query proxies certain table requests (e.g. Container and Connection) to the
@@ -125,6 +218,10 @@ func (s server) queryLocal(table db.TableType) (interface{}, error) {
 		return s.conn.SelectFromBlueprint(nil), nil
 	case db.ImageTable:
 		return s.conn.SelectFromImage(nil), nil
+	case db.ScalingGroupTable:
+		return s.conn.SelectFromScalingGroup(nil), nil
+	case db.TrafficTable:
+		return s.conn.SelectFromTraffic(nil), nil
 	default:
 		return nil, fmt.Errorf("unrecognized table: %s", table)
 	}
@@ -134,7 +231,7 @@ func (s server) queryFromDaemon(table db.TableType) (
 	interface{}, error) {
 
 	switch table {
-	case db.MachineTable, db.BlueprintTable:
+	case db.MachineTable, db.BlueprintTable, db.ScalingGroupTable:
 		return s.queryLocal(table)
 	}
 
@@ -154,6 +251,8 @@ func (s server) queryFromDaemon(table db.TableType) (
 		return leaderClient.QueryLoadBalancers()
 	case db.ImageTable:
 		return leaderClient.QueryImages()
+	case db.TrafficTable:
+		return leaderClient.QueryTraffic()
 	default:
 		return nil, fmt.Errorf("unrecognized table: %s", table)
 	}
@@ -187,6 +286,37 @@ func (s server) QueryCounters(ctx context.Context, in *pb.CountersRequest) (
 	return &pb.CountersReply{Counters: counter.Dump()}, nil
 }
 
+// Health returns the health of the subsystems running in this process, or, if
+// `in.Host` is set, of the minion on that host.
+func (s server) Health(ctx context.Context, in *pb.HealthRequest) (
+	*pb.HealthReply, error) {
+
+	if in.Host == "" {
+		return &pb.HealthReply{Checks: health.Dump()}, nil
+	}
+
+	if !s.runningOnDaemon {
+		return nil, errDaemonOnlyRPC
+	}
+
+	clnt, err := newClient(api.RemoteAddress(in.Host), s.clientCreds)
+	if err != nil {
+		return nil, err
+	}
+	defer clnt.Close()
+
+	checks, err := clnt.QueryHealth("")
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &pb.HealthReply{}
+	for i := range checks {
+		reply.Checks = append(reply.Checks, &checks[i])
+	}
+	return reply, nil
+}
+
 func (s server) Deploy(cts context.Context, deployReq *pb.DeployRequest) (
 	*pb.DeployReply, error) {
 
@@ -206,10 +336,21 @@ func (s server) Deploy(cts context.Context, deployReq *pb.DeployRequest) (
 		}
 	}
 
-	err = s.conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
+	if err := newBlueprint.ContainerNetwork().Validate(); err != nil {
+		return &pb.DeployReply{}, err
+	}
+
+	err = s.conn.Txn(db.BlueprintTable,
+		db.MachineTable).Run(func(view db.Database) error {
 		bp, err := view.GetBlueprint()
 		if err != nil {
 			bp = view.InsertBlueprint()
+		} else if len(view.SelectFromMachine(nil)) > 0 {
+			err := checkNetworkChange(bp.ContainerNetwork(),
+				newBlueprint.ContainerNetwork())
+			if err != nil {
+				return err
+			}
 		}
 
 		bp.Blueprint = newBlueprint
@@ -233,6 +374,42 @@ func (s server) Deploy(cts context.Context, deployReq *pb.DeployRequest) (
 	return &pb.DeployReply{}, nil
 }
 
+// checkNetworkChange returns an error if the container network can't change from
+// `old` to `new` while machines are running.  Minions configure the network when
+// they boot, so only the encapsulation, which the daemon applies to every minion,
+// may change.
+func checkNetworkChange(old, new blueprint.NetworkConfig) error {
+	old.Encapsulation = new.Encapsulation
+	if old != new {
+		return errors.New("the container network can't change while " +
+			"machines are running, except for its encapsulation")
+	}
+	return nil
+}
+
+func (s server) QueryAudit(_ context.Context, _ *pb.AuditRequest) (
+	*pb.AuditReply, error) {
+
+	if !s.runningOnDaemon {
+		return nil, errDaemonOnlyRPC
+	}
+
+	if s.audit == nil {
+		return nil, errors.New("the audit log is disabled")
+	}
+
+	records, err := s.audit.read()
+	if err != nil {
+		return nil, err
+	}
+
+	json, err := json.Marshal(records)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.AuditReply{Records: string(json)}, nil
+}
+
 func (s server) Version(_ context.Context, _ *pb.VersionRequest) (
 	*pb.VersionReply, error) {
 	return &pb.VersionReply{Version: version.Version}, nil
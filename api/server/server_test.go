@@ -3,6 +3,8 @@ package server
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"testing"
 	"time"
 
@@ -14,7 +16,9 @@ import (
 	"github.com/quilt/quilt/api/pb"
 	"github.com/quilt/quilt/blueprint"
 	"github.com/quilt/quilt/connection"
+	"github.com/quilt/quilt/connection/credentials"
 	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/health"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -37,7 +41,7 @@ func TestQueryErrors(t *testing.T) {
 		client.Client, error) {
 		return nil, errors.New("get leader error")
 	}
-	s := server{db.New(), true, nil}
+	s := server{db.New(), true, nil, nil}
 	_, err = s.Query(context.Background(),
 		&pb.DBQuery{Table: string(db.ContainerTable)})
 	assert.EqualError(t, err, "get leader error")
@@ -62,10 +66,13 @@ func TestQueryMachinesDaemon(t *testing.T) {
 
 	exp := `[{"ID":1,"BlueprintID":"","Role":"Master","Provider":"Amazon",` +
 		`"Region":"","Size":"size","DiskSize":0,"SSHKeys":null,"FloatingIP":"",` +
-		`"Preemptible":false,"CloudID":"","PublicIP":"8.8.8.8",` +
-		`"PrivateIP":"9.9.9.9","Status":"connected"}]`
+		`"Preemptible":false,"Image":"","BootScript":"","CloudID":"",` +
+		`"PublicIP":"8.8.8.8","PrivateIP":"9.9.9.9","Error":"",` +
+		`"BootHash":"","Status":"connected",` +
+		`"DisconnectTime":"0001-01-01T00:00:00Z","Replacements":0,` +
+		`"HostKeys":null}]`
 
-	checkQuery(t, server{conn, true, nil}, db.MachineTable, exp)
+	checkQuery(t, server{conn, true, nil, nil}, db.MachineTable, exp)
 }
 
 func TestQueryContainersCluster(t *testing.T) {
@@ -85,7 +92,7 @@ func TestQueryContainersCluster(t *testing.T) {
 	exp := `[{"DockerID":"docker-id","Command":["cmd","arg"],` +
 		`"Created":"0001-01-01T00:00:00Z","Image":"image"}]`
 
-	checkQuery(t, server{conn, false, nil}, db.ContainerTable, exp)
+	checkQuery(t, server{conn, false, nil, nil}, db.ContainerTable, exp)
 }
 
 func TestQueryContainersDaemon(t *testing.T) {
@@ -134,7 +141,7 @@ func TestQueryContainersDaemon(t *testing.T) {
 		`"Image":"notScheduled"},{"BlueprintID":"onWorker",` +
 		`"DockerID":"dockerID","Created":"0001-01-01T00:00:00Z",` +
 		`"Image":"onWorker"}]`
-	checkQuery(t, server{conn, true, nil}, db.ContainerTable, exp)
+	checkQuery(t, server{conn, true, nil, nil}, db.ContainerTable, exp)
 }
 
 func TestBadDeployment(t *testing.T) {
@@ -183,6 +190,54 @@ func testInvalidImage(t *testing.T, s server, img, expErr string) {
 	assert.EqualError(t, err, expErr)
 }
 
+func TestInvalidNetwork(t *testing.T) {
+	conn := db.New()
+	s := server{conn: conn, runningOnDaemon: true}
+
+	deployment := `{"Network": {"Subnet": "10.0.0.0/16", "Encapsulation": "gre"}}`
+	_, err := s.Deploy(context.Background(),
+		&pb.DeployRequest{Deployment: deployment})
+	assert.EqualError(t, err, `unknown network encapsulation "gre": `+
+		`must be "stt", "geneve" or "vxlan"`)
+
+	_, err = conn.GetBlueprintNamespace()
+	assert.Error(t, err)
+}
+
+func TestNetworkChange(t *testing.T) {
+	conn := db.New()
+	s := server{conn: conn, runningOnDaemon: true}
+
+	deploy := func(deployment string) error {
+		_, err := s.Deploy(context.Background(),
+			&pb.DeployRequest{Deployment: deployment})
+		return err
+	}
+
+	// Without machines, the network may change freely.
+	assert.NoError(t, deploy(`{"Network": {"Subnet": "10.0.0.0/16"}}`))
+	assert.NoError(t, deploy(`{"Network": {"Subnet": "172.30.0.0/16"}}`))
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		view.Commit(view.InsertMachine())
+		return nil
+	})
+
+	err := deploy(`{"Network": {"Subnet": "10.0.0.0/16"}}`)
+	assert.EqualError(t, err, "the container network can't change while "+
+		"machines are running, except for its encapsulation")
+	err = deploy(`{"Network": {"Subnet": "172.30.0.0/16", "MTU": 8950}}`)
+	assert.Error(t, err)
+
+	bps := conn.SelectFromBlueprint(nil)
+	assert.Equal(t, "172.30.0.0/16", bps[0].ContainerNetwork().Subnet)
+
+	assert.NoError(t, deploy(`{"Network": {"Subnet": "172.30.0.0/16", `+
+		`"Encapsulation": "vxlan"}}`))
+	bps = conn.SelectFromBlueprint(nil)
+	assert.Equal(t, blueprint.VXLAN, bps[0].ContainerNetwork().Encapsulation)
+}
+
 func TestDeploy(t *testing.T) {
 	conn := db.New()
 	s := server{conn: conn, runningOnDaemon: true}
@@ -312,6 +367,36 @@ func TestDaemonOnlyEndpoints(t *testing.T) {
 
 	_, err = server{runningOnDaemon: false}.Deploy(nil, nil)
 	assert.EqualError(t, err, errDaemonOnlyRPC.Error())
+
+	_, err = server{runningOnDaemon: false}.QueryAudit(nil, nil)
+	assert.EqualError(t, err, errDaemonOnlyRPC.Error())
+
+	_, err = server{runningOnDaemon: false}.Health(nil,
+		&pb.HealthRequest{Host: "9.9.9.9"})
+	assert.EqualError(t, err, errDaemonOnlyRPC.Error())
+}
+
+func TestHealth(t *testing.T) {
+	health.New("engine", time.Minute).Report(nil)
+
+	reply, err := server{runningOnDaemon: true}.Health(nil, &pb.HealthRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, []*pb.HealthCheck{{Name: "engine", Healthy: true}},
+		reply.Checks)
+
+	minionChecks := []pb.HealthCheck{{Name: "etcd", Detail: "no etcd leader"}}
+	newClient = func(host string, _ connection.Credentials) (client.Client, error) {
+		assert.Equal(t, api.RemoteAddress("9.9.9.9"), host)
+		mc := new(mocks.Client)
+		mc.On("QueryHealth", "").Return(minionChecks, nil)
+		mc.On("Close").Return(nil)
+		return mc, nil
+	}
+
+	reply, err = server{runningOnDaemon: true}.Health(nil,
+		&pb.HealthRequest{Host: "9.9.9.9"})
+	assert.NoError(t, err)
+	assert.Equal(t, []*pb.HealthCheck{&minionChecks[0]}, reply.Checks)
 }
 
 func TestQueryImagesCluster(t *testing.T) {
@@ -327,7 +412,7 @@ func TestQueryImagesCluster(t *testing.T) {
 	})
 
 	exp := `[{"ID":1,"Name":"foo","Dockerfile":"","DockerID":"","Status":""}]`
-	checkQuery(t, server{conn, false, nil}, db.ImageTable, exp)
+	checkQuery(t, server{conn, false, nil, nil}, db.ImageTable, exp)
 }
 
 func TestQueryImagesDaemon(t *testing.T) {
@@ -342,5 +427,66 @@ func TestQueryImagesDaemon(t *testing.T) {
 	}
 
 	exp := `[{"ID":0,"Name":"bar","Dockerfile":"","DockerID":"","Status":""}]`
-	checkQuery(t, server{db.New(), true, nil}, db.ImageTable, exp)
+	checkQuery(t, server{db.New(), true, nil, nil}, db.ImageTable, exp)
+}
+
+func TestQueryTrafficCluster(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		traffic := view.InsertTraffic()
+		traffic.From = "web"
+		traffic.To = "db"
+		traffic.Bytes = 100
+		view.Commit(traffic)
+		return nil
+	})
+
+	exp := `[{"From":"web","To":"db","Packets":0,"Bytes":100}]`
+	checkQuery(t, server{conn, false, nil, nil}, db.TrafficTable, exp)
+}
+
+func TestQueryTrafficDaemon(t *testing.T) {
+	newLeaderClient = func(_ []db.Machine, _ connection.Credentials) (
+		client.Client, error) {
+		mc := new(mocks.Client)
+		mc.On("QueryTraffic").Return([]db.Traffic{{
+			From: "public", To: "web", Packets: 1,
+		}}, nil)
+		mc.On("Close").Return(nil)
+		return mc, nil
+	}
+
+	exp := `[{"From":"public","To":"web","Packets":1,"Bytes":0}]`
+	checkQuery(t, server{db.New(), true, nil, nil}, db.TrafficTable, exp)
+}
+
+func TestRunMultipleListeners(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quilt-server")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	insecure := credentials.Insecure{}
+	err = Run(db.New(), []Listener{
+		{Address: "unix://" + dir + "/a.sock", Creds: insecure},
+		{Address: "bad", Creds: insecure},
+	}, true, insecure, "")
+	assert.EqualError(t, err, "malformed listen address: bad")
+
+	addrs := []string{"unix://" + dir + "/a.sock", "unix://" + dir + "/b.sock"}
+	var listeners []Listener
+	for _, addr := range addrs {
+		listeners = append(listeners, Listener{Address: addr, Creds: insecure})
+	}
+	go Run(db.New(), listeners, true, insecure, "")
+
+	for _, addr := range addrs {
+		c, err := client.New(addr, insecure)
+		assert.NoError(t, err)
+
+		_, err = c.Version()
+		assert.NoError(t, err)
+		c.Close()
+	}
 }
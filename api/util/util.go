@@ -35,11 +35,17 @@ func GetContainer(containers []db.Container, blueprintID string) (db.Container,
 
 // GetPublicIP returns the public IP for the machine with the given private IP.
 func GetPublicIP(machines []db.Machine, privateIP string) (string, error) {
+	m, err := GetMachine(machines, privateIP)
+	return m.PublicIP, err
+}
+
+// GetMachine returns the machine with the given private IP.
+func GetMachine(machines []db.Machine, privateIP string) (db.Machine, error) {
 	for _, m := range machines {
 		if m.PrivateIP == privateIP {
-			return m.PublicIP, nil
+			return m, nil
 		}
 	}
 
-	return "", fmt.Errorf("no machine with private IP %s", privateIP)
+	return db.Machine{}, fmt.Errorf("no machine with private IP %s", privateIP)
 }
@@ -13,6 +13,14 @@ let fs = require('fs'); // eslint-disable-line prefer-const
 const githubCache = {};
 const objectHasKey = Object.prototype.hasOwnProperty;
 
+// The system containers whose images may be overridden by the deployment.
+const systemImageNames = ['etcd', 'ovs', 'registry', 'cadvisor', 'router'];
+
+// The DNS domain of hostnames in deployments that don't set one.
+const defaultDNSDomain = 'q';
+const dnsNamePattern =
+  /^([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)*[a-z0-9]([a-z0-9-]*[a-z0-9])?$/;
+
 /**
  * Gets the public key associated with a github username.
  * @param {string} user - The GitHub username.
@@ -107,6 +115,8 @@ global._quiltDeployment = new Deployment({});
 // hostname, but since the public internet is not a container or load balancer,
 // we need a special label for it).
 const publicInternetLabel = 'public';
+const cidrLabelPrefix = 'cidr:';
+const domainLabelPrefix = 'domain:';
 
 // Global unique ID counter.
 let uniqueIDCounter = 0;
@@ -130,19 +140,68 @@ function createDeployment(deploymentOpts) {
  *   the behavor of the namespace.  Options include: `maxPrice` which defines
  *   the price that should be bid in spot auctions for preemptible machines,
  *   `namespace` which instructs the deployment what namespace it should
- *   operate in, and `adminACL` which defines what network traffic should be
- *   allowed to access the deployment.
+ *   operate in, `adminACL` which defines what network traffic should be
+ *   allowed to access the deployment, `healthPolicy` which defines when
+ *   machines that stop responding should be replaced, and `systemImages` and
+ *   `disableCadvisor` which configure the containers Quilt runs on each
+ *   machine, `dnsUpstream` which lists the DNS resolvers that queries for
+ *   external names are forwarded to, `dnsDomain` and
+ *   `dnsNamespaceSubdomain` which set the DNS domain of hostnames, and
+ *   `network` which configures the container network.
+ * @param {Object} [deploymentOpts.healthPolicy] - `replaceAfter` is the number
+ *   of minutes a machine may stay disconnected before it is terminated and
+ *   booted again, and `maxReplacements` limits how many times a single machine
+ *   is replaced (3 by default). Machines are never replaced if `replaceAfter`
+ *   isn't set.
+ * @param {Object.<string, string>} [deploymentOpts.systemImages] - Overrides
+ *   the images of Quilt's system containers, e.g. to use a patched etcd or an
+ *   internal mirror. The keys may be `etcd`, `ovs`, `registry`, `cadvisor`
+ *   and `router`.
+ *   Running containers are upgraded when their image changes.
+ * @param {boolean} [deploymentOpts.disableCadvisor] - If true, workers don't
+ *   run cadvisor.
+ * @param {string[]} [deploymentOpts.dnsUpstream] - The addresses of the DNS
+ *   resolvers that containers' queries for names outside of Quilt are
+ *   forwarded to, e.g. `8.8.8.8` or `10.1.0.2:53`. By default, each machine
+ *   forwards queries to its own resolvers.
+ * @param {string} [deploymentOpts.dnsDomain] - The DNS domain that hostnames
+ *   belong to, e.g. `svc.mycompany.internal`. Defaults to `q`.
+ * @param {boolean} [deploymentOpts.dnsNamespaceSubdomain] - If true,
+ *   hostnames belong to a subdomain of `dnsDomain` named after the namespace,
+ *   e.g. `web.my-namespace.svc.mycompany.internal`.
+ * @param {Object} [deploymentOpts.network] - The container network.
+ *   `subnet` is the CIDR that containers are given addresses in (`10.0.0.0/8`
+ *   by default), and should not overlap with the networks the machines are
+ *   in. `gatewayIP` and `loadBalancerIP` default to the first two addresses
+ *   of the subnet. `mtu` is the MTU of container interfaces (1400 by
+ *   default), and `encapsulation` is the tunneling protocol used between
+ *   machines: `stt` (the default), `geneve` or `vxlan`. The network is fixed
+ *   when a machine boots, so changing it only affects new machines.
  */
 function Deployment(deploymentOpts = {}) {
   this.maxPrice = getNumber('maxPrice', deploymentOpts.maxPrice);
   this.namespace = deploymentOpts.namespace || 'default-namespace';
   this.adminACL = getStringArray('adminACL', deploymentOpts.adminACL);
+  this.healthPolicy = getHealthPolicy(deploymentOpts.healthPolicy);
+  this.systemImages = getSystemImages(deploymentOpts.systemImages);
+  this.disableCadvisor = getBoolean('disableCadvisor',
+    deploymentOpts.disableCadvisor);
+  this.dnsUpstream = getStringArray('dnsUpstream', deploymentOpts.dnsUpstream);
+  this.dnsDomain = getDNSDomain(deploymentOpts.dnsDomain);
+  this.dnsNamespaceSubdomain = getBoolean('dnsNamespaceSubdomain',
+    deploymentOpts.dnsNamespaceSubdomain);
+  this.network = getNetwork(deploymentOpts.network);
+  if (this.dnsNamespaceSubdomain && !dnsNamePattern.test(this.namespace)) {
+    throw new Error('dnsNamespaceSubdomain requires the namespace to be a ' +
+      `valid DNS label (was: ${stringify(this.namespace)})`);
+  }
 
   checkExtraKeys(deploymentOpts, this);
 
   this.machines = [];
   this.containers = new Set();
   this.loadBalancers = [];
+  this.ingresses = [];
 }
 
 /**
@@ -200,6 +259,17 @@ function hash(str) {
   return shaSum.digest('hex');
 }
 
+/**
+ * @returns {string} The DNS domain that the deployment's hostnames belong to.
+ */
+Deployment.prototype.domain = function domain() {
+  const base = this.dnsDomain || defaultDNSDomain;
+  if (this.dnsNamespaceSubdomain) {
+    return `${this.namespace}.${base}`;
+  }
+  return base;
+};
+
 // Convert the deployment to the QRI deployment format.
 Deployment.prototype.toQuiltRepresentation = function toQuiltRepresentation() {
   setQuiltIDs(this.machines);
@@ -213,10 +283,19 @@ Deployment.prototype.toQuiltRepresentation = function toQuiltRepresentation() {
   // Convert the load balancers.
   this.loadBalancers.forEach((lb) => {
     connections = connections.concat(lb.getQuiltConnections());
-    loadBalancers.push({
+    const quiltLB = {
       name: lb.name,
       hostnames: lb.containers.map(c => c.hostname),
-    });
+    };
+    if (lb.probe !== undefined) {
+      quiltLB.probe = lb.probe;
+    }
+    loadBalancers.push(quiltLB);
+  });
+
+  const ingresses = this.ingresses.map((ing) => {
+    connections = connections.concat(ing.getQuiltConnections());
+    return ing.toQuiltRepresentation();
   });
 
   this.containers.forEach((c) => {
@@ -228,6 +307,7 @@ Deployment.prototype.toQuiltRepresentation = function toQuiltRepresentation() {
   const quiltDeployment = {
     machines: this.machines,
     loadBalancers,
+    ingresses,
     containers,
     connections,
     placements,
@@ -235,11 +315,27 @@ Deployment.prototype.toQuiltRepresentation = function toQuiltRepresentation() {
     namespace: this.namespace,
     adminACL: this.adminACL,
     maxPrice: this.maxPrice,
+    healthPolicy: this.healthPolicy,
+    systemImages: this.systemImages,
+    disableCadvisor: this.disableCadvisor,
+    dnsUpstream: this.dnsUpstream,
+    dnsDomain: this.dnsDomain,
+    dnsNamespaceSubdomain: this.dnsNamespaceSubdomain,
+    network: this.network,
   };
   vet(quiltDeployment);
   return quiltDeployment;
 };
 
+/**
+ * @private
+ * @param {string} label - The label of one end of a connection.
+ * @returns {boolean} Whether `label` refers to an address outside of Quilt.
+ */
+function isExternalLabel(label) {
+  return label.startsWith(cidrLabelPrefix) || label.startsWith(domainLabelPrefix);
+}
+
 /**
  * Checks if the namespace is lower case, and if all referenced 
  * containers in connections and load balancers are really deployed.
@@ -254,8 +350,9 @@ function vet(deployment) {
                   'uppercase letters. Namespaces must be lowercase.');
   }
   const lbHostnames = deployment.loadBalancers.map(l => l.name);
+  const ingressHostnames = deployment.ingresses.map(i => i.name);
   const containerHostnames = deployment.containers.map(c => c.hostname);
-  const hostnames = lbHostnames.concat(containerHostnames);
+  const hostnames = lbHostnames.concat(ingressHostnames, containerHostnames);
 
   const hostnameMap = { [publicInternetLabel]: true };
   hostnames.forEach((hostname) => {
@@ -267,13 +364,54 @@ function vet(deployment) {
 
   deployment.connections.forEach((conn) => {
     [conn.from, conn.to].forEach((host) => {
-      if (!hostnameMap[host]) {
+      if (!hostnameMap[host] && !isExternalLabel(host)) {
         throw new Error(`connection ${stringify(conn)} references ` +
                     `an undefined hostname: ${host}`);
       }
     });
   });
 
+  deployment.ingresses.forEach((ing) => {
+    ing.routes.forEach((route) => {
+      if (!hostnameMap[route.to]) {
+        throw new Error(`ingress ${ing.name} routes to an undefined ` +
+                    `container or load balancer: ${route.to}`);
+      }
+    });
+  });
+
+  // Public load balancers and ingresses accept connections on every worker, so
+  // their ports can't be shared with anything else.
+  const lbNames = {};
+  lbHostnames.forEach((name) => {
+    lbNames[name] = true;
+  });
+  const ingressNames = {};
+  ingressHostnames.forEach((name) => {
+    ingressNames[name] = true;
+  });
+  const publicPorts = {};
+  deployment.connections.forEach((conn) => {
+    if (conn.from !== publicInternetLabel) {
+      return;
+    }
+    const owners = publicPorts[conn.minPort] || [];
+    if (!owners.includes(conn.to)) {
+      publicPorts[conn.minPort] = owners.concat([conn.to]);
+    }
+  });
+  Object.keys(publicPorts).forEach((port) => {
+    const owners = publicPorts[port];
+    if (owners.length > 1 && owners.some(owner => lbNames[owner])) {
+      throw new Error(`public port ${port} is exposed by a load balancer, ` +
+                  `so it can't be shared by ${owners.join(', ')}`);
+    }
+    if (owners.length > 1 && owners.some(owner => ingressNames[owner])) {
+      throw new Error(`public port ${port} is exposed by an ingress, ` +
+                  `so it can't be shared by ${owners.join(', ')}`);
+    }
+  });
+
   const dockerfiles = {};
   deployment.containers.forEach((c) => {
     const name = c.image.name;
@@ -321,22 +459,40 @@ Deployment.prototype.deploy = function deploy(list) {
  * @implements {Connectable}
  * @constructor
  *
+ * @example <caption>Only balance traffic across the containers that respond to
+ * HTTP requests for /healthz on port 80.</caption>
+ * const lb = new LoadBalancer('web', containers, {
+ *   probe: {port: 80, path: '/healthz'},
+ * });
+ *
  * @param {string} name - The name of the load balancer.
  * @param {Container[]} containers - The containers behind the load balancer.
+ * @param {Object} [optionalArgs] - Optional arguments that modify the load
+ *   balancer.
+ * @param {Object} [optionalArgs.probe] - A health check for the containers
+ *   behind the load balancer. Containers that fail the probe stop receiving
+ *   load balanced traffic until they pass it again.
+ * @param {number} optionalArgs.probe.port - The port the containers are
+ *   probed on.
+ * @param {string} [optionalArgs.probe.path] - If set, the probe is an HTTP GET
+ *   request for this path, which must respond with a status below 400.
+ *   Otherwise, the probe only checks that a TCP connection can be opened.
  */
-function LoadBalancer(name, containers) {
+function LoadBalancer(name, containers, optionalArgs = {}) {
   if (typeof name !== 'string') {
     throw new Error(`name must be a string; was ${stringify(name)}`);
   }
   this.name = uniqueHostname(name);
   this.containers = boxContainers(containers);
+  this.probe = getProbe(optionalArgs.probe);
 
   this.allowedInboundConnections = [];
+  this.incomingPublic = [];
 }
 
 // Get the Quilt hostname that represents the entire load balancer.
 LoadBalancer.prototype.hostname = function lbHostname() {
-  return `${this.name}.q`;
+  return `${this.name}.${hostnameDomain()}`;
 };
 
 LoadBalancer.prototype.deploy = function lbDeploy(deployment) {
@@ -347,13 +503,32 @@ LoadBalancer.prototype.deploy = function lbDeploy(deployment) {
  * Allows inbound connections to the load balancer. Note that this does not
  * allow direct connections to the containers behind the load balancer.
  *
- * @param {Container|Container[]} srcArg - The containers that can open
- *   connections to this load balancer.
+ * Connections from publicInternet can be made to any worker machine, and are
+ * spread across the containers behind the load balancer, regardless of the
+ * machine they run on.
+ *
+ * @example <caption>Expose port 80 of the web containers to the public
+ * internet through a load balancer.</caption>
+ * const web = new LoadBalancer('web', webContainers);
+ * web.allowFrom(publicInternet, 80);
+ *
+ * @param {Container|Container[]|publicInternet} srcArg - The containers that
+ *   can open connections to this load balancer.
  * @param {int|Port|PortRange} portRange - The ports on which containers can
- *   open connections.
+ *   open connections. Connections from publicInternet must be to a single port.
  * @returns {void}
  */
 LoadBalancer.prototype.allowFrom = function lbAllowFrom(srcArg, portRange) {
+  if (srcArg === publicInternet) {
+    const range = boxRange(portRange);
+    if (range.min !== range.max) {
+      throw new Error('public internet can only connect to single ports ' +
+              'and not to port ranges');
+    }
+    this.incomingPublic.push(range);
+    return;
+  }
+
   let src;
   try {
     src = boxContainers(srcArg);
@@ -392,15 +567,352 @@ const publicInternet = {
   },
 };
 
+/**
+ * Creates an address outside of Quilt that containers can be allowed to
+ * connect to. Containers can only reach the address on the ports allowed by
+ * its connections, and can't reach any other public address on those ports.
+ * @private
+ * @implements {Connectable}
+ * @constructor
+ *
+ * @param {string} label - The label of the address in connections.
+ */
+function ExternalAddress(label) {
+  this.label = label;
+}
+
+/**
+ * Allows containers to open connections to the external address.
+ *
+ * @param {Container|Container[]} srcArg - The containers that can open
+ *   connections to the address.
+ * @param {int|Port} portRange - The port the containers can connect to.
+ * @returns {void}
+ */
+ExternalAddress.prototype.allowFrom = function externalAllowFrom(srcArg, portRange) {
+  let src;
+  try {
+    src = boxContainers(srcArg);
+  } catch (err) {
+    throw new Error(`Only containers can connect to ${this.label}. ` +
+              'Check that you\'re allowing connections from a Container or ' +
+              'list of containers and not from a Load Balancer or other object.');
+  }
+
+  const range = boxRange(portRange);
+  if (range.min !== range.max) {
+    throw new Error(`${this.label} can only be connected to on single ports ` +
+            'and not on port ranges');
+  }
+
+  src.forEach((c) => {
+    c.outgoingExternal.push({ to: this.label, range });
+  });
+};
+
+const cidrPattern = /^(\d{1,3})\.(\d{1,3})\.(\d{1,3})\.(\d{1,3})(\/(\d{1,2}))?$/;
+
+/**
+ * Returns an IPv4 CIDR outside of Quilt that containers can be allowed to
+ * connect to.
+ *
+ * @example <caption>Allow the web containers to connect to the databases in
+ * 52.1.0.0/16 on port 5432.</caption>
+ * allow(web, cidr('52.1.0.0/16'), 5432);
+ *
+ * @param {string} block - The CIDR, e.g. `52.1.0.0/16`. A single address is
+ *   treated as a /32.
+ * @returns {Connectable} The CIDR.
+ */
+function cidr(block) {
+  const match = cidrPattern.exec(block);
+  const valid = match !== null &&
+    match.slice(1, 5).every(octet => Number(octet) <= 255) &&
+    (match[6] === undefined || Number(match[6]) <= 32);
+  if (!valid) {
+    throw new Error(`cidr must be an IPv4 CIDR (was: ${stringify(block)})`);
+  }
+
+  const normalized = match[5] === undefined ? `${block}/32` : block;
+  return new ExternalAddress(`${cidrLabelPrefix}${normalized}`);
+}
+
+const domainLabelPattern = /^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?$/;
+
+/**
+ * Returns a domain outside of Quilt that containers can be allowed to connect
+ * to. The domain is resolved periodically, and containers may connect to any
+ * of the IPv4 addresses it resolves to.
+ *
+ * @example <caption>Allow the web containers to connect to api.example.com on
+ * port 443.</caption>
+ * allow(web, domain('api.example.com'), 443);
+ *
+ * @param {string} name - The domain name, e.g. `api.example.com`.
+ * @returns {Connectable} The domain.
+ */
+function domain(name) {
+  if (typeof name !== 'string' ||
+      !name.split('.').every(part => domainLabelPattern.test(part))) {
+    throw new Error(`domain must be a valid domain name (was: ${stringify(name)})`);
+  }
+  return new ExternalAddress(`${domainLabelPrefix}${name.toLowerCase()}`);
+}
+
 LoadBalancer.prototype.getQuiltConnections = function lbGetQuiltConnections() {
-  return this.allowedInboundConnections.map(conn => ({
+  const connections = this.allowedInboundConnections.map(conn => ({
     from: conn.from.hostname,
     to: this.name,
     minPort: conn.minPort,
     maxPort: conn.maxPort,
   }));
+
+  this.incomingPublic.forEach((rng) => {
+    connections.push({
+      from: publicInternetLabel,
+      to: this.name,
+      minPort: rng.min,
+      maxPort: rng.max,
+    });
+  });
+
+  return connections;
+};
+
+/**
+ * Creates a new Ingress, which routes HTTP and HTTPS requests from the public
+ * internet to containers and load balancers according to the requests' host
+ * and path. Every worker accepts requests for the ingress, and forwards them to
+ * the destination's containers wherever they run. HTTPS requests are
+ * terminated by the ingress, using the given certificates. So that private keys
+ * aren't stored in the blueprint, certificates are read from files in
+ * `/etc/quilt/certs` on each worker, which may be installed by the workers'
+ * boot script.
+ * @constructor
+ *
+ * @example <caption>Serve example.com from the web containers, except for
+ * requests for /api, which go to the api container.</caption>
+ * const ingress = new Ingress('frontend', {
+ *   routes: [
+ *     {host: 'example.com', to: webLB, port: 80},
+ *     {host: 'example.com', path: '/api', to: api, port: 8080},
+ *   ],
+ *   certificates: [{
+ *     host: 'example.com',
+ *     certFile: 'example.com.crt',
+ *     keyFile: 'example.com.key',
+ *   }],
+ * });
+ *
+ * @param {string} name - The name of the ingress.
+ * @param {Object} opts - The routes and certificates of the ingress.
+ * @param {Object[]} opts.routes - Requests whose host matches `host` and whose
+ *   path starts with `path` are forwarded to `port` of `to`, a Container or
+ *   LoadBalancer. Routes without a host handle the requests for unknown hosts,
+ *   and routes without a path handle every path. When several paths match, the
+ *   longest wins.
+ * @param {Object[]} [opts.certificates] - The names of the files in
+ *   `/etc/quilt/certs` holding the PEM encoded certificate and private key of
+ *   each `host`, e.g. `example.com` or `*.example.com`, that is served over
+ *   HTTPS.
+ * @param {int} [opts.httpPort=80] - The public port HTTP is served on.
+ * @param {int} [opts.httpsPort=443] - The public port HTTPS is served on, if
+ *   there are certificates.
+ */
+function Ingress(name, opts = {}) {
+  if (typeof name !== 'string') {
+    throw new Error(`name must be a string; was ${stringify(name)}`);
+  }
+  this.name = uniqueHostname(name);
+  this.routes = getIngressRoutes(opts.routes);
+  this.certificates = getIngressCertificates(opts.certificates);
+  this.httpPort = getIngressPort('httpPort', opts.httpPort, 80);
+  this.httpsPort = getIngressPort('httpsPort', opts.httpsPort, 443);
+  if (this.certificates.length > 0 && this.httpPort === this.httpsPort) {
+    throw new Error('httpPort and httpsPort must be different');
+  }
+
+  checkExtraKeys(opts, this);
+}
+
+Ingress.prototype.deploy = function ingressDeploy(deployment) {
+  deployment.ingresses.push(this);
+};
+
+Ingress.prototype.getQuiltConnections = function ingressGetQuiltConnections() {
+  const ports = [this.httpPort];
+  if (this.certificates.length > 0) {
+    ports.push(this.httpsPort);
+  }
+  return ports.map(port => ({
+    from: publicInternetLabel,
+    to: this.name,
+    minPort: port,
+    maxPort: port,
+  }));
 };
 
+Ingress.prototype.toQuiltRepresentation = function ingressToQuiltRepresentation() {
+  const ingress = {
+    name: this.name,
+    routes: this.routes,
+    certificates: this.certificates,
+    httpPort: this.httpPort,
+  };
+  if (this.certificates.length > 0) {
+    ingress.httpsPort = this.httpsPort;
+  }
+  return ingress;
+};
+
+// Hosts and paths are written into the router's configuration, so they're
+// restricted to characters that can't change its meaning.
+const ingressHostPattern = /^[a-zA-Z0-9*.-]+$/;
+const ingressPathPattern = /^\/[a-zA-Z0-9\-._~!$&'()*+,=:@%/]*$/;
+
+// Certificate files must be in the certificate directory, so their names can't
+// contain slashes, or be "." or "..".
+const ingressFilePattern = /^[a-zA-Z0-9_-][a-zA-Z0-9._-]*$/;
+
+/**
+ * Validates the routes of an ingress.
+ * @private
+ * @param {Object[]} arg - The routes passed to the Ingress constructor.
+ * @returns {Object[]} The routes, with their destination replaced by its
+ *   hostname.
+ */
+function getIngressRoutes(arg) {
+  if (!Array.isArray(arg) || arg.length === 0) {
+    throw new Error('routes must be a non-empty array');
+  }
+
+  const seen = {};
+  return arg.map((route) => {
+    checkIngressKeys('route', route, ['host', 'path', 'to', 'port']);
+
+    const result = {};
+    if (route.host !== undefined) {
+      result.host = getIngressHost(route.host);
+    }
+    if (route.path !== undefined) {
+      const path = getString('route path', route.path);
+      if (!ingressPathPattern.test(path)) {
+        throw new Error('route path must start with "/" and be a valid URL ' +
+                    `path (was: ${stringify(path)})`);
+      }
+      result.path = path;
+    }
+
+    if (route.to instanceof Container) {
+      result.to = route.to.hostname;
+    } else if (route.to instanceof LoadBalancer) {
+      result.to = route.to.name;
+    } else {
+      throw new Error('routes must be to a Container or LoadBalancer ' +
+                  `(was: ${stringify(route.to)})`);
+    }
+    result.port = getIngressPort('route port', route.port);
+
+    const key = `${result.host || ''}${result.path || '/'}`;
+    if (seen[key]) {
+      throw new Error(`multiple routes for ${key}`);
+    }
+    seen[key] = true;
+    return result;
+  });
+}
+
+/**
+ * Validates the certificates of an ingress.
+ * @private
+ * @param {Object[]} [arg] - The certificates passed to the Ingress constructor.
+ * @returns {Object[]} The certificates.
+ */
+function getIngressCertificates(arg) {
+  if (arg === undefined) {
+    return [];
+  }
+  if (!Array.isArray(arg)) {
+    throw new Error(`certificates must be an array (was: ${stringify(arg)})`);
+  }
+
+  return arg.map((cert) => {
+    checkIngressKeys('certificate', cert, ['host', 'certFile', 'keyFile']);
+    if (cert.certFile === undefined || cert.keyFile === undefined) {
+      throw new Error('certificates must have a certFile and keyFile');
+    }
+    return {
+      host: getIngressHost(cert.host),
+      certFile: getIngressFile('certFile', cert.certFile),
+      keyFile: getIngressFile('keyFile', cert.keyFile),
+    };
+  });
+}
+
+/**
+ * @private
+ * @param {string} argName - The name of `arg` (for logging).
+ * @param {string} arg - The name of a certificate file.
+ * @returns {string} The name, after ensuring it names a file in the
+ *   certificate directory.
+ */
+function getIngressFile(argName, arg) {
+  const file = getString(argName, arg);
+  if (!ingressFilePattern.test(file)) {
+    throw new Error(`${argName} must be the name of a file in ` +
+                `/etc/quilt/certs (was: ${stringify(file)})`);
+  }
+  return file;
+}
+
+/**
+ * @private
+ * @param {string} argName - The name of `arg` (for logging).
+ * @param {Object} arg - The object passed to the Ingress constructor.
+ * @param {string[]} keys - The keys `arg` may have.
+ * @returns {void}
+ */
+function checkIngressKeys(argName, arg, keys) {
+  if (arg === null || typeof arg !== 'object') {
+    throw new Error(`${argName} must be an object (was: ${stringify(arg)})`);
+  }
+  const extras = Object.keys(arg).filter(key => !keys.includes(key));
+  if (extras.length > 0) {
+    throw new Error(`Unrecognized keys passed to ${argName}: ${extras}`);
+  }
+}
+
+/**
+ * @private
+ * @param {string} arg - The host of a route or certificate.
+ * @returns {string} The host, after ensuring it's a valid hostname.
+ */
+function getIngressHost(arg) {
+  const host = getString('host', arg);
+  if (!ingressHostPattern.test(host)) {
+    throw new Error(`host must be a valid hostname (was: ${stringify(host)})`);
+  }
+  return host;
+}
+
+/**
+ * @private
+ * @param {string} argName - The name of `arg` (for logging).
+ * @param {int} [arg] - The port.
+ * @param {int} [defaultPort] - The port to use if `arg` is undefined.
+ * @returns {int} The port, after ensuring it's a valid port number.
+ */
+function getIngressPort(argName, arg, defaultPort) {
+  if (arg === undefined && defaultPort !== undefined) {
+    return defaultPort;
+  }
+  if (!Number.isInteger(arg) || arg <= 0 || arg > 65535) {
+    throw new Error(`${argName} must be a valid port number (was: ${
+      stringify(arg)})`);
+  }
+  return arg;
+}
+
 /**
  * Boxes a container into a list of containers, or do nothing if `x` is a list
  * of containers.
@@ -623,6 +1135,303 @@ function getBoolean(argName, arg) {
   throw new Error(`${argName} must be a boolean (was: ${stringify(arg)})`);
 }
 
+/**
+ * @private
+ * @param {Object.<string, string>} arg - The system images that might be
+ *   undefined.
+ * @returns {Object.<string, string>} An empty object if `arg` is not defined,
+ *   and otherwise ensures that `arg` only maps the names of system containers
+ *   to images and then returns it.
+ */
+function getSystemImages(arg) {
+  const images = getStringMap('systemImages', arg);
+  const extras = Object.keys(images).filter(
+    key => !systemImageNames.includes(key));
+  if (extras.length > 0) {
+    throw new Error(`Unrecognized keys passed to systemImages: ${extras}`);
+  }
+  return images;
+}
+
+/**
+ * @private
+ * @param {string} arg - The DNS domain that might be undefined.
+ * @returns {string} An empty string if `arg` is not defined, and otherwise
+ *   ensures that `arg` is a valid DNS name and then returns it in lower case.
+ */
+function getDNSDomain(arg) {
+  const domain = getString('dnsDomain', arg).toLowerCase();
+  if (domain !== '' && !dnsNamePattern.test(domain)) {
+    throw new Error(`dnsDomain must be a valid DNS name (was: ${stringify(arg)})`);
+  }
+  return domain;
+}
+
+/**
+ * @private
+ * @param {Object} arg - The health policy that might be undefined.
+ * @returns {Object|undefined} Undefined if `arg` is not defined, and
+ *   otherwise ensures that `arg` only contains the numeric `replaceAfter` and
+ *   `maxReplacements` fields and then returns it.
+ */
+function getHealthPolicy(arg) {
+  if (arg === undefined) {
+    return undefined;
+  }
+  if (typeof arg !== 'object') {
+    throw new Error(`healthPolicy must be an object (was: ${stringify(arg)})`);
+  }
+
+  const policy = {
+    replaceAfter: getNumber('replaceAfter', arg.replaceAfter),
+    maxReplacements: getNumber('maxReplacements', arg.maxReplacements),
+  };
+  const extras = Object.keys(arg).filter(key => !objectHasKey.call(policy, key));
+  if (extras.length > 0) {
+    throw new Error(`Unrecognized keys passed to healthPolicy: ${extras}`);
+  }
+  return policy;
+}
+
+const encapsulations = ['stt', 'geneve', 'vxlan'];
+
+/**
+ * @private
+ * @param {Object} arg - The container network that might be undefined.
+ * @returns {Object|undefined} Undefined if `arg` is not defined, and
+ *   otherwise ensures that `arg` only contains the string `subnet`,
+ *   `gatewayIP`, `loadBalancerIP` and `encapsulation` fields and the integer
+ *   `mtu` field, and then returns it.  The addresses are validated by the
+ *   daemon.
+ */
+function getNetwork(arg) {
+  if (arg === undefined) {
+    return undefined;
+  }
+  if (typeof arg !== 'object') {
+    throw new Error(`network must be an object (was: ${stringify(arg)})`);
+  }
+
+  const network = {
+    subnet: getString('subnet', arg.subnet),
+    gatewayIP: getString('gatewayIP', arg.gatewayIP),
+    loadBalancerIP: getString('loadBalancerIP', arg.loadBalancerIP),
+    mtu: getNumber('mtu', arg.mtu),
+    encapsulation: getString('encapsulation', arg.encapsulation),
+  };
+  const extras = Object.keys(arg).filter(key => !objectHasKey.call(network, key));
+  if (extras.length > 0) {
+    throw new Error(`Unrecognized keys passed to network: ${extras}`);
+  }
+  if (!Number.isInteger(network.mtu) || network.mtu < 0) {
+    throw new Error(`mtu must be a positive integer (was: ${stringify(arg.mtu)})`);
+  }
+  if (network.encapsulation !== '' &&
+      !encapsulations.includes(network.encapsulation)) {
+    throw new Error(`encapsulation must be one of ${encapsulations.join(', ')} ` +
+      `(was: ${stringify(arg.encapsulation)})`);
+  }
+  return network;
+}
+
+/**
+ * @private
+ * @param {Object} arg - The autoscaling bounds that might be undefined.
+ * @returns {Object|undefined} Undefined if `arg` is not defined, and
+ *   otherwise ensures that `arg` contains numeric `min` and `max` fields
+ *   such that 0 <= `min` <= `max` and 0 < `max`, and then returns them.
+ */
+function getAutoscale(arg) {
+  if (arg === undefined) {
+    return undefined;
+  }
+  if (typeof arg !== 'object') {
+    throw new Error(`autoscale must be an object (was: ${stringify(arg)})`);
+  }
+
+  const autoscale = {
+    min: getNumber('min', arg.min),
+    max: getNumber('max', arg.max),
+  };
+  const extras = Object.keys(arg).filter(
+    key => !objectHasKey.call(autoscale, key));
+  if (extras.length > 0) {
+    throw new Error(`Unrecognized keys passed to autoscale: ${extras}`);
+  }
+  if (autoscale.min < 0 || autoscale.max <= 0 ||
+      autoscale.min > autoscale.max) {
+    throw new Error('autoscale must satisfy 0 <= min <= max and 0 < max ' +
+      `(was: ${stringify(arg)})`);
+  }
+  return autoscale;
+}
+
+/**
+ * @private
+ * @param {Object} arg - The container security settings that might be
+ *   undefined.
+ * @returns {Object|undefined} Undefined if `arg` is not defined, and
+ *   otherwise ensures that `arg` only contains valid security settings and
+ *   then returns them.
+ */
+function getSecurity(arg) {
+  if (arg === undefined) {
+    return undefined;
+  }
+  if (typeof arg !== 'object') {
+    throw new Error(`security must be an object (was: ${stringify(arg)})`);
+  }
+
+  const security = {
+    privileged: getBoolean('privileged', arg.privileged),
+    capAdd: getStringArray('capAdd', arg.capAdd),
+    capDrop: getStringArray('capDrop', arg.capDrop),
+    user: getString('user', arg.user),
+    readOnlyRootfs: getBoolean('readOnlyRootfs', arg.readOnlyRootfs),
+    seccomp: getString('seccomp', arg.seccomp),
+    apparmor: getString('apparmor', arg.apparmor),
+  };
+  const extras = Object.keys(arg).filter(
+    key => !objectHasKey.call(security, key));
+  if (extras.length > 0) {
+    throw new Error(`Unrecognized keys passed to security: ${extras}`);
+  }
+  if (!['', 'default', 'unconfined'].includes(security.seccomp)) {
+    throw new Error('seccomp must be "default" or "unconfined" ' +
+      `(was: ${stringify(security.seccomp)})`);
+  }
+  return security;
+}
+
+const ratePattern = /^(\d+)(bit|kbit|mbit|gbit)$/;
+const rateUnits = { bit: 1, kbit: 1e3, mbit: 1e6, gbit: 1e9 };
+
+/**
+ * Converts a rate to bits per second.
+ * @private
+ *
+ * @param {string} argName - The name of `arg` (for logging).
+ * @param {number|string|undefined} arg - A positive number of bits per second,
+ *   or a string such as `10mbit`.
+ * @returns {number} The rate in bits per second, or 0 if `arg` is undefined.
+ */
+function getRate(argName, arg) {
+  if (arg === undefined) {
+    return 0;
+  }
+  if (typeof arg === 'number' && Number.isInteger(arg) && arg > 0) {
+    return arg;
+  }
+
+  const match = typeof arg === 'string' ? ratePattern.exec(arg) : null;
+  if (match === null || Number(match[1]) === 0) {
+    throw new Error(`${argName} must be a positive number of bits per ` +
+      `second, or a string such as "10mbit" (was: ${stringify(arg)})`);
+  }
+  return Number(match[1]) * rateUnits[match[2]];
+}
+
+/**
+ * @private
+ * @param {Object} arg - The container bandwidth limits that might be
+ *   undefined.
+ * @returns {Object|undefined} Undefined if `arg` is not defined, and
+ *   otherwise the limits in bits per second.
+ */
+function getBandwidth(arg) {
+  if (arg === undefined) {
+    return undefined;
+  }
+  if (typeof arg !== 'object') {
+    throw new Error(`bandwidth must be an object (was: ${stringify(arg)})`);
+  }
+
+  const bandwidth = {
+    ingress: getRate('ingress', arg.ingress),
+    egress: getRate('egress', arg.egress),
+  };
+  const extras = Object.keys(arg).filter(
+    key => !objectHasKey.call(bandwidth, key));
+  if (extras.length > 0) {
+    throw new Error(`Unrecognized keys passed to bandwidth: ${extras}`);
+  }
+  return bandwidth;
+}
+
+const maxTrafficClass = 63;
+
+/**
+ * @private
+ * @param {number|undefined} arg - The traffic class of a container.
+ * @returns {number|undefined} Undefined if `arg` is not defined, and otherwise
+ *   ensures that `arg` is a valid DSCP value and returns it.
+ */
+function getTrafficClass(arg) {
+  if (arg === undefined) {
+    return undefined;
+  }
+  if (!Number.isInteger(arg) || arg < 1 || arg > maxTrafficClass) {
+    throw new Error(`trafficClass must be an integer between 1 and ${maxTrafficClass} ` +
+      `(was: ${stringify(arg)})`);
+  }
+  return arg;
+}
+
+/**
+ * Validates the options of a connection.
+ * @private
+ *
+ * @param {Object} [opts] - The options passed to `allow` or `allowFrom`.
+ * @returns {Object} The options, with the bandwidth in bits per second.
+ */
+function getConnectionOptions(opts = {}) {
+  if (typeof opts !== 'object') {
+    throw new Error(`connection options must be an object (was: ${stringify(opts)})`);
+  }
+
+  const extras = Object.keys(opts).filter(key => key !== 'bandwidth');
+  if (extras.length > 0) {
+    throw new Error(`Unrecognized keys passed to connection options: ${extras}`);
+  }
+  return { bandwidth: getRate('bandwidth', opts.bandwidth) };
+}
+
+/**
+ * Validates the probe of a load balancer.
+ * @private
+ * @param {Object} [arg] - The probe passed to the LoadBalancer constructor.
+ * @returns {Object|undefined} The probe, or undefined if `arg` is undefined.
+ */
+function getProbe(arg) {
+  if (arg === undefined) {
+    return undefined;
+  }
+  if (typeof arg !== 'object') {
+    throw new Error(`probe must be an object (was: ${stringify(arg)})`);
+  }
+
+  const extras = Object.keys(arg).filter(
+    key => key !== 'port' && key !== 'path');
+  if (extras.length > 0) {
+    throw new Error(`Unrecognized keys passed to probe: ${extras}`);
+  }
+
+  if (!Number.isInteger(arg.port) || arg.port <= 0 || arg.port > 65535) {
+    throw new Error(`probe port must be a valid port number (was: ${
+      stringify(arg.port)})`);
+  }
+
+  const probe = {port: arg.port};
+  if (arg.path !== undefined) {
+    const path = getString('probe path', arg.path);
+    if (!path.startsWith('/')) {
+      throw new Error(`probe path must start with "/" (was: ${stringify(path)})`);
+    }
+    probe.path = path;
+  }
+  return probe;
+}
+
 /**
  * Creates a new Machine object, which represents a machine to be deployed.
  * @constructor
@@ -676,6 +1485,16 @@ function getBoolean(argName, arg) {
  *   the machine.
  * @param {boolean} [optionalArgs.preemptible=false] - Whether the machine
  *   should be preemptible. Only supported on the Amazon provider.
+ * @param {string} [optionalArgs.image] - The machine image to boot instead of
+ *   the provider's default (provider-specific; e.g., an AMI ID for Amazon, or a
+ *   box for Vagrant). The image must be based on Ubuntu 16.04.
+ * @param {string} [optionalArgs.bootScript] - A shell script to run after
+ *   Quilt's own boot script. Changing the image or boot script of a running
+ *   machine causes it to be replaced.
+ * @param {Object} [optionalArgs.autoscale] - Turns the machine into a group
+ *   of between `min` and `max` workers. The group grows when containers can't
+ *   be placed on the existing workers, and shrinks when workers are idle. Only
+ *   supported for workers without a floating IP.
  */
 function Machine(optionalArgs) {
   this._refID = uniqueID();
@@ -690,6 +1509,12 @@ function Machine(optionalArgs) {
   this.cpu = boxRange(optionalArgs.cpu);
   this.ram = boxRange(optionalArgs.ram);
   this.preemptible = getBoolean('preemptible', optionalArgs.preemptible);
+  this.image = getString('image', optionalArgs.image);
+  this.bootScript = getString('bootScript', optionalArgs.bootScript);
+  this.autoscale = getAutoscale(optionalArgs.autoscale);
+  if (this.autoscale !== undefined && this.floatingIp !== '') {
+    throw new Error('autoscaled machines cannot have a floatingIp');
+  }
 
   checkExtraKeys(optionalArgs, this);
 }
@@ -829,6 +1654,33 @@ Image.prototype.clone = function imageClone() {
  *   by this argument changes and the blueprint is re-run, Quilt will re-start
  *   the container using the new files.  Files are installed with permissions
  *   0644 and parent directories are automatically created.
+ * @param {Object} [optionalArgs.security] - The privileges that the container
+ *   runs with.  By default, containers are unprivileged.
+ * @param {boolean} [optionalArgs.security.privileged] - Whether the container
+ *   has full access to the host.  Avoid this unless absolutely necessary.
+ * @param {string[]} [optionalArgs.security.capAdd] - Linux capabilities to
+ *   grant the container, e.g. `NET_ADMIN`.
+ * @param {string[]} [optionalArgs.security.capDrop] - Linux capabilities to
+ *   remove from the container.
+ * @param {string} [optionalArgs.security.user] - The user (and optionally,
+ *   the group) to run the container's command as.
+ * @param {boolean} [optionalArgs.security.readOnlyRootfs] - Whether the
+ *   container's root filesystem is mounted read-only.
+ * @param {string} [optionalArgs.security.seccomp] - Either `default`, to
+ *   apply Docker's default seccomp profile, or `unconfined`.
+ * @param {string} [optionalArgs.security.apparmor] - The AppArmor profile to
+ *   apply to the container.
+ * @param {Object} [optionalArgs.bandwidth] - Limits on the container's
+ *   traffic.  Rates are numbers of bits per second, or strings such as
+ *   `10mbit`, and are unlimited if unset.
+ * @param {number|string} [optionalArgs.bandwidth.ingress] - The rate at which
+ *   the container receives traffic.
+ * @param {number|string} [optionalArgs.bandwidth.egress] - The rate at which
+ *   the container sends traffic.
+ * @param {number} [optionalArgs.trafficClass] - The DSCP value, between 1 and
+ *   63, that the container's packets are marked with.  The container's
+ *   net_cls classid is also set to `0x10:trafficClass`, so that hosts can
+ *   classify its traffic.  The container doesn't need to be privileged.
  */
 function Container(hostnamePrefix, image, optionalArgs = {}) {
   // refID is used to distinguish deployments with multiple references to the
@@ -851,11 +1703,16 @@ function Container(hostnamePrefix, image, optionalArgs = {}) {
   this.env = getStringMap('env', optionalArgs.env);
   this.filepathToContent = getStringMap('filepathToContent',
     optionalArgs.filepathToContent);
+  this.security = getSecurity(optionalArgs.security);
+  this.bandwidth = getBandwidth(optionalArgs.bandwidth);
+  this.trafficClass = getTrafficClass(optionalArgs.trafficClass);
 
   // Don't allow callers to modify the arguments by reference.
   this.command = _.clone(this.command);
   this.env = _.clone(this.env);
   this.filepathToContent = _.clone(this.filepathToContent);
+  this.security = _.clone(this.security);
+  this.bandwidth = _.clone(this.bandwidth);
   this.image = this.image.clone();
 
   checkExtraKeys(optionalArgs, this);
@@ -866,6 +1723,7 @@ function Container(hostnamePrefix, image, optionalArgs = {}) {
 
   this.allowedInboundConnections = [];
   this.outgoingPublic = [];
+  this.outgoingExternal = [];
   this.incomingPublic = [];
 }
 
@@ -921,7 +1779,7 @@ Container.prototype.withFiles = function containerWithFiles(fileMap) {
  * @returns {string} The container's hostname.
  */
 Container.prototype.getHostname = function containerGetHostname() {
-  return `${this.hostname}.q`;
+  return `${this.hostname}.${hostnameDomain()}`;
 };
 
 Container.prototype.hash = function containerHash() {
@@ -931,6 +1789,9 @@ Container.prototype.hash = function containerHash() {
     env: this.env,
     filepathToContent: this.filepathToContent,
     hostname: this.hostname,
+    security: this.security,
+    bandwidth: this.bandwidth,
+    trafficClass: this.trafficClass,
   });
 };
 
@@ -962,8 +1823,33 @@ function containerGetPlacementsWithID() {
   });
 };
 
+/**
+ * Allows containers to open connections to this container.
+ *
+ * @param {Container|Container[]|publicInternet} srcArg - The containers that
+ *   can open connections to this container.
+ * @param {int|Port|PortRange} portRange - The ports they can connect to.
+ * @param {Object} [opts] - Options of the connections.
+ * @param {number|string} [opts.bandwidth] - The rate, in bits per second or
+ *   as a string such as `10mbit`, that traffic in each direction of each
+ *   connection is limited to.  It's only supported on single ports.
+ * @returns {void}
+ */
 Container.prototype.allowFrom =
-function containerAllowFrom(srcArg, portRange) {
+function containerAllowFrom(srcArg, portRange, opts) {
+  const { bandwidth } = getConnectionOptions(opts);
+  if (bandwidth !== 0) {
+    const range = boxRange(portRange);
+    if (srcArg === publicInternet) {
+      throw new Error('bandwidth limits are not supported on connections ' +
+        'from the public internet');
+    }
+    if (range.min !== range.max) {
+      throw new Error('bandwidth limits are only supported on single ports ' +
+        'and not on port ranges');
+    }
+  }
+
   if (srcArg === publicInternet) {
     this.allowFromPublic(portRange);
     return;
@@ -980,7 +1866,7 @@ function containerAllowFrom(srcArg, portRange) {
 
   src.forEach((c) => {
     this.allowedInboundConnections.push(
-      new Connection(c, boxRange(portRange)));
+      new Connection(c, boxRange(portRange), bandwidth));
   });
 };
 
@@ -1012,12 +1898,16 @@ function containerGetQuiltConnections() {
   const connections = [];
 
   this.allowedInboundConnections.forEach((conn) => {
-    connections.push({
+    const quiltConn = {
       from: conn.from.hostname,
       to: this.hostname,
       minPort: conn.minPort,
       maxPort: conn.maxPort,
-    });
+    };
+    if (conn.bandwidth !== 0) {
+      quiltConn.bandwidth = conn.bandwidth;
+    }
+    connections.push(quiltConn);
   });
 
   this.outgoingPublic.forEach((rng) => {
@@ -1029,6 +1919,15 @@ function containerGetQuiltConnections() {
     });
   });
 
+  this.outgoingExternal.forEach((ext) => {
+    connections.push({
+      from: this.hostname,
+      to: ext.to,
+      minPort: ext.range.min,
+      maxPort: ext.range.max,
+    });
+  });
+
   this.incomingPublic.forEach((rng) => {
     connections.push({
       from: publicInternetLabel,
@@ -1050,6 +1949,9 @@ function containerToQuiltRepresentation() {
     env: this.env,
     filepathToContent: this.filepathToContent,
     hostname: this.hostname,
+    security: this.security,
+    bandwidth: this.bandwidth,
+    trafficClass: this.trafficClass,
   };
 };
 
@@ -1144,11 +2046,23 @@ function MachineRule(exclusive, optionalArgs) {
  *   Examples of connectable objects are Containers, LoadBalancers, publicInternet,
  *   and user-defined objects that implement allowFrom.
  * @param {int|Port|PortRange} port - The ports that traffic is allowed on.
+ * @param {Object} [opts] - Options of the connections.  Only connections
+ *   between containers support options.
+ * @param {number|string} [opts.bandwidth] - The rate that traffic in each
+ *   direction of each connection is limited to.  See
+ *   {@link Container#allowFrom}.
  * @returns {void}
  */
-function allow(src, dst, port) {
+function allow(src, dst, port, opts) {
   boxConnectable(dst).forEach((c) => {
-    c.allowFrom(src, port);
+    if (opts === undefined) {
+      c.allowFrom(src, port);
+    } else if (c instanceof Container) {
+      c.allowFrom(src, port, opts);
+    } else {
+      throw new Error('connection options are only supported on ' +
+        'connections between containers');
+    }
   });
 }
 
@@ -1158,11 +2072,14 @@ function allow(src, dst, port) {
  *
  * @param {string} from - The host from which connections are allowed.
  * @param {PortRange} ports - The port numbers which are allowed.
+ * @param {number} [bandwidth] - The limit, in bits per second, on the traffic
+ *   in each direction of the connection, or 0 if it's unlimited.
  */
-function Connection(from, ports) {
+function Connection(from, ports, bandwidth = 0) {
   this.minPort = ports.min;
   this.maxPort = ports.max;
   this.from = from;
+  this.bandwidth = bandwidth;
 }
 
 /**
@@ -1196,6 +2113,15 @@ function getDeployment() {
   return global._quiltDeployment;
 }
 
+/**
+ * @returns {string} The DNS domain of hostnames in the current deployment, or
+ *   the default domain if there is no deployment.
+ */
+function hostnameDomain() {
+  const deployment = getDeployment();
+  return deployment ? deployment.domain() : defaultDNSDomain;
+}
+
 /**
  * Resets global unique counters. Used only for unit testing.
  * @private
@@ -1216,8 +2142,11 @@ module.exports = {
   PortRange,
   Range,
   LoadBalancer,
+  Ingress,
   allow,
+  cidr,
   createDeployment,
+  domain,
   getDeployment,
   githubKeys,
   publicInternet,
@@ -163,6 +163,52 @@ describe('Bindings', () => {
         preemptible: true,
       }]);
     });
+    it('image and boot script', () => {
+      deployment.deploy(new b.Machine({
+        provider: 'Amazon',
+        image: 'ami-1234',
+        bootScript: 'echo hello',
+      }).asWorker());
+      checkMachines([{
+        role: 'Worker',
+        provider: 'Amazon',
+        image: 'ami-1234',
+        bootScript: 'echo hello',
+      }]);
+    });
+    it('autoscale', () => {
+      deployment.deploy(new b.Machine({
+        provider: 'Amazon',
+        autoscale: { min: 1, max: 5 },
+      }).asWorker());
+      checkMachines([{
+        role: 'Worker',
+        provider: 'Amazon',
+        autoscale: { min: 1, max: 5 },
+      }]);
+    });
+    it('autoscale does not change the machine ID', () => {
+      const machine = new b.Machine({ provider: 'Amazon' }).asMaster();
+      const autoscaled = new b.Machine({
+        provider: 'Amazon',
+        autoscale: { max: 3 },
+      }).asMaster();
+      expect(autoscaled.hash()).to.equal(machine.hash());
+    });
+    it('errors on invalid autoscale bounds', () => {
+      expect(() => new b.Machine({ autoscale: 3 }))
+        .to.throw('autoscale must be an object (was: 3)');
+      expect(() => new b.Machine({ autoscale: { min: 1 } }))
+        .to.throw('autoscale must satisfy 0 <= min <= max and 0 < max');
+      expect(() => new b.Machine({ autoscale: { min: 3, max: 2 } }))
+        .to.throw('autoscale must satisfy 0 <= min <= max and 0 < max');
+      expect(() => new b.Machine({ autoscale: { max: 2, size: 1 } }))
+        .to.throw('Unrecognized keys passed to autoscale: size');
+      expect(() => new b.Machine({
+        floatingIp: '8.8.8.8',
+        autoscale: { max: 2 },
+      })).to.throw('autoscaled machines cannot have a floatingIp');
+    });
   });
 
   describe('Container', () => {
@@ -222,6 +268,78 @@ describe('Bindings', () => {
         filepathToContent: {},
       }]);
     });
+    it('security', () => {
+      const c = new b.Container('host', 'image', {
+        security: { privileged: true, capAdd: ['NET_ADMIN'] },
+      });
+      deployment.deploy([c, c.clone()]);
+      const { containers } = deployment.toQuiltRepresentation();
+      expect(containers).to.have.lengthOf(2);
+      containers.forEach(dbc => expect(dbc.security).to.eql({
+        privileged: true,
+        capAdd: ['NET_ADMIN'],
+        capDrop: [],
+        user: '',
+        readOnlyRootfs: false,
+        seccomp: '',
+        apparmor: '',
+      }));
+    });
+    it('errors on invalid security settings', () => {
+      expect(() => new b.Container('host', 'image', { security: true }))
+        .to.throw('security must be an object (was: true)');
+      expect(() => new b.Container('host', 'image', {
+        security: { privileged: 'yes' },
+      })).to.throw('privileged must be a boolean (was: "yes")');
+      expect(() => new b.Container('host', 'image', {
+        security: { caps: [] },
+      })).to.throw('Unrecognized keys passed to security: caps');
+      expect(() => new b.Container('host', 'image', {
+        security: { seccomp: 'strict' },
+      })).to.throw('seccomp must be "default" or "unconfined" ' +
+        '(was: "strict")');
+    });
+    it('bandwidth', () => {
+      const c = new b.Container('host', 'image', {
+        bandwidth: { ingress: '10mbit', egress: 1500 },
+      });
+      deployment.deploy([c, c.clone()]);
+      const { containers } = deployment.toQuiltRepresentation();
+      expect(containers).to.have.lengthOf(2);
+      containers.forEach(dbc => expect(dbc.bandwidth).to.eql({
+        ingress: 10000000,
+        egress: 1500,
+      }));
+    });
+    it('errors on invalid bandwidth limits', () => {
+      expect(() => new b.Container('host', 'image', { bandwidth: '10mbit' }))
+        .to.throw('bandwidth must be an object (was: "10mbit")');
+      expect(() => new b.Container('host', 'image', {
+        bandwidth: { ingress: '10mb' },
+      })).to.throw('ingress must be a positive number of bits per second, ' +
+        'or a string such as "10mbit" (was: "10mb")');
+      expect(() => new b.Container('host', 'image', {
+        bandwidth: { egress: -1 },
+      })).to.throw('egress must be a positive number of bits per second');
+      expect(() => new b.Container('host', 'image', {
+        bandwidth: { in: '1gbit' },
+      })).to.throw('Unrecognized keys passed to bandwidth: in');
+    });
+    it('trafficClass', () => {
+      const c = new b.Container('host', 'image', { trafficClass: 10 });
+      deployment.deploy([c, c.clone()]);
+      const { containers } = deployment.toQuiltRepresentation();
+      expect(containers).to.have.lengthOf(2);
+      containers.forEach(dbc => expect(dbc.trafficClass).to.equal(10));
+    });
+    it('errors on invalid traffic classes', () => {
+      expect(() => new b.Container('host', 'image', { trafficClass: 64 }))
+        .to.throw('trafficClass must be an integer between 1 and 63 (was: 64)');
+      expect(() => new b.Container('host', 'image', { trafficClass: 0 }))
+        .to.throw('trafficClass must be an integer between 1 and 63 (was: 0)');
+      expect(() => new b.Container('host', 'image', { trafficClass: '10' }))
+        .to.throw('trafficClass must be an integer between 1 and 63 (was: "10")');
+    });
     it('hostname', () => {
       const c = new b.Container('host', new b.Image('image'));
       deployment.deploy(c);
@@ -475,11 +593,166 @@ describe('Bindings', () => {
         },
       ]);
     });
+    it('probe', () => {
+      deployment.deploy(new b.LoadBalancer('web_tier',
+        [new b.Container('host', 'nginx')],
+        {probe: {port: 80, path: '/healthz'}}));
+      deployment.deploy(new b.LoadBalancer('database',
+        [new b.Container('db', 'postgres')], {probe: {port: 5432}}));
+      checkLoadBalancers([
+        {
+          name: 'web_tier',
+          hostnames: ['host'],
+          probe: {port: 80, path: '/healthz'},
+        },
+        {
+          name: 'database',
+          hostnames: ['db'],
+          probe: {port: 5432},
+        },
+      ]);
+    });
+    it('errors on invalid probes', () => {
+      expect(() => new b.LoadBalancer('foo', [], {probe: 80})).to.throw(
+        'probe must be an object (was: 80)');
+      expect(() => new b.LoadBalancer('foo', [], {probe: {path: '/'}})).to.throw(
+        'probe port must be a valid port number (was: undefined)');
+      expect(() => new b.LoadBalancer('foo', [], {probe: {port: 70000}})).to.throw(
+        'probe port must be a valid port number (was: 70000)');
+      expect(() => new b.LoadBalancer('foo', [],
+        {probe: {port: 80, path: 'healthz'}})).to.throw(
+        'probe path must start with "/" (was: "healthz")');
+      expect(() => new b.LoadBalancer('foo', [],
+        {probe: {port: 80, timeout: 5}})).to.throw(
+        'Unrecognized keys passed to probe: timeout');
+    });
     it('get LoadBalancer hostname', () => {
       const foo = new b.LoadBalancer('foo', []);
       expect(foo.hostname()).to.equal('foo.q');
     });
   });
+  describe('Ingress', () => {
+    let web;
+    let api;
+    let webLB;
+    beforeEach(() => {
+      web = new b.Container('web', 'nginx');
+      api = new b.Container('api', 'api');
+      webLB = new b.LoadBalancer('weblb', [web]);
+      deployment.deploy([web, api, webLB]);
+    });
+
+    const checkIngresses = function checkIngresses(expected) {
+      const { ingresses } = deployment.toQuiltRepresentation();
+      expect(ingresses).to.eql(expected);
+    };
+
+    it('basic', () => {
+      deployment.deploy(new b.Ingress('frontend', {
+        routes: [
+          {host: 'example.com', to: webLB, port: 80},
+          {host: 'example.com', path: '/api', to: api, port: 8080},
+        ],
+      }));
+      checkIngresses([{
+        name: 'frontend',
+        routes: [
+          {host: 'example.com', to: 'weblb', port: 80},
+          {host: 'example.com', path: '/api', to: 'api', port: 8080},
+        ],
+        certificates: [],
+        httpPort: 80,
+      }]);
+      checkConnections([{
+        from: 'public', to: 'frontend', minPort: 80, maxPort: 80,
+      }]);
+    });
+    it('certificates', () => {
+      deployment.deploy(new b.Ingress('frontend', {
+        routes: [{to: web, port: 80}],
+        certificates: [{
+          host: '*.example.com',
+          certFile: 'example.com.crt',
+          keyFile: 'example.com.key',
+        }],
+        httpPort: 8000,
+        httpsPort: 8443,
+      }));
+      checkIngresses([{
+        name: 'frontend',
+        routes: [{to: 'web', port: 80}],
+        certificates: [{
+          host: '*.example.com',
+          certFile: 'example.com.crt',
+          keyFile: 'example.com.key',
+        }],
+        httpPort: 8000,
+        httpsPort: 8443,
+      }]);
+      checkConnections([
+        {from: 'public', to: 'frontend', minPort: 8000, maxPort: 8000},
+        {from: 'public', to: 'frontend', minPort: 8443, maxPort: 8443},
+      ]);
+    });
+    it('errors on invalid routes', () => {
+      expect(() => new b.Ingress('foo', {})).to.throw(
+        'routes must be a non-empty array');
+      expect(() => new b.Ingress('foo', {routes: [{to: 'web', port: 80}]}))
+        .to.throw('routes must be to a Container or LoadBalancer ' +
+          '(was: "web")');
+      expect(() => new b.Ingress('foo', {routes: [{to: web}]})).to.throw(
+        'route port must be a valid port number (was: undefined)');
+      expect(() => new b.Ingress('foo', {routes: [
+        {to: web, port: 80, path: 'api'}]})).to.throw(
+        'route path must start with "/" and be a valid URL path (was: "api")');
+      expect(() => new b.Ingress('foo', {routes: [
+        {to: web, port: 80, host: 'a.com; return 200'}]})).to.throw(
+        'host must be a valid hostname (was: "a.com; return 200")');
+      expect(() => new b.Ingress('foo', {routes: [
+        {to: web, port: 80, weight: 2}]})).to.throw(
+        'Unrecognized keys passed to route: weight');
+      expect(() => new b.Ingress('foo', {routes: [
+        {to: web, port: 80, path: '/'}, {to: api, port: 80}]})).to.throw(
+        'multiple routes for /');
+    });
+    it('errors on invalid options', () => {
+      const routes = [{to: web, port: 80}];
+      expect(() => new b.Ingress('foo', {routes, certificates: [{host: 'a'}]}))
+        .to.throw('certificates must have a certFile and keyFile');
+      expect(() => new b.Ingress('foo', {routes, certificates: [
+        {host: 'a', certFile: '../a.crt', keyFile: 'a.key'}]})).to.throw(
+        'certFile must be the name of a file in /etc/quilt/certs ' +
+        '(was: "../a.crt")');
+      expect(() => new b.Ingress('foo', {routes, certificates: [
+        {host: 'a', certFile: 'a.crt', keyFile: '..'}]})).to.throw(
+        'keyFile must be the name of a file in /etc/quilt/certs (was: "..")');
+      expect(() => new b.Ingress('foo', {routes, httpPort: 0})).to.throw(
+        'httpPort must be a valid port number (was: 0)');
+      expect(() => new b.Ingress('foo', {routes,
+        certificates: [{host: 'a', certFile: 'a.crt', keyFile: 'a.key'}],
+        httpsPort: 80})).to.throw('httpPort and httpsPort must be different');
+      expect(() => new b.Ingress('foo', {routes, tls: true})).to.throw(
+        'Unrecognized keys passed to Ingress constructor: tls');
+    });
+    it('routes must be to deployed containers', () => {
+      const other = new b.Container('other', 'nginx');
+      deployment.deploy(new b.Ingress('frontend', {
+        routes: [{to: other, port: 80}],
+      }));
+      expect(() => deployment.toQuiltRepresentation()).to.throw(
+        'ingress frontend routes to an undefined container or load ' +
+        'balancer: other');
+    });
+    it('public ingress ports can\'t be shared', () => {
+      deployment.deploy(new b.Ingress('frontend', {
+        routes: [{to: web, port: 80}],
+      }));
+      api.allowFrom(b.publicInternet, 80);
+      expect(() => deployment.toQuiltRepresentation()).to.throw(
+        'public port 80 is exposed by an ingress, so it can\'t be ' +
+        'shared by frontend, api');
+    });
+  });
   describe('AllowFrom', () => {
     let foo;
     let bar;
@@ -548,6 +821,37 @@ describe('Bindings', () => {
         maxPort: 80,
       }]);
     });
+    it('allow connections from publicInternet to LoadBalancer', () => {
+      fooLoadBalancer.allowFrom(b.publicInternet, 80);
+      fooLoadBalancer.allowFrom(b.publicInternet, 80);
+      checkConnections([
+        {
+          from: 'public',
+          to: 'fooLoadBalancer',
+          minPort: 80,
+          maxPort: 80,
+        },
+        {
+          from: 'public',
+          to: 'fooLoadBalancer',
+          minPort: 80,
+          maxPort: 80,
+        },
+      ]);
+    });
+    it('connect from publicInternet to LoadBalancer port range', () => {
+      expect(() =>
+        fooLoadBalancer.allowFrom(b.publicInternet, new b.PortRange(80, 81))).to
+        .throw('public internet can only connect to single ports ' +
+                        'and not to port ranges');
+    });
+    it('public LoadBalancer ports can\'t be shared', () => {
+      fooLoadBalancer.allowFrom(b.publicInternet, 80);
+      bar.allowFrom(b.publicInternet, 80);
+      expect(() => deployment.toQuiltRepresentation()).to.throw(
+        'public port 80 is exposed by a load balancer, so it can\'t be ' +
+        'shared by fooLoadBalancer, bar');
+    });
     it('connect to publicInternet port range', () => {
       expect(() =>
         b.publicInternet.allowFrom(foo, new b.PortRange(80, 81))).to
@@ -640,6 +944,60 @@ describe('Bindings', () => {
         { from: 'bar', to: 'serv', minPort: 80, maxPort: 80 },
       ]);
     });
+    it('dst is a CIDR', () => {
+      b.allow(fooBarGroup, b.cidr('52.1.0.0/16'), 5432);
+      b.allow(qux, b.cidr('52.2.0.1'), 5432);
+      checkConnections([
+        { from: 'foo', to: 'cidr:52.1.0.0/16', minPort: 5432, maxPort: 5432 },
+        { from: 'bar', to: 'cidr:52.1.0.0/16', minPort: 5432, maxPort: 5432 },
+        { from: 'qux', to: 'cidr:52.2.0.1/32', minPort: 5432, maxPort: 5432 },
+      ]);
+    });
+
+    it('dst is a domain', () => {
+      b.allow(foo, b.domain('API.example.com'), 443);
+      checkConnections([
+        { from: 'foo', to: 'domain:api.example.com', minPort: 443, maxPort: 443 },
+      ]);
+    });
+
+    it('errors on invalid external addresses', () => {
+      expect(() => b.cidr('52.1.0.0/33')).to.throw(
+        'cidr must be an IPv4 CIDR (was: "52.1.0.0/33")');
+      expect(() => b.cidr('256.1.0.0/16')).to.throw(
+        'cidr must be an IPv4 CIDR (was: "256.1.0.0/16")');
+      expect(() => b.domain('example..com')).to.throw(
+        'domain must be a valid domain name (was: "example..com")');
+      expect(() => b.allow(foo, b.domain('example.com'), new b.PortRange(1, 2)))
+        .to.throw('domain:example.com can only be connected to on single ' +
+          'ports and not on port ranges');
+      expect(() => b.allow(lb, b.cidr('52.1.0.0/16'), 80)).to.throw(
+        'Only containers can connect to cidr:52.1.0.0/16.');
+    });
+
+    it('bandwidth', () => {
+      b.allow(foo, bar, 80, { bandwidth: '5mbit' });
+      qux.allowFrom(foo, 22, { bandwidth: 64000 });
+      checkConnections([
+        { from: 'foo', to: 'bar', minPort: 80, maxPort: 80, bandwidth: 5000000 },
+        { from: 'foo', to: 'qux', minPort: 22, maxPort: 22, bandwidth: 64000 },
+      ]);
+    });
+
+    it('errors on invalid bandwidth limits', () => {
+      expect(() => b.allow(foo, bar, new b.PortRange(1, 2), { bandwidth: '1mbit' }))
+        .to.throw('bandwidth limits are only supported on single ports and ' +
+          'not on port ranges');
+      expect(() => b.allow(b.publicInternet, bar, 80, { bandwidth: '1mbit' }))
+        .to.throw('bandwidth limits are not supported on connections from ' +
+          'the public internet');
+      expect(() => b.allow(foo, lb, 80, { bandwidth: '1mbit' })).to.throw(
+        'connection options are only supported on connections between containers');
+      expect(() => b.allow(foo, bar, 80, { bandwidth: '1mbps' })).to.throw(
+        'bandwidth must be a positive number of bits per second');
+      expect(() => b.allow(foo, bar, 80, { rate: '1mbit' })).to.throw(
+        'Unrecognized keys passed to connection options: rate');
+    });
   });
   describe('Vet', () => {
     let foo;
@@ -769,6 +1127,122 @@ describe('Bindings', () => {
     it('default admin ACL', () => {
       expect(deployment.toQuiltRepresentation().adminACL).to.eql([]);
     });
+    it('health policy', () => {
+      deployment = b.createDeployment({
+        healthPolicy: { replaceAfter: 10, maxReplacements: 2 },
+      });
+      expect(deployment.toQuiltRepresentation().healthPolicy).to.eql(
+        { replaceAfter: 10, maxReplacements: 2 });
+    });
+    it('default health policy', () => {
+      expect(deployment.toQuiltRepresentation().healthPolicy).to.equal(undefined);
+    });
+    it('system images', () => {
+      deployment = b.createDeployment({
+        systemImages: { etcd: 'mirror/etcd:v3.0.17' },
+        disableCadvisor: true,
+      });
+      const rep = deployment.toQuiltRepresentation();
+      expect(rep.systemImages).to.eql({ etcd: 'mirror/etcd:v3.0.17' });
+      expect(rep.disableCadvisor).to.equal(true);
+    });
+    it('default system images', () => {
+      const rep = deployment.toQuiltRepresentation();
+      expect(rep.systemImages).to.eql({});
+      expect(rep.disableCadvisor).to.equal(false);
+    });
+    it('errors on invalid system images', () => {
+      expect(() => b.createDeployment({ systemImages: { etcd: 3 } }))
+        .to.throw('systemImages must be a string map (value 3 associated ' +
+          'with etcd is not a string)');
+      expect(() => b.createDeployment({ systemImages: { foo: 'bar' } }))
+        .to.throw('Unrecognized keys passed to systemImages: foo');
+      expect(() => b.createDeployment({ disableCadvisor: 'yes' }))
+        .to.throw('disableCadvisor must be a boolean (was: "yes")');
+    });
+    it('dns upstream', () => {
+      deployment = b.createDeployment({ dnsUpstream: ['8.8.8.8'] });
+      expect(deployment.toQuiltRepresentation().dnsUpstream)
+        .to.eql(['8.8.8.8']);
+      expect(() => b.createDeployment({ dnsUpstream: '8.8.8.8' }))
+        .to.throw('dnsUpstream must be an array of strings ' +
+          '(was: "8.8.8.8")');
+    });
+    it('dns domain', () => {
+      deployment = b.createDeployment({ dnsDomain: 'Svc.Example.com' });
+      const c = new b.Container('host', 'image');
+      const lb = new b.LoadBalancer('lb', [c]);
+      expect(c.getHostname()).to.equal('host.svc.example.com');
+      expect(lb.hostname()).to.equal('lb.svc.example.com');
+      const rep = deployment.toQuiltRepresentation();
+      expect(rep.dnsDomain).to.equal('svc.example.com');
+      expect(rep.dnsNamespaceSubdomain).to.equal(false);
+    });
+    it('dns namespace subdomain', () => {
+      deployment = b.createDeployment({
+        namespace: 'ns',
+        dnsNamespaceSubdomain: true,
+      });
+      expect(new b.Container('host', 'image').getHostname())
+        .to.equal('host.ns.q');
+    });
+    it('dns domain without a deployment', () => {
+      const c = new b.Container('host', 'image');
+      const lb = new b.LoadBalancer('lb', [c]);
+      global._quiltDeployment = undefined; // eslint-disable-line
+      try {
+        expect(c.getHostname()).to.equal('host.q');
+        expect(lb.hostname()).to.equal('lb.q');
+      } finally {
+        global._quiltDeployment = deployment; // eslint-disable-line
+      }
+    });
+    it('network', () => {
+      deployment = b.createDeployment({});
+      expect(deployment.toQuiltRepresentation().network).to.equal(undefined);
+
+      deployment = b.createDeployment({
+        network: { subnet: '172.30.0.0/16', mtu: 8950, encapsulation: 'vxlan' },
+      });
+      expect(deployment.toQuiltRepresentation().network).to.eql({
+        subnet: '172.30.0.0/16',
+        gatewayIP: '',
+        loadBalancerIP: '',
+        mtu: 8950,
+        encapsulation: 'vxlan',
+      });
+    });
+    it('errors on an invalid network', () => {
+      expect(() => b.createDeployment({ network: '10.0.0.0/8' }))
+        .to.throw('network must be an object (was: "10.0.0.0/8")');
+      expect(() => b.createDeployment({ network: { cidr: '10.0.0.0/8' } }))
+        .to.throw('Unrecognized keys passed to network: cidr');
+      expect(() => b.createDeployment({ network: { mtu: '1400' } }))
+        .to.throw('mtu must be a number (was: "1400")');
+      expect(() => b.createDeployment({ network: { mtu: 1400.5 } }))
+        .to.throw('mtu must be a positive integer (was: 1400.5)');
+      expect(() => b.createDeployment({ network: { encapsulation: 'gre' } }))
+        .to.throw('encapsulation must be one of stt, geneve, vxlan (was: "gre")');
+    });
+    it('errors on an invalid dns domain', () => {
+      expect(() => b.createDeployment({ dnsDomain: 'bad_domain' }))
+        .to.throw('dnsDomain must be a valid DNS name (was: "bad_domain")');
+      expect(() => b.createDeployment({ dnsDomain: 'example.com.' }))
+        .to.throw('dnsDomain must be a valid DNS name (was: "example.com.")');
+      expect(() => b.createDeployment({
+        namespace: 'my_ns',
+        dnsNamespaceSubdomain: true,
+      })).to.throw('dnsNamespaceSubdomain requires the namespace to be a ' +
+        'valid DNS label (was: "my_ns")');
+    });
+    it('errors on an invalid health policy', () => {
+      expect(() => b.createDeployment({ healthPolicy: 10 }))
+        .to.throw('healthPolicy must be an object (was: 10)');
+      expect(() => b.createDeployment({ healthPolicy: { replaceAfter: '1' } }))
+        .to.throw('replaceAfter must be a number (was: "1")');
+      expect(() => b.createDeployment({ healthPolicy: { badArg: 1 } }))
+        .to.throw('Unrecognized keys passed to healthPolicy: badArg');
+    });
   });
   describe('githubKeys()', () => {});
   describe('baseInfrastructure()', () => {
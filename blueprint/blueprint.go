@@ -1,25 +1,108 @@
 package blueprint
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"os/exec"
+	"strings"
 )
 
 // A Blueprint is an abstract representation of the policy language.
 type Blueprint struct {
 	Containers    []Container    `json:",omitempty"`
 	LoadBalancers []LoadBalancer `json:",omitempty"`
+	Ingresses     []Ingress      `json:",omitempty"`
 	Connections   []Connection   `json:",omitempty"`
 	Placements    []Placement    `json:",omitempty"`
 	Machines      []Machine      `json:",omitempty"`
 
-	AdminACL  []string `json:",omitempty"`
-	MaxPrice  float64  `json:",omitempty"`
-	Namespace string   `json:",omitempty"`
+	AdminACL     []string      `json:",omitempty"`
+	MaxPrice     float64       `json:",omitempty"`
+	Namespace    string        `json:",omitempty"`
+	HealthPolicy *HealthPolicy `json:",omitempty"`
+
+	// SystemImages overrides the images of the containers Quilt runs on each
+	// machine.  The keys are "etcd", "ovs", "registry", "cadvisor" and "router".
+	SystemImages    map[string]string `json:",omitempty"`
+	DisableCadvisor bool              `json:",omitempty"`
+
+	// DNSUpstream lists the resolvers that minions forward queries for names
+	// outside of Quilt to.  If it's empty, each minion uses its own resolvers.
+	DNSUpstream []string `json:",omitempty"`
+
+	// DNSDomain is the DNS domain of the deployment's hostnames.  If
+	// DNSNamespaceSubdomain is set, hostnames are within a subdomain named
+	// after the namespace instead.
+	DNSDomain             string `json:",omitempty"`
+	DNSNamespaceSubdomain bool   `json:",omitempty"`
+
+	// Network configures the addressing and encapsulation of the container
+	// network.  Unset fields take their values from DefaultNetwork.
+	Network *NetworkConfig `json:",omitempty"`
+}
+
+// DefaultDomain is the DNS domain of hostnames in deployments that don't set one.
+const DefaultDomain = "q"
+
+// NetworkConfig describes the virtual network that containers are attached to.
+type NetworkConfig struct {
+	// Subnet is the CIDR from which containers and load balancers are given
+	// addresses.
+	Subnet string `json:",omitempty"`
+
+	// GatewayIP is the address of the router that containers use to reach
+	// hosts outside of Subnet, and LoadBalancerIP is reserved for the load
+	// balancer router.  They default to the first two addresses in Subnet.
+	GatewayIP      string `json:",omitempty"`
+	LoadBalancerIP string `json:",omitempty"`
+
+	// MTU is the MTU of container interfaces.  It must leave room for the
+	// encapsulation headers added to traffic between machines.
+	MTU int `json:",omitempty"`
+
+	// Encapsulation is the tunneling protocol used between machines.
+	Encapsulation string `json:",omitempty"`
+}
+
+// The supported tunneling protocols.
+const (
+	STT    = "stt"
+	Geneve = "geneve"
+	VXLAN  = "vxlan"
+)
+
+// DefaultNetwork is the container network of deployments that don't configure
+// one.
+var DefaultNetwork = NetworkConfig{
+	Subnet:         "10.0.0.0/8",
+	GatewayIP:      "10.0.0.1",
+	LoadBalancerIP: "10.0.0.2",
+	MTU:            1400,
+	Encapsulation:  STT,
+}
+
+// The range of container MTUs we accept.  The lower bound is the minimum MTU
+// of an IPv4 link.
+const (
+	minMTU = 576
+	maxMTU = 9000
+)
+
+// A HealthPolicy describes how the daemon should react to machines whose minion
+// stops responding.
+type HealthPolicy struct {
+	// ReplaceAfter is the number of minutes a machine may stay disconnected
+	// before it is replaced.  Zero disables automatic replacement.
+	ReplaceAfter int `json:",omitempty"`
+
+	// MaxReplacements limits how many times a single machine may be replaced,
+	// so that a machine that keeps failing doesn't cause an endless boot loop.
+	MaxReplacements int `json:",omitempty"`
 }
 
 // A Placement constraint guides on what type of machine a container can be
@@ -52,21 +135,170 @@ type Container struct {
 	Env               map[string]string `json:",omitempty"`
 	FilepathToContent map[string]string `json:",omitempty"`
 	Hostname          string            `json:",omitempty"`
+	Security          *Security         `json:",omitempty"`
+	Bandwidth         *Bandwidth        `json:",omitempty"`
+
+	// The DSCP value, between 1 and MaxTrafficClass, that the container's
+	// packets are marked with, or zero if they aren't marked.  It's also the
+	// minor number of the container's net_cls classid.
+	TrafficClass int `json:",omitempty"`
+}
+
+// MaxTrafficClass is the largest traffic class, as DSCP values are six bits.
+const MaxTrafficClass = 63
+
+// Bandwidth limits the rates, in bits per second, at which a container receives
+// (Ingress) and sends (Egress) traffic.  A zero rate is unlimited.
+type Bandwidth struct {
+	Ingress int `json:",omitempty"`
+	Egress  int `json:",omitempty"`
+}
+
+// String describes the limits of `bw`, or returns the empty string if there are
+// none.
+func (bw *Bandwidth) String() string {
+	if bw == nil {
+		return ""
+	}
+
+	var limits []string
+	if bw.Ingress != 0 {
+		limits = append(limits, "ingress "+FormatRate(bw.Ingress))
+	}
+	if bw.Egress != 0 {
+		limits = append(limits, "egress "+FormatRate(bw.Egress))
+	}
+	return strings.Join(limits, ", ")
+}
+
+// FormatRate formats a rate in bits per second with the largest unit that
+// represents it exactly, e.g. "10mbit".
+func FormatRate(bps int) string {
+	for _, unit := range []struct {
+		name string
+		size int
+	}{{"gbit", 1000 * 1000 * 1000}, {"mbit", 1000 * 1000}, {"kbit", 1000}} {
+		if bps != 0 && bps%unit.size == 0 {
+			return fmt.Sprintf("%d%s", bps/unit.size, unit.name)
+		}
+	}
+	return fmt.Sprintf("%dbit", bps)
+}
+
+// Security describes the privileges a container runs with.  Containers without a
+// Security section run unprivileged, with Docker's default capabilities and
+// profiles.
+type Security struct {
+	Privileged bool `json:",omitempty"`
+
+	// Linux capabilities to add to, or drop from, Docker's defaults, e.g.
+	// "NET_ADMIN".
+	CapAdd  []string `json:",omitempty"`
+	CapDrop []string `json:",omitempty"`
+
+	// The user, and optionally group, that runs the container's command.
+	User string `json:",omitempty"`
+
+	ReadOnlyRootfs bool `json:",omitempty"`
+
+	// Seccomp is either "default", for Docker's default seccomp profile, or
+	// "unconfined".  AppArmor is the name of the AppArmor profile to confine
+	// the container with.
+	Seccomp  string `json:",omitempty"`
+	AppArmor string `json:",omitempty"`
+}
+
+// IsPrivileged returns whether the container runs in privileged mode.  A nil
+// Security is unprivileged.
+func (sec *Security) IsPrivileged() bool {
+	return sec != nil && sec.Privileged
+}
+
+func (sec *Security) String() string {
+	if sec == nil {
+		return ""
+	}
+	return fmt.Sprintf("%+v", *sec)
 }
 
 // A LoadBalancer represents a load balanced group of containers.
 type LoadBalancer struct {
 	Name      string   `json:",omitempty"`
 	Hostnames []string `json:",omitempty"`
+	Probe     *Probe   `json:",omitempty"`
+}
+
+// A Probe describes how minions check whether the backends of a load balancer are
+// healthy.  Backends that fail the probe don't receive load balanced traffic.
+type Probe struct {
+	// Port is the port that backends are probed on.
+	Port int `json:",omitempty"`
+
+	// If Path is set, the probe is an HTTP GET request for Path, which must
+	// respond with a status below 400.  Otherwise, the probe only checks that
+	// a TCP connection can be established.
+	Path string `json:",omitempty"`
+}
+
+// String returns a description of the probe, or the empty string if `p` is nil.
+func (p *Probe) String() string {
+	if p == nil {
+		return ""
+	}
+
+	if p.Path == "" {
+		return fmt.Sprintf("tcp:%d", p.Port)
+	}
+	return fmt.Sprintf("http:%d%s", p.Port, p.Path)
+}
+
+// An Ingress routes HTTP requests from the public internet to containers and load
+// balancers, based on the host and path of each request.
+type Ingress struct {
+	Name         string               `json:",omitempty"`
+	Routes       []IngressRoute       `json:",omitempty"`
+	Certificates []IngressCertificate `json:",omitempty"`
+
+	// The public ports that the ingress accepts HTTP and HTTPS requests on.
+	// HTTPS is only served if there are Certificates.
+	HTTPPort  int `json:",omitempty"`
+	HTTPSPort int `json:",omitempty"`
+}
+
+// An IngressRoute forwards requests for Host whose path begins with Path to Port of
+// the container or load balancer with the hostname To.  Routes without a Host
+// match requests for hosts that no other route matches.
+type IngressRoute struct {
+	Host string `json:",omitempty"`
+	Path string `json:",omitempty"`
+	To   string `json:",omitempty"`
+	Port int    `json:",omitempty"`
+}
+
+// An IngressCertificate names the files holding the PEM encoded certificate and
+// private key presented to HTTPS clients requesting Host.  The files are read from
+// IngressCertificateDir on each worker, so that private keys aren't stored in the
+// blueprint.
+type IngressCertificate struct {
+	Host     string `json:",omitempty"`
+	CertFile string `json:",omitempty"`
+	KeyFile  string `json:",omitempty"`
 }
 
+// IngressCertificateDir is the directory on each worker that the files of ingress
+// certificates are read from.
+const IngressCertificateDir = "/etc/quilt/certs"
+
 // A Connection allows the container with the `From` hostname to speak to the container
-// with the `To` hostname in ports in the range [MinPort, MaxPort]
+// with the `To` hostname in ports in the range [MinPort, MaxPort].  If Bandwidth is
+// set, it limits the rate, in bits per second, of the traffic in each direction of
+// the connection.
 type Connection struct {
-	From    string `json:",omitempty"`
-	To      string `json:",omitempty"`
-	MinPort int    `json:",omitempty"`
-	MaxPort int    `json:",omitempty"`
+	From      string `json:",omitempty"`
+	To        string `json:",omitempty"`
+	MinPort   int    `json:",omitempty"`
+	MaxPort   int    `json:",omitempty"`
+	Bandwidth int    `json:",omitempty"`
 }
 
 // A ConnectionSlice allows for slices of Collections to be used in joins
@@ -85,6 +317,21 @@ type Machine struct {
 	SSHKeys     []string `json:",omitempty"`
 	FloatingIP  string   `json:",omitempty"`
 	Preemptible bool     `json:",omitempty"`
+
+	// Image overrides the provider's default machine image, and BootScript is
+	// run after Quilt's own boot script.
+	Image      string `json:",omitempty"`
+	BootScript string `json:",omitempty"`
+
+	// If Autoscale is set, the machine is a template for a group of workers
+	// whose size changes with the load on the cluster.
+	Autoscale *Autoscale `json:",omitempty"`
+}
+
+// Autoscale bounds the number of workers an autoscaling group may boot.
+type Autoscale struct {
+	Min int `json:",omitempty"`
+	Max int `json:",omitempty"`
 }
 
 // A Range defines a range of acceptable values for a Machine attribute
@@ -97,6 +344,21 @@ type Range struct {
 // network.
 const PublicInternetLabel = "public"
 
+// Connections to addresses outside of Quilt label their destination with one of
+// these prefixes, followed by a CIDR or a domain name, e.g. "cidr:52.1.0.0/16" or
+// "domain:api.example.com".
+const (
+	CIDRLabelPrefix   = "cidr:"
+	DomainLabelPrefix = "domain:"
+)
+
+// IsExternalLabel returns whether `label` refers to a CIDR or domain outside of
+// Quilt.
+func IsExternalLabel(label string) bool {
+	return strings.HasPrefix(label, CIDRLabelPrefix) ||
+		strings.HasPrefix(label, DomainLabelPrefix)
+}
+
 // Accepts returns true if `x` is within the range specified by `blueprintr` (include),
 // or if no max is specified and `x` is larger than `blueprintr.min`.
 func (blueprintr Range) Accepts(x float64) bool {
@@ -151,6 +413,134 @@ func FromJSON(jsonStr string) (bp Blueprint, err error) {
 	return bp, err
 }
 
+// Domain returns the DNS domain that the deployment's hostnames belong to.
+func (bp Blueprint) Domain() string {
+	domain := bp.DNSDomain
+	if domain == "" {
+		domain = DefaultDomain
+	}
+
+	if bp.DNSNamespaceSubdomain && bp.Namespace != "" {
+		domain = bp.Namespace + "." + domain
+	}
+	return strings.ToLower(domain)
+}
+
+// ContainerNetwork returns the deployment's container network, with the fields
+// it doesn't set filled in from DefaultNetwork.
+func (bp Blueprint) ContainerNetwork() NetworkConfig {
+	if bp.Network == nil {
+		return DefaultNetwork
+	}
+	return bp.Network.WithDefaults()
+}
+
+// WithDefaults returns a copy of `nc` with its unset fields filled in.  If a
+// subnet is set, the gateway and load balancer addresses default to its first
+// two addresses rather than to those of DefaultNetwork.
+func (nc NetworkConfig) WithDefaults() NetworkConfig {
+	if nc.Subnet == "" {
+		nc.Subnet = DefaultNetwork.Subnet
+	}
+
+	if _, subnet, err := net.ParseCIDR(nc.Subnet); err == nil {
+		if nc.GatewayIP == "" {
+			nc.GatewayIP = nthIP(*subnet, 1)
+		}
+		if nc.LoadBalancerIP == "" {
+			nc.LoadBalancerIP = nthIP(*subnet, 2)
+		}
+	}
+
+	if nc.MTU == 0 {
+		nc.MTU = DefaultNetwork.MTU
+	}
+
+	if nc.Encapsulation == "" {
+		nc.Encapsulation = DefaultNetwork.Encapsulation
+	}
+	return nc
+}
+
+// Validate returns an error if `nc` isn't a usable container network.  It
+// expects that the defaults have already been filled in.
+func (nc NetworkConfig) Validate() error {
+	ip, subnet, err := net.ParseCIDR(nc.Subnet)
+	if err != nil || ip.To4() == nil {
+		return fmt.Errorf("network subnet must be an IPv4 CIDR (was: %q)",
+			nc.Subnet)
+	}
+
+	if !ip.Equal(subnet.IP) {
+		return fmt.Errorf("network subnet %s has host bits set", nc.Subnet)
+	}
+
+	// Containers need some room beside the network, gateway and load
+	// balancer addresses.
+	if ones, _ := subnet.Mask.Size(); ones > 28 {
+		return fmt.Errorf("network subnet %s is too small", nc.Subnet)
+	}
+
+	gateway := net.ParseIP(nc.GatewayIP)
+	if gateway == nil || !subnet.Contains(gateway) {
+		return fmt.Errorf("network gateway %q must be an address in %s",
+			nc.GatewayIP, nc.Subnet)
+	}
+
+	lb := net.ParseIP(nc.LoadBalancerIP)
+	if lb == nil || !subnet.Contains(lb) {
+		return fmt.Errorf("network load balancer IP %q must be an address "+
+			"in %s", nc.LoadBalancerIP, nc.Subnet)
+	}
+
+	if gateway.Equal(lb) || gateway.Equal(subnet.IP) || lb.Equal(subnet.IP) {
+		return fmt.Errorf("the network gateway (%s), load balancer IP (%s) "+
+			"and subnet address must all differ", gateway, lb)
+	}
+
+	broadcast := BroadcastIP(*subnet)
+	if gateway.Equal(broadcast) || lb.Equal(broadcast) {
+		return fmt.Errorf("the network gateway (%s) and load balancer IP (%s) "+
+			"can't be the broadcast address", gateway, lb)
+	}
+
+	if nc.MTU < minMTU || nc.MTU > maxMTU {
+		return fmt.Errorf("network MTU must be between %d and %d (was: %d)",
+			minMTU, maxMTU, nc.MTU)
+	}
+
+	switch nc.Encapsulation {
+	case STT, Geneve, VXLAN:
+	default:
+		return fmt.Errorf("unknown network encapsulation %q: must be %q, "+
+			"%q or %q", nc.Encapsulation, STT, Geneve, VXLAN)
+	}
+	return nil
+}
+
+// BroadcastIP returns the broadcast address of the IPv4 `subnet`.
+func BroadcastIP(subnet net.IPNet) net.IP {
+	ip := subnet.IP.To4()
+	res := make(net.IP, 4)
+	for i := range res {
+		res[i] = ip[i] | ^subnet.Mask[len(subnet.Mask)-4+i]
+	}
+	return res
+}
+
+// nthIP returns the address `n` after the network address of `subnet`.
+func nthIP(subnet net.IPNet, n uint32) string {
+	ip := subnet.IP.To4()
+	if ip == nil {
+		return ""
+	}
+
+	ip32 := binary.BigEndian.Uint32(ip) + n
+	res := make(net.IP, 4)
+	binary.BigEndian.PutUint32(res, ip32)
+	return res.String()
+}
+
 // String returns the Blueprint in its deployment representation.
 func (bp Blueprint) String() string {
 	jsonBytes, err := json.Marshal(bp)
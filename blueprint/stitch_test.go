@@ -1,6 +1,7 @@
 package blueprint
 
 import (
+	"net"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,3 +14,108 @@ func TestMissingNode(t *testing.T) {
 	_, err := FromFile("unused")
 	assert.Error(t, err)
 }
+
+func TestDomain(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "q", Blueprint{}.Domain())
+	assert.Equal(t, "q", Blueprint{Namespace: "ns"}.Domain())
+	assert.Equal(t, "svc.example.com",
+		Blueprint{DNSDomain: "Svc.Example.com"}.Domain())
+	assert.Equal(t, "ns.svc.example.com", Blueprint{Namespace: "ns",
+		DNSDomain: "svc.example.com", DNSNamespaceSubdomain: true}.Domain())
+	assert.Equal(t, "ns.q", Blueprint{Namespace: "ns",
+		DNSNamespaceSubdomain: true}.Domain())
+}
+
+func TestContainerNetwork(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, DefaultNetwork, Blueprint{}.ContainerNetwork())
+	assert.Equal(t, DefaultNetwork,
+		Blueprint{Network: &NetworkConfig{}}.ContainerNetwork())
+
+	bp := Blueprint{Network: &NetworkConfig{
+		Subnet: "172.30.0.0/16", Encapsulation: VXLAN}}
+	assert.Equal(t, NetworkConfig{
+		Subnet:         "172.30.0.0/16",
+		GatewayIP:      "172.30.0.1",
+		LoadBalancerIP: "172.30.0.2",
+		MTU:            1400,
+		Encapsulation:  VXLAN,
+	}, bp.ContainerNetwork())
+
+	bp = Blueprint{Network: &NetworkConfig{Subnet: "172.30.0.0/16",
+		GatewayIP: "172.30.255.254", MTU: 8950}}
+	assert.Equal(t, NetworkConfig{
+		Subnet:         "172.30.0.0/16",
+		GatewayIP:      "172.30.255.254",
+		LoadBalancerIP: "172.30.0.2",
+		MTU:            8950,
+		Encapsulation:  STT,
+	}, bp.ContainerNetwork())
+}
+
+func TestValidateNetwork(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, DefaultNetwork.Validate())
+	assert.NoError(t, NetworkConfig{Subnet: "192.168.64.0/20",
+		Encapsulation: Geneve}.WithDefaults().Validate())
+
+	checkErr := func(nc NetworkConfig, exp string) {
+		err := nc.WithDefaults().Validate()
+		if assert.Error(t, err) {
+			assert.Equal(t, exp, err.Error())
+		}
+	}
+
+	checkErr(NetworkConfig{Subnet: "10.0.0.0"},
+		`network subnet must be an IPv4 CIDR (was: "10.0.0.0")`)
+	checkErr(NetworkConfig{Subnet: "fd00::/64"},
+		`network subnet must be an IPv4 CIDR (was: "fd00::/64")`)
+	checkErr(NetworkConfig{Subnet: "10.1.2.3/8"},
+		"network subnet 10.1.2.3/8 has host bits set")
+	checkErr(NetworkConfig{Subnet: "10.0.0.0/30"},
+		"network subnet 10.0.0.0/30 is too small")
+	checkErr(NetworkConfig{Subnet: "10.0.0.0/16", GatewayIP: "10.1.0.1"},
+		`network gateway "10.1.0.1" must be an address in 10.0.0.0/16`)
+	checkErr(NetworkConfig{Subnet: "10.0.0.0/16", LoadBalancerIP: "bad"},
+		`network load balancer IP "bad" must be an address in 10.0.0.0/16`)
+	checkErr(NetworkConfig{Subnet: "10.0.0.0/16", GatewayIP: "10.0.0.2"},
+		"the network gateway (10.0.0.2), load balancer IP (10.0.0.2) "+
+			"and subnet address must all differ")
+	checkErr(NetworkConfig{Subnet: "10.0.0.0/16", GatewayIP: "10.0.0.0"},
+		"the network gateway (10.0.0.0), load balancer IP (10.0.0.2) "+
+			"and subnet address must all differ")
+	checkErr(NetworkConfig{Subnet: "10.0.0.0/16", GatewayIP: "10.0.255.255"},
+		"the network gateway (10.0.255.255) and load balancer IP (10.0.0.2) "+
+			"can't be the broadcast address")
+	checkErr(NetworkConfig{Subnet: "10.0.0.0/16",
+		LoadBalancerIP: "10.0.255.255"},
+		"the network gateway (10.0.0.1) and load balancer IP (10.0.255.255) "+
+			"can't be the broadcast address")
+	checkErr(NetworkConfig{MTU: 100},
+		"network MTU must be between 576 and 9000 (was: 100)")
+	checkErr(NetworkConfig{Encapsulation: "gre"},
+		`unknown network encapsulation "gre": must be "stt", "geneve" or "vxlan"`)
+}
+
+func TestBroadcastIP(t *testing.T) {
+	t.Parallel()
+
+	check := func(cidr, exp string) {
+		_, subnet, err := net.ParseCIDR(cidr)
+		assert.NoError(t, err)
+		assert.Equal(t, exp, BroadcastIP(*subnet).String())
+	}
+	check("10.0.0.0/8", "10.255.255.255")
+	check("172.30.0.0/16", "172.30.255.255")
+	check("192.168.64.0/20", "192.168.79.255")
+
+	// Masks may be 16 bytes long.
+	assert.Equal(t, "10.255.255.255", BroadcastIP(net.IPNet{
+		IP:   net.IPv4(10, 0, 0, 0),
+		Mask: net.CIDRMask(104, 128),
+	}).String())
+}
@@ -27,6 +27,9 @@ var commands = map[string]command.SubCommand{
 	"version":    command.NewVersionCommand(),
 	"debug-logs": command.NewDebugCommand(),
 	"counters":   &command.Counters{},
+	"audit":      &command.Audit{},
+	"health":     &command.Health{},
+	"traffic":    &command.Traffic{},
 }
 
 // Run parses and runs the cli subcommand given the command line arguments.
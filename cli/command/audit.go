@@ -0,0 +1,126 @@
+package command
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/quilt/quilt/api"
+	"github.com/quilt/quilt/util"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+var auditCommands = "quilt audit [OPTIONS]"
+var auditExplanation = `Display the daemon's audit log, which records each call that
+changed the deployment, who made it, and whether it succeeded.
+
+To show the calls made in the last day:
+quilt audit -since 24h
+
+To export the entire log as JSON lines:
+quilt audit -o audit.jsonl`
+
+// Audit implements the `quilt audit` command.
+type Audit struct {
+	since  time.Duration
+	output string
+
+	connectionHelper
+}
+
+// InstallFlags sets up parsing for command line flags.
+func (aCmd *Audit) InstallFlags(flags *flag.FlagSet) {
+	aCmd.connectionHelper.InstallFlags(flags)
+	flags.DurationVar(&aCmd.since, "since", 0,
+		"only show calls made within this duration (e.g. 1h30m)")
+	flags.StringVar(&aCmd.output, "o", "",
+		"write the records to this file as JSON lines instead of printing them")
+
+	flags.Usage = func() {
+		util.PrintUsageString(auditCommands, auditExplanation, flags)
+	}
+}
+
+// Parse parses the command line arguments for the audit command.
+func (aCmd *Audit) Parse(args []string) error {
+	return nil
+}
+
+// Run queries and displays the audit log.
+func (aCmd *Audit) Run() int {
+	records, err := aCmd.client.QueryAudit()
+	if err != nil {
+		log.WithError(err).Error("Failed to query the audit log")
+		return 1
+	}
+
+	if aCmd.since != 0 {
+		records = recordsSince(records, time.Now().Add(-aCmd.since))
+	}
+
+	if aCmd.output == "" {
+		printAudit(os.Stdout, records)
+		return 0
+	}
+
+	f, err := util.AppFs.Create(aCmd.output)
+	if err != nil {
+		log.WithError(err).Error("Failed to create output file")
+		return 1
+	}
+	defer f.Close()
+
+	if err := writeAuditJSON(f, records); err != nil {
+		log.WithError(err).Error("Failed to write audit records")
+		return 1
+	}
+	return 0
+}
+
+func recordsSince(records []api.AuditRecord, since time.Time) []api.AuditRecord {
+	var filtered []api.AuditRecord
+	for _, record := range records {
+		if !record.Time.Before(since) {
+			filtered = append(filtered, record)
+		}
+	}
+	return filtered
+}
+
+func writeAuditJSON(out io.Writer, records []api.AuditRecord) error {
+	encoder := json.NewEncoder(out)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printAudit(out io.Writer, records []api.AuditRecord) {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "TIME\tCALLER\tMETHOD\tREQUEST\tRESULT")
+	for _, record := range records {
+		caller := "local"
+		if record.Certificate != "" {
+			cert := record.Certificate
+			if len(cert) > 12 {
+				cert = cert[:12]
+			}
+			caller = fmt.Sprintf("%s (%s)", record.Role, cert)
+		}
+
+		method := strings.TrimPrefix(record.Method, "/API/")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			record.Time.Format(time.RFC3339), caller, method,
+			record.Request, record.Result)
+	}
+}
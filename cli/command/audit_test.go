@@ -0,0 +1,88 @@
+package command
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/quilt/quilt/api"
+	"github.com/quilt/quilt/api/client/mocks"
+	"github.com/quilt/quilt/util"
+)
+
+var testRecords = []api.AuditRecord{{
+	Time:    time.Date(2017, 11, 1, 12, 0, 0, 0, time.UTC),
+	Method:  "/API/Deploy",
+	Request: `namespace "ns": 1 machines, 2 containers`,
+	Result:  "ok",
+}, {
+	Time:        time.Date(2017, 11, 2, 12, 0, 0, 0, time.UTC),
+	Method:      "/API/Deploy",
+	Role:        "deployer",
+	Certificate: "0123456789abcdef",
+	Request:     "invalid blueprint",
+	Result:      "unexpected end of JSON input",
+}}
+
+func TestAuditFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := &Audit{}
+	flags := &flag.FlagSet{}
+	cmd.InstallFlags(flags)
+	assert.NotNil(t, flags.Usage)
+
+	assert.NoError(t, flags.Parse([]string{"-since", "1h", "-o", "out.jsonl"}))
+	assert.Equal(t, time.Hour, cmd.since)
+	assert.Equal(t, "out.jsonl", cmd.output)
+}
+
+func TestAuditRun(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	mockClient := new(mocks.Client)
+	cmd := &Audit{output: "out.jsonl"}
+	cmd.client = mockClient
+
+	mockClient.On("QueryAudit").Once().Return(nil, assert.AnError)
+	assert.Equal(t, 1, cmd.Run())
+
+	mockClient.On("QueryAudit").Once().Return(testRecords, nil)
+	assert.Equal(t, 0, cmd.Run())
+
+	out, err := util.ReadFile("out.jsonl")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Time":"2017-11-01T12:00:00Z","Method":"/API/Deploy",`+
+		`"Role":"","Certificate":"",`+
+		`"Request":"namespace \"ns\": 1 machines, 2 containers","Result":"ok"}
+{"Time":"2017-11-02T12:00:00Z","Method":"/API/Deploy","Role":"deployer",`+
+		`"Certificate":"0123456789abcdef","Request":"invalid blueprint",`+
+		`"Result":"unexpected end of JSON input"}
+`, out)
+}
+
+func TestRecordsSince(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2017, 11, 2, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, testRecords[1:], recordsSince(testRecords, since))
+	assert.Empty(t, recordsSince(testRecords, since.Add(24*time.Hour)))
+}
+
+func TestPrintAudit(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+	printAudit(&b, testRecords)
+	assert.Equal(t, `TIME                   CALLER                    METHOD   `+
+		`REQUEST                                    RESULT
+2017-11-01T12:00:00Z   local                     Deploy   `+
+		`namespace "ns": 1 machines, 2 containers   ok
+2017-11-02T12:00:00Z   deployer (0123456789ab)   Deploy   `+
+		`invalid blueprint                          unexpected end of JSON input
+`, b.String())
+}
@@ -6,12 +6,18 @@ import (
 	"encoding/base64"
 	"flag"
 	"fmt"
+	"path/filepath"
+	"strings"
 
+	homedir "github.com/mitchellh/go-homedir"
 	"golang.org/x/crypto/ssh"
 
+	"github.com/quilt/quilt/api"
 	"github.com/quilt/quilt/api/server"
 	"github.com/quilt/quilt/cli/command/credentials"
 	"github.com/quilt/quilt/cloud"
+	"github.com/quilt/quilt/connection"
+	connCredentials "github.com/quilt/quilt/connection/credentials"
 	"github.com/quilt/quilt/connection/credentials/tls"
 	tlsIO "github.com/quilt/quilt/connection/credentials/tls/io"
 	"github.com/quilt/quilt/db"
@@ -25,26 +31,38 @@ import (
 // Daemon contains the options for running the Quilt daemon.
 type Daemon struct {
 	adminSSHPrivateKey string
-
-	*connectionFlags
+	auditLog           string
+	listenAddrs        listenAddrs
+	tlsDir             string
 }
 
 // NewDaemonCommand creates a new Daemon command instance.
 func NewDaemonCommand() *Daemon {
-	return &Daemon{
-		connectionFlags: &connectionFlags{},
-	}
+	return &Daemon{}
 }
 
 var daemonCommands = "quilt daemon [OPTIONS]"
-var daemonExplanation = "Start the quilt daemon, which listens for quilt API requests."
+var daemonExplanation = `Start the quilt daemon, which listens for quilt API requests.
+
+The daemon may listen on several addresses at once. Connections to Unix sockets
+are secured by the socket's permissions, while TCP connections use the
+credentials in -tls-dir. For example, to accept remote connections while still
+serving local blueprint runs:
+quilt daemon -tls-dir ~/.quilt/tls -H unix:///tmp/quilt.sock -H tcp://0.0.0.0:9001`
 
 // InstallFlags sets up parsing for command line flags
 func (dCmd *Daemon) InstallFlags(flags *flag.FlagSet) {
-	dCmd.connectionFlags.InstallFlags(flags)
+	flags.Var(&dCmd.listenAddrs, "H", fmt.Sprintf("an address to listen on. "+
+		"May be repeated to listen on several addresses (default %s)",
+		api.DefaultSocket))
+	flags.StringVar(&dCmd.tlsDir, "tls-dir", "",
+		"the directory in which to lookup tls certs")
 	flags.StringVar(&dCmd.adminSSHPrivateKey, "admin-ssh-private-key", "",
 		"if specified, all machines will be configured to allow access from "+
 			"this private SSH key")
+	flags.StringVar(&dCmd.auditLog, "audit-log", defaultAuditLog(),
+		"the file to record calls that change the deployment in. If empty, "+
+			"calls aren't audited")
 	flags.Usage = func() {
 		util.PrintUsageString(daemonCommands, daemonExplanation, flags)
 	}
@@ -52,6 +70,9 @@ func (dCmd *Daemon) InstallFlags(flags *flag.FlagSet) {
 
 // Parse parses the command line arguments for the daemon command.
 func (dCmd *Daemon) Parse(args []string) error {
+	if len(dCmd.listenAddrs) == 0 {
+		dCmd.listenAddrs = listenAddrs{api.DefaultSocket}
+	}
 	return nil
 }
 
@@ -101,10 +122,17 @@ func (dCmd *Daemon) Run() int {
 
 	conn := db.New()
 	go engine.Run(conn, getPublicKey(sshKey))
-	go server.Run(conn, dCmd.host, true, creds)
+	go engine.RunAutoscaler(conn, creds)
+	go func() {
+		err := server.Run(conn, apiListeners(dCmd.listenAddrs, creds), true,
+			creds, dCmd.auditLog)
+		if err != nil {
+			log.WithError(err).Error("Failed to start the API server")
+		}
+	}()
 
 	var minionTLSDir string
-	if _, isTLS := creds.(tls.TLS); isTLS {
+	if tlsCreds, isTLS := creds.(tls.TLS); isTLS {
 		minionTLSDir = "/home/quilt/.quilt/tls"
 
 		ca, err := tlsIO.ReadCA(dCmd.tlsDir)
@@ -114,12 +142,55 @@ func (dCmd *Daemon) Run() int {
 		}
 
 		go cloud.SyncCredentials(conn, minionTLSDir, sshKey, ca)
+		go cloud.RotateCredentials(dCmd.tlsDir, ca, tlsCreds)
 	}
 
 	cloud.Run(conn, creds, minionTLSDir)
 	return 0
 }
 
+// listenAddrs is a flag.Value that collects the address given by each -H flag.
+type listenAddrs []string
+
+func (addrs *listenAddrs) String() string {
+	return strings.Join(*addrs, ", ")
+}
+
+func (addrs *listenAddrs) Set(addr string) error {
+	if _, _, err := api.ParseListenAddress(addr); err != nil {
+		return err
+	}
+	*addrs = append(*addrs, addr)
+	return nil
+}
+
+// apiListeners pairs each of `addrs` with the credentials that secure it. Unix
+// sockets don't require TLS, so that users don't need to supply credentials when
+// running blueprints locally. They're secured by the socket's permissions
+// instead. Every other listener is secured by `creds`.
+func apiListeners(addrs []string, creds connection.Credentials) []server.Listener {
+	var listeners []server.Listener
+	for _, addr := range addrs {
+		listenerCreds := creds
+		if proto, _, _ := api.ParseListenAddress(addr); proto == "unix" {
+			listenerCreds = connCredentials.Insecure{}
+		}
+		listeners = append(listeners, server.Listener{
+			Address: addr,
+			Creds:   listenerCreds,
+		})
+	}
+	return listeners
+}
+
+func defaultAuditLog() string {
+	dir, err := homedir.Dir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, ".quilt", "audit.log")
+}
+
 func newSSHPrivateKey() (ssh.Signer, error) {
 	key, err := goRSA.GenerateKey(rand.Reader, 2048)
 	if err != nil {
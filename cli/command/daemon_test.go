@@ -6,6 +6,10 @@ import (
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/quilt/quilt/api"
+	"github.com/quilt/quilt/api/server"
+	"github.com/quilt/quilt/connection/credentials"
+	"github.com/quilt/quilt/connection/credentials/tls"
 	"github.com/quilt/quilt/util"
 )
 
@@ -17,6 +21,31 @@ func TestDaemonFlags(t *testing.T) {
 
 	assert.NoError(t, err)
 	assert.Equal(t, "admin", cmd.adminSSHPrivateKey)
+	assert.Equal(t, listenAddrs{api.DefaultSocket}, cmd.listenAddrs)
+
+	cmd = NewDaemonCommand()
+	err = parseHelper(cmd, []string{"-H", "unix:///tmp/quilt.sock",
+		"-H", "tcp://0.0.0.0:9001"})
+	assert.NoError(t, err)
+	assert.Equal(t, listenAddrs{"unix:///tmp/quilt.sock", "tcp://0.0.0.0:9001"},
+		cmd.listenAddrs)
+
+	var addrs listenAddrs
+	assert.EqualError(t, addrs.Set("0.0.0.0:9001"),
+		"malformed listen address: 0.0.0.0:9001")
+	assert.Empty(t, addrs)
+}
+
+func TestAPIListeners(t *testing.T) {
+	t.Parallel()
+
+	creds := tls.TLS{}
+	listeners := apiListeners([]string{"unix:///tmp/quilt.sock",
+		"tcp://0.0.0.0:9001"}, creds)
+	assert.Equal(t, []server.Listener{
+		{Address: "unix:///tmp/quilt.sock", Creds: credentials.Insecure{}},
+		{Address: "tcp://0.0.0.0:9001", Creds: creds},
+	}, listeners)
 }
 
 func TestParsePrivateKey(t *testing.T) {
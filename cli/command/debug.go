@@ -46,6 +46,7 @@ type Debug struct {
 
 type logTarget struct {
 	ip          string
+	hostKeys    []string
 	dir         string
 	blueprintID string
 	cmds        []logCmd
@@ -171,9 +172,9 @@ func (dCmd Debug) Run() int {
 		return 1
 	}
 
-	ipMap := map[string]string{}
+	minions := map[string]db.Machine{}
 	for _, m := range machines {
-		ipMap[m.PrivateIP] = m.PublicIP
+		minions[m.PrivateIP] = m
 	}
 
 	containers, err := dCmd.client.QueryContainers()
@@ -187,7 +188,7 @@ func (dCmd Debug) Run() int {
 
 	var targets []logTarget
 	mTargets := machinesToTargets(machines)
-	cTargets := containersToTargets(containers, ipMap)
+	cTargets := containersToTargets(containers, minions)
 	if !(dCmd.machines || dCmd.containers) {
 		targets = append(append(targets, cTargets...), mTargets...)
 		if targets, err = filterTargets(targets, dCmd.ids); err != nil {
@@ -245,7 +246,7 @@ func (dCmd Debug) downloadLogs(targets []logTarget) int {
 			continue
 		}
 
-		conn, err := dCmd.sshGetter(t.ip, dCmd.privateKey)
+		conn, err := dCmd.sshGetter(t.ip, dCmd.privateKey, t.hostKeys)
 		if err != nil {
 			errno++
 			log.Error(err)
@@ -297,6 +298,7 @@ func machinesToTargets(machines []db.Machine) []logTarget {
 
 		t := logTarget{
 			ip:          m.PublicIP,
+			hostKeys:    m.HostKeys,
 			dir:         machineDir,
 			blueprintID: m.BlueprintID,
 			cmds:        append(machineCmds, roleCmds...),
@@ -306,21 +308,24 @@ func machinesToTargets(machines []db.Machine) []logTarget {
 	return targets
 }
 
-func containersToTargets(containers []db.Container, ips map[string]string) []logTarget {
+func containersToTargets(containers []db.Container,
+	minions map[string]db.Machine) []logTarget {
+
 	targets := []logTarget{}
 	for _, c := range containers {
 		if c.Minion == "" {
 			continue
 		}
 
-		ip, ok := ips[c.Minion]
+		m, ok := minions[c.Minion]
 		if !ok {
 			log.Errorf("No machine with private IP %s", c.Minion)
 			continue
 		}
 
 		t := logTarget{
-			ip:          ip,
+			ip:          m.PublicIP,
+			hostKeys:    m.HostKeys,
 			dir:         containerDir,
 			blueprintID: c.BlueprintID,
 			cmds:        nil,
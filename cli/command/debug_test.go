@@ -434,7 +434,7 @@ func TestDebug(t *testing.T) {
 		testCmd := test.cmd
 
 		mockSSHClient := new(mockSSH.Client)
-		testCmd.sshGetter = func(host string, keyPath string) (
+		testCmd.sshGetter = func(host string, keyPath string, _ []string) (
 			ssh.Client, error) {
 
 			assert.Equal(t, testCmd.privateKey, keyPath)
@@ -0,0 +1,153 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/quilt/quilt/api/client"
+	"github.com/quilt/quilt/api/pb"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/util"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+var healthCommands = "quilt health [OPTIONS]"
+var healthExplanation = `Check the health of the daemon and of every machine in the
+cluster.
+
+For the daemon, this includes whether each cloud provider region is reachable,
+whether the daemon is connected to its minions, and whether the engine is
+running. For each machine, this includes the Docker daemon, the system
+containers, OVS, and whether the machine knows the etcd leader.
+
+Exits with a non-zero status if any check fails.`
+
+// Health implements the `quilt health` command.
+type Health struct {
+	connectionHelper
+}
+
+// The health of a single process in the cluster.
+type targetHealth struct {
+	target string
+	checks []pb.HealthCheck
+	err    error
+}
+
+// InstallFlags sets up parsing for command line flags.
+func (hCmd *Health) InstallFlags(flags *flag.FlagSet) {
+	hCmd.connectionHelper.InstallFlags(flags)
+	flags.Usage = func() {
+		util.PrintUsageString(healthCommands, healthExplanation, flags)
+	}
+}
+
+// Parse parses the command line arguments for the health command.
+func (hCmd *Health) Parse(args []string) error {
+	return nil
+}
+
+// Run queries and displays the health of the cluster.
+func (hCmd *Health) Run() int {
+	results, err := queryHealth(hCmd.client)
+	if err != nil {
+		log.WithError(err).Error("Failed to query machines")
+		return 1
+	}
+
+	if unhealthy := printHealth(os.Stdout, results); unhealthy != 0 {
+		return 1
+	}
+	return 0
+}
+
+// queryHealth queries the health of the daemon, and of every machine that has
+// booted, in parallel.
+func queryHealth(c client.Client) ([]targetHealth, error) {
+	machines, err := c.QueryMachines()
+	if err != nil {
+		return nil, err
+	}
+
+	var booted []db.Machine
+	for _, m := range machines {
+		if m.PublicIP != "" {
+			booted = append(booted, m)
+		}
+	}
+	sort.Sort(machinesByID(booted))
+
+	results := make([]targetHealth, len(booted)+1)
+	results[0].target = daemonTarget
+	results[0].checks, results[0].err = c.QueryHealth("")
+
+	var wg sync.WaitGroup
+	for i, m := range booted {
+		wg.Add(1)
+		go func(result *targetHealth, m db.Machine) {
+			defer wg.Done()
+			result.target = util.ShortUUID(m.BlueprintID)
+			result.checks, result.err = c.QueryHealth(m.PublicIP)
+		}(&results[i+1], m)
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// printHealth writes a table of `results` to `out`, followed by a summary. It
+// returns the number of failed checks.
+func printHealth(out io.Writer, results []targetHealth) (unhealthy int) {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+
+	var total int
+	fmt.Fprintln(w, "TARGET\tCHECK\tSTATUS\tDETAIL")
+	for _, result := range results {
+		if result.err != nil {
+			total++
+			unhealthy++
+			fmt.Fprintf(w, "%s\t\tunreachable\t%s\n",
+				result.target, result.err)
+			continue
+		}
+
+		for _, check := range result.checks {
+			total++
+			status := "healthy"
+			if !check.Healthy {
+				unhealthy++
+				status = "unhealthy"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				result.target, check.Name, status, check.Detail)
+		}
+	}
+	w.Flush()
+
+	if unhealthy == 0 {
+		fmt.Fprintf(out, "\nAll %d checks passed\n", total)
+	} else {
+		fmt.Fprintf(out, "\n%d of %d checks failed\n", unhealthy, total)
+	}
+	return unhealthy
+}
+
+type machinesByID []db.Machine
+
+func (machines machinesByID) Len() int {
+	return len(machines)
+}
+
+func (machines machinesByID) Less(i, j int) bool {
+	return machines[i].BlueprintID < machines[j].BlueprintID
+}
+
+func (machines machinesByID) Swap(i, j int) {
+	machines[i], machines[j] = machines[j], machines[i]
+}
@@ -0,0 +1,81 @@
+package command
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/quilt/quilt/api/client/mocks"
+	"github.com/quilt/quilt/api/pb"
+	"github.com/quilt/quilt/db"
+)
+
+func TestHealthRun(t *testing.T) {
+	t.Parallel()
+
+	mockClient := new(mocks.Client)
+	mockClient.On("QueryMachines").Once().Return(nil, assert.AnError)
+
+	cmd := &Health{}
+	cmd.client = mockClient
+	assert.Equal(t, 1, cmd.Run())
+
+	mockClient.On("QueryMachines").Return(nil, nil)
+	mockClient.On("QueryHealth", "").Return(
+		[]pb.HealthCheck{{Name: "engine", Healthy: true}}, nil)
+	assert.Equal(t, 0, cmd.Run())
+}
+
+func TestQueryHealth(t *testing.T) {
+	t.Parallel()
+
+	mockClient := new(mocks.Client)
+	mockClient.On("QueryMachines").Return([]db.Machine{
+		{BlueprintID: "2", PublicIP: "2.2.2.2"},
+		{BlueprintID: "1", PublicIP: "1.1.1.1"},
+		{BlueprintID: "3"},
+	}, nil)
+	mockClient.On("QueryHealth", "").Return(
+		[]pb.HealthCheck{{Name: "engine", Healthy: true}}, nil)
+	mockClient.On("QueryHealth", "1.1.1.1").Return(nil, assert.AnError)
+	mockClient.On("QueryHealth", "2.2.2.2").Return(
+		[]pb.HealthCheck{{Name: "etcd", Detail: "no etcd leader"}}, nil)
+
+	results, err := queryHealth(mockClient)
+	assert.NoError(t, err)
+	assert.Equal(t, []targetHealth{
+		{target: "daemon",
+			checks: []pb.HealthCheck{{Name: "engine", Healthy: true}}},
+		{target: "1", err: assert.AnError},
+		{target: "2", checks: []pb.HealthCheck{
+			{Name: "etcd", Detail: "no etcd leader"}}},
+	}, results)
+}
+
+func TestPrintHealth(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+	unhealthy := printHealth(&b, []targetHealth{
+		{target: "daemon",
+			checks: []pb.HealthCheck{{Name: "engine", Healthy: true}}},
+		{target: "1", err: assert.AnError},
+		{target: "2", checks: []pb.HealthCheck{
+			{Name: "etcd", Detail: "no etcd leader"}}},
+	})
+	assert.Equal(t, 2, unhealthy)
+	assert.Equal(t, `TARGET   CHECK    STATUS        DETAIL
+daemon   engine   healthy       
+1                 unreachable   `+assert.AnError.Error()+`
+2        etcd     unhealthy     no etcd leader
+
+2 of 3 checks failed
+`, b.String())
+
+	b.Reset()
+	unhealthy = printHealth(&b, []targetHealth{{target: "daemon",
+		checks: []pb.HealthCheck{{Name: "engine", Healthy: true}}}})
+	assert.Equal(t, 0, unhealthy)
+	assert.Contains(t, b.String(), "All 1 checks passed")
+}
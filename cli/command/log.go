@@ -108,13 +108,14 @@ func (lCmd *Log) Run() int {
 
 	host := contHost
 	if resolvedMachine {
-		host = mach.PublicIP
+		host = mach
 		cmd = append(cmd, "minion")
 	} else {
 		cmd = append(cmd, cont.DockerID)
 	}
 
-	sshClient, err := lCmd.sshGetter(host, lCmd.privateKey)
+	sshClient, err := lCmd.sshGetter(host.PublicIP, lCmd.privateKey,
+		host.HostKeys)
 	if err != nil {
 		log.WithError(err).Info("Error opening SSH connection")
 		return 1
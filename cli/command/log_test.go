@@ -123,7 +123,8 @@ func TestLog(t *testing.T) {
 		testCmd := test.cmd
 
 		mockSSHClient := new(mockSSH.Client)
-		testCmd.sshGetter = func(host, key string) (ssh.Client, error) {
+		testCmd.sshGetter = func(host, key string, _ []string) (
+			ssh.Client, error) {
 			assert.Equal(t, test.expHost, host)
 			assert.Equal(t, "key", key)
 			return mockSSHClient, nil
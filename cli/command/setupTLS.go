@@ -4,6 +4,7 @@ import (
 	"errors"
 	"flag"
 
+	"github.com/quilt/quilt/connection/auth"
 	tlsIO "github.com/quilt/quilt/connection/credentials/tls/io"
 	"github.com/quilt/quilt/connection/credentials/tls/rsa"
 	"github.com/quilt/quilt/util"
@@ -13,17 +14,38 @@ import (
 
 // SetupTLS contains the options for setting up Quilt TLS.
 type SetupTLS struct {
-	outDir string
+	outDir  string
+	rotate  bool
+	caDir   string
+	roleStr string
+
+	role auth.Role
 }
 
 const setupTLSCommands = `quilt setup-tls [OPTIONS] OUT_DIR`
 const setupTLSExplanation = `Create the files necessary for TLS-encrypted communication
-with Quilt.  It generates private keys and certs for the signing CA, and peers.`
+with Quilt.  It generates private keys and certs for the signing CA, and peers.
+The peer certs grant the admin role, and are used by the daemon.
+
+With -role and -ca-dir, it instead creates client certs for OUT_DIR that grant the
+given role, signed by the CA in CA_DIR.  The roles are:
+  read-only: may query the deployment
+  deployer:  may also change the deployment
+  admin:     may call any RPC
+
+With -rotate, it reissues the peer certs in OUT_DIR using the existing CA, so that
+they can be replaced before they expire.  The certs keep their role.`
 
 // InstallFlags sets up flag parsing for the SetupTLS command.
 func (sCmd *SetupTLS) InstallFlags(flags *flag.FlagSet) {
 	flags.StringVar(&sCmd.outDir, "outDir", "",
 		"the directory to write the certificates")
+	flags.BoolVar(&sCmd.rotate, "rotate", false,
+		"reissue the signed certificate using the existing CA")
+	flags.StringVar(&sCmd.caDir, "ca-dir", "",
+		"the directory containing the CA to sign client certificates with")
+	flags.StringVar(&sCmd.roleStr, "role", "",
+		"the role granted by client certificates")
 
 	flags.Usage = func() {
 		util.PrintUsageString(setupTLSCommands, setupTLSExplanation, flags)
@@ -39,6 +61,18 @@ func (sCmd *SetupTLS) Parse(args []string) error {
 		sCmd.outDir = args[0]
 	}
 
+	if sCmd.roleStr != "" {
+		role, err := auth.ParseRole(sCmd.roleStr)
+		if err != nil {
+			return err
+		}
+		sCmd.role = role
+	}
+
+	if !sCmd.rotate && (sCmd.caDir == "") != (sCmd.roleStr == "") {
+		return errors.New("-role and -ca-dir must be used together")
+	}
+
 	return nil
 }
 
@@ -54,6 +88,14 @@ func (sCmd *SetupTLS) AfterRun() error {
 
 // Run creates the TLS configuration.
 func (sCmd *SetupTLS) Run() int {
+	if sCmd.rotate {
+		return sCmd.runRotate()
+	}
+
+	if sCmd.caDir != "" {
+		return sCmd.runClient()
+	}
+
 	if err := util.AppFs.Mkdir(sCmd.outDir, 0700); err != nil {
 		log.WithError(err).Error("Failed to create output directory")
 		return 1
@@ -67,19 +109,92 @@ func (sCmd *SetupTLS) Run() int {
 
 	// Generate a signed certificate for use by the Daemon server, and client
 	// connections.
-	signed, err := rsa.NewSigned(ca)
+	signed, err := rsa.NewSigned(ca, auth.Admin)
+	if err != nil {
+		log.WithError(err).Error("Unable to create signed key pair")
+		return 1
+	}
+
+	return writeTLSFiles(tlsIO.DaemonFiles(sCmd.outDir, ca, signed))
+}
+
+// runClient creates client certificates for `role`. The CA's private key isn't
+// copied, so the client can't mint certificates of its own.
+func (sCmd *SetupTLS) runClient() int {
+	ca, err := tlsIO.ReadCA(sCmd.caDir)
+	if err != nil {
+		log.WithError(err).Error("Failed to read CA")
+		return 1
+	}
+
+	if err := util.AppFs.Mkdir(sCmd.outDir, 0700); err != nil {
+		log.WithError(err).Error("Failed to create output directory")
+		return 1
+	}
+
+	signed, err := rsa.NewSigned(ca, sCmd.role)
 	if err != nil {
 		log.WithError(err).Error("Unable to create signed key pair")
 		return 1
 	}
 
-	for _, f := range tlsIO.DaemonFiles(sCmd.outDir, ca, signed) {
+	return writeTLSFiles(tlsIO.MinionFiles(sCmd.outDir, ca, signed))
+}
+
+// runRotate replaces the signed certificate with a new one from the existing CA.
+// A running daemon rotates its own certificate, and those of the minions, so this
+// is only necessary for directories that aren't used by a daemon.
+func (sCmd *SetupTLS) runRotate() int {
+	caDir := sCmd.caDir
+	if caDir == "" {
+		caDir = sCmd.outDir
+	}
+
+	ca, err := tlsIO.ReadCA(caDir)
+	if err != nil {
+		log.WithError(err).Error("Failed to read CA. " +
+			"Did you run `quilt setup-tls` to generate TLS credentials?")
+		return 1
+	}
+
+	// Certificates keep their role, except for those issued before roles were
+	// introduced, which were all issued to the daemon.
+	role := sCmd.role
+	if role == "" {
+		role = auth.Admin
+		certStr, err := util.ReadFile(tlsIO.SignedCertPath(sCmd.outDir))
+		if err == nil {
+			cert, err := rsa.ParseCert(ca, certStr)
+			if err == nil && auth.HasRole(cert) {
+				role = auth.CertRole(cert)
+			}
+		}
+	}
+
+	signed, err := rsa.NewSigned(ca, role)
+	if err != nil {
+		log.WithError(err).Error("Unable to create signed key pair")
+		return 1
+	}
+
+	if code := writeTLSFiles(tlsIO.SignedFiles(sCmd.outDir, signed)); code != 0 {
+		return code
+	}
+
+	log.WithFields(log.Fields{
+		"expiry": signed.NotAfter(),
+		"role":   role,
+	}).Info("Rotated signed certificate")
+	return 0
+}
+
+func writeTLSFiles(files []tlsIO.File) int {
+	for _, f := range files {
 		if err := util.WriteFile(f.Path, []byte(f.Content), f.Mode); err != nil {
 			log.WithError(err).WithField("path", f.Path).
 				Error("Unable to write file")
 			return 1
 		}
 	}
-
 	return 0
 }
@@ -5,7 +5,10 @@ import (
 	"testing"
 
 	"github.com/quilt/quilt/cli/command/credentials"
+	"github.com/quilt/quilt/connection/auth"
 	"github.com/quilt/quilt/connection/credentials/tls"
+	tlsIO "github.com/quilt/quilt/connection/credentials/tls/io"
+	"github.com/quilt/quilt/connection/credentials/tls/rsa"
 	"github.com/quilt/quilt/util"
 
 	"github.com/spf13/afero"
@@ -27,6 +30,76 @@ func TestSetupTLS(t *testing.T) {
 	assert.True(t, ok)
 }
 
+func TestSetupTLSRotate(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	// Rotation requires an existing CA.
+	cmd := SetupTLS{outDir: "tls", rotate: true}
+	assert.NotZero(t, cmd.Run())
+
+	cmd = SetupTLS{outDir: "tls"}
+	assert.Zero(t, cmd.Run())
+
+	caKey, err := util.ReadFile("tls/certificate_authority.key")
+	assert.NoError(t, err)
+	cert, err := util.ReadFile("tls/quilt.crt")
+	assert.NoError(t, err)
+
+	cmd = SetupTLS{outDir: "tls", rotate: true}
+	assert.Zero(t, cmd.Run())
+
+	newCAKey, err := util.ReadFile("tls/certificate_authority.key")
+	assert.NoError(t, err)
+	assert.Equal(t, caKey, newCAKey)
+
+	newCert, err := util.ReadFile("tls/quilt.crt")
+	assert.NoError(t, err)
+	assert.NotEqual(t, cert, newCert)
+
+	_, err = credentials.Read("tls")
+	assert.NoError(t, err)
+}
+
+func TestSetupTLSClient(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	cmd := SetupTLS{outDir: "tls"}
+	assert.Zero(t, cmd.Run())
+
+	cmd = SetupTLS{outDir: "client", caDir: "tls", role: auth.ReadOnly}
+	assert.Zero(t, cmd.Run())
+	assert.Equal(t, auth.ReadOnly, readRole(t, "tls", "client"))
+
+	// Clients don't get the CA's private key.
+	_, err := util.ReadFile("client/certificate_authority.key")
+	assert.Error(t, err)
+
+	// Rotated certificates keep their role.
+	cmd = SetupTLS{outDir: "client", caDir: "tls", rotate: true}
+	assert.Zero(t, cmd.Run())
+	assert.Equal(t, auth.ReadOnly, readRole(t, "tls", "client"))
+
+	cmd = SetupTLS{outDir: "tls", rotate: true}
+	assert.Zero(t, cmd.Run())
+	assert.Equal(t, auth.Admin, readRole(t, "tls", "tls"))
+
+	// Minting client certificates requires a CA.
+	cmd = SetupTLS{outDir: "other", caDir: "missing", role: auth.Deployer}
+	assert.NotZero(t, cmd.Run())
+}
+
+func readRole(t *testing.T, caDir, dir string) auth.Role {
+	ca, err := tlsIO.ReadCA(caDir)
+	assert.NoError(t, err)
+
+	certStr, err := util.ReadFile(tlsIO.SignedCertPath(dir))
+	assert.NoError(t, err)
+
+	cert, err := rsa.ParseCert(ca, certStr)
+	assert.NoError(t, err)
+	return auth.CertRole(cert)
+}
+
 func checkSetupTLSParsing(t *testing.T, args []string, exp string, expErr error) {
 	cmd := &SetupTLS{}
 	err := parseHelper(cmd, args)
@@ -35,6 +108,37 @@ func checkSetupTLSParsing(t *testing.T, args []string, exp string, expErr error)
 	assert.Equal(t, exp, cmd.outDir)
 }
 
+func TestSetupTLSRotateFlag(t *testing.T) {
+	t.Parallel()
+
+	cmd := &SetupTLS{}
+	assert.NoError(t, parseHelper(cmd, []string{"-rotate", "foo"}))
+	assert.True(t, cmd.rotate)
+	assert.Equal(t, "foo", cmd.outDir)
+}
+
+func TestSetupTLSRoleFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := &SetupTLS{}
+	assert.NoError(t, parseHelper(cmd,
+		[]string{"-role", "deployer", "-ca-dir", "tls", "foo"}))
+	assert.Equal(t, auth.Deployer, cmd.role)
+	assert.Equal(t, "tls", cmd.caDir)
+
+	cmd = &SetupTLS{}
+	err := parseHelper(cmd, []string{"-role", "root", "-ca-dir", "tls", "foo"})
+	assert.EqualError(t, err, "unknown role: root")
+
+	cmd = &SetupTLS{}
+	err = parseHelper(cmd, []string{"-role", "deployer", "foo"})
+	assert.EqualError(t, err, "-role and -ca-dir must be used together")
+
+	cmd = &SetupTLS{}
+	err = parseHelper(cmd, []string{"-ca-dir", "tls", "foo"})
+	assert.EqualError(t, err, "-role and -ca-dir must be used together")
+}
+
 func TestSetupTLSFlags(t *testing.T) {
 	t.Parallel()
 
@@ -12,6 +12,9 @@ import (
 
 	units "github.com/docker/go-units"
 	"github.com/quilt/quilt/blueprint"
+	"github.com/quilt/quilt/connection"
+	"github.com/quilt/quilt/connection/credentials/tls"
+	"github.com/quilt/quilt/connection/credentials/tls/rsa"
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/util"
 )
@@ -59,6 +62,8 @@ func (pCmd *Show) Run() int {
 }
 
 func (pCmd *Show) run() (err error) {
+	writeTLSWarnings(os.Stderr, pCmd.tlsDir, pCmd.creds, time.Now())
+
 	machines, err := pCmd.client.QueryMachines()
 	if err != nil {
 		return fmt.Errorf("unable to query machines: %s", err)
@@ -67,6 +72,16 @@ func (pCmd *Show) run() (err error) {
 	writeMachines(os.Stdout, machines)
 	fmt.Println()
 
+	groups, err := pCmd.client.QueryScalingGroups()
+	if err != nil {
+		return fmt.Errorf("unable to query scaling groups: %s", err)
+	}
+
+	if len(groups) != 0 {
+		writeScalingGroups(os.Stdout, groups)
+		fmt.Println()
+	}
+
 	clusterUp := false
 	for _, m := range machines {
 		if m.Status == db.Connected || m.Status == db.Reconnecting {
@@ -116,10 +131,43 @@ func (pCmd *Show) run() (err error) {
 
 	writeContainers(os.Stdout, containers, machines, connections, images,
 		!pCmd.noTruncate)
+	writeBandwidthLimits(os.Stdout, connections)
 
 	return nil
 }
 
+// writeTLSWarnings warns if the TLS credentials used to connect to the daemon
+// are about to expire.
+func writeTLSWarnings(fd io.Writer, tlsDir string, creds connection.Credentials,
+	now time.Time) {
+
+	tlsCreds, ok := creds.(tls.TLS)
+	if !ok {
+		return
+	}
+
+	if expiry := tlsCreds.CAExpiry(); now.Add(rsa.RenewBefore).After(expiry) {
+		fmt.Fprintf(fd, "WARNING: The TLS certificate authority in %s %s. "+
+			"It can't be rotated, so the cluster must be restarted with "+
+			"credentials from `quilt setup-tls`.\n",
+			tlsDir, expiryStr(expiry, now))
+	}
+
+	if expiry := tlsCreds.CertExpiry(); now.Add(rsa.RenewBefore).After(expiry) {
+		fmt.Fprintf(fd, "WARNING: The TLS certificate in %s %s. "+
+			"The daemon rotates it automatically, or it can be reissued "+
+			"with `quilt setup-tls -rotate %s`.\n",
+			tlsDir, expiryStr(expiry, now), tlsDir)
+	}
+}
+
+func expiryStr(expiry, now time.Time) string {
+	if expiry.Before(now) {
+		return fmt.Sprintf("expired %s ago", units.HumanDuration(now.Sub(expiry)))
+	}
+	return fmt.Sprintf("expires in %s", units.HumanDuration(expiry.Sub(now)))
+}
+
 func writeMachines(fd io.Writer, machines []db.Machine) {
 	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
 	defer w.Flush()
@@ -132,9 +180,41 @@ func writeMachines(fd io.Writer, machines []db.Machine) {
 			pubIP = m.FloatingIP
 		}
 
+		status := m.Status
+		if m.Replacements != 0 {
+			status = fmt.Sprintf("%s (replaced %d times)", status,
+				m.Replacements)
+		}
+
+		// Explain why the machine isn't booting.
+		if m.Error != "" && status == "" {
+			status = m.Error
+		} else if m.Error != "" {
+			status = fmt.Sprintf("%s (%s)", status, m.Error)
+		}
+
 		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
 			util.ShortUUID(m.BlueprintID), m.Role, m.Provider, m.Region,
-			m.Size, pubIP, m.Status)
+			m.Size, pubIP, status)
+	}
+}
+
+func writeScalingGroups(fd io.Writer, groups []db.ScalingGroup) {
+	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "SCALING GROUP\tMIN\tMAX\tSIZE\tLAST EVENT")
+
+	sort.Sort(db.ScalingGroupSlice(groups))
+	for _, group := range groups {
+		event := group.LastEvent
+		if !group.LastScaled.IsZero() {
+			duration := units.HumanDuration(time.Since(group.LastScaled))
+			event = fmt.Sprintf("%s %s ago", event, duration)
+		}
+
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n",
+			util.ShortUUID(group.BlueprintID), group.Min, group.Max,
+			group.Size, event)
 	}
 }
 
@@ -159,10 +239,12 @@ func writeContainers(fd io.Writer, containers []db.Container, machines []db.Mach
 	}
 
 	ipIDMap := map[string]string{}
-	idMachineMap := map[string]db.Machine{}
+	ipMachineMap := map[string]db.Machine{}
 	for _, m := range machines {
 		ipIDMap[m.PrivateIP] = m.BlueprintID
-		idMachineMap[m.BlueprintID] = m
+		if m.PrivateIP != "" {
+			ipMachineMap[m.PrivateIP] = m
+		}
 	}
 
 	machineDBC := map[string][]db.Container{}
@@ -201,12 +283,32 @@ func writeContainers(fd io.Writer, containers []db.Container, machines []db.Mach
 				status = dbc.Status
 			case dbc.Minion != "":
 				status = "scheduled"
+			case dbc.Unschedulable:
+				status = "unschedulable"
 			default:
 				if imgStatus, ok := imageStatusMap[dbc.Image]; ok {
 					status = imgStatus
 				}
 			}
 
+			if dbc.Health != "" {
+				status = strings.TrimSpace(
+					status + " (" + dbc.Health + ")")
+			}
+
+			if dbc.Security.IsPrivileged() {
+				status = strings.TrimSpace(status + " (privileged)")
+			}
+
+			if bw := dbc.Bandwidth.String(); bw != "" {
+				status = strings.TrimSpace(status + " (" + bw + ")")
+			}
+
+			if dbc.TrafficClass != 0 {
+				status = strings.TrimSpace(status +
+					fmt.Sprintf(" (class %d)", dbc.TrafficClass))
+			}
+
 			created := ""
 			if !dbc.Created.IsZero() {
 				createdTime := dbc.Created.Local()
@@ -215,7 +317,7 @@ func writeContainers(fd io.Writer, containers []db.Container, machines []db.Mach
 			}
 
 			publicPorts := hostnamePublicPorts[dbc.Hostname]
-			publicIP := publicIPStr(idMachineMap[machineID], publicPorts)
+			publicIP := publicIPStr(ipMachineMap[dbc.Minion], publicPorts)
 
 			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
 				util.ShortUUID(dbc.BlueprintID),
@@ -225,6 +327,30 @@ func writeContainers(fd io.Writer, containers []db.Container, machines []db.Mach
 	}
 }
 
+// writeBandwidthLimits writes the connections that have a bandwidth limit, if any.
+func writeBandwidthLimits(fd io.Writer, connections []db.Connection) {
+	var limited []db.Connection
+	for _, c := range connections {
+		if c.Bandwidth > 0 {
+			limited = append(limited, c)
+		}
+	}
+
+	if len(limited) == 0 {
+		return
+	}
+	sort.Sort(db.ConnectionSlice(limited))
+
+	fmt.Fprintln(fd)
+	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "FROM\tTO\tPORT\tBANDWIDTH")
+	for _, c := range limited {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", c.From, c.To, c.MinPort,
+			blueprint.FormatRate(c.Bandwidth))
+	}
+}
+
 func containerStr(image string, args []string, truncate bool) string {
 	if image == "" {
 		return ""
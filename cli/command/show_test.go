@@ -12,6 +12,11 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/quilt/quilt/api/client/mocks"
+	"github.com/quilt/quilt/blueprint"
+	"github.com/quilt/quilt/connection/auth"
+	"github.com/quilt/quilt/connection/credentials"
+	"github.com/quilt/quilt/connection/credentials/tls"
+	"github.com/quilt/quilt/connection/credentials/tls/rsa"
 	"github.com/quilt/quilt/db"
 )
 
@@ -44,6 +49,7 @@ func TestShowErrors(t *testing.T) {
 	mockClient.On("QueryMachines").Return([]db.Machine{{Status: db.Connected}}, nil)
 	mockClient.On("QueryContainers").Return(nil, mockErr)
 	mockClient.On("QueryImages").Return(nil, nil)
+	mockClient.On("QueryScalingGroups").Return(nil, nil)
 	cmd := &Show{false, connectionHelper{client: mockClient}}
 	assert.EqualError(t, cmd.run(), "unable to query containers: error")
 
@@ -53,8 +59,16 @@ func TestShowErrors(t *testing.T) {
 	mockClient.On("QueryMachines").Return([]db.Machine{{Status: db.Connected}}, nil)
 	mockClient.On("QueryConnections").Return(nil, mockErr)
 	mockClient.On("QueryImages").Return(nil, nil)
+	mockClient.On("QueryScalingGroups").Return(nil, nil)
 	cmd = &Show{false, connectionHelper{client: mockClient}}
 	assert.EqualError(t, cmd.run(), "unable to query connections: error")
+
+	// Error querying scaling groups
+	mockClient = new(mocks.Client)
+	mockClient.On("QueryMachines").Return(nil, nil)
+	mockClient.On("QueryScalingGroups").Return(nil, mockErr)
+	cmd = &Show{false, connectionHelper{client: mockClient}}
+	assert.EqualError(t, cmd.run(), "unable to query scaling groups: error")
 }
 
 // Test that we don't query the cluster if it's not up.
@@ -62,6 +76,7 @@ func TestMachineOnly(t *testing.T) {
 	t.Parallel()
 
 	mockClient := new(mocks.Client)
+	mockClient.On("QueryScalingGroups").Return(nil, nil)
 	cmd := &Show{false, connectionHelper{client: mockClient}}
 
 	// Test failing to query machines.
@@ -89,10 +104,46 @@ func TestShowSuccess(t *testing.T) {
 	mockClient.On("QueryMachines").Return(nil, nil)
 	mockClient.On("QueryConnections").Return(nil, nil)
 	mockClient.On("QueryImages").Return(nil, nil)
+	mockClient.On("QueryScalingGroups").Return(nil, nil)
 	cmd := &Show{false, connectionHelper{client: mockClient}}
 	assert.Equal(t, 0, cmd.Run())
 }
 
+func TestTLSWarnings(t *testing.T) {
+	t.Parallel()
+
+	ca, err := rsa.NewCertificateAuthority()
+	assert.NoError(t, err)
+
+	signed, err := rsa.NewSigned(ca, auth.Admin)
+	assert.NoError(t, err)
+
+	creds, err := tls.New(ca.CertString(), signed.CertString(),
+		signed.PrivateKeyString())
+	assert.NoError(t, err)
+
+	var b bytes.Buffer
+	writeTLSWarnings(&b, "tls", credentials.Insecure{}, time.Now())
+	assert.Empty(t, b.String())
+
+	writeTLSWarnings(&b, "tls", creds, time.Now())
+	assert.Empty(t, b.String())
+
+	writeTLSWarnings(&b, "tls", creds, signed.NotAfter().Add(-72*time.Hour))
+	assert.Equal(t, "WARNING: The TLS certificate in tls expires in 3 days. "+
+		"The daemon rotates it automatically, or it can be reissued with "+
+		"`quilt setup-tls -rotate tls`.\n", b.String())
+
+	b.Reset()
+	writeTLSWarnings(&b, "tls", creds, ca.NotAfter().Add(time.Hour))
+	assert.Equal(t, "WARNING: The TLS certificate authority in tls expired "+
+		"About an hour ago. It can't be rotated, so the cluster must be "+
+		"restarted with credentials from `quilt setup-tls`.\n"+
+		"WARNING: The TLS certificate in tls expired 9 years ago. "+
+		"The daemon rotates it automatically, or it can be reissued with "+
+		"`quilt setup-tls -rotate tls`.\n", b.String())
+}
+
 func TestMachineOutput(t *testing.T) {
 	t.Parallel()
 
@@ -114,6 +165,21 @@ func TestMachineOutput(t *testing.T) {
 			PublicIP:    "9.9.9.9",
 			FloatingIP:  "10.10.10.10",
 			Status:      db.Connected,
+		}, {
+			BlueprintID:  "3",
+			Role:         db.Worker,
+			Provider:     "DigitalOcean",
+			Region:       "sfo1",
+			Size:         "2gb",
+			Status:       db.Booting,
+			Replacements: 2,
+		}, {
+			BlueprintID: "4",
+			Role:        db.Worker,
+			Provider:    "Amazon",
+			Region:      "us-west-1",
+			Size:        "m4.large",
+			Error:       "quota: InstanceLimitExceeded",
 		},
 	}
 
@@ -129,11 +195,47 @@ func TestMachineOutput(t *testing.T) {
 		`________PUBLIC_IP______STATUS
 1__________Master____Amazon__________us-west-1____m4.large____8.8.8.8________connected
 2__________Worker____DigitalOcean____sfo1_________2gb_________10.10.10.10____connected
+3__________Worker____DigitalOcean____sfo1_________2gb_____________` +
+		`___________booting_(replaced_2_times)
+4__________Worker____Amazon__________us-west-1____m4.large________` +
+		`___________quota:_InstanceLimitExceeded
 `
 
 	assert.Equal(t, exp, result)
 }
 
+func TestScalingGroupOutput(t *testing.T) {
+	t.Parallel()
+
+	groups := []db.ScalingGroup{
+		{
+			ID:          2,
+			BlueprintID: "2",
+			Min:         1,
+			Max:         3,
+			Size:        1,
+		}, {
+			ID:          1,
+			BlueprintID: "1",
+			Max:         5,
+			Size:        2,
+			LastScaled:  time.Now().Add(-5 * time.Minute),
+			LastEvent:   "grew to 2 (1 unschedulable containers)",
+		},
+	}
+
+	var b bytes.Buffer
+	writeScalingGroups(&b, groups)
+	result := strings.Replace(string(b.Bytes()), " ", "_", -1)
+
+	exp := `SCALING_GROUP____MIN____MAX____SIZE____LAST_EVENT
+1________________0______5______2_______grew_to_2_` +
+		`(1_unschedulable_containers)_5_minutes_ago
+2________________1______3______1_______
+`
+	assert.Equal(t, exp, result)
+}
+
 func checkContainerOutput(t *testing.T, containers []db.Container,
 	machines []db.Machine, connections []db.Connection, images []db.Image,
 	truncate bool, exp string) {
@@ -334,6 +436,77 @@ func TestContainerOutputCustomImage(t *testing.T) {
 3_______________________custom-dockerfile_________________scheduled_______________
 `
 	checkContainerOutput(t, containers, nil, nil, images, true, exp)
+
+	// Built, but there's no room for it in the cluster.
+	containers = []db.Container{
+		{BlueprintID: "3", Image: "custom-dockerfile", Unschedulable: true},
+	}
+	exp = `CONTAINER____MACHINE____COMMAND_______________HOSTNAME____STATUS` +
+		`___________CREATED____PUBLIC_IP
+3_______________________custom-dockerfile_________________unschedulable_______________
+`
+	checkContainerOutput(t, containers, nil, nil, images, true, exp)
+
+	// Privileged containers are flagged.
+	containers = []db.Container{
+		{BlueprintID: "3", Image: "custom-dockerfile", Minion: "foo",
+			Security: &blueprint.Security{Privileged: true}},
+	}
+	exp = `CONTAINER____MACHINE____COMMAND_______________HOSTNAME____STATUS` +
+		`____________________CREATED____PUBLIC_IP
+3_______________________custom-dockerfile_________________` +
+		`scheduled_(privileged)_______________
+`
+	checkContainerOutput(t, containers, nil, nil, images, true, exp)
+
+	// The health of load balancer backends is shown.
+	containers = []db.Container{
+		{BlueprintID: "3", Image: "custom-dockerfile", Minion: "foo",
+			Status: "running", Health: db.ContainerHealthy},
+	}
+	exp = `CONTAINER____MACHINE____COMMAND_______________HOSTNAME____STATUS` +
+		`_______________CREATED____PUBLIC_IP
+3_______________________custom-dockerfile_________________running_(healthy)_______________
+`
+	checkContainerOutput(t, containers, nil, nil, images, true, exp)
+
+	// Bandwidth limits and traffic classes are shown.
+	containers = []db.Container{
+		{BlueprintID: "3", Image: "custom-dockerfile", Minion: "foo",
+			Status:       "running",
+			Bandwidth:    &blueprint.Bandwidth{Ingress: 10000000},
+			TrafficClass: 10},
+	}
+	exp = `CONTAINER____MACHINE____COMMAND_______________HOSTNAME____STATUS` +
+		`_________________________________CREATED____PUBLIC_IP
+3_______________________custom-dockerfile_________________` +
+		`running_(ingress_10mbit)_(class_10)_______________
+`
+	checkContainerOutput(t, containers, nil, nil, images, true, exp)
+}
+
+func TestBandwidthLimitOutput(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+	writeBandwidthLimits(&b, []db.Connection{
+		{From: "web", To: "db", MinPort: 5432, MaxPort: 5432},
+	})
+	assert.Empty(t, b.String())
+
+	writeBandwidthLimits(&b, []db.Connection{
+		{From: "web", To: "db", MinPort: 5432, MaxPort: 5432},
+		{From: "web", To: "cache", MinPort: 6379, MaxPort: 6379,
+			Bandwidth: 1500},
+		{From: "batch", To: "db", MinPort: 5432, MaxPort: 5432,
+			Bandwidth: 5000000},
+	})
+	result := strings.Replace(b.String(), " ", "_", -1)
+	assert.Equal(t, `
+FROM_____TO_______PORT____BANDWIDTH
+batch____db_______5432____5mbit
+web______cache____6379____1500bit
+`, result)
 }
 
 func TestContainerStr(t *testing.T) {
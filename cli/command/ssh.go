@@ -105,9 +105,10 @@ func (sCmd SSH) Run() int {
 
 	host := contHost
 	if resolvedMachine {
-		host = mach.PublicIP
+		host = mach
 	}
-	sshClient, err := sCmd.sshGetter(host, sCmd.privateKey)
+	sshClient, err := sCmd.sshGetter(host.PublicIP, sCmd.privateKey,
+		host.HostKeys)
 	if err != nil {
 		log.WithError(err).Error("Failed to setup SSH connection")
 		return 1
@@ -168,30 +169,32 @@ func getMachine(c client.Client, id string) (db.Machine, error) {
 	return *choice, nil
 }
 
-func getContainer(c client.Client, id string) (host string, cont db.Container,
+// getContainer returns the container with the given BlueprintID, and the machine
+// it's running on.
+func getContainer(c client.Client, id string) (host db.Machine, cont db.Container,
 	err error) {
 
 	containers, err := c.QueryContainers()
 	if err != nil {
-		return "", db.Container{}, err
+		return db.Machine{}, db.Container{}, err
 	}
 
 	machines, err := c.QueryMachines()
 	if err != nil {
-		return "", db.Container{}, err
+		return db.Machine{}, db.Container{}, err
 	}
 
 	container, err := util.GetContainer(containers, id)
 	if err != nil {
-		return "", db.Container{}, err
+		return db.Machine{}, db.Container{}, err
 	}
 
-	ip, err := util.GetPublicIP(machines, container.Minion)
+	host, err = util.GetMachine(machines, container.Minion)
 	if err != nil {
-		return "", db.Container{}, err
+		return db.Machine{}, db.Container{}, err
 	}
 
-	return ip, container, nil
+	return host, container, nil
 }
 
 func containerExec(c ssh.Client, dockerID string, allocatePTY bool, cmd string) error {
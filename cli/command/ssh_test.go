@@ -159,6 +159,7 @@ type sshTest struct {
 	machines       []db.Machine
 	containers     []db.Container
 	expHost        string
+	expHostKeys    []string
 	expUseShell    bool
 	expRunArgs     string
 	expAllocatePTY bool
@@ -173,8 +174,13 @@ func TestSSH(t *testing.T) {
 				privateKey: "key",
 				target:     "tgt",
 			},
-			machines:    []db.Machine{{BlueprintID: "tgt", PublicIP: "host"}},
+			machines: []db.Machine{{
+				BlueprintID: "tgt",
+				PublicIP:    "host",
+				HostKeys:    []string{"hostKey"},
+			}},
 			expHost:     "host",
+			expHostKeys: []string{"hostKey"},
 			expUseShell: true,
 		},
 		// Machine with exec command.
@@ -194,7 +200,11 @@ func TestSSH(t *testing.T) {
 				privateKey: "key",
 				target:     "tgt",
 			},
-			machines: []db.Machine{{PrivateIP: "priv", PublicIP: "host"}},
+			machines: []db.Machine{{
+				PrivateIP: "priv",
+				PublicIP:  "host",
+				HostKeys:  []string{"hostKey"},
+			}},
 			containers: []db.Container{{
 				Minion:      "priv",
 				BlueprintID: "tgt",
@@ -202,6 +212,7 @@ func TestSSH(t *testing.T) {
 			}},
 			expAllocatePTY: true,
 			expHost:        "host",
+			expHostKeys:    []string{"hostKey"},
 			expRunArgs:     "docker exec -it dockerID sh",
 		},
 		// Container with exec.
@@ -243,10 +254,11 @@ func TestSSH(t *testing.T) {
 		testCmd := test.cmd
 
 		mockSSHClient := new(mockSSH.Client)
-		testCmd.sshGetter = func(host string, keyPath string) (
-			ssh.Client, error) {
+		testCmd.sshGetter = func(host string, keyPath string,
+			hostKeys []string) (ssh.Client, error) {
 			assert.Equal(t, test.expHost, host)
 			assert.Equal(t, testCmd.privateKey, keyPath)
+			assert.Equal(t, test.expHostKeys, hostKeys)
 			return mockSSHClient, nil
 		}
 		mockSSHClient.On("Close").Return(nil)
@@ -298,7 +310,7 @@ func TestNoMatch(t *testing.T) {
 func TestSSHExitError(t *testing.T) {
 	// Test error with exit code.
 	mockSSHClient := new(mockSSH.Client)
-	mockSSHGetter := func(host string, keyPath string) (ssh.Client, error) {
+	mockSSHGetter := func(string, string, []string) (ssh.Client, error) {
 		return mockSSHClient, nil
 	}
 	mockSSHClient.On("Close").Return(nil)
@@ -319,7 +331,7 @@ func TestSSHExitError(t *testing.T) {
 
 	// Test error without exit code.
 	mockSSHClient = new(mockSSH.Client)
-	mockSSHGetter = func(host string, keyPath string) (ssh.Client, error) {
+	mockSSHGetter = func(string, string, []string) (ssh.Client, error) {
 		return mockSSHClient, nil
 	}
 	mockSSHClient.On("Close").Return(nil)
@@ -0,0 +1,154 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	units "github.com/docker/go-units"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/util"
+)
+
+var trafficCommands = "quilt traffic [OPTIONS]"
+var trafficExplanation = `Display the traffic sent over the connections of the
+running blueprint.
+
+The top talkers are the containers, load balancers, and external addresses that
+sent and received the most bytes.  They are followed by the volume of each edge
+of the connection graph, largest first, and the traffic class its sender marks
+its packets with, if any.  The traffic is counted by the workers since they
+started.`
+
+// Traffic implements the `quilt traffic` command.
+type Traffic struct {
+	top int
+
+	connectionHelper
+}
+
+// A talker is an endpoint of the connection graph, and the bytes it exchanged.
+type talker struct {
+	name           string
+	sent, received uint64
+}
+
+// InstallFlags sets up parsing for command line flags.
+func (cmd *Traffic) InstallFlags(flags *flag.FlagSet) {
+	cmd.connectionHelper.InstallFlags(flags)
+	flags.IntVar(&cmd.top, "n", 10, "the number of top talkers to display")
+	flags.Usage = func() {
+		util.PrintUsageString(trafficCommands, trafficExplanation, flags)
+	}
+}
+
+// Parse parses the command line arguments for the traffic command.
+func (cmd *Traffic) Parse(args []string) error {
+	return nil
+}
+
+// Run retrieves and prints the traffic of the cluster.
+func (cmd *Traffic) Run() int {
+	traffic, err := cmd.client.QueryTraffic()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to query traffic: %s\n", err)
+		return 1
+	}
+
+	printTraffic(os.Stdout, traffic, cmd.top)
+	return 0
+}
+
+// printTraffic writes the `top` endpoints that exchanged the most bytes, and the
+// traffic of every edge, to `out`.
+func printTraffic(out io.Writer, traffic []db.Traffic, top int) {
+	talkersByName := map[string]*talker{}
+	getTalker := func(name string) *talker {
+		if _, ok := talkersByName[name]; !ok {
+			talkersByName[name] = &talker{name: name}
+		}
+		return talkersByName[name]
+	}
+
+	for _, t := range traffic {
+		getTalker(t.From).sent += t.Bytes
+		getTalker(t.To).received += t.Bytes
+	}
+
+	var talkers []talker
+	for _, t := range talkersByName {
+		talkers = append(talkers, *t)
+	}
+	sort.Sort(talkersByBytes(talkers))
+	if top >= 0 && len(talkers) > top {
+		talkers = talkers[:top]
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "TALKER\tSENT\tRECEIVED")
+	for _, t := range talkers {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", t.name, humanBytes(t.sent),
+			humanBytes(t.received))
+	}
+	w.Flush()
+	fmt.Fprintln(out)
+
+	sort.Sort(trafficByBytes(traffic))
+	w = tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "FROM\tTO\tCLASS\tPACKETS\tBYTES")
+	for _, t := range traffic {
+		class := ""
+		if t.TrafficClass != 0 {
+			class = fmt.Sprintf("%d", t.TrafficClass)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", t.From, t.To, class,
+			t.Packets, humanBytes(t.Bytes))
+	}
+	w.Flush()
+}
+
+func humanBytes(bytes uint64) string {
+	return units.HumanSize(float64(bytes))
+}
+
+type talkersByBytes []talker
+
+func (slc talkersByBytes) Len() int {
+	return len(slc)
+}
+
+func (slc talkersByBytes) Less(i, j int) bool {
+	left := slc[i].sent + slc[i].received
+	right := slc[j].sent + slc[j].received
+	if left != right {
+		return left > right
+	}
+	return slc[i].name < slc[j].name
+}
+
+func (slc talkersByBytes) Swap(i, j int) {
+	slc[i], slc[j] = slc[j], slc[i]
+}
+
+type trafficByBytes []db.Traffic
+
+func (slc trafficByBytes) Len() int {
+	return len(slc)
+}
+
+func (slc trafficByBytes) Less(i, j int) bool {
+	if slc[i].Bytes != slc[j].Bytes {
+		return slc[i].Bytes > slc[j].Bytes
+	}
+	if slc[i].From != slc[j].From {
+		return slc[i].From < slc[j].From
+	}
+	return slc[i].To < slc[j].To
+}
+
+func (slc trafficByBytes) Swap(i, j int) {
+	slc[i], slc[j] = slc[j], slc[i]
+}
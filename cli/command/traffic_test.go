@@ -0,0 +1,55 @@
+package command
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/quilt/quilt/api/client/mocks"
+	"github.com/quilt/quilt/db"
+)
+
+func TestTrafficRun(t *testing.T) {
+	t.Parallel()
+
+	mockClient := new(mocks.Client)
+	mockClient.On("QueryTraffic").Once().Return(nil, assert.AnError)
+
+	cmd := &Traffic{}
+	cmd.client = mockClient
+	assert.Equal(t, 1, cmd.Run())
+
+	mockClient.On("QueryTraffic").Return([]db.Traffic{
+		{From: "web", To: "db", Packets: 1, Bytes: 100}}, nil)
+	assert.Equal(t, 0, cmd.Run())
+}
+
+func TestPrintTraffic(t *testing.T) {
+	t.Parallel()
+
+	traffic := []db.Traffic{
+		{From: "web", To: "db", TrafficClass: 10, Packets: 10, Bytes: 2000},
+		{From: "public", To: "web", Packets: 5, Bytes: 500},
+		{From: "db", To: "web", Packets: 20, Bytes: 30000},
+		{From: "web", To: "domain:example.com", Packets: 1, Bytes: 100},
+	}
+
+	var b bytes.Buffer
+	printTraffic(&b, traffic, 2)
+	assert.Equal(t, `TALKER   SENT    RECEIVED
+web      2.1kB   30.5kB
+db       30kB    2kB
+
+FROM     TO                   CLASS   PACKETS   BYTES
+db       web                          20        30kB
+web      db                   10      10        2kB
+public   web                          5         500B
+web      domain:example.com           1         100B
+`, b.String())
+
+	b.Reset()
+	printTraffic(&b, nil, 10)
+	assert.Equal(t, "TALKER   SENT   RECEIVED\n\n"+
+		"FROM   TO   CLASS   PACKETS   BYTES\n", b.String())
+}
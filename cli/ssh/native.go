@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 
 	"github.com/quilt/quilt/util"
 
@@ -23,8 +24,10 @@ type NativeClient struct {
 	*ssh.Client
 }
 
-// New returns an SSH Client connected to the given host.
-func New(host string, keyPath string) (Client, error) {
+// New returns an SSH Client connected to the given host.  The host must present
+// one of `hostKeys`, the fingerprints reported by the host's minion.  If the
+// minion hasn't reported any yet, the host's key is trusted on first use.
+func New(host string, keyPath string, hostKeys []string) (Client, error) {
 	var auth ssh.AuthMethod
 	if keyPath != "" {
 		signer, err := signerFromFile(keyPath)
@@ -38,13 +41,68 @@ func New(host string, keyPath string) (Client, error) {
 	sshConfig := &ssh.ClientConfig{
 		User:            "quilt",
 		Auth:            []ssh.AuthMethod{auth},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: util.HostKeyCallback(hostKeys, trustOnFirstUse(host)),
 	}
 
 	client, err := ssh.Dial("tcp", fmt.Sprintf("%s:22", host), sshConfig)
 	return NativeClient{client}, err
 }
 
+// trustOnFirstUse returns a function that checks the host key fingerprint
+// presented by `host` against the one it presented the first time we connected
+// to it.  Hosts we haven't seen before are added to the known hosts file.
+func trustOnFirstUse(host string) func(string) error {
+	return func(fingerprint string) error {
+		path, err := knownHostsPath()
+		if err != nil {
+			return err
+		}
+
+		known, err := util.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		for _, line := range strings.Split(known, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 2 || fields[0] != host {
+				continue
+			}
+
+			if fields[1] != fingerprint {
+				return fmt.Errorf("host key %s for %s doesn't match the "+
+					"trusted key %s. If the machine was replaced, "+
+					"remove it from %s", fingerprint, host, fields[1],
+					path)
+			}
+			return nil
+		}
+
+		log.WithFields(log.Fields{
+			"host":        host,
+			"fingerprint": fingerprint,
+		}).Warn("Trusting unverified host key on first use")
+
+		if err := util.AppFs.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return err
+		}
+
+		if known != "" && !strings.HasSuffix(known, "\n") {
+			known += "\n"
+		}
+		known += fmt.Sprintf("%s %s\n", host, fingerprint)
+		return util.WriteFile(path, []byte(known), 0600)
+	}
+}
+
+func knownHostsPath() (string, error) {
+	dir, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, ".quilt", "known_hosts"), nil
+}
+
 var defaultKeys = []string{"id_rsa", "id_dsa", "id_ecdsa", "id_ed25519", "quilt"}
 
 // Gets the signers for the default private key locations if possible
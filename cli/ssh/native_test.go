@@ -77,6 +77,26 @@ func TestEncryptedKey(t *testing.T) {
 		"`ssh-add`")
 }
 
+func TestTrustOnFirstUse(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	assert.NoError(t, trustOnFirstUse("host1")("fingerprint1"))
+	assert.NoError(t, trustOnFirstUse("host2")("fingerprint2"))
+	assert.NoError(t, trustOnFirstUse("host1")("fingerprint1"))
+
+	path, err := knownHostsPath()
+	assert.NoError(t, err)
+
+	known, err := util.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "host1 fingerprint1\nhost2 fingerprint2\n", known)
+
+	err = trustOnFirstUse("host2")("changed")
+	assert.EqualError(t, err, "host key changed for host2 doesn't match the "+
+		"trusted key fingerprint2. If the machine was replaced, remove it "+
+		"from "+path)
+}
+
 func writeRandomKey(path string, encrypt bool) error {
 	key, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
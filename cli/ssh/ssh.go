@@ -18,5 +18,6 @@ type Client interface {
 	Shell() error
 }
 
-// Getter is used to retrieve a Client.
-type Getter func(string, string) (Client, error)
+// Getter is used to retrieve a Client.  It takes the host to connect to, the path
+// to the private key, and the host key fingerprints reported by the host.
+type Getter func(string, string, []string) (Client, error)
@@ -54,6 +54,10 @@ var amis = map[string]string{
 	"us-west-2":      "ami-afed21d7",
 }
 
+// The tag that records the hash of the image and boot script machines were booted
+// with.  See cfg.BootHash.
+const bootHashTag = "quilt-boot-hash"
+
 var sleep = time.Sleep
 
 var timeout = 5 * time.Minute
@@ -98,6 +102,8 @@ func newAmazon(namespace, region string) *Provider {
 type bootReq struct {
 	groupID     string
 	cfg         string
+	image       string
+	bootHash    string
 	size        string
 	diskSize    int
 	preemptible bool
@@ -119,6 +125,8 @@ func (prvdr *Provider) Boot(bootSet []db.Machine) error {
 		br := bootReq{
 			groupID:     groupID,
 			cfg:         cfg.Ubuntu(m, ""),
+			image:       m.Image,
+			bootHash:    cfg.BootHash(m),
 			size:        m.Size,
 			diskSize:    m.DiskSize,
 			preemptible: m.Preemptible,
@@ -143,8 +151,8 @@ func (prvdr *Provider) Boot(bootSet []db.Machine) error {
 
 func (prvdr *Provider) bootReserved(br bootReq, count int64) error {
 	cloudConfig64 := base64.StdEncoding.EncodeToString([]byte(br.cfg))
-	resp, err := prvdr.RunInstances(&ec2.RunInstancesInput{
-		ImageId:          aws.String(amis[prvdr.region]),
+	input := &ec2.RunInstancesInput{
+		ImageId:          aws.String(prvdr.image(br)),
 		InstanceType:     aws.String(br.size),
 		UserData:         &cloudConfig64,
 		SecurityGroupIds: []*string{aws.String(br.groupID)},
@@ -152,7 +160,15 @@ func (prvdr *Provider) bootReserved(br bootReq, count int64) error {
 			blockDevice(br.diskSize)},
 		MaxCount: &count,
 		MinCount: &count,
-	})
+	}
+	if br.bootHash != "" {
+		input.TagSpecifications = []*ec2.TagSpecification{{
+			ResourceType: aws.String(ec2.ResourceTypeInstance),
+			Tags:         bootHashTags(br.bootHash),
+		}}
+	}
+
+	resp, err := prvdr.RunInstances(input)
 	if err != nil {
 		return err
 	}
@@ -177,7 +193,7 @@ func (prvdr *Provider) bootSpot(br bootReq, count int64) error {
 	cloudConfig64 := base64.StdEncoding.EncodeToString([]byte(br.cfg))
 	spots, err := prvdr.RequestSpotInstances(spotPrice, count,
 		&ec2.RequestSpotLaunchSpecification{
-			ImageId:          aws.String(amis[prvdr.region]),
+			ImageId:          aws.String(prvdr.image(br)),
 			InstanceType:     aws.String(br.size),
 			UserData:         &cloudConfig64,
 			SecurityGroupIds: []*string{aws.String(br.groupID)},
@@ -192,7 +208,16 @@ func (prvdr *Provider) bootSpot(br bootReq, count int64) error {
 		ids = append(ids, *request.SpotInstanceRequestId)
 	}
 
-	err = prvdr.wait(ids, true)
+	// Spot requests can't be tagged when they're made, so tag them afterwards.
+	// The instances they launch aren't tagged, so `List` reads the hash from the
+	// spot request instead.
+	if br.bootHash != "" {
+		err = prvdr.CreateTags(ids, bootHashTags(br.bootHash))
+	}
+
+	if err == nil {
+		err = prvdr.wait(ids, true)
+	}
 	if err != nil {
 		if stopErr := prvdr.stopSpots(ids); stopErr != nil {
 			log.WithError(stopErr).WithField("ids", ids).
@@ -202,6 +227,27 @@ func (prvdr *Provider) bootSpot(br bootReq, count int64) error {
 	return err
 }
 
+// image returns the AMI that `br` should boot.
+func (prvdr *Provider) image(br bootReq) string {
+	if br.image != "" {
+		return br.image
+	}
+	return amis[prvdr.region]
+}
+
+func bootHashTags(hash string) []*ec2.Tag {
+	return []*ec2.Tag{{Key: aws.String(bootHashTag), Value: aws.String(hash)}}
+}
+
+func parseBootHash(tags []*ec2.Tag) string {
+	for _, tag := range tags {
+		if resolveString(tag.Key) == bootHashTag {
+			return resolveString(tag.Value)
+		}
+	}
+	return ""
+}
+
 // Stop shuts down `machines` in `prvdr`.
 func (prvdr *Provider) Stop(machines []db.Machine) error {
 	var spotIDs, instIDs []string
@@ -288,6 +334,9 @@ func (prvdr *Provider) listSpots() (machines []awsMachine, err error) {
 	for _, spot := range spots {
 		machines = append(machines, awsMachine{
 			spotID: resolveString(spot.SpotInstanceRequestId),
+			machine: db.Machine{
+				BootHash: parseBootHash(spot.Tags),
+			},
 		})
 	}
 	return machines, nil
@@ -354,6 +403,7 @@ func (prvdr *Provider) listInstances() (instances []awsMachine, err error) {
 					FloatingIP: floatingIP,
 					Size:       resolveString(inst.InstanceType),
 					DiskSize:   diskSize,
+					BootHash:   parseBootHash(inst.Tags),
 				},
 			})
 		}
@@ -384,7 +434,9 @@ func (prvdr *Provider) List() (machines []db.Machine, err error) {
 		awsMachines = append(awsMachines, mIntf.(awsMachine))
 	}
 	for _, pair := range bootedSpots {
-		awsMachines = append(awsMachines, pair.R.(awsMachine))
+		awsm := pair.R.(awsMachine)
+		awsm.machine.BootHash = pair.L.(awsMachine).machine.BootHash
+		awsMachines = append(awsMachines, awsm)
 	}
 	for _, mIntf := range nonbootedSpots {
 		awsMachines = append(awsMachines, mIntf.(awsMachine))
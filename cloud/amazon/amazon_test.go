@@ -53,6 +53,7 @@ func TestList(t *testing.T) {
 			State: &ec2.InstanceState{
 				Name: aws.String(ec2.InstanceStateNameRunning),
 			},
+			Tags: bootHashTags("reservedHash"),
 			BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
 				{
 					Ebs: &ec2.EbsInstanceBlockDevice{
@@ -81,6 +82,7 @@ func TestList(t *testing.T) {
 				State: aws.String(
 					ec2.SpotInstanceStateActive),
 				InstanceId: aws.String("inst1"),
+				Tags:       bootHashTags("spotHash"),
 			}, {
 				SpotInstanceRequestId: aws.String("spot2"),
 				State: aws.String(
@@ -112,6 +114,7 @@ func TestList(t *testing.T) {
 			DiskSize:    32,
 			FloatingIP:  "8.8.8.8",
 			Preemptible: false,
+			BootHash:    "reservedHash",
 		},
 		{
 			CloudID:     "spot1",
@@ -119,6 +122,7 @@ func TestList(t *testing.T) {
 			PrivateIP:   "privateIP",
 			Size:        "size",
 			Preemptible: true,
+			BootHash:    "spotHash",
 		},
 		{
 			CloudID:     "spot2",
@@ -400,6 +404,73 @@ func TestBoot(t *testing.T) {
 	mc.AssertExpectations(t)
 }
 
+func TestBootImage(t *testing.T) {
+	mc := new(mocks.Client)
+	mc.On("DescribeSecurityGroup", mock.Anything).Return([]*ec2.SecurityGroup{{
+		GroupId: aws.String("groupId")}}, nil)
+	mc.On("RequestSpotInstances", mock.Anything, mock.Anything,
+		mock.Anything).Return([]*ec2.SpotInstanceRequest{{
+		SpotInstanceRequestId: aws.String("spot1"),
+	}}, nil)
+	mc.On("CreateTags", mock.Anything, mock.Anything).Return(nil)
+	mc.On("RunInstances", mock.Anything).Return(&ec2.Reservation{
+		Instances: []*ec2.Instance{{InstanceId: aws.String("reserved1")}},
+	}, nil)
+	mc.On("DescribeInstances", mock.Anything).Return(
+		&ec2.DescribeInstancesOutput{
+			Reservations: []*ec2.Reservation{{
+				Instances: []*ec2.Instance{{
+					InstanceId:   aws.String("reserved1"),
+					InstanceType: aws.String("m4.large"),
+				}, {
+					InstanceId:            aws.String("inst1"),
+					SpotInstanceRequestId: aws.String("spot1"),
+					InstanceType:          aws.String("m4.large"),
+				}},
+			}},
+		}, nil)
+	mc.On("DescribeAddresses").Return(nil, nil)
+	mc.On("DescribeSpotInstanceRequests", mock.Anything, mock.Anything).Return(
+		[]*ec2.SpotInstanceRequest{{
+			InstanceId:            aws.String("inst1"),
+			SpotInstanceRequestId: aws.String("spot1"),
+			State: aws.String(ec2.SpotInstanceStateActive),
+		}}, nil)
+
+	amazonProvider := newAmazon(testNamespace, DefaultRegion)
+	amazonProvider.Client = mc
+
+	m := db.Machine{
+		Role:       db.Worker,
+		Size:       "m4.large",
+		DiskSize:   32,
+		Image:      "ami-custom",
+		BootScript: "echo hello",
+	}
+	spot := m
+	spot.Preemptible = true
+	err := amazonProvider.Boot([]db.Machine{m, spot})
+	assert.Nil(t, err)
+
+	hash := cfg.BootHash(m)
+	mc.AssertCalled(t, "RunInstances", &ec2.RunInstancesInput{
+		ImageId:      aws.String("ami-custom"),
+		InstanceType: aws.String("m4.large"),
+		UserData: aws.String(base64.StdEncoding.EncodeToString(
+			[]byte(cfg.Ubuntu(m, "")))),
+		SecurityGroupIds: aws.StringSlice([]string{"groupId"}),
+		BlockDeviceMappings: []*ec2.BlockDeviceMapping{
+			blockDevice(32)},
+		MaxCount: aws.Int64(1),
+		MinCount: aws.Int64(1),
+		TagSpecifications: []*ec2.TagSpecification{{
+			ResourceType: aws.String(ec2.ResourceTypeInstance),
+			Tags:         bootHashTags(hash),
+		}},
+	})
+	mc.AssertCalled(t, "CreateTags", []string{"spot1"}, bootHashTags(hash))
+}
+
 // This test attempts to boot a preemptible and non-preemptible instance,
 // but simulates a boot error where the machines never show up in `List`.
 // We should consider this a boot failure, and try to clean up by stopping
@@ -31,6 +31,8 @@ type Client interface {
 	DisassociateAddress(associationID string) error
 
 	DescribeVolumes(id string) ([]*ec2.Volume, error)
+
+	CreateTags(ids []string, tags []*ec2.Tag) error
 }
 
 type awsClient struct {
@@ -174,6 +176,14 @@ func (ac awsClient) DescribeVolumes(id string) ([]*ec2.Volume, error) {
 	return resp.Volumes, err
 }
 
+func (ac awsClient) CreateTags(ids []string, tags []*ec2.Tag) error {
+	c.Inc("Create Tags")
+	_, err := ac.client.CreateTags(&ec2.CreateTagsInput{
+		Resources: stringSlice(ids),
+		Tags:      tags})
+	return err
+}
+
 // New creates a new Client.
 func New(region string) Client {
 	c.Inc("New Client")
@@ -58,4 +58,7 @@ func TestErrors(t *testing.T) {
 
 	_, err = ac.DescribeVolumes("")
 	assert.EqualError(t, err, "test")
+
+	err = ac.CreateTags(nil, nil)
+	assert.EqualError(t, err, "test")
 }
@@ -72,6 +72,20 @@ func (_m *Client) CreateSecurityGroup(name string, description string) (string,
 	return r0, r1
 }
 
+// CreateTags provides a mock function with given fields: ids, tags
+func (_m *Client) CreateTags(ids []string, tags []*ec2.Tag) error {
+	ret := _m.Called(ids, tags)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]string, []*ec2.Tag) error); ok {
+		r0 = rf(ids, tags)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DescribeAddresses provides a mock function with given fields:
 func (_m *Client) DescribeAddresses() ([]*ec2.Address, error) {
 	ret := _m.Called()
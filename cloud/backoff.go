@@ -0,0 +1,171 @@
+package cloud
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// An errorClass describes how a failed cloud provider call should be retried.
+type errorClass string
+
+const (
+	// Throttled errors mean that the provider is rate limiting our API calls.
+	throttled errorClass = "throttled"
+
+	// Quota errors mean that the account isn't allowed any more resources.
+	quota errorClass = "quota"
+
+	// Auth failures mean that the provider rejected our credentials.
+	authFailure errorClass = "auth"
+
+	// Transient errors are expected to go away on their own, e.g. timeouts or
+	// internal errors on the provider's end.
+	transient errorClass = "transient"
+
+	// Permanent errors mean that the request itself is invalid, so retrying it
+	// won't help until it changes.
+	permanent errorClass = "permanent"
+)
+
+// The patterns are matched against the lowercased error message in order, so that,
+// e.g., rate limit errors returned with a 403 status aren't mistaken for
+// authentication failures.
+var errorPatterns = []struct {
+	class   errorClass
+	pattern *regexp.Regexp
+}{
+	{throttled, regexp.MustCompile(`throttl|rate ?limit|requestlimitexceeded|` +
+		`too many requests|\b429\b`)},
+	{quota, regexp.MustCompile(`quota|limitexceeded|limit exceeded|` +
+		`droplet limit`)},
+	{authFailure, regexp.MustCompile(`authfailure|unauthorized|forbidden|` +
+		`unable to authenticate|invalidclienttokenid|signaturedoesnotmatch|` +
+		`credentials|permission|\b40[13]\b`)},
+	{transient, regexp.MustCompile(`timeout|timed out|temporar|unavailable|` +
+		`connection re(set|fused)|internal ?error|\beof\b|` +
+		`insufficientinstancecapacity|\b50[0234]\b`)},
+	{permanent, regexp.MustCompile(`invalid|malformed|not found|unsupported|` +
+		`\b400\b`)},
+}
+
+// How long to wait after the first failure of each class of error.  The wait
+// doubles after each consecutive failure, up to `maxDelays`.
+var baseDelays = map[errorClass]time.Duration{
+	throttled:   10 * time.Second,
+	quota:       time.Minute,
+	authFailure: 5 * time.Minute,
+	transient:   5 * time.Second,
+	permanent:   time.Minute,
+}
+
+var maxDelays = map[errorClass]time.Duration{
+	throttled:   5 * time.Minute,
+	quota:       30 * time.Minute,
+	authFailure: time.Hour,
+	transient:   2 * time.Minute,
+	permanent:   30 * time.Minute,
+}
+
+func classify(err error) errorClass {
+	msg := strings.ToLower(err.Error())
+	for _, p := range errorPatterns {
+		if p.pattern.MatchString(msg) {
+			return p.class
+		}
+	}
+	return transient
+}
+
+// A providerError is an error returned by a cloud provider, along with its class.
+type providerError struct {
+	class errorClass
+	err   error
+}
+
+func (err providerError) Error() string {
+	return fmt.Sprintf("%s: %s", err.class, err.err)
+}
+
+// A backoffError is returned instead of calling the provider while we're waiting
+// to retry a failed call.
+type backoffError struct {
+	lastErr providerError
+	wait    time.Duration
+}
+
+func (err backoffError) Error() string {
+	return fmt.Sprintf("%s (retrying in %s)", err.lastErr, err.wait)
+}
+
+type retryState struct {
+	failures int
+	until    time.Time
+	lastErr  providerError
+}
+
+func (state *retryState) fail(err providerError) {
+	delay := baseDelays[err.class] << uint(state.failures)
+	if max := maxDelays[err.class]; delay > max || delay <= 0 {
+		delay = max
+	}
+
+	state.failures++
+	state.until = now().Add(delay)
+	state.lastErr = err
+}
+
+func (state retryState) waitErr() error {
+	if wait := state.until.Sub(now()); wait > 0 {
+		return backoffError{lastErr: state.lastErr, wait: wait}
+	}
+	return nil
+}
+
+// A backoff rate limits the calls to a single provider and region.  Throttling and
+// authentication errors affect every type of call to the region, while other
+// errors only delay retries of the same type of call.  For example, running out of
+// quota while booting machines shouldn't prevent us from stopping them.
+type backoff struct {
+	region  retryState
+	actions map[string]*retryState
+}
+
+func newBackoff() *backoff {
+	return &backoff{actions: map[string]*retryState{}}
+}
+
+// call runs `fn` unless a previous failure means that `action` should not be
+// retried yet.  Errors returned by `fn` are classified as providerErrors.
+func (b *backoff) call(action string, fn func() error) error {
+	state, ok := b.actions[action]
+	if !ok {
+		state = &retryState{}
+		b.actions[action] = state
+	}
+
+	if err := b.region.waitErr(); err != nil {
+		return err
+	}
+
+	if err := state.waitErr(); err != nil {
+		return err
+	}
+
+	err := fn()
+	if err == nil {
+		b.region = retryState{}
+		*state = retryState{}
+		return nil
+	}
+
+	pErr := providerError{class: classify(err), err: err}
+	switch pErr.class {
+	case throttled, authFailure:
+		b.region.fail(pErr)
+	default:
+		state.fail(pErr)
+	}
+	return pErr
+}
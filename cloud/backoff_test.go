@@ -0,0 +1,97 @@
+package cloud
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]errorClass{
+		"RequestLimitExceeded: Request limit exceeded.": throttled,
+		"googleapi: Error 403: Rate Limit Exceeded, " +
+			"rateLimitExceeded": throttled,
+		"POST https://api.digitalocean.com/v2/droplets: 429 " +
+			"Too many": throttled,
+		"InstanceLimitExceeded: Your quota allows for 0 more instances": quota,
+		"googleapi: Error 403: Quota 'CPUS' exceeded, quotaExceeded":    quota,
+		"creating this/these droplet(s) will exceed your droplet limit": quota,
+		"AuthFailure: AWS was not able to validate the " +
+			"credentials": authFailure,
+		"GET https://api.digitalocean.com/v2/droplets: 401 Unable to " +
+			"authenticate you.": authFailure,
+		"googleapi: Error 503: Service unavailable":         transient,
+		"dial tcp: i/o timeout":                             transient,
+		"InvalidAMIID.Malformed: Invalid id: ami-1":         permanent,
+		"googleapi: Error 404: The resource was not found.": permanent,
+		"something unexpected":                              transient,
+	}
+
+	for msg, exp := range tests {
+		assert.Equal(t, exp, classify(errors.New(msg)), msg)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	start := time.Now()
+	now = func() time.Time { return start }
+	defer func() { now = time.Now }()
+
+	b := newBackoff()
+	calls := 0
+	fail := func(msg string) func() error {
+		return func() error {
+			calls++
+			return errors.New(msg)
+		}
+	}
+	succeed := func() error {
+		calls++
+		return nil
+	}
+
+	err := b.call("boot", fail("i/o timeout"))
+	assert.EqualError(t, err, "transient: i/o timeout")
+	assert.Equal(t, 1, calls)
+
+	// Other actions are unaffected by transient errors.
+	assert.NoError(t, b.call("stop", succeed))
+	assert.Equal(t, 2, calls)
+
+	err = b.call("boot", succeed)
+	assert.EqualError(t, err, "transient: i/o timeout (retrying in 5s)")
+	assert.Equal(t, 2, calls)
+
+	// Consecutive failures back off exponentially.
+	now = func() time.Time { return start.Add(5 * time.Second) }
+	b.call("boot", fail("i/o timeout"))
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, start.Add(15*time.Second), b.actions["boot"].until)
+
+	// Up to a maximum.
+	b.actions["boot"].failures = 20
+	b.call("boot", fail("i/o timeout"))
+	assert.Equal(t, 3, calls)
+	now = func() time.Time { return start.Add(15 * time.Second) }
+	b.call("boot", fail("i/o timeout"))
+	assert.Equal(t, 4, calls)
+	assert.Equal(t, start.Add(15*time.Second+2*time.Minute),
+		b.actions["boot"].until)
+
+	// Success resets the backoff.
+	now = func() time.Time { return start.Add(time.Hour) }
+	assert.NoError(t, b.call("boot", succeed))
+	assert.Equal(t, retryState{}, *b.actions["boot"])
+
+	// Throttling affects every action in the region.
+	err = b.call("list", fail("Throttling: Rate exceeded"))
+	assert.EqualError(t, err, "throttled: Throttling: Rate exceeded")
+	err = b.call("stop", succeed)
+	assert.EqualError(t, err,
+		"throttled: Throttling: Rate exceeded (retrying in 10s)")
+	assert.Equal(t, 6, calls)
+}
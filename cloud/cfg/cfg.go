@@ -2,6 +2,7 @@ package cfg
 
 import (
 	"bytes"
+	"crypto/sha1"
 	"fmt"
 	"strings"
 	"text/template"
@@ -44,12 +45,14 @@ func Ubuntu(m db.Machine, inboundPublic string) string {
 		LogLevel   string
 		MinionOpts string
 		DockerOpts string
+		BootScript string
 	}{
 		QuiltImage: img,
 		SSHKeys:    strings.Join(m.SSHKeys, "\n"),
 		LogLevel:   log.GetLevel().String(),
 		MinionOpts: minionOptions(m.Role, inboundPublic, MinionTLSDir),
 		DockerOpts: dockerOpts,
+		BootScript: m.BootScript,
 	})
 	if err != nil {
 		panic(err)
@@ -58,6 +61,16 @@ func Ubuntu(m db.Machine, inboundPublic string) string {
 	return cloudConfigBytes.String()
 }
 
+// BootHash returns a hash of the image and boot script that `m` should be booted
+// with.  Providers record it on the machines they boot so that machines are
+// replaced when either changes.  Machines that use the defaults have an empty hash.
+func BootHash(m db.Machine) string {
+	if m.Image == "" && m.BootScript == "" {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha1.Sum([]byte(m.Image+"\n"+m.BootScript)))
+}
+
 func minionOptions(role db.Role, inboundPublic, tlsDir string) string {
 	options := fmt.Sprintf("--role %q", role)
 
@@ -36,3 +36,23 @@ func TestCloudConfig(t *testing.T) {
 		t.Errorf("res: %s\nexp: %s", res, exp)
 	}
 }
+
+func TestBootHash(t *testing.T) {
+	if hash := BootHash(db.Machine{Size: "m4.large"}); hash != "" {
+		t.Errorf("expected an empty hash for the defaults, got %s", hash)
+	}
+
+	image := BootHash(db.Machine{Image: "ami-1234"})
+	script := BootHash(db.Machine{BootScript: "ami-1234"})
+	both := BootHash(db.Machine{Image: "ami-1234", BootScript: "echo hi"})
+	if image == "" || image == script || image == both || script == both {
+		t.Errorf("expected distinct hashes, got %s, %s, and %s",
+			image, script, both)
+	}
+
+	again := BootHash(db.Machine{Image: "ami-1234", Role: db.Master})
+	if again != image {
+		t.Errorf("expected the hash to only depend on the image and boot "+
+			"script, got %s and %s", image, again)
+	}
+}
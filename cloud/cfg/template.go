@@ -23,7 +23,8 @@ initialize_ovs() {
 		fi ; \
 		insmod /modules/$(uname -r)/openvswitch.ko \
 	         && insmod /modules/$(uname -r)/vport-geneve.ko \
-	         && insmod /modules/$(uname -r)/vport-stt.ko"
+	         && insmod /modules/$(uname -r)/vport-stt.ko \
+	         && insmod /modules/$(uname -r)/vport-vxlan.ko"
 
 	[Install]
 	WantedBy=multi-user.target
@@ -66,6 +67,8 @@ initialize_minion() {
 	-v /var/run/docker.sock:/var/run/docker.sock \
 	-v /etc/ssl/certs/ca-certificates.crt:/etc/ssl/certs/ca-certificates.crt \
 	-v /home/quilt/.ssh:/home/quilt/.ssh:rw \
+	-v /etc/ssh:/etc/ssh:ro \
+	-v /sys/fs/cgroup:/host/sys/fs/cgroup:rw \
 	-v /run/docker:/run/docker:rw {{.DockerOpts}} {{.QuiltImage}} \
 	quilt -l {{.LogLevel}} minion {{.MinionOpts}}
 	Restart=on-failure
@@ -139,6 +142,16 @@ systemctl enable {docker,ovs,minion}.service
 # Start our services
 systemctl restart {docker,ovs,minion}.service
 
+{{- if .BootScript}}
+
+# Run the user's boot script.
+cat << 'QUILT_BOOT_SCRIPT_EOF' > /usr/local/bin/quilt-boot-script
+{{.BootScript}}
+QUILT_BOOT_SCRIPT_EOF
+chmod +x /usr/local/bin/quilt-boot-script
+/usr/local/bin/quilt-boot-script >> /var/log/bootscript.log 2>&1
+{{- end}}
+
 echo -n "Completed Boot Script: " >> /var/log/bootscript.log
 date >> /var/log/bootscript.log
     `
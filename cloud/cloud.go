@@ -6,6 +6,7 @@ import (
 	"time"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/quilt/quilt/api"
 	"github.com/quilt/quilt/blueprint"
 	"github.com/quilt/quilt/cloud/acl"
 	"github.com/quilt/quilt/cloud/amazon"
@@ -17,6 +18,7 @@ import (
 	"github.com/quilt/quilt/connection"
 	"github.com/quilt/quilt/counter"
 	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/health"
 	"github.com/quilt/quilt/join"
 	"github.com/quilt/quilt/util"
 )
@@ -42,6 +44,10 @@ type cloud struct {
 	providerName db.ProviderName
 	region       string
 	provider     provider
+	backoff      *backoff
+
+	// Whether the provider's API is reachable.
+	health health.Check
 }
 
 var myIP = util.MyIP
@@ -100,7 +106,14 @@ func newCloud(conn db.Conn, pName db.ProviderName, region, ns string) (cloud, er
 		namespace:    ns,
 		region:       region,
 		providerName: pName,
+		backoff:      newBackoff(),
+	}
+
+	healthName := fmt.Sprintf("cloud %s", pName)
+	if region != "" {
+		healthName += "-" + region
 	}
+	cld.health = health.New(healthName, 5*time.Minute)
 
 	var err error
 	cld.provider, err = newProvider(pName, ns, region)
@@ -126,6 +139,7 @@ func (cld cloud) run(stop <-chan struct{}) {
 		select {
 		case <-stop:
 			log.Debugf("Stop Cloud %s", cld)
+			cld.health.Remove()
 			return
 		default:
 		}
@@ -134,6 +148,7 @@ func (cld cloud) run(stop <-chan struct{}) {
 
 		// Somewhat of a crude rate-limit of once every five seconds to
 		// avoid stressing out the cloud providers with too many calls.
+		// Failing calls are additionally backed off by `cld.backoff`.
 		sleep(5 * time.Second)
 	}
 }
@@ -189,19 +204,26 @@ func (cld cloud) boot(machines []db.Machine) {
 			Role:        m.Role,
 			Provider:    m.Provider,
 			Region:      m.Region,
+			Image:       m.Image,
+			BootScript:  m.BootScript,
 		})
 	}
 
 	setStatuses(cld.conn, machines, db.Booting)
 	defer setStatuses(cld.conn, machines, "")
-	cld.updateCloud(cloudMachines, provider.Boot, "boot")
+	err := cld.updateCloud(cloudMachines, provider.Boot, "boot")
+
+	// Record the error so that users can tell why their machines aren't booting.
+	setErrors(cld.conn, machines, err)
 }
 
 type machineAction func(provider, []db.Machine) error
 
-func (cld cloud) updateCloud(machines []db.Machine, fn machineAction, action string) {
+func (cld cloud) updateCloud(machines []db.Machine, fn machineAction,
+	action string) error {
+
 	if len(machines) == 0 {
-		return
+		return nil
 	}
 
 	logFields := log.Fields{
@@ -211,12 +233,21 @@ func (cld cloud) updateCloud(machines []db.Machine, fn machineAction, action str
 	}
 
 	c.Inc(action)
-	if err := fn(cld.provider, machines); err != nil {
+	err := cld.backoff.call(action, func() error {
+		return fn(cld.provider, machines)
+	})
+
+	switch err.(type) {
+	case nil:
+		log.WithFields(logFields).Infof("Updated machines.")
+	case backoffError:
+		logFields["error"] = err
+		log.WithFields(logFields).Debug("Waiting to update machines.")
+	default:
 		logFields["error"] = err
 		log.WithFields(logFields).Errorf("Failed to update machines.")
-	} else {
-		log.WithFields(logFields).Infof("Updated machines.")
 	}
+	return err
 }
 
 type joinResult struct {
@@ -232,7 +263,19 @@ func (cld cloud) join() (joinResult, error) {
 
 	cloudMachines, err := cld.get()
 	if err != nil {
-		log.WithError(err).Error("Failed to list machines")
+		if _, ok := err.(backoffError); ok {
+			log.WithError(err).Debug("Waiting to list machines")
+		} else {
+			log.WithError(err).Error("Failed to list machines")
+		}
+
+		// Machines that haven't been booted yet won't be until the
+		// provider's API is reachable again.
+		pending := cld.conn.SelectFromMachine(func(m db.Machine) bool {
+			return m.Provider == cld.providerName &&
+				m.Region == cld.region && m.CloudID == ""
+		})
+		setErrors(cld.conn, pending, err)
 		return res, err
 	}
 
@@ -260,9 +303,13 @@ func (cld cloud) join() (joinResult, error) {
 
 		cloudMachines = getMachineRoles(cloudMachines)
 
+		var replaced []db.Machine
+		machines, cloudMachines, replaced = releaseReplaced(view, machines,
+			cloudMachines)
+
 		dbResult := syncDB(cloudMachines, machines)
 		res.boot = dbResult.boot
-		res.terminate = dbResult.stop
+		res.terminate = append(dbResult.stop, replaced...)
 		res.updateIPs = dbResult.updateIPs
 
 		for _, pair := range dbResult.pairs {
@@ -275,9 +322,10 @@ func (cld cloud) join() (joinResult, error) {
 
 			if dbm.PublicIP != m.PublicIP {
 				// We're changing the association between a database
-				// machine and a cloud machine, so the status is not
-				// applicable.
+				// machine and a cloud machine, so the status and host
+				// keys are not applicable.
 				dbm.Status = ""
+				dbm.HostKeys = nil
 			}
 			dbm.PublicIP = m.PublicIP
 			dbm.PrivateIP = m.PrivateIP
@@ -297,6 +345,76 @@ func (cld cloud) join() (joinResult, error) {
 	return res, err
 }
 
+// releaseReplaced handles the database machines that are being replaced.  While a
+// replaced machine's cloud instance still exists, neither is joined, and the
+// instance is stopped.  The machine keeps its CloudID until then, so that an
+// instance whose Stop failed is stopped again rather than joined with the
+// machine's replacement.  Once the instance is gone, the machine is detached from
+// it, so that syncDB boots a fresh instance.  releaseReplaced returns the database
+// and cloud machines that may still be joined, and the cloud machines that should
+// be terminated.
+func releaseReplaced(view db.Database, dbms, cms []db.Machine) (
+	[]db.Machine, []db.Machine, []db.Machine) {
+
+	cloudIDs := map[string]struct{}{}
+	for _, cm := range cms {
+		cloudIDs[cm.CloudID] = struct{}{}
+	}
+
+	replacedIDs := map[string]struct{}{}
+	var keepDBMs []db.Machine
+	for _, dbm := range dbms {
+		if dbm.Status != db.Replacing {
+			keepDBMs = append(keepDBMs, dbm)
+			continue
+		}
+
+		if _, ok := cloudIDs[dbm.CloudID]; ok && dbm.CloudID != "" {
+			replacedIDs[dbm.CloudID] = struct{}{}
+			continue
+		}
+
+		dbm.CloudID = ""
+		dbm.PublicIP = ""
+		dbm.PrivateIP = ""
+		dbm.Status = ""
+		dbm.HostKeys = nil
+		view.Commit(dbm)
+		keepDBMs = append(keepDBMs, dbm)
+	}
+
+	var keepCMs, stop []db.Machine
+	for _, cm := range cms {
+		if _, ok := replacedIDs[cm.CloudID]; ok {
+			stop = append(stop, cm)
+		} else {
+			keepCMs = append(keepCMs, cm)
+		}
+	}
+	return keepDBMs, keepCMs, stop
+}
+
+// The ports that the machines in a cluster use to communicate with each other.
+// The providers open both TCP and UDP for each range.
+var clusterPorts = []struct{ min, max int }{
+	{2379, 2380}, // etcd clients and peers.
+	{4789, 4789}, // VXLAN tunnels.
+	{5000, 5000}, // The leader's image registry.
+	{6081, 6081}, // Geneve tunnels.
+	{6640, 6640}, // OVSDB and the OVN databases.
+	{7471, 7471}, // STT tunnels.
+	{api.DefaultRemotePort, api.DefaultRemotePort}, // The minion API server.
+	{9080, 9080}, // Routers forwarding ingress requests to each other.
+	{9999, 9999}, // The minion gRPC server.
+}
+
+// The ports that the daemon uses to reach the machines.
+var daemonPorts = []struct{ min, max int }{
+	{22, 22}, // SSH.
+	{api.DefaultRemotePort, api.DefaultRemotePort}, // The minion API server.
+	{9999, 9999}, // The minion gRPC server.
+}
+
 // getACLs generates the set of ACLs that `cld` should have installed. It requires a list
 // of every machine in every region so that acls can be generated that allow them to
 // communicate with each other.  The machines just in this `cld`s region are not
@@ -304,8 +422,7 @@ func (cld cloud) join() (joinResult, error) {
 func (cld cloud) getACLs(bp db.Blueprint, machines []db.Machine) map[acl.ACL]struct{} {
 	aclSet := map[acl.ACL]struct{}{}
 
-	// Always allow traffic from the Quilt controller, so we append local.
-	for _, cidr := range append(bp.AdminACL, "local") {
+	for _, cidr := range bp.AdminACL {
 		acl := acl.ACL{
 			CidrIP:  cidr,
 			MinPort: 1,
@@ -314,17 +431,33 @@ func (cld cloud) getACLs(bp db.Blueprint, machines []db.Machine) map[acl.ACL]str
 		aclSet[acl] = struct{}{}
 	}
 
-	/* for _, m := range machines {
-		if m.PublicIP != "" {
-			// XXX: Look into the minimal set of necessary ports.
+	// Always allow the Quilt controller to reach the machines, but only on the
+	// ports it uses.
+	for _, ports := range daemonPorts {
+		acl := acl.ACL{
+			CidrIP:  "local",
+			MinPort: ports.min,
+			MaxPort: ports.max,
+		}
+		aclSet[acl] = struct{}{}
+	}
+
+	// Machines may be spread across regions and providers, so they reach each
+	// other over their public IPs.  Only open the ports the minions need.
+	for _, m := range machines {
+		if m.PublicIP == "" {
+			continue
+		}
+
+		for _, ports := range clusterPorts {
 			acl := acl.ACL{
 				CidrIP:  m.PublicIP + "/32",
-				MinPort: 1,
-				MaxPort: 65535,
+				MinPort: ports.min,
+				MaxPort: ports.max,
 			}
 			aclSet[acl] = struct{}{}
 		}
-	} */
+	}
 
 	for _, conn := range bp.Connections {
 		if conn.From == blueprint.PublicInternetLabel {
@@ -355,7 +488,10 @@ func (cld cloud) syncACLs(unresolvedACLs []acl.ACL) {
 	}
 
 	c.Inc("SetACLs")
-	if err := cld.provider.SetACLs(acls); err != nil {
+	err := cld.backoff.call("set ACLs", func() error {
+		return cld.provider.SetACLs(acls)
+	})
+	if err != nil {
 		log.WithError(err).Warnf("Could not update ACLs in %s.", cld)
 	}
 }
@@ -376,6 +512,7 @@ func syncDB(cms []db.Machine, dbms []db.Machine) syncDBResult {
 
 		if dbm.CloudID == m.CloudID && dbm.Provider == m.Provider &&
 			dbm.Preemptible == m.Preemptible &&
+			cfg.BootHash(dbm) == m.BootHash &&
 			dbm.Region == m.Region && dbm.Size == m.Size &&
 			(m.DiskSize == 0 || dbm.DiskSize == m.DiskSize) &&
 			(m.Role == db.None || dbm.Role == m.Role) {
@@ -393,6 +530,7 @@ func syncDB(cms []db.Machine, dbms []db.Machine) syncDBResult {
 			dbm.Region != m.Region ||
 			dbm.Size != m.Size ||
 			dbm.Preemptible != m.Preemptible ||
+			cfg.BootHash(dbm) != m.BootHash ||
 			(m.DiskSize != 0 && dbm.DiskSize != m.DiskSize) ||
 			(m.Role != db.None && dbm.Role != m.Role) {
 			return -1
@@ -438,8 +576,16 @@ func syncDB(cms []db.Machine, dbms []db.Machine) syncDBResult {
 func (cld cloud) get() ([]db.Machine, error) {
 	c.Inc("List")
 
-	machines, err := cld.provider.List()
-	if err != nil {
+	var machines []db.Machine
+	err := cld.backoff.call("list", func() (err error) {
+		machines, err = cld.provider.List()
+		return err
+	})
+	cld.health.Report(err)
+
+	if _, ok := err.(backoffError); ok {
+		return nil, err
+	} else if err != nil {
 		return nil, fmt.Errorf("list %s: %s", cld, err)
 	}
 
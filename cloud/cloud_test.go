@@ -9,6 +9,7 @@ import (
 
 	"github.com/quilt/quilt/blueprint"
 	"github.com/quilt/quilt/cloud/acl"
+	"github.com/quilt/quilt/cloud/cfg"
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/join"
 	"github.com/stretchr/testify/assert"
@@ -33,6 +34,8 @@ type fakeProvider struct {
 	aclRequests  []acl.ACL
 
 	listError error
+	bootError error
+	stopError error
 }
 
 func fakeValidRegions(p db.ProviderName) []string {
@@ -59,6 +62,10 @@ func (p *fakeProvider) List() ([]db.Machine, error) {
 }
 
 func (p *fakeProvider) Boot(bootSet []db.Machine) error {
+	if p.bootError != nil {
+		return p.bootError
+	}
+
 	for _, toBoot := range bootSet {
 		// Record the boot request before we mutate it with implementation
 		// details of our fakeProvider.
@@ -68,6 +75,7 @@ func (p *fakeProvider) Boot(bootSet []db.Machine) error {
 		idStr := strconv.Itoa(p.idCounter)
 		toBoot.CloudID = idStr
 		toBoot.PublicIP = idStr
+		toBoot.BootHash = cfg.BootHash(toBoot)
 
 		// A machine's role is `None` until the minion boots, at which
 		// `getMachineRoles` will populate this field with the correct role.
@@ -84,6 +92,10 @@ func (p *fakeProvider) Boot(bootSet []db.Machine) error {
 }
 
 func (p *fakeProvider) Stop(machines []db.Machine) error {
+	if p.stopError != nil {
+		return p.stopError
+	}
+
 	for _, machine := range machines {
 		delete(p.machines, machine.CloudID)
 		p.stopRequests = append(p.stopRequests, machine.CloudID)
@@ -240,6 +252,25 @@ func TestSyncDB(t *testing.T) {
 			stop: []db.Machine{{Preemptible: true}},
 		})
 
+	// Test changed images and boot scripts.
+	dbImage := db.Machine{CloudID: "id", Image: "image", BootScript: "script"}
+	cmImage := db.Machine{CloudID: "id", BootHash: cfg.BootHash(dbImage)}
+	checkSyncDB([]db.Machine{cmImage}, []db.Machine{dbImage}, syncDBResult{})
+
+	dbNewScript := dbImage
+	dbNewScript.BootScript = "new script"
+	checkSyncDB([]db.Machine{cmImage}, []db.Machine{dbNewScript},
+		syncDBResult{
+			boot: []db.Machine{dbNewScript},
+			stop: []db.Machine{cmImage},
+		})
+
+	checkSyncDB([]db.Machine{{CloudID: "id"}}, []db.Machine{dbImage},
+		syncDBResult{
+			boot: []db.Machine{dbImage},
+			stop: []db.Machine{{CloudID: "id"}},
+		})
+
 	// Test matching role as priority over PublicIP
 	dbMaster.PublicIP = "worker"
 	cmMasterList.PublicIP = "master"
@@ -568,6 +599,107 @@ func TestCloudRunOnce(t *testing.T) {
 	})
 }
 
+func TestReplaceMachine(t *testing.T) {
+	cld := newTestCloud(FakeAmazon, testRegion, "ns")
+	setNamespace(cld.conn, "ns")
+	cld.conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.InsertMachine()
+		m.BlueprintID = "master"
+		m.Role = db.Master
+		m.Provider = FakeAmazon
+		m.Region = testRegion
+		m.Size = "m4.large"
+		view.Commit(m)
+		return nil
+	})
+	cld.runOnce()
+
+	providerInst := cld.provider.(*fakeProvider)
+	providerInst.clearLogs()
+
+	dbm := cld.conn.SelectFromMachine(nil)[0]
+	assert.Equal(t, "1", dbm.CloudID)
+
+	dbm.Status = db.Replacing
+	dbm.Replacements = 1
+	cld.conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+		view.Commit(dbm)
+		return nil
+	})
+
+	// Until the dead instance is stopped, the machine keeps its CloudID, and
+	// no replacement is booted.
+	providerInst.stopError = errors.New("stop failed")
+	cld.runOnce()
+	assert.Empty(t, providerInst.bootRequests)
+	dbms := cld.conn.SelectFromMachine(nil)
+	assert.Len(t, dbms, 1)
+	assert.Equal(t, "1", dbms[0].CloudID)
+	assert.Equal(t, db.Replacing, dbms[0].Status)
+
+	providerInst.stopError = nil
+	cld.backoff = newBackoff()
+	cld.runOnce()
+
+	// The dead instance is terminated, and a replacement is booted with the
+	// same role.
+	assert.Equal(t, []string{"1"}, providerInst.stopRequests)
+	assert.Equal(t, []db.Machine{{
+		Provider: FakeAmazon,
+		Region:   testRegion,
+		Size:     "m4.large",
+		Role:     db.Master,
+	}}, providerInst.bootRequests)
+
+	cld.runOnce()
+	dbms = cld.conn.SelectFromMachine(nil)
+	assert.Len(t, dbms, 1)
+	assert.Equal(t, "master", dbms[0].BlueprintID)
+	assert.Equal(t, "2", dbms[0].CloudID)
+	assert.Equal(t, "2", dbms[0].PublicIP)
+	assert.Equal(t, "", dbms[0].Status)
+	assert.Equal(t, 1, dbms[0].Replacements)
+}
+
+func TestBootCustomImage(t *testing.T) {
+	cld := newTestCloud(FakeAmazon, testRegion, "ns")
+	setNamespace(cld.conn, "ns")
+	cld.conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.InsertMachine()
+		m.Role = db.Worker
+		m.Provider = FakeAmazon
+		m.Region = testRegion
+		m.Size = "m4.large"
+		m.Image = "ami-custom"
+		m.BootScript = "echo hello"
+		view.Commit(m)
+		return nil
+	})
+	cld.runOnce()
+
+	// The provider is asked for the custom image and boot script.
+	providerInst := cld.provider.(*fakeProvider)
+	assert.Equal(t, []db.Machine{{
+		Provider:   FakeAmazon,
+		Region:     testRegion,
+		Size:       "m4.large",
+		Role:       db.Worker,
+		Image:      "ami-custom",
+		BootScript: "echo hello",
+	}}, providerInst.bootRequests)
+	providerInst.clearLogs()
+
+	// The booted machine matches the database on the next pass, so it isn't
+	// terminated and booted again.
+	cld.runOnce()
+	assert.Empty(t, providerInst.stopRequests)
+	assert.Empty(t, providerInst.bootRequests)
+
+	dbms := cld.conn.SelectFromMachine(nil)
+	assert.Len(t, dbms, 1)
+	assert.Equal(t, "1", dbms[0].CloudID)
+}
+
 func TestACLs(t *testing.T) {
 	myIP = func() (string, error) {
 		return "5.6.7.8", nil
@@ -591,18 +723,23 @@ func TestGetACLs(t *testing.T) {
 	cld := newTestCloud(FakeAmazon, testRegion, "ns")
 
 	exp := map[acl.ACL]struct{}{
-		{CidrIP: "local", MinPort: 1, MaxPort: 65535}: {},
+		{CidrIP: "local", MinPort: 22, MaxPort: 22}:     {},
+		{CidrIP: "local", MinPort: 9000, MaxPort: 9000}: {},
+		{CidrIP: "local", MinPort: 9999, MaxPort: 9999}: {},
 	}
 
-	// Empty blueprint should have "local" added to it.
+	// Empty blueprint should have "local" added to it, but only for the ports
+	// the daemon uses.
 	acls := cld.getACLs(db.Blueprint{}, nil)
 	assert.Equal(t, exp, acls)
 
-	// A blueprint with local, shouldn't have it added a second time.
+	// Admins are allowed to reach every port.
 	acls = cld.getACLs(db.Blueprint{
-		Blueprint: blueprint.Blueprint{AdminACL: []string{"local"}},
+		Blueprint: blueprint.Blueprint{AdminACL: []string{"1.2.3.4/32"}},
 	}, nil)
-	assert.Equal(t, exp, acls)
+	assert.Len(t, acls, 4)
+	assert.Contains(t, acls, acl.ACL{CidrIP: "1.2.3.4/32", MinPort: 1,
+		MaxPort: 65535})
 
 	// Connections that aren't to or from public, shouldn't affect the acls.
 	acls = cld.getACLs(db.Blueprint{
@@ -631,12 +768,23 @@ func TestGetACLs(t *testing.T) {
 	exp[acl.ACL{CidrIP: "0.0.0.0/0", MinPort: 1, MaxPort: 2}] = struct{}{}
 	assert.Equal(t, exp, acls)
 
-	// Machines have holes opened up for them.
+	// Machines have holes opened up for them, but only for the cluster's ports.
 	exp = map[acl.ACL]struct{}{
-		{CidrIP: "local", MinPort: 1, MaxPort: 65535}:      {},
-		{CidrIP: "1.2.3.4/32", MinPort: 1, MaxPort: 65535}: {},
+		{CidrIP: "local", MinPort: 22, MaxPort: 22}:          {},
+		{CidrIP: "local", MinPort: 9000, MaxPort: 9000}:      {},
+		{CidrIP: "local", MinPort: 9999, MaxPort: 9999}:      {},
+		{CidrIP: "1.2.3.4/32", MinPort: 2379, MaxPort: 2380}: {},
+		{CidrIP: "1.2.3.4/32", MinPort: 4789, MaxPort: 4789}: {},
+		{CidrIP: "1.2.3.4/32", MinPort: 5000, MaxPort: 5000}: {},
+		{CidrIP: "1.2.3.4/32", MinPort: 6081, MaxPort: 6081}: {},
+		{CidrIP: "1.2.3.4/32", MinPort: 6640, MaxPort: 6640}: {},
+		{CidrIP: "1.2.3.4/32", MinPort: 7471, MaxPort: 7471}: {},
+		{CidrIP: "1.2.3.4/32", MinPort: 9000, MaxPort: 9000}: {},
+		{CidrIP: "1.2.3.4/32", MinPort: 9080, MaxPort: 9080}: {},
+		{CidrIP: "1.2.3.4/32", MinPort: 9999, MaxPort: 9999}: {},
 	}
-	acls = cld.getACLs(db.Blueprint{}, []db.Machine{{PublicIP: "1.2.3.4"}})
+	acls = cld.getACLs(db.Blueprint{}, []db.Machine{
+		{PublicIP: "1.2.3.4"}, {PrivateIP: "5.6.7.8"}})
 	assert.Equal(t, exp, acls)
 }
 
@@ -664,7 +812,51 @@ func TestGetError(t *testing.T) {
 	cld := newTestCloud(FakeAmazon, testRegion, "ns")
 	cld.provider.(*fakeProvider).listError = errors.New("err")
 	_, err := cld.get()
-	assert.EqualError(t, err, "list FakeAmazon-Fake region-ns: err")
+	assert.EqualError(t, err, "list FakeAmazon-Fake region-ns: transient: err")
+
+	// Until the backoff expires, the provider isn't called at all.
+	cld.provider.(*fakeProvider).listError = nil
+	_, err = cld.get()
+	assert.IsType(t, backoffError{}, err)
+}
+
+func TestBootError(t *testing.T) {
+	start := time.Now()
+	now = func() time.Time { return start }
+	defer func() { now = time.Now }()
+
+	cld := newTestCloud(FakeAmazon, testRegion, "ns")
+	setNamespace(cld.conn, "ns")
+	cld.conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.InsertMachine()
+		m.Role = db.Master
+		m.Provider = FakeAmazon
+		m.Region = testRegion
+		m.Size = "m4.large"
+		view.Commit(m)
+		return nil
+	})
+
+	providerInst := cld.provider.(*fakeProvider)
+	providerInst.bootError = errors.New("InstanceLimitExceeded")
+	cld.runOnce()
+
+	// The second attempt to boot within `runOnce` is backed off.
+	dbm := cld.conn.SelectFromMachine(nil)[0]
+	assert.Equal(t, "quota: InstanceLimitExceeded (retrying in 1m0s)", dbm.Error)
+	assert.Equal(t, "", dbm.Status)
+
+	// Booting isn't retried until the backoff expires.
+	providerInst.bootError = nil
+	cld.runOnce()
+	assert.Empty(t, providerInst.bootRequests)
+
+	now = func() time.Time { return start.Add(time.Minute) }
+	cld.runOnce()
+	assert.Len(t, providerInst.bootRequests, 1)
+	dbm = cld.conn.SelectFromMachine(nil)[0]
+	assert.Equal(t, "", dbm.Error)
+	assert.Equal(t, "1", dbm.CloudID)
 }
 
 func setNamespace(conn db.Conn, ns string) {
@@ -12,76 +12,89 @@ import (
 	"github.com/spf13/afero/sftpfs"
 	"golang.org/x/crypto/ssh"
 
+	"github.com/quilt/quilt/connection/auth"
+	"github.com/quilt/quilt/connection/credentials/tls"
 	tlsIO "github.com/quilt/quilt/connection/credentials/tls/io"
 	"github.com/quilt/quilt/connection/credentials/tls/rsa"
 	"github.com/quilt/quilt/counter"
 	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/util"
 )
 
 var credentialsCounter = counter.New("Cloud Credentials")
 
 // SyncCredentials installs TLS certificates on all machines. It generates
 // the certificates using the given certificate authority, and copies them
-// over using the given ssh key. It only installs certificates once -- once
-// certificates are in place on a machine, they are left alone.
-// XXX: The logic to avoid overwriting existing certificates does not work
-// between restarts to the daemon. If a minion has been initialized with
-// certificates but the daemon restarts, it will overwrite the existing
-// certificate. However, because this code does not cause the minion to reload
-// the certificate from disk, it will continue to run with the old certificates.
-// This will not cause any interruption to connections as long as the same
-// certificate authority is used by the daemon.
+// over using the given ssh key. Certificates are reinstalled when they're
+// about to expire, or weren't signed by the certificate authority. Minions
+// reload their certificates from disk, so rotation doesn't interrupt them.
+// Machines must present the host keys reported by their minion. Until a minion
+// reports its keys, the first key its machine presents is trusted.
 func SyncCredentials(conn db.Conn, dstDir string, sshKey ssh.Signer, ca rsa.KeyPair) {
-	credentialedMachines := map[string]struct{}{}
+	// The expiry of the certificates installed on each machine.
+	credentialedMachines := map[string]time.Time{}
 	for range conn.TriggerTick(30, db.MachineTable).C {
 		machines := conn.SelectFromMachine(nil)
-		syncCredentialsOnce(dstDir, sshKey, ca, machines, credentialedMachines)
+		syncCredentialsOnce(conn, dstDir, sshKey, ca, machines,
+			credentialedMachines)
 	}
 }
 
-func syncCredentialsOnce(dstDir string, sshKey ssh.Signer, ca rsa.KeyPair,
-	machines []db.Machine, credentialedMachines map[string]struct{}) {
+func syncCredentialsOnce(conn db.Conn, dstDir string, sshKey ssh.Signer,
+	ca rsa.KeyPair, machines []db.Machine,
+	credentialedMachines map[string]time.Time) {
 	credentialsCounter.Inc("Install to cluster")
 	for _, m := range machines {
-		_, hasCreds := credentialedMachines[m.PublicIP]
-		if hasCreds || m.PublicIP == "" {
+		expiry, hasCreds := credentialedMachines[m.PublicIP]
+		if (hasCreds && !needsRotation(expiry)) || m.PublicIP == "" {
 			continue
 		}
 
 		credentialsCounter.Inc("Install " + m.PublicIP)
-		if generateAndInstallCerts(m.PublicIP, dstDir, sshKey, ca) {
-			credentialedMachines[m.PublicIP] = struct{}{}
+		hostKeyCallback := util.HostKeyCallback(m.HostKeys,
+			trustHostKey(conn, m.ID))
+		expiry, ok := installCerts(m.PublicIP, dstDir, sshKey, hostKeyCallback,
+			ca)
+		if ok {
+			credentialedMachines[m.PublicIP] = expiry
 		}
 	}
 }
 
-// generateAndInstallCerts attempts to generate a certificate key pair and install
-// it onto host. Returns whether it was successful.
-func generateAndInstallCerts(host string, dstDir string, sshKey ssh.Signer,
-	ca rsa.KeyPair) bool {
-	fs, err := getSftpFs(host, sshKey)
+// installCerts attempts to install a signed certificate with the Minion role onto
+// host. If the host already has a valid certificate, e.g. because the daemon
+// restarted, it's left alone. Returns when the installed certificate expires, and
+// whether it was successful.
+func installCerts(host string, dstDir string, sshKey ssh.Signer,
+	hostKeyCallback ssh.HostKeyCallback, ca rsa.KeyPair) (time.Time, bool) {
+	fs, err := getSftpFs(host, sshKey, hostKeyCallback)
 	if err != nil {
 		// This error is probably benign because failures to SSH are expected
 		// while the machine is still booting.
 		log.WithError(err).WithField("host", host).
 			Debug("Failed to get SFTP client. Retrying.")
-		return false
+		return time.Time{}, false
 	}
 	defer fs.Close()
 
+	expiry, err := installedExpiry(fs, dstDir, ca)
+	if err == nil && !needsRotation(expiry) {
+		return expiry, true
+	}
+
 	// Generate new certificates signed by the CA for use by the minion for all
 	// communication.
-	signed, err := rsa.NewSigned(ca)
+	signed, err := rsa.NewSigned(ca, auth.Minion)
 	if err != nil {
 		log.WithError(err).WithField("host", host).
 			Error("Failed to generate certs. Retrying.")
-		return false
+		return time.Time{}, false
 	}
 
 	if err := fs.MkdirAll(dstDir, 0755); err != nil {
 		log.WithError(err).WithField("host", host).Error(
 			"Failed to create TLS directory. Retrying.")
-		return false
+		return time.Time{}, false
 	}
 
 	for _, f := range tlsIO.MinionFiles(dstDir, ca, signed) {
@@ -91,11 +104,125 @@ func generateAndInstallCerts(host string, dstDir string, sshKey ssh.Signer,
 				"path":  f.Path,
 				"host":  host,
 			}).Error("Failed to write file")
-			return false
+			return time.Time{}, false
 		}
 	}
 
-	return true
+	log.WithFields(log.Fields{
+		"host":   host,
+		"expiry": signed.NotAfter(),
+	}).Info("Installed TLS certificate")
+	return signed.NotAfter(), true
+}
+
+// installedExpiry returns when the certificate already installed in `fs`
+// expires. Certificates without the Minion role, e.g. those installed before
+// roles were introduced, are treated as expired.
+func installedExpiry(fs afero.Fs, dir string, ca rsa.KeyPair) (time.Time, error) {
+	certBytes, err := afero.ReadFile(fs, tlsIO.SignedCertPath(dir))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	cert, err := rsa.ParseCert(ca, string(certBytes))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if role := auth.CertRole(cert); role != auth.Minion {
+		return time.Time{}, fmt.Errorf("unexpected role: %s", role)
+	}
+	return cert.NotAfter, nil
+}
+
+// trustHostKey returns a function that records `fingerprint` as the host key of
+// the machine with the given ID, unless its minion has already reported its keys.
+func trustHostKey(conn db.Conn, id int) func(string) error {
+	return func(fingerprint string) error {
+		return conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+			machines := view.SelectFromMachine(func(m db.Machine) bool {
+				return m.ID == id
+			})
+			if len(machines) != 1 {
+				return fmt.Errorf("no machine with ID %d", id)
+			}
+
+			m := machines[0]
+			if len(m.HostKeys) != 0 {
+				// The minion reported its keys since the connection
+				// started, so check against those instead.
+				for _, key := range m.HostKeys {
+					if key == fingerprint {
+						return nil
+					}
+				}
+				return fmt.Errorf("host key %s for %s doesn't match "+
+					"the keys reported by its minion", fingerprint,
+					m.PublicIP)
+			}
+
+			log.WithFields(log.Fields{
+				"host":        m.PublicIP,
+				"fingerprint": fingerprint,
+			}).Info("Trusting host key on first use")
+			m.HostKeys = []string{fingerprint}
+			view.Commit(m)
+			return nil
+		})
+	}
+}
+
+// RotateCredentials reissues the daemon's signed certificate in `dir` before it
+// expires, and updates `creds` to use it.
+func RotateCredentials(dir string, ca rsa.KeyPair, creds tls.TLS) {
+	for range time.Tick(time.Hour) {
+		if err := rotateCredentialsOnce(dir, ca, creds); err != nil {
+			log.WithError(err).Error("Failed to rotate TLS certificate")
+		}
+	}
+}
+
+func rotateCredentialsOnce(dir string, ca rsa.KeyPair, creds tls.TLS) error {
+	// Certificates issued before roles were introduced are only granted
+	// ReadOnly, so they're reissued right away.
+	if !needsRotation(creds.CertExpiry()) {
+		certStr, err := util.ReadFile(tlsIO.SignedCertPath(dir))
+		if err != nil {
+			return fmt.Errorf("read certificate: %s", err)
+		}
+
+		cert, err := rsa.ParseCert(ca, certStr)
+		if err != nil {
+			return fmt.Errorf("parse certificate: %s", err)
+		}
+
+		if auth.CertRole(cert) == auth.Admin {
+			return nil
+		}
+	}
+
+	credentialsCounter.Inc("Rotate daemon")
+	signed, err := rsa.NewSigned(ca, auth.Admin)
+	if err != nil {
+		return fmt.Errorf("generate certs: %s", err)
+	}
+
+	for _, f := range tlsIO.SignedFiles(dir, signed) {
+		if err := util.WriteFile(f.Path, []byte(f.Content), f.Mode); err != nil {
+			return fmt.Errorf("write %s: %s", f.Path, err)
+		}
+	}
+
+	if err := tlsIO.ReloadCredentials(dir, creds); err != nil {
+		return fmt.Errorf("reload: %s", err)
+	}
+
+	log.WithField("expiry", signed.NotAfter()).Info("Rotated TLS certificate")
+	return nil
+}
+
+func needsRotation(expiry time.Time) bool {
+	return now().Add(rsa.RenewBefore).After(expiry)
 }
 
 func write(fs afero.Fs, path, contents string, mode os.FileMode) error {
@@ -132,18 +259,15 @@ func (fs sftpFsImpl) Close() error {
 	return fs.client.Close()
 }
 
-// getSftpFsImpl gets an SFTP connection to `host` authenticated by `sshKey`.
-func getSftpFsImpl(host string, sshKey ssh.Signer) (sftpFs, error) {
+// getSftpFsImpl gets an SFTP connection to `host` authenticated by `sshKey`. The
+// host's key is verified by `hostKeyCallback`.
+func getSftpFsImpl(host string, sshKey ssh.Signer,
+	hostKeyCallback ssh.HostKeyCallback) (sftpFs, error) {
 	sshConfig := &ssh.ClientConfig{
-		User:    "quilt",
-		Auth:    []ssh.AuthMethod{ssh.PublicKeys(sshKey)},
-		Timeout: 5 * time.Second,
-		// XXX: We have to ignore the host key because we don't keep track of
-		// the host keys of machines. Once we do, this should use strict host
-		// key checking. For now, this means that a machine could theoretically
-		// man in the middle as the target machine and obtain signed
-		// certificates.
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		User:            "quilt",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(sshKey)},
+		Timeout:         5 * time.Second,
+		HostKeyCallback: hostKeyCallback,
 	}
 	sshClient, err := ssh.Dial("tcp", fmt.Sprintf("%s:22", host), sshConfig)
 	if err != nil {
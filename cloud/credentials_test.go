@@ -3,14 +3,19 @@ package cloud
 import (
 	"crypto/rand"
 	goRSA "crypto/rsa"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/ssh"
 
+	"github.com/quilt/quilt/connection/auth"
+	tlsIO "github.com/quilt/quilt/connection/credentials/tls/io"
 	"github.com/quilt/quilt/connection/credentials/tls/rsa"
 	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/util"
 )
 
 // Test the success path when generating and installing credentials on a new
@@ -25,7 +30,8 @@ func TestSyncCredentials(t *testing.T) {
 	expHost := "8.8.8.8"
 	mockFs := afero.NewMemMapFs()
 
-	getSftpFs = func(host string, signer ssh.Signer) (sftpFs, error) {
+	getSftpFs = func(host string, signer ssh.Signer,
+		_ ssh.HostKeyCallback) (sftpFs, error) {
 		assert.Equal(t, expSigner, signer)
 		assert.Equal(t, expHost, host)
 		return mockSFTPFs{mockFs}, nil
@@ -34,8 +40,8 @@ func TestSyncCredentials(t *testing.T) {
 	ca, err := rsa.NewCertificateAuthority()
 	assert.NoError(t, err)
 
-	credentialedMachines := map[string]struct{}{}
-	syncCredentialsOnce("out", expSigner, ca,
+	credentialedMachines := map[string]time.Time{}
+	syncCredentialsOnce(db.New(), "out", expSigner, ca,
 		[]db.Machine{{PublicIP: expHost}}, credentialedMachines)
 	assert.Len(t, credentialedMachines, 1)
 
@@ -54,35 +60,179 @@ func TestSyncCredentials(t *testing.T) {
 }
 
 func TestSyncCredentialsSkip(t *testing.T) {
+	conn := db.New()
 	ca, err := rsa.NewCertificateAuthority()
 	assert.NoError(t, err)
 
 	// Test that we skip machines that have not booted yet.
-	credentialedMachines := map[string]struct{}{}
-	syncCredentialsOnce("", nil, ca,
+	credentialedMachines := map[string]time.Time{}
+	syncCredentialsOnce(conn, "", nil, ca,
 		[]db.Machine{{Role: db.Worker}}, credentialedMachines)
 	assert.Empty(t, credentialedMachines, 0)
 
 	// Test that we skip machines that have already been setup.
-	credentialedMachines = map[string]struct{}{
-		"8.8.8.8": {},
+	credentialedMachines = map[string]time.Time{
+		"8.8.8.8": time.Now().Add(2 * rsa.RenewBefore),
 	}
-	syncCredentialsOnce("", nil, ca, []db.Machine{
+	syncCredentialsOnce(conn, "", nil, ca, []db.Machine{
 		{Role: db.Worker, PublicIP: "8.8.8.8"},
 	}, credentialedMachines)
 	assert.Len(t, credentialedMachines, 1)
 
 	// Test that if we fail to get an SFTP client, we bail.
-	getSftpFs = func(host string, _ ssh.Signer) (sftpFs, error) {
+	getSftpFs = func(string, ssh.Signer, ssh.HostKeyCallback) (sftpFs, error) {
 		return nil, assert.AnError
 	}
-	credentialedMachines = map[string]struct{}{}
-	syncCredentialsOnce("", nil, ca, []db.Machine{
+	credentialedMachines = map[string]time.Time{}
+	syncCredentialsOnce(conn, "", nil, ca, []db.Machine{
 		{Role: db.Worker, PublicIP: "8.8.8.8"},
 	}, credentialedMachines)
 	assert.Empty(t, credentialedMachines)
 }
 
+func TestSyncCredentialsRotate(t *testing.T) {
+	conn := db.New()
+	mockFs := afero.NewMemMapFs()
+	getSftpFs = func(host string, signer ssh.Signer,
+		_ ssh.HostKeyCallback) (sftpFs, error) {
+		return mockSFTPFs{mockFs}, nil
+	}
+	defer func() { now = time.Now }()
+
+	ca, err := rsa.NewCertificateAuthority()
+	assert.NoError(t, err)
+
+	machines := []db.Machine{{PublicIP: "8.8.8.8"}}
+	credentialedMachines := map[string]time.Time{}
+	syncCredentialsOnce(conn, "out", nil, ca, machines, credentialedMachines)
+	installed := readCert(t, mockFs)
+	expiry := credentialedMachines["8.8.8.8"]
+
+	// If the daemon restarts, it should keep the installed certificate.
+	credentialedMachines = map[string]time.Time{}
+	syncCredentialsOnce(conn, "out", nil, ca, machines, credentialedMachines)
+	assert.Equal(t, installed, readCert(t, mockFs))
+	assert.Equal(t, expiry, credentialedMachines["8.8.8.8"])
+
+	// Certificates signed by a different CA should be replaced.
+	otherCA, err := rsa.NewCertificateAuthority()
+	assert.NoError(t, err)
+
+	credentialedMachines = map[string]time.Time{}
+	syncCredentialsOnce(conn, "out", nil, otherCA, machines, credentialedMachines)
+	assert.NotEqual(t, installed, readCert(t, mockFs))
+
+	// So should certificates that don't have the minion role.
+	admin, err := rsa.NewSigned(otherCA, auth.Admin)
+	assert.NoError(t, err)
+	assert.NoError(t, afero.WriteFile(mockFs, "out/quilt.crt",
+		[]byte(admin.CertString()), 0644))
+
+	credentialedMachines = map[string]time.Time{}
+	syncCredentialsOnce(conn, "out", nil, otherCA, machines, credentialedMachines)
+	installed = readCert(t, mockFs)
+	assert.NotEqual(t, admin.CertString(), installed)
+
+	cert, err := rsa.ParseCert(otherCA, installed)
+	assert.NoError(t, err)
+	assert.Equal(t, auth.Minion, auth.CertRole(cert))
+	expiry = credentialedMachines["8.8.8.8"]
+
+	// Certificates should be rotated once they're about to expire.
+	now = func() time.Time {
+		return expiry.Add(-rsa.RenewBefore).Add(time.Second)
+	}
+	syncCredentialsOnce(conn, "out", nil, otherCA, machines, credentialedMachines)
+	assert.NotEqual(t, installed, readCert(t, mockFs))
+}
+
+func TestTrustHostKey(t *testing.T) {
+	conn := db.New()
+	var id int
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.InsertMachine()
+		m.PublicIP = "8.8.8.8"
+		view.Commit(m)
+		id = m.ID
+		return nil
+	})
+
+	hostKeys := func() []string {
+		return conn.SelectFromMachine(nil)[0].HostKeys
+	}
+
+	// The first key is trusted, and recorded for later connections.
+	assert.NoError(t, trustHostKey(conn, id)("key"))
+	assert.Equal(t, []string{"key"}, hostKeys())
+
+	assert.NoError(t, trustHostKey(conn, id)("key"))
+	assert.EqualError(t, trustHostKey(conn, id)("other"), "host key other "+
+		"for 8.8.8.8 doesn't match the keys reported by its minion")
+	assert.Equal(t, []string{"key"}, hostKeys())
+
+	assert.EqualError(t, trustHostKey(conn, id+1)("key"),
+		fmt.Sprintf("no machine with ID %d", id+1))
+}
+
+func TestRotateCredentials(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+	defer func() { now = time.Now }()
+
+	ca, err := rsa.NewCertificateAuthority()
+	assert.NoError(t, err)
+
+	signed, err := rsa.NewSigned(ca, auth.Admin)
+	assert.NoError(t, err)
+
+	for _, f := range tlsIO.DaemonFiles("tls", ca, signed) {
+		util.WriteFile(f.Path, []byte(f.Content), f.Mode)
+	}
+
+	creds, err := tlsIO.ReadCredentials("tls")
+	assert.NoError(t, err)
+
+	// Nothing happens if the certificate isn't about to expire.
+	assert.NoError(t, rotateCredentialsOnce("tls", ca, creds))
+	certStr, err := util.ReadFile(tlsIO.SignedCertPath("tls"))
+	assert.NoError(t, err)
+	assert.Equal(t, signed.CertString(), certStr)
+
+	now = func() time.Time {
+		return signed.NotAfter().Add(-time.Hour)
+	}
+	assert.NoError(t, rotateCredentialsOnce("tls", ca, creds))
+	certStr, err = util.ReadFile(tlsIO.SignedCertPath("tls"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, signed.CertString(), certStr)
+
+	cert, err := rsa.ParseCert(ca, certStr)
+	assert.NoError(t, err)
+	assert.Equal(t, cert.NotAfter, creds.CertExpiry())
+	assert.Equal(t, auth.Admin, auth.CertRole(cert))
+
+	// Certificates that don't grant Admin, such as those without a role, are
+	// reissued even if they aren't expiring.
+	now = time.Now
+	signed, err = rsa.NewSigned(ca, auth.ReadOnly)
+	assert.NoError(t, err)
+	for _, f := range tlsIO.SignedFiles("tls", signed) {
+		util.WriteFile(f.Path, []byte(f.Content), f.Mode)
+	}
+
+	assert.NoError(t, rotateCredentialsOnce("tls", ca, creds))
+	certStr, err = util.ReadFile(tlsIO.SignedCertPath("tls"))
+	assert.NoError(t, err)
+	cert, err = rsa.ParseCert(ca, certStr)
+	assert.NoError(t, err)
+	assert.Equal(t, auth.Admin, auth.CertRole(cert))
+}
+
+func readCert(t *testing.T, fs afero.Fs) string {
+	certBytes, err := afero.ReadFile(fs, "out/quilt.crt")
+	assert.NoError(t, err)
+	return string(certBytes)
+}
+
 type mockSFTPFs struct {
 	afero.Fs
 }
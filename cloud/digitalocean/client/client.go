@@ -20,12 +20,23 @@ type Client interface {
 	ListFloatingIPs(*godo.ListOptions) ([]godo.FloatingIP, *godo.Response, error)
 	AssignFloatingIP(string, int) (*godo.Action, *godo.Response, error)
 	UnassignFloatingIP(string) (*godo.Action, *godo.Response, error)
+
+	ListFirewalls(*godo.ListOptions) ([]godo.Firewall, *godo.Response, error)
+	CreateFirewall(*godo.FirewallRequest) (*godo.Firewall, *godo.Response, error)
+	AddFirewallRules(string, *godo.FirewallRulesRequest) (*godo.Response, error)
+	RemoveFirewallRules(string, *godo.FirewallRulesRequest) (*godo.Response, error)
+	DeleteFirewall(string) (*godo.Response, error)
+
+	CreateTag(*godo.TagCreateRequest) (*godo.Tag, *godo.Response, error)
+	TagResources(string, *godo.TagResourcesRequest) (*godo.Response, error)
 }
 
 type client struct {
 	droplets          godo.DropletsService
 	floatingIPs       godo.FloatingIPsService
 	floatingIPActions godo.FloatingIPActionsService
+	firewalls         godo.FirewallsService
+	tags              godo.TagsService
 }
 
 var c = counter.New("Digital Ocean")
@@ -70,6 +81,47 @@ func (client client) UnassignFloatingIP(ip string) (*godo.Action, *godo.Response
 	return client.floatingIPActions.Unassign(context.Background(), ip)
 }
 
+func (client client) ListFirewalls(opt *godo.ListOptions) ([]godo.Firewall,
+	*godo.Response, error) {
+	c.Inc("List Firewalls")
+	return client.firewalls.List(context.Background(), opt)
+}
+
+func (client client) CreateFirewall(req *godo.FirewallRequest) (*godo.Firewall,
+	*godo.Response, error) {
+	c.Inc("Create Firewall")
+	return client.firewalls.Create(context.Background(), req)
+}
+
+func (client client) AddFirewallRules(id string, req *godo.FirewallRulesRequest) (
+	*godo.Response, error) {
+	c.Inc("Add Firewall Rules")
+	return client.firewalls.AddRules(context.Background(), id, req)
+}
+
+func (client client) RemoveFirewallRules(id string, req *godo.FirewallRulesRequest) (
+	*godo.Response, error) {
+	c.Inc("Remove Firewall Rules")
+	return client.firewalls.RemoveRules(context.Background(), id, req)
+}
+
+func (client client) DeleteFirewall(id string) (*godo.Response, error) {
+	c.Inc("Delete Firewall")
+	return client.firewalls.Delete(context.Background(), id)
+}
+
+func (client client) CreateTag(req *godo.TagCreateRequest) (*godo.Tag,
+	*godo.Response, error) {
+	c.Inc("Create Tag")
+	return client.tags.Create(context.Background(), req)
+}
+
+func (client client) TagResources(tag string, req *godo.TagResourcesRequest) (
+	*godo.Response, error) {
+	c.Inc("Tag Resources")
+	return client.tags.TagResources(context.Background(), tag, req)
+}
+
 // New creates a new DigitalOcean client.
 func New(oauthClient *http.Client) Client {
 	api := godo.NewClient(oauthClient)
@@ -77,5 +129,7 @@ func New(oauthClient *http.Client) Client {
 		droplets:          api.Droplets,
 		floatingIPs:       api.FloatingIPs,
 		floatingIPActions: api.FloatingIPActions,
+		firewalls:         api.Firewalls,
+		tags:              api.Tags,
 	}
 }
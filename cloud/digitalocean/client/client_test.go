@@ -42,4 +42,29 @@ func TestError(t *testing.T) {
 	_, _, err = c.UnassignFloatingIP("a")
 	assert.EqualError(t, err,
 		"Post https://api.digitalocean.com/v2/floating_ips/a/actions: test")
+
+	_, _, err = c.ListFirewalls(&godo.ListOptions{})
+	assert.EqualError(t, err, "Get https://api.digitalocean.com/v2/firewalls: test")
+
+	_, _, err = c.CreateFirewall(&godo.FirewallRequest{})
+	assert.EqualError(t, err, "Post https://api.digitalocean.com/v2/firewalls: test")
+
+	_, err = c.AddFirewallRules("a", &godo.FirewallRulesRequest{})
+	assert.EqualError(t, err,
+		"Post https://api.digitalocean.com/v2/firewalls/a/rules: test")
+
+	_, err = c.RemoveFirewallRules("a", &godo.FirewallRulesRequest{})
+	assert.EqualError(t, err,
+		"Delete https://api.digitalocean.com/v2/firewalls/a/rules: test")
+
+	_, err = c.DeleteFirewall("a")
+	assert.EqualError(t, err,
+		"Delete https://api.digitalocean.com/v2/firewalls/a: test")
+
+	_, _, err = c.CreateTag(&godo.TagCreateRequest{})
+	assert.EqualError(t, err, "Post https://api.digitalocean.com/v2/tags: test")
+
+	_, err = c.TagResources("a", &godo.TagResourcesRequest{})
+	assert.EqualError(t, err,
+		"Post https://api.digitalocean.com/v2/tags/a/resources: test")
 }
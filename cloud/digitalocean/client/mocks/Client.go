@@ -9,6 +9,29 @@ type Client struct {
 	mock.Mock
 }
 
+// AddFirewallRules provides a mock function with given fields: _a0, _a1
+func (_m *Client) AddFirewallRules(_a0 string, _a1 *godo.FirewallRulesRequest) (*godo.Response, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *godo.Response
+	if rf, ok := ret.Get(0).(func(string, *godo.FirewallRulesRequest) *godo.Response); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*godo.Response)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, *godo.FirewallRulesRequest) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // AssignFloatingIP provides a mock function with given fields: _a0, _a1
 func (_m *Client) AssignFloatingIP(_a0 string, _a1 int) (*godo.Action, *godo.Response, error) {
 	ret := _m.Called(_a0, _a1)
@@ -73,6 +96,70 @@ func (_m *Client) CreateDroplet(_a0 *godo.DropletCreateRequest) (*godo.Droplet,
 	return r0, r1, r2
 }
 
+// CreateFirewall provides a mock function with given fields: _a0
+func (_m *Client) CreateFirewall(_a0 *godo.FirewallRequest) (*godo.Firewall, *godo.Response, error) {
+	ret := _m.Called(_a0)
+
+	var r0 *godo.Firewall
+	if rf, ok := ret.Get(0).(func(*godo.FirewallRequest) *godo.Firewall); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*godo.Firewall)
+		}
+	}
+
+	var r1 *godo.Response
+	if rf, ok := ret.Get(1).(func(*godo.FirewallRequest) *godo.Response); ok {
+		r1 = rf(_a0)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*godo.Response)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(*godo.FirewallRequest) error); ok {
+		r2 = rf(_a0)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// CreateTag provides a mock function with given fields: _a0
+func (_m *Client) CreateTag(_a0 *godo.TagCreateRequest) (*godo.Tag, *godo.Response, error) {
+	ret := _m.Called(_a0)
+
+	var r0 *godo.Tag
+	if rf, ok := ret.Get(0).(func(*godo.TagCreateRequest) *godo.Tag); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*godo.Tag)
+		}
+	}
+
+	var r1 *godo.Response
+	if rf, ok := ret.Get(1).(func(*godo.TagCreateRequest) *godo.Response); ok {
+		r1 = rf(_a0)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*godo.Response)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(*godo.TagCreateRequest) error); ok {
+		r2 = rf(_a0)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // DeleteDroplet provides a mock function with given fields: _a0
 func (_m *Client) DeleteDroplet(_a0 int) (*godo.Response, error) {
 	ret := _m.Called(_a0)
@@ -96,6 +183,29 @@ func (_m *Client) DeleteDroplet(_a0 int) (*godo.Response, error) {
 	return r0, r1
 }
 
+// DeleteFirewall provides a mock function with given fields: _a0
+func (_m *Client) DeleteFirewall(_a0 string) (*godo.Response, error) {
+	ret := _m.Called(_a0)
+
+	var r0 *godo.Response
+	if rf, ok := ret.Get(0).(func(string) *godo.Response); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*godo.Response)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetDroplet provides a mock function with given fields: _a0
 func (_m *Client) GetDroplet(_a0 int) (*godo.Droplet, *godo.Response, error) {
 	ret := _m.Called(_a0)
@@ -160,6 +270,38 @@ func (_m *Client) ListDroplets(_a0 *godo.ListOptions) ([]godo.Droplet, *godo.Res
 	return r0, r1, r2
 }
 
+// ListFirewalls provides a mock function with given fields: _a0
+func (_m *Client) ListFirewalls(_a0 *godo.ListOptions) ([]godo.Firewall, *godo.Response, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []godo.Firewall
+	if rf, ok := ret.Get(0).(func(*godo.ListOptions) []godo.Firewall); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]godo.Firewall)
+		}
+	}
+
+	var r1 *godo.Response
+	if rf, ok := ret.Get(1).(func(*godo.ListOptions) *godo.Response); ok {
+		r1 = rf(_a0)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*godo.Response)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(*godo.ListOptions) error); ok {
+		r2 = rf(_a0)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // ListFloatingIPs provides a mock function with given fields: _a0
 func (_m *Client) ListFloatingIPs(_a0 *godo.ListOptions) ([]godo.FloatingIP, *godo.Response, error) {
 	ret := _m.Called(_a0)
@@ -192,6 +334,52 @@ func (_m *Client) ListFloatingIPs(_a0 *godo.ListOptions) ([]godo.FloatingIP, *go
 	return r0, r1, r2
 }
 
+// RemoveFirewallRules provides a mock function with given fields: _a0, _a1
+func (_m *Client) RemoveFirewallRules(_a0 string, _a1 *godo.FirewallRulesRequest) (*godo.Response, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *godo.Response
+	if rf, ok := ret.Get(0).(func(string, *godo.FirewallRulesRequest) *godo.Response); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*godo.Response)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, *godo.FirewallRulesRequest) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// TagResources provides a mock function with given fields: _a0, _a1
+func (_m *Client) TagResources(_a0 string, _a1 *godo.TagResourcesRequest) (*godo.Response, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *godo.Response
+	if rf, ok := ret.Get(0).(func(string, *godo.TagResourcesRequest) *godo.Response); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*godo.Response)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, *godo.TagResourcesRequest) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UnassignFloatingIP provides a mock function with given fields: _a0
 func (_m *Client) UnassignFloatingIP(_a0 string) (*godo.Action, *godo.Response, error) {
 	ret := _m.Called(_a0)
@@ -37,6 +37,10 @@ var apiKeyPath = ".digitalocean/key"
 // 16.04.1 x64 created at 2017-02-03.
 var imageID = 22601368
 
+// Droplets are tagged with the hash of the image and boot script they were booted
+// with.  See cfg.BootHash.
+const bootHashTagPrefix = "quilt-boot-hash:"
+
 // The Provider object represents a connection to DigitalOcean.
 type Provider struct {
 	client.Client
@@ -100,38 +104,55 @@ func (prvdr Provider) List() (machines []db.Machine, err error) {
 		floatingIPListOpt.Page++
 	}
 
-	dropletListOpt := &godo.ListOptions{} // Keep track of the page we're on.
-	// DigitalOcean's API has a paginated list of droplets.
-	for {
-		droplets, resp, err := prvdr.ListDroplets(dropletListOpt)
+	droplets, err := prvdr.listDroplets()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range droplets {
+		pubIP, err := d.PublicIPv4()
 		if err != nil {
-			return nil, fmt.Errorf("list droplets: %s", err)
+			return nil, fmt.Errorf("get public IP: %s", err)
 		}
 
-		for _, d := range droplets {
-			if d.Name != prvdr.namespace || d.Region.Slug != prvdr.region {
-				continue
-			}
+		privIP, err := d.PrivateIPv4()
+		if err != nil {
+			return nil, fmt.Errorf("get private IP: %s", err)
+		}
 
-			pubIP, err := d.PublicIPv4()
-			if err != nil {
-				return nil, fmt.Errorf("get public IP: %s", err)
+		machine := db.Machine{
+			CloudID:     strconv.Itoa(d.ID),
+			PublicIP:    pubIP,
+			PrivateIP:   privIP,
+			FloatingIP:  floatingIPs[d.ID],
+			Size:        d.SizeSlug,
+			Preemptible: false,
+		}
+		for _, tag := range d.Tags {
+			if strings.HasPrefix(tag, bootHashTagPrefix) {
+				machine.BootHash = strings.TrimPrefix(tag, bootHashTagPrefix)
 			}
+		}
+		machines = append(machines, machine)
+	}
+	return machines, nil
+}
 
-			privIP, err := d.PrivateIPv4()
-			if err != nil {
-				return nil, fmt.Errorf("get private IP: %s", err)
-			}
+// listDroplets returns the droplets in `prvdr`'s namespace and region.
+func (prvdr Provider) listDroplets() ([]godo.Droplet, error) {
+	var droplets []godo.Droplet
+	dropletListOpt := &godo.ListOptions{} // Keep track of the page we're on.
+	// DigitalOcean's API has a paginated list of droplets.
+	for {
+		page, resp, err := prvdr.ListDroplets(dropletListOpt)
+		if err != nil {
+			return nil, fmt.Errorf("list droplets: %s", err)
+		}
 
-			machine := db.Machine{
-				CloudID:     strconv.Itoa(d.ID),
-				PublicIP:    pubIP,
-				PrivateIP:   privIP,
-				FloatingIP:  floatingIPs[d.ID],
-				Size:        d.SizeSlug,
-				Preemptible: false,
+		for _, d := range page {
+			if d.Name == prvdr.namespace && d.Region.Slug == prvdr.region {
+				droplets = append(droplets, d)
 			}
-			machines = append(machines, machine)
 		}
 
 		if resp.Links == nil || resp.Links.IsLastPage() {
@@ -140,7 +161,56 @@ func (prvdr Provider) List() (machines []db.Machine, err error) {
 
 		dropletListOpt.Page++
 	}
-	return machines, nil
+	return droplets, nil
+}
+
+// syncTags tags the droplets that were booted before the namespace's firewall
+// existed, so that the firewall applies to them too.  Droplets booted since are
+// tagged by Boot.
+func (prvdr Provider) syncTags() error {
+	droplets, err := prvdr.listDroplets()
+	if err != nil {
+		return err
+	}
+
+	var untagged []godo.Resource
+	for _, d := range droplets {
+		if !hasTag(d.Tags, prvdr.namespace) {
+			untagged = append(untagged, godo.Resource{
+				ID:   strconv.Itoa(d.ID),
+				Type: godo.DropletResourceType,
+			})
+		}
+	}
+
+	if len(untagged) == 0 {
+		return nil
+	}
+
+	log.WithField("droplets", untagged).Debug("DigitalOcean: Tag droplets")
+
+	// Tagging fails if the tag doesn't exist, which is the case if every droplet
+	// in the namespace predates the firewall.
+	_, _, err = prvdr.CreateTag(&godo.TagCreateRequest{Name: prvdr.namespace})
+	if err != nil {
+		return fmt.Errorf("create tag: %s", err)
+	}
+
+	_, err = prvdr.TagResources(prvdr.namespace,
+		&godo.TagResourcesRequest{Resources: untagged})
+	if err != nil {
+		return fmt.Errorf("tag droplets: %s", err)
+	}
+	return nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
 }
 
 // Boot will boot every machine in a goroutine, and wait for the machines to come up.
@@ -172,9 +242,15 @@ func (prvdr Provider) createAndAttach(m db.Machine) error {
 		Name:              prvdr.namespace,
 		Region:            prvdr.region,
 		Size:              m.Size,
-		Image:             godo.DropletCreateImage{ID: imageID},
+		Image:             dropletImage(m.Image),
 		PrivateNetworking: true,
 		UserData:          cloudConfig,
+
+		// The namespace's firewalls apply to all droplets with this tag.
+		Tags: []string{prvdr.namespace},
+	}
+	if hash := cfg.BootHash(m); hash != "" {
+		createReq.Tags = append(createReq.Tags, bootHashTagPrefix+hash)
 	}
 
 	d, _, err := prvdr.CreateDroplet(createReq)
@@ -189,6 +265,19 @@ func (prvdr Provider) createAndAttach(m db.Machine) error {
 	return wait.Wait(pred)
 }
 
+// dropletImage parses a user specified image, which may either be an image ID or a
+// slug.
+func dropletImage(image string) godo.DropletCreateImage {
+	if image == "" {
+		return godo.DropletCreateImage{ID: imageID}
+	}
+
+	if id, err := strconv.Atoi(image); err == nil {
+		return godo.DropletCreateImage{ID: id}
+	}
+	return godo.DropletCreateImage{Slug: image}
+}
+
 // UpdateFloatingIPs updates Droplet to Floating IP associations.
 func (prvdr Provider) UpdateFloatingIPs(desired []db.Machine) error {
 	curr, err := prvdr.List()
@@ -284,8 +373,262 @@ func (prvdr Provider) deleteAndWait(ids string) error {
 	return wait.Wait(pred)
 }
 
-// SetACLs is not supported in DigitalOcean.
+// SetACLs adds and removes the inbound rules of `prvdr`'s firewall so that it
+// conforms to `acls`.  The firewall applies to all droplets tagged with the
+// namespace, and always allows traffic between them, so untagged droplets are
+// tagged first.  Regions without machines are passed no ACLs, in which case
+// their firewall is deleted.
 func (prvdr Provider) SetACLs(acls []acl.ACL) error {
-	log.Debug("DigitalOcean does not support ACLs")
+	fw, err := prvdr.getFirewall()
+	if err != nil {
+		return err
+	}
+
+	if len(acls) == 0 {
+		if fw == nil {
+			return nil
+		}
+
+		log.WithField("name", fw.Name).Debug("DigitalOcean: Delete firewall")
+		if _, err := prvdr.DeleteFirewall(fw.ID); err != nil {
+			return fmt.Errorf("delete firewall: %s", err)
+		}
+		return nil
+	}
+
+	if err := prvdr.syncTags(); err != nil {
+		return err
+	}
+
+	target := inboundRules(prvdr.namespace, acls)
+	if fw == nil {
+		return prvdr.createFirewall(target)
+	}
+
+	curr := parseInboundRules(fw.InboundRules)
+
+	// Walk the rules in their original order, rather than a join's random
+	// order, so that the rules are changed in a consistent order.
+	var addRules, removeRules []godo.InboundRule
+	currSet := ruleSet(curr)
+	for _, rule := range target {
+		if !currSet[rule] {
+			log.WithField("rule", rule).Debug("DigitalOcean: Add rule")
+			addRules = append(addRules, rule.toGodo())
+		}
+	}
+
+	targetSet := ruleSet(target)
+	for _, rule := range curr {
+		if !targetSet[rule] {
+			log.WithField("rule", rule).Debug("DigitalOcean: Remove rule")
+			removeRules = append(removeRules, rule.toGodo())
+		}
+	}
+
+	if len(addRules) != 0 {
+		_, err := prvdr.AddFirewallRules(fw.ID,
+			&godo.FirewallRulesRequest{InboundRules: addRules})
+		if err != nil {
+			return fmt.Errorf("add firewall rules: %s", err)
+		}
+	}
+
+	if len(removeRules) != 0 {
+		_, err := prvdr.RemoveFirewallRules(fw.ID,
+			&godo.FirewallRulesRequest{InboundRules: removeRules})
+		if err != nil {
+			return fmt.Errorf("remove firewall rules: %s", err)
+		}
+	}
+
 	return nil
 }
+
+// getFirewall returns the firewall for `prvdr`'s region, or nil if it doesn't
+// exist.
+func (prvdr Provider) getFirewall() (*godo.Firewall, error) {
+	name := prvdr.firewallName()
+
+	listOpt := &godo.ListOptions{}
+	for {
+		fws, resp, err := prvdr.ListFirewalls(listOpt)
+		if err != nil {
+			return nil, fmt.Errorf("list firewalls: %s", err)
+		}
+
+		for _, fw := range fws {
+			if fw.Name == name {
+				return &fw, nil
+			}
+		}
+
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		listOpt.Page++
+	}
+	return nil, nil
+}
+
+// createFirewall creates the firewall for `prvdr`'s region with the inbound
+// `rules`.  It allows all outbound traffic.
+func (prvdr Provider) createFirewall(rules []firewallRule) error {
+	var inbound []godo.InboundRule
+	for _, rule := range rules {
+		inbound = append(inbound, rule.toGodo())
+	}
+
+	name := prvdr.firewallName()
+	log.WithField("name", name).Debug("DigitalOcean: Create firewall")
+	_, _, err := prvdr.CreateFirewall(&godo.FirewallRequest{
+		Name:         name,
+		Tags:         []string{prvdr.namespace},
+		InboundRules: inbound,
+		OutboundRules: []godo.OutboundRule{
+			{Protocol: "tcp", PortRange: "all", Destinations: allAddresses},
+			{Protocol: "udp", PortRange: "all", Destinations: allAddresses},
+			{Protocol: "icmp", Destinations: allAddresses},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("create firewall: %s", err)
+	}
+	return nil
+}
+
+// Each region has its own firewall, even though they all apply to every droplet
+// in the namespace, so that regions don't race to create a shared firewall.  All
+// regions install the same ACLs, so the firewalls don't conflict.
+func (prvdr Provider) firewallName() string {
+	return fmt.Sprintf("%s-%s", prvdr.namespace, prvdr.region)
+}
+
+var allAddresses = &godo.Destinations{Addresses: []string{"0.0.0.0/0", "::/0"}}
+
+// A firewallRule is a single inbound rule, allowing one protocol from one source.
+// DigitalOcean allows rules with several sources, so rules are split by source
+// when parsed in order to compare them.  ICMP rules have no ports.
+type firewallRule struct {
+	protocol         string
+	minPort, maxPort int
+	address, tag     string
+}
+
+func (rule firewallRule) toGodo() godo.InboundRule {
+	var ports string
+	switch {
+	case rule.protocol == "icmp":
+	case rule.minPort == 1 && rule.maxPort == 65535:
+		ports = "all"
+	case rule.minPort == rule.maxPort:
+		ports = strconv.Itoa(rule.minPort)
+	default:
+		ports = fmt.Sprintf("%d-%d", rule.minPort, rule.maxPort)
+	}
+
+	sources := &godo.Sources{}
+	if rule.tag != "" {
+		sources.Tags = []string{rule.tag}
+	} else {
+		sources.Addresses = []string{rule.address}
+	}
+	return godo.InboundRule{
+		Protocol:  rule.protocol,
+		PortRange: ports,
+		Sources:   sources,
+	}
+}
+
+// inboundRules returns the firewall rules that implement `acls`, and allow all
+// traffic from droplets tagged with `tag`.  Like the other providers, ACLs allow
+// both TCP and UDP on their ports, and ICMP.  The ICMP rules are shared by all
+// ACLs with the same source.
+func inboundRules(tag string, acls []acl.ACL) (rules []firewallRule) {
+	rules = []firewallRule{
+		{protocol: "tcp", minPort: 1, maxPort: 65535, tag: tag},
+		{protocol: "udp", minPort: 1, maxPort: 65535, tag: tag},
+		{protocol: "icmp", tag: tag},
+	}
+
+	icmp := map[string]bool{}
+	for _, a := range acls {
+		for _, protocol := range []string{"tcp", "udp"} {
+			rules = append(rules, firewallRule{
+				protocol: protocol,
+				minPort:  a.MinPort,
+				maxPort:  a.MaxPort,
+				address:  a.CidrIP,
+			})
+		}
+
+		if !icmp[a.CidrIP] {
+			icmp[a.CidrIP] = true
+			rules = append(rules, firewallRule{
+				protocol: "icmp",
+				address:  a.CidrIP,
+			})
+		}
+	}
+	return rules
+}
+
+// parseInboundRules splits `rules` into firewallRules with a single source each.
+func parseInboundRules(rules []godo.InboundRule) (parsed []firewallRule) {
+	for _, rule := range rules {
+		if rule.Sources == nil {
+			continue
+		}
+
+		var minPort, maxPort int
+		if rule.Protocol != "icmp" {
+			var err error
+			minPort, maxPort, err = parsePorts(rule.PortRange)
+			if err != nil {
+				log.WithError(err).WithField("rule", rule).Warn(
+					"DigitalOcean: Failed to parse firewall rule")
+				continue
+			}
+		}
+
+		base := firewallRule{
+			protocol: rule.Protocol,
+			minPort:  minPort,
+			maxPort:  maxPort,
+		}
+		for _, addr := range rule.Sources.Addresses {
+			r := base
+			r.address = addr
+			parsed = append(parsed, r)
+		}
+		for _, tag := range rule.Sources.Tags {
+			r := base
+			r.tag = tag
+			parsed = append(parsed, r)
+		}
+	}
+	return parsed
+}
+
+func ruleSet(rules []firewallRule) map[firewallRule]bool {
+	set := map[firewallRule]bool{}
+	for _, rule := range rules {
+		set[rule] = true
+	}
+	return set
+}
+
+func parsePorts(ports string) (int, int, error) {
+	if ports == "all" || ports == "0" || ports == "" {
+		return 1, 65535, nil
+	}
+
+	rangeEnds := strings.Split(ports, "-")
+	minPort, err := strconv.Atoi(rangeEnds[0])
+	if err != nil || len(rangeEnds) == 1 {
+		return minPort, minPort, err
+	}
+
+	maxPort, err := strconv.Atoi(rangeEnds[1])
+	return minPort, maxPort, err
+}
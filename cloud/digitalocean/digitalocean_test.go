@@ -86,6 +86,7 @@ func TestList(t *testing.T) {
 			SizeSlug:  "size",
 			VolumeIDs: []string{"foo"},
 			Region:    sfo,
+			Tags:      []string{"foo", bootHashTagPrefix + "hash"},
 		},
 	}
 
@@ -147,6 +148,7 @@ func TestList(t *testing.T) {
 			FloatingIP:  "floatingIP",
 			Size:        "size",
 			Preemptible: false,
+			BootHash:    "hash",
 		},
 	})
 
@@ -179,6 +181,40 @@ func TestList(t *testing.T) {
 	machines, err = doPrvdr.List()
 	assert.Nil(t, machines)
 	assert.EqualError(t, err, "get public IP: no networks have been defined")
+
+	// Listing is read-only, even if droplets aren't tagged with the namespace.
+	mc.AssertNotCalled(t, "CreateTag", mock.Anything)
+	mc.AssertNotCalled(t, "TagResources", mock.Anything, mock.Anything)
+}
+
+func TestSyncTags(t *testing.T) {
+	mc := new(mocks.Client)
+	doPrvdr, err := newDigitalOcean(testNamespace, DefaultRegion)
+	assert.Nil(t, err)
+	doPrvdr.Client = mc
+
+	droplets := []godo.Droplet{
+		{ID: 1, Name: testNamespace, Region: sfo},
+		{ID: 2, Name: testNamespace, Region: sfo, Tags: []string{testNamespace}},
+		{ID: 3, Name: "other", Region: sfo},
+	}
+	mc.On("ListDroplets", mock.Anything).Return(droplets, &godo.Response{}, nil)
+
+	// Only the droplet in the namespace that's missing the tag is tagged.
+	mc.On("CreateTag", &godo.TagCreateRequest{Name: testNamespace}).Return(
+		nil, nil, nil).Once()
+	mc.On("TagResources", testNamespace, &godo.TagResourcesRequest{
+		Resources: []godo.Resource{{ID: "1", Type: godo.DropletResourceType}},
+	}).Return(nil, nil).Once()
+	assert.NoError(t, doPrvdr.syncTags())
+	mc.AssertExpectations(t)
+
+	mc.On("CreateTag", mock.Anything).Return(nil, nil, nil).Once()
+	mc.On("TagResources", mock.Anything, mock.Anything).Return(nil, errMock).Once()
+	assert.EqualError(t, doPrvdr.syncTags(), "tag droplets: error")
+
+	mc.On("CreateTag", mock.Anything).Return(nil, nil, errMock).Once()
+	assert.EqualError(t, doPrvdr.syncTags(), "create tag: error")
 }
 
 func TestBoot(t *testing.T) {
@@ -237,6 +273,13 @@ func TestBoot(t *testing.T) {
 	assert.EqualError(t, err, errMsg)
 }
 
+func TestDropletImage(t *testing.T) {
+	assert.Equal(t, godo.DropletCreateImage{ID: imageID}, dropletImage(""))
+	assert.Equal(t, godo.DropletCreateImage{ID: 123}, dropletImage("123"))
+	assert.Equal(t, godo.DropletCreateImage{Slug: "ubuntu-16-04-x64"},
+		dropletImage("ubuntu-16-04-x64"))
+}
+
 func TestBootPreemptible(t *testing.T) {
 	t.Parallel()
 
@@ -317,21 +360,176 @@ func TestStop(t *testing.T) {
 }
 
 func TestSetACLs(t *testing.T) {
+	mc := new(mocks.Client)
 	doPrvdr, err := newDigitalOcean(testNamespace, DefaultRegion)
 	assert.Nil(t, err)
-	err = doPrvdr.SetACLs([]acl.ACL{
-		{
-			CidrIP:  "digital",
-			MinPort: 1,
-			MaxPort: 65535,
+	doPrvdr.Client = mc
+
+	digital := acl.ACL{CidrIP: "digital", MinPort: 1, MaxPort: 65535}
+	ssh := acl.ACL{CidrIP: "ocean", MinPort: 22, MaxPort: 22}
+	web := acl.ACL{CidrIP: "ocean", MinPort: 80, MaxPort: 81}
+	fwName := testNamespace + "-" + DefaultRegion
+
+	rule := func(protocol, ports, source string) godo.InboundRule {
+		sources := &godo.Sources{Addresses: []string{source}}
+		if source == testNamespace {
+			sources = &godo.Sources{Tags: []string{source}}
+		}
+		return godo.InboundRule{
+			Protocol:  protocol,
+			PortRange: ports,
+			Sources:   sources,
+		}
+	}
+	tagRules := []godo.InboundRule{
+		rule("tcp", "all", testNamespace),
+		rule("udp", "all", testNamespace),
+		rule("icmp", "", testNamespace),
+	}
+
+	// Every droplet is already tagged, so the tags don't need syncing.
+	mc.On("ListDroplets", mock.Anything).Return([]godo.Droplet{{
+		ID:     1,
+		Name:   testNamespace,
+		Region: sfo,
+		Tags:   []string{testNamespace},
+	}}, &godo.Response{}, nil)
+
+	// The firewall doesn't exist yet, so it's created with all of the rules.
+	// ACLs from the same address share an ICMP rule.
+	mc.On("ListFirewalls", mock.Anything).Return([]godo.Firewall{
+		{ID: "other", Name: "other"},
+	}, &godo.Response{}, nil).Once()
+	mc.On("CreateFirewall", mock.Anything).Return(nil, nil, nil).Once()
+
+	err = doPrvdr.SetACLs([]acl.ACL{digital, ssh, web})
+	assert.NoError(t, err)
+
+	createReq := mc.Calls[2].Arguments.Get(0).(*godo.FirewallRequest)
+	assert.Equal(t, fwName, createReq.Name)
+	assert.Equal(t, []string{testNamespace}, createReq.Tags)
+	assert.Equal(t, append(tagRules,
+		rule("tcp", "all", "digital"),
+		rule("udp", "all", "digital"),
+		rule("icmp", "", "digital"),
+		rule("tcp", "22", "ocean"),
+		rule("udp", "22", "ocean"),
+		rule("icmp", "", "ocean"),
+		rule("tcp", "80-81", "ocean"),
+		rule("udp", "80-81", "ocean"),
+	), createReq.InboundRules)
+	assert.Len(t, createReq.OutboundRules, 3)
+	mc.AssertNotCalled(t, "AddFirewallRules", mock.Anything, mock.Anything)
+
+	// The firewall exists, but is missing some rules and has a stale ACL.  Rules
+	// with several sources are compared source by source.
+	multiSource := rule("tcp", "1-65535", "digital")
+	multiSource.Sources.Tags = []string{testNamespace}
+	mc.On("ListFirewalls", mock.Anything).Return([]godo.Firewall{{
+		ID:   "fw",
+		Name: fwName,
+		InboundRules: []godo.InboundRule{
+			multiSource,
+			rule("icmp", "", "digital"),
+			rule("udp", "0", "digital"),
+			rule("tcp", "22", "ocean"),
+			rule("udp", "22", "ocean"),
+			rule("icmp", "", "ocean"),
+			rule("tcp", "443", "stale"),
+			rule("udp", "443", "stale"),
+			rule("icmp", "", "stale"),
 		},
-		{
-			CidrIP:  "ocean",
-			MinPort: 22,
-			MaxPort: 22,
+	}}, &godo.Response{}, nil).Once()
+	mc.On("AddFirewallRules", "fw", mock.Anything).Return(nil, nil).Once()
+	mc.On("RemoveFirewallRules", "fw", mock.Anything).Return(nil, nil).Once()
+
+	err = doPrvdr.SetACLs([]acl.ACL{digital, ssh, web})
+	assert.NoError(t, err)
+	mc.AssertCalled(t, "AddFirewallRules", "fw", &godo.FirewallRulesRequest{
+		InboundRules: []godo.InboundRule{
+			rule("udp", "all", testNamespace),
+			rule("icmp", "", testNamespace),
+			rule("tcp", "80-81", "ocean"),
+			rule("udp", "80-81", "ocean"),
+		},
+	})
+	mc.AssertCalled(t, "RemoveFirewallRules", "fw", &godo.FirewallRulesRequest{
+		InboundRules: []godo.InboundRule{
+			rule("tcp", "443", "stale"),
+			rule("udp", "443", "stale"),
+			rule("icmp", "", "stale"),
+		},
+	})
+
+	// Removing an ACL doesn't remove the ICMP rule that other ACLs from the same
+	// address rely on.
+	mc.On("ListFirewalls", mock.Anything).Return([]godo.Firewall{{
+		ID:   "fw",
+		Name: fwName,
+		InboundRules: append(tagRules,
+			rule("tcp", "22", "ocean"),
+			rule("udp", "22", "ocean"),
+			rule("icmp", "", "ocean"),
+			rule("tcp", "80-81", "ocean"),
+			rule("udp", "80-81", "ocean"),
+		),
+	}}, &godo.Response{}, nil).Once()
+	mc.On("RemoveFirewallRules", "fw", mock.Anything).Return(nil, nil).Once()
+
+	err = doPrvdr.SetACLs([]acl.ACL{ssh})
+	assert.NoError(t, err)
+	mc.AssertCalled(t, "RemoveFirewallRules", "fw", &godo.FirewallRulesRequest{
+		InboundRules: []godo.InboundRule{
+			rule("tcp", "80-81", "ocean"),
+			rule("udp", "80-81", "ocean"),
 		},
 	})
+	mc.AssertNumberOfCalls(t, "AddFirewallRules", 1)
+
+	// The region has no machines, so its firewall is deleted.
+	mc.On("ListFirewalls", mock.Anything).Return([]godo.Firewall{{
+		ID:   "fw",
+		Name: fwName,
+	}}, &godo.Response{}, nil).Once()
+	mc.On("DeleteFirewall", "fw").Return(nil, nil).Once()
+	err = doPrvdr.SetACLs(nil)
 	assert.NoError(t, err)
+	mc.AssertCalled(t, "DeleteFirewall", "fw")
+
+	// There's no firewall to delete.
+	mc.On("ListFirewalls", mock.Anything).Return(nil, &godo.Response{}, nil).Once()
+	err = doPrvdr.SetACLs(nil)
+	assert.NoError(t, err)
+	mc.AssertNumberOfCalls(t, "DeleteFirewall", 1)
+	mc.AssertNumberOfCalls(t, "CreateFirewall", 1)
+
+	// Errors are passed up.
+	mc.On("ListFirewalls", mock.Anything).Return([]godo.Firewall{{
+		ID:   "fw",
+		Name: fwName,
+	}}, &godo.Response{}, nil).Once()
+	mc.On("DeleteFirewall", "fw").Return(nil, errMock).Once()
+	err = doPrvdr.SetACLs(nil)
+	assert.EqualError(t, err, "delete firewall: error")
+
+	mc.On("ListFirewalls", mock.Anything).Return(nil, nil, errMock).Once()
+	err = doPrvdr.SetACLs(nil)
+	assert.EqualError(t, err, "list firewalls: error")
+}
+
+func TestParsePorts(t *testing.T) {
+	check := func(ports string, expMin, expMax int) {
+		min, max, err := parsePorts(ports)
+		assert.NoError(t, err)
+		assert.Equal(t, expMin, min)
+		assert.Equal(t, expMax, max)
+	}
+	check("80", 80, 80)
+	check("1-65535", 1, 65535)
+	check("all", 1, 65535)
+
+	_, _, err := parsePorts("foo")
+	assert.Error(t, err)
 }
 
 func TestUpdateFloatingIPs(t *testing.T) {
@@ -342,7 +540,6 @@ func TestUpdateFloatingIPs(t *testing.T) {
 	err := client.UpdateFloatingIPs(nil)
 	assert.EqualError(t, err,
 		fmt.Sprintf("list machines: list floating IPs: %s", errMsg))
-	mc.AssertExpectations(t)
 
 	// Test assigning a floating IP.
 	mc.On("AssignFloatingIP", "ip", 1).Return(nil, nil, nil).Once()
@@ -356,7 +553,6 @@ func TestUpdateFloatingIPs(t *testing.T) {
 		},
 	)
 	assert.NoError(t, err)
-	mc.AssertExpectations(t)
 
 	// Test error when assigning a floating IP.
 	mc.On("AssignFloatingIP", "ip", 1).Return(nil, nil, errMock).Once()
@@ -369,7 +565,6 @@ func TestUpdateFloatingIPs(t *testing.T) {
 		},
 	)
 	assert.EqualError(t, err, fmt.Sprintf("assign IP (ip to 1): %s", errMsg))
-	mc.AssertExpectations(t)
 
 	// Test assigning one floating IP, and unassigning another.
 	mc.On("AssignFloatingIP", "ip", 1).Return(nil, nil, nil).Once()
@@ -385,7 +580,6 @@ func TestUpdateFloatingIPs(t *testing.T) {
 		},
 	)
 	assert.NoError(t, err)
-	mc.AssertExpectations(t)
 
 	// Test error when unassigning a floating IP.
 	mc.On("UnassignFloatingIP", "remove").Return(nil, nil, errMock).Once()
@@ -398,7 +592,6 @@ func TestUpdateFloatingIPs(t *testing.T) {
 		},
 	)
 	assert.EqualError(t, err, fmt.Sprintf("unassign IP (remove): %s", errMsg))
-	mc.AssertExpectations(t)
 
 	// Test changing a floating IP, which requires removing the old one, and
 	// assigning the new.
@@ -413,7 +606,6 @@ func TestUpdateFloatingIPs(t *testing.T) {
 		},
 	)
 	assert.NoError(t, err)
-	mc.AssertExpectations(t)
 
 	// Test machines that need no changes.
 	err = client.syncFloatingIPs(
@@ -425,7 +617,6 @@ func TestUpdateFloatingIPs(t *testing.T) {
 		},
 	)
 	assert.NoError(t, err)
-	mc.AssertExpectations(t)
 
 	err = client.syncFloatingIPs(
 		[]db.Machine{},
@@ -1,7 +1,10 @@
 package foreman
 
 import (
+	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -9,9 +12,11 @@ import (
 
 	"golang.org/x/net/context"
 
+	"github.com/quilt/quilt/blueprint"
 	"github.com/quilt/quilt/connection"
 	"github.com/quilt/quilt/counter"
 	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/health"
 	"github.com/quilt/quilt/minion/pb"
 
 	log "github.com/Sirupsen/logrus"
@@ -54,6 +59,8 @@ type minion struct {
 
 var c = counter.New("Foreman")
 
+var foremanHealth = health.New("foreman", 5*time.Minute)
+
 // Init the first time the foreman operates on a new namespace.  It queries the currently
 // running VMs for their previously assigned roles, and writes them to the database.
 func Init(conn db.Conn) {
@@ -79,7 +86,13 @@ func Init(conn db.Conn) {
 func RunOnce(conn db.Conn) {
 	c.Inc("Run")
 
+	// Declared before the `blueprint` string shadows the package.
+	var network blueprint.NetworkConfig
 	var blueprint string
+	var systemImages map[string]string
+	var disableCadvisor bool
+	var dnsUpstream []string
+	var dnsDomain string
 	var machines []db.Machine
 	conn.Txn(db.BlueprintTable,
 		db.MachineTable).Run(func(view db.Database) error {
@@ -90,6 +103,15 @@ func RunOnce(conn db.Conn) {
 
 		bp, _ := view.GetBlueprint()
 		blueprint = bp.Blueprint.String()
+		disableCadvisor = bp.Blueprint.DisableCadvisor
+		if len(bp.Blueprint.SystemImages) != 0 {
+			systemImages = bp.Blueprint.SystemImages
+		}
+		dnsDomain = bp.Blueprint.Domain()
+		network = bp.Blueprint.ContainerNetwork()
+		if len(bp.Blueprint.DNSUpstream) != 0 {
+			dnsUpstream = bp.Blueprint.DNSUpstream
+		}
 
 		return nil
 	})
@@ -111,15 +133,28 @@ func RunOnce(conn db.Conn) {
 		}
 
 		newConfig := pb.MinionConfig{
-			FloatingIP:     m.machine.FloatingIP,
-			PrivateIP:      m.machine.PrivateIP,
-			Blueprint:      blueprint,
-			Provider:       string(m.machine.Provider),
-			Size:           m.machine.Size,
-			Region:         m.machine.Region,
-			EtcdMembers:    etcdIPs,
-			AuthorizedKeys: m.machine.SSHKeys,
-			DiskSize:		int32(m.machine.DiskSize),
+			FloatingIP:      m.machine.FloatingIP,
+			PrivateIP:       m.machine.PrivateIP,
+			Blueprint:       blueprint,
+			Provider:        string(m.machine.Provider),
+			Size:            m.machine.Size,
+			Region:          m.machine.Region,
+			EtcdMembers:     etcdIPs,
+			AuthorizedKeys:  m.machine.SSHKeys,
+			DiskSize:        int32(m.machine.DiskSize),
+			SystemImages:    systemImages,
+			DisableCadvisor: disableCadvisor,
+			DNSUpstream:     dnsUpstream,
+			DNSDomain:       dnsDomain,
+			ContainerSubnet: network.Subnet,
+			GatewayIP:       network.GatewayIP,
+			LoadBalancerIP:  network.LoadBalancerIP,
+			MTU:             int32(network.MTU),
+			Encapsulation:   network.Encapsulation,
+
+			// The host keys are reported by the minion, rather than
+			// configured, so copy them to avoid spurious updates.
+			HostKeys: m.config.HostKeys,
 		}
 
 		if reflect.DeepEqual(newConfig, m.config) {
@@ -131,6 +166,26 @@ func RunOnce(conn db.Conn) {
 			return
 		}
 	})
+
+	foremanHealth.Report(connectionErr(machines))
+}
+
+// connectionErr returns an error listing the machines whose minions the foreman
+// isn't connected to.
+func connectionErr(machines []db.Machine) error {
+	var disconnected []string
+	for _, m := range machines {
+		if !IsConnected(m.PublicIP) {
+			disconnected = append(disconnected, m.PublicIP)
+		}
+	}
+
+	if len(disconnected) == 0 {
+		return nil
+	}
+	sort.Strings(disconnected)
+	return fmt.Errorf("not connected to %d of %d minions: %s",
+		len(disconnected), len(machines), strings.Join(disconnected, ", "))
 }
 
 // GetMachineRole uses the minion map to find the associated minion with
@@ -142,6 +197,15 @@ func GetMachineRole(pubIP string) db.Role {
 	return db.None
 }
 
+// GetHostKeys returns the SSH host key fingerprints reported by the minion at
+// pubIP, according to the foreman's last update cycle.
+func GetHostKeys(pubIP string) []string {
+	if min, ok := minions[pubIP]; ok && min.connected {
+		return min.config.HostKeys
+	}
+	return nil
+}
+
 // IsConnected returns whether the foreman is connected to the minion at pubIP.
 func IsConnected(pubIP string) bool {
 	min, ok := minions[pubIP]
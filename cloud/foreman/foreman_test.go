@@ -6,6 +6,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/quilt/quilt/blueprint"
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/minion/pb"
 )
@@ -142,6 +143,50 @@ func TestBootEtcd(t *testing.T) {
 		clients.clients["w1-pub"].mc.EtcdMembers)
 }
 
+func TestNetworkConfig(t *testing.T) {
+	conn, clients := startTest(t, map[string]pb.MinionConfig_Role{
+		"w1-pub": pb.MinionConfig_WORKER,
+	})
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.InsertMachine()
+		m.Role = db.Worker
+		m.PublicIP = "w1-pub"
+		m.PrivateIP = "w1-priv"
+		m.CloudID = "ignored"
+		view.Commit(m)
+		return nil
+	})
+
+	// Minions are given the default network if the blueprint doesn't set one.
+	RunOnce(conn)
+	mc := clients.clients["w1-pub"].mc
+	assert.Equal(t, "10.0.0.0/8", mc.ContainerSubnet)
+	assert.Equal(t, "10.0.0.1", mc.GatewayIP)
+	assert.Equal(t, "10.0.0.2", mc.LoadBalancerIP)
+	assert.Equal(t, int32(1400), mc.MTU)
+	assert.Equal(t, "stt", mc.Encapsulation)
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		bp := view.InsertBlueprint()
+		bp.Blueprint.Network = &blueprint.NetworkConfig{
+			Subnet:        "172.30.0.0/16",
+			MTU:           8950,
+			Encapsulation: blueprint.VXLAN,
+		}
+		view.Commit(bp)
+		return nil
+	})
+
+	RunOnce(conn)
+	mc = clients.clients["w1-pub"].mc
+	assert.Equal(t, "172.30.0.0/16", mc.ContainerSubnet)
+	assert.Equal(t, "172.30.0.1", mc.GatewayIP)
+	assert.Equal(t, "172.30.0.2", mc.LoadBalancerIP)
+	assert.Equal(t, int32(8950), mc.MTU)
+	assert.Equal(t, "vxlan", mc.Encapsulation)
+}
+
 func TestBootEtcdRoleConflict(t *testing.T) {
 	conn, clients := startTest(t, map[string]pb.MinionConfig_Role{
 		"m1-pub": pb.MinionConfig_MASTER,
@@ -234,6 +279,20 @@ func TestIsConnected(t *testing.T) {
 	assert.True(t, IsConnected("host"))
 }
 
+func TestConnectionErr(t *testing.T) {
+	minions = map[string]*minion{
+		"1.1.1.1": {connected: true},
+		"2.2.2.2": {connected: false},
+	}
+
+	machines := []db.Machine{
+		{PublicIP: "3.3.3.3"}, {PublicIP: "2.2.2.2"}, {PublicIP: "1.1.1.1"},
+	}
+	assert.EqualError(t, connectionErr(machines),
+		"not connected to 2 of 3 minions: 2.2.2.2, 3.3.3.3")
+	assert.NoError(t, connectionErr(machines[2:]))
+}
+
 func startTest(t *testing.T, roles map[string]pb.MinionConfig_Role) (db.Conn, *clients) {
 	conn := db.New()
 	minions = map[string]*minion{}
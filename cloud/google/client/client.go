@@ -28,6 +28,7 @@ type Client interface {
 		accessConfig *compute.AccessConfig) (*compute.Operation, error)
 	DeleteAccessConfig(zone, instance, accessConfig,
 		networkInterface string) (*compute.Operation, error)
+	SetTags(zone, instance string, tags *compute.Tags) (*compute.Operation, error)
 	GetZoneOperation(zone, operation string) (*compute.Operation, error)
 	GetGlobalOperation(operation string) (*compute.Operation, error)
 	ListFirewalls() (*compute.FirewallList, error)
@@ -137,6 +138,12 @@ func (ci *client) DeleteAccessConfig(zone, instance, accessConfig,
 		accessConfig, networkInterface).Do()
 }
 
+func (ci *client) SetTags(zone, instance string, tags *compute.Tags) (
+	*compute.Operation, error) {
+	c.Inc("Set Tags")
+	return ci.gce.Instances.SetTags(ci.projID, zone, instance, tags).Do()
+}
+
 func (ci *client) GetZoneOperation(zone, operation string) (
 	*compute.Operation, error) {
 	c.Inc("Get Zone Op")
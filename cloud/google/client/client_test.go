@@ -47,6 +47,9 @@ func TestErrors(t *testing.T) {
 	assert.EqualError(t, err, "Post "+inst+
 		"/deleteAccessConfig?accessConfig=ac&alt=json&networkInterface=ni: test")
 
+	_, err = c.SetTags("z", "i", nil)
+	assert.EqualError(t, err, "Post "+inst+"/setTags?alt=json: test")
+
 	_, err = c.GetZoneOperation("z", "o")
 	assert.EqualError(t, err, "Get "+zone+"operations/o?alt=json: test")
 
@@ -330,3 +330,26 @@ func (_m *Client) PatchFirewall(name string, firewall *compute.Firewall) (*compu
 
 	return r0, r1
 }
+
+// SetTags provides a mock function with given fields: zone, instance, tags
+func (_m *Client) SetTags(zone string, instance string, tags *compute.Tags) (*compute.Operation, error) {
+	ret := _m.Called(zone, instance, tags)
+
+	var r0 *compute.Operation
+	if rf, ok := ret.Get(0).(func(string, string, *compute.Tags) *compute.Operation); ok {
+		r0 = rf(zone, instance, tags)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*compute.Operation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, *compute.Tags) error); ok {
+		r1 = rf(zone, instance, tags)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -35,6 +35,10 @@ const ephemeralIPName = "External NAT"
 // floatingIPName is a constant for what we label NATs with floating IPs in GCE.
 const floatingIPName = "Floating IP"
 
+// bootHashKey is the metadata key that records the hash of the image and boot
+// script instances were booted with.  See cfg.BootHash.
+const bootHashKey = "quilt-boot-hash"
+
 const computeBaseURL string = "https://www.googleapis.com/compute/v1/projects"
 const (
 	// These are the various types of Operations that the GCE API returns
@@ -129,12 +133,22 @@ func (prvdr *Provider) List() ([]db.Machine, error) {
 			floatingIP = accessConfig.NatIP
 		}
 
+		var bootHash string
+		if instance.Metadata != nil {
+			for _, item := range instance.Metadata.Items {
+				if item.Key == bootHashKey && item.Value != nil {
+					bootHash = *item.Value
+				}
+			}
+		}
+
 		machines = append(machines, db.Machine{
 			CloudID:    instance.Name,
 			PublicIP:   accessConfig.NatIP,
 			FloatingIP: floatingIP,
 			PrivateIP:  iface.NetworkIP,
 			Size:       mtype,
+			BootHash:   bootHash,
 		})
 	}
 	return machines, nil
@@ -150,7 +164,7 @@ func (prvdr *Provider) Boot(bootSet []db.Machine) error {
 		}
 
 		name := "quilt-" + uuid.NewV4().String()
-		_, err := prvdr.instanceNew(name, m.Size, cfg.Ubuntu(m, ""))
+		_, err := prvdr.instanceNew(name, m)
 		if err != nil {
 			log.WithFields(log.Fields{
 				"error": err,
@@ -239,20 +253,28 @@ func (prvdr *Provider) operationWait(ops []*compute.Operation, domain int) (err
 // Create new GCE instance.
 //
 // Does not check if the operation succeeds.
-func (prvdr *Provider) instanceNew(name string, size string,
-	cloudConfig string) (*compute.Operation, error) {
+func (prvdr *Provider) instanceNew(name string, m db.Machine) (
+	*compute.Operation, error) {
+
+	img := prvdr.imgURL
+	if m.Image != "" {
+		img = m.Image
+	}
+
+	cloudConfig := cfg.Ubuntu(m, "")
+	bootHash := cfg.BootHash(m)
 	instance := &compute.Instance{
 		Name:        name,
 		Description: prvdr.ns,
 		MachineType: fmt.Sprintf("zones/%s/machineTypes/%s",
 			prvdr.zone,
-			size),
+			m.Size),
 		Disks: []*compute.AttachedDisk{
 			{
 				Boot:       true,
 				AutoDelete: true,
 				InitializeParams: &compute.AttachedDiskInitializeParams{
-					SourceImage: prvdr.imgURL,
+					SourceImage: img,
 				},
 			},
 		},
@@ -273,6 +295,10 @@ func (prvdr *Provider) instanceNew(name string, size string,
 					Key:   "startup-script",
 					Value: &cloudConfig,
 				},
+				{
+					Key:   bootHashKey,
+					Value: &bootHash,
+				},
 			},
 		},
 		Tags: &compute.Tags{
@@ -369,8 +395,14 @@ func parseInts(intStrings []string) (parsed []int, err error) {
 	return parsed, nil
 }
 
-// SetACLs adds and removes acls in `prvdr` so that it conforms to `acls`.
+// SetACLs adds and removes acls in `prvdr` so that it conforms to `acls`.  The
+// firewalls apply to instances tagged with the zone, so untagged instances are
+// tagged first.
 func (prvdr *Provider) SetACLs(acls []acl.ACL) error {
+	if err := prvdr.syncTags(); err != nil {
+		return err
+	}
+
 	fws, err := prvdr.listFirewalls()
 	if err != nil {
 		return err
@@ -437,6 +469,51 @@ func (prvdr *Provider) SetACLs(acls []acl.ACL) error {
 	return nil
 }
 
+// syncTags tags the cluster's instances that are missing the zone tag, so that
+// the zone-scoped firewalls apply to them.  Instances are tagged when they boot,
+// so this only affects instances whose tags were changed by hand.
+func (prvdr *Provider) syncTags() error {
+	instances, err := prvdr.ListInstances(prvdr.zone,
+		fmt.Sprintf("description eq %s", prvdr.ns))
+	if err != nil {
+		return fmt.Errorf("list instances: %s", err)
+	}
+
+	var ops []*compute.Operation
+	for _, instance := range instances.Items {
+		tags := &compute.Tags{}
+		if instance.Tags != nil {
+			*tags = *instance.Tags
+		}
+
+		if hasTag(tags.Items, prvdr.zone) {
+			continue
+		}
+
+		log.WithField("instance", instance.Name).Debug("Google: Tag instance")
+		tags.Items = append(tags.Items, prvdr.zone)
+		op, err := prvdr.SetTags(prvdr.zone, instance.Name, tags)
+		if err != nil {
+			return fmt.Errorf("set tags of %s: %s", instance.Name, err)
+		}
+		ops = append(ops, op)
+	}
+
+	if len(ops) == 0 {
+		return nil
+	}
+	return prvdr.operationWait(ops, local)
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
 // UpdateFloatingIPs updates IPs of machines by recreating their network interfaces.
 func (prvdr *Provider) UpdateFloatingIPs(machines []db.Machine) error {
 	for _, m := range machines {
@@ -7,6 +7,7 @@ import (
 	"github.com/quilt/quilt/cloud/acl"
 	"github.com/quilt/quilt/cloud/google/client/mocks"
 	"github.com/quilt/quilt/db"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 
 	compute "google.golang.org/api/compute/v1"
@@ -29,6 +30,7 @@ func (s *GoogleTestSuite) SetupTest() {
 }
 
 func (s *GoogleTestSuite) TestList() {
+	bootHash := "hash"
 	s.gce.On("ListInstances", "zone-1",
 		"description eq namespace").Return(&compute.InstanceList{
 		Items: []*compute.Instance{
@@ -45,6 +47,12 @@ func (s *GoogleTestSuite) TestList() {
 						NetworkIP: "y.y.y.y",
 					},
 				},
+				Metadata: &compute.Metadata{
+					Items: []*compute.MetadataItems{{
+						Key:   bootHashKey,
+						Value: &bootHash,
+					}},
+				},
 			},
 		},
 	}, nil)
@@ -57,6 +65,7 @@ func (s *GoogleTestSuite) TestList() {
 		PublicIP:  "x.x.x.x",
 		PrivateIP: "y.y.y.y",
 		Size:      "type-1",
+		BootHash:  "hash",
 	})
 }
 
@@ -168,6 +177,51 @@ func (s *GoogleTestSuite) TestParseACLs() {
 	s.EqualError(err, "parse ports of firewall: unrecognized port format: 1-80-81")
 }
 
+func (s *GoogleTestSuite) TestSyncTags() {
+	s.gce.On("ListInstances", "zone-1",
+		"description eq namespace").Return(&compute.InstanceList{
+		Items: []*compute.Instance{
+			{
+				Name: "tagged",
+				Tags: &compute.Tags{Items: []string{"zone-1"}},
+			},
+			{
+				Name: "untagged",
+				Tags: &compute.Tags{
+					Items:       []string{"other"},
+					Fingerprint: "fingerprint",
+				},
+			},
+			{Name: "noTags"},
+		},
+	}, nil).Once()
+
+	op := &compute.Operation{Name: "op", Zone: "zone-1"}
+	s.gce.On("SetTags", "zone-1", "untagged", &compute.Tags{
+		Items:       []string{"other", "zone-1"},
+		Fingerprint: "fingerprint",
+	}).Return(op, nil).Once()
+	s.gce.On("SetTags", "zone-1", "noTags", &compute.Tags{
+		Items: []string{"zone-1"},
+	}).Return(op, nil).Once()
+	s.gce.On("GetZoneOperation", "zone-1", "op").Return(
+		&compute.Operation{Status: "DONE"}, nil)
+
+	s.NoError(s.syncTags())
+	s.gce.AssertExpectations(s.T())
+
+	s.gce.On("ListInstances", "zone-1", "description eq namespace").Return(
+		&compute.InstanceList{Items: []*compute.Instance{{Name: "noTags"}}},
+		nil).Once()
+	s.gce.On("SetTags", "zone-1", "noTags", mock.Anything).Return(
+		nil, errors.New("err")).Once()
+	s.EqualError(s.syncTags(), "set tags of noTags: err")
+
+	s.gce.On("ListInstances", "zone-1", "description eq namespace").Return(
+		nil, errors.New("err")).Once()
+	s.EqualError(s.syncTags(), "list instances: err")
+}
+
 func TestGoogleTestSuite(t *testing.T) {
 	suite.Run(t, new(GoogleTestSuite))
 }
@@ -2,7 +2,9 @@ package cloud
 
 import (
 	"errors"
+	"time"
 
+	"github.com/quilt/quilt/blueprint"
 	"github.com/quilt/quilt/cloud/foreman"
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/util"
@@ -17,24 +19,83 @@ func updateMachineStatuses(conn db.Conn) {
 	}
 }
 
+// defaultMaxReplacements is the number of times a machine is replaced if the
+// blueprint's health policy doesn't specify a limit.
+const defaultMaxReplacements = 3
+
 func updateMachineStatusesOnce(conn db.Conn) {
-	conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+	conn.Txn(db.BlueprintTable, db.MachineTable).Run(func(view db.Database) error {
+		var policy blueprint.HealthPolicy
+		bp, err := view.GetBlueprint()
+		if err == nil && bp.HealthPolicy != nil {
+			policy = *bp.HealthPolicy
+		}
+
 		for _, dbm := range view.SelectFromMachine(nil) {
 			// Don't touch machines that are booting. `clst.boot` will take
 			// care of unsetting the status when it's no longer booting.
-			if dbm.Status == db.Booting {
+			// Similarly, machines that are being replaced are handled by
+			// the cloud provider's join.
+			if dbm.Status == db.Booting || dbm.Status == db.Replacing {
 				continue
 			}
 
-			if newStatus, ok := status(dbm); ok && newStatus != dbm.Status {
-				dbm.Status = newStatus
-				view.Commit(dbm)
+			newStatus, ok := status(dbm)
+			if !ok {
+				continue
 			}
+
+			if newStatus != db.Reconnecting {
+				dbm.DisconnectTime = time.Time{}
+			} else if dbm.DisconnectTime.IsZero() {
+				dbm.DisconnectTime = now()
+			}
+			dbm.Status = newStatus
+
+			// The host keys reported by the minion are authenticated by TLS,
+			// so they replace keys that were trusted on first use.
+			if newStatus == db.Connected {
+				hostKeys := getHostKeys(dbm.PublicIP)
+				if len(hostKeys) > 0 {
+					dbm.HostKeys = hostKeys
+				}
+			}
+
+			if shouldReplace(dbm, policy) {
+				log.WithField("machine", dbm).Warn(
+					"Machine has been disconnected for too long. " +
+						"Replacing it.")
+				c.Inc("Replace Machine")
+				dbm.Status = db.Replacing
+				dbm.DisconnectTime = time.Time{}
+				dbm.Replacements++
+			}
+			view.Commit(dbm)
 		}
 		return nil
 	})
 }
 
+// shouldReplace returns whether `m` has been disconnected for longer than
+// `policy` allows, and is still allowed to be replaced.
+func shouldReplace(m db.Machine, policy blueprint.HealthPolicy) bool {
+	if policy.ReplaceAfter <= 0 || m.Status != db.Reconnecting ||
+		m.DisconnectTime.IsZero() {
+		return false
+	}
+
+	maxReplacements := policy.MaxReplacements
+	if maxReplacements <= 0 {
+		maxReplacements = defaultMaxReplacements
+	}
+	if m.Replacements >= maxReplacements {
+		return false
+	}
+
+	replaceAfter := time.Duration(policy.ReplaceAfter) * time.Minute
+	return now().Sub(m.DisconnectTime) >= replaceAfter
+}
+
 // status returns a status string for the given machine. If no string could be
 // determined, the second return value is false.
 func status(m db.Machine) (string, bool) {
@@ -60,25 +121,46 @@ func status(m db.Machine) (string, bool) {
 }
 
 func setStatuses(conn db.Conn, machines []db.Machine, status string) {
+	updateMachines(conn, machines, func(m *db.Machine) {
+		m.Status = status
+	})
+}
+
+// setErrors records `err`, which may be nil, as the last cloud provider error of
+// each of `machines`.
+func setErrors(conn db.Conn, machines []db.Machine, err error) {
+	var msg string
+	if err != nil {
+		msg = err.Error()
+	}
+
+	updateMachines(conn, machines, func(m *db.Machine) {
+		m.Error = msg
+	})
+}
+
+func updateMachines(conn db.Conn, machines []db.Machine, update func(*db.Machine)) {
 	for _, m := range machines {
-		if err := setStatus(conn, m.BlueprintID, status); err != nil {
+		if err := updateMachine(conn, m.ID, update); err != nil {
 			log.WithFields(log.Fields{
 				"error":   err,
-				"machine": m.BlueprintID,
-				"status":  status,
-			}).Warn("Failed to update status of machine")
+				"machine": m,
+			}).Warn("Failed to update machine")
 		}
 	}
 }
 
-func setStatus(conn db.Conn, id string, status string) error {
+// updateMachine applies `update` to the machine with database ID `id`.  Machines
+// are looked up by their database ID because all the machines in a scaling group
+// share the same BlueprintID.
+func updateMachine(conn db.Conn, id int, update func(*db.Machine)) error {
 	return conn.Txn(db.MachineTable).Run(func(view db.Database) error {
 		matchingMachines := view.SelectFromMachine(func(m db.Machine) bool {
-			return m.BlueprintID == id
+			return m.ID == id
 		})
 		switch len(matchingMachines) {
 		case 1:
-			matchingMachines[0].Status = status
+			update(&matchingMachines[0])
 			view.Commit(matchingMachines[0])
 			return nil
 		case 0:
@@ -90,3 +172,5 @@ func setStatus(conn db.Conn, id string, status string) error {
 }
 
 var isConnected = foreman.IsConnected
+var getHostKeys = foreman.GetHostKeys
+var now = time.Now
@@ -2,9 +2,12 @@ package cloud
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/quilt/quilt/blueprint"
+	"github.com/quilt/quilt/cloud/foreman"
 	"github.com/quilt/quilt/db"
 )
 
@@ -20,6 +23,10 @@ func TestUpdateMachineStatuses(t *testing.T) {
 		}
 	}
 
+	disconnectTime := time.Now()
+	now = func() time.Time { return disconnectTime }
+	defer func() { now = time.Now }()
+
 	conn := db.New()
 	conn.Txn(db.MachineTable).Run(func(view db.Database) error {
 		// An unbooted machine.
@@ -84,6 +91,154 @@ func TestUpdateMachineStatuses(t *testing.T) {
 	assert.Contains(t, actual, db.Machine{BlueprintID: "3", Status: db.Connecting})
 	assert.Contains(t, actual, db.Machine{BlueprintID: "4", Status: db.Connecting})
 	assert.Contains(t, actual, db.Machine{BlueprintID: "5", Status: db.Connected})
-	assert.Contains(t, actual, db.Machine{BlueprintID: "6", Status: db.Reconnecting})
-	assert.Contains(t, actual, db.Machine{BlueprintID: "7", Status: db.Reconnecting})
+	assert.Contains(t, actual, db.Machine{BlueprintID: "6",
+		Status: db.Reconnecting, DisconnectTime: disconnectTime})
+	assert.Contains(t, actual, db.Machine{BlueprintID: "7",
+		Status: db.Reconnecting, DisconnectTime: disconnectTime})
+}
+
+func TestUpdateHostKeys(t *testing.T) {
+	isConnected = func(host string) bool {
+		return host == "connected"
+	}
+	getHostKeys = func(host string) []string {
+		if host == "connected" {
+			return []string{"reported"}
+		}
+		return nil
+	}
+	defer func() { getHostKeys = foreman.GetHostKeys }()
+
+	conn := db.New()
+	conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+		for _, ip := range []string{"connected", "disconnected"} {
+			m := view.InsertMachine()
+			m.PublicIP = ip
+			m.HostKeys = []string{"trusted"}
+			view.Commit(m)
+		}
+		return nil
+	})
+
+	updateMachineStatusesOnce(conn)
+
+	// Only the connected minion's keys are updated. The disconnected machine
+	// keeps the key that was trusted on first use.
+	hostKeys := map[string][]string{}
+	for _, m := range conn.SelectFromMachine(nil) {
+		hostKeys[m.PublicIP] = m.HostKeys
+	}
+	assert.Equal(t, map[string][]string{
+		"connected":    {"reported"},
+		"disconnected": {"trusted"},
+	}, hostKeys)
+}
+
+func TestReplaceMachines(t *testing.T) {
+	isConnected = func(host string) bool { return false }
+
+	start := time.Now()
+	now = func() time.Time { return start }
+	defer func() { now = time.Now }()
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		bp := view.InsertBlueprint()
+		bp.HealthPolicy = &blueprint.HealthPolicy{
+			ReplaceAfter:    10,
+			MaxReplacements: 2,
+		}
+		view.Commit(bp)
+
+		m := view.InsertMachine()
+		m.BlueprintID = "1"
+		m.Status = db.Connected
+		m.PublicIP = "1.1.1.1"
+		view.Commit(m)
+		return nil
+	})
+
+	getMachine := func() db.Machine {
+		machines := conn.SelectFromMachine(nil)
+		assert.Len(t, machines, 1)
+		return machines[0]
+	}
+
+	// The machine just disconnected, so it's not replaced yet.
+	updateMachineStatusesOnce(conn)
+	m := getMachine()
+	assert.Equal(t, db.Reconnecting, m.Status)
+	assert.Equal(t, start, m.DisconnectTime)
+
+	now = func() time.Time { return start.Add(9 * time.Minute) }
+	updateMachineStatusesOnce(conn)
+	assert.Equal(t, db.Reconnecting, getMachine().Status)
+
+	// After the policy's timeout, the machine should be marked for replacement.
+	now = func() time.Time { return start.Add(10 * time.Minute) }
+	updateMachineStatusesOnce(conn)
+	m = getMachine()
+	assert.Equal(t, db.Replacing, m.Status)
+	assert.Equal(t, 1, m.Replacements)
+	assert.True(t, m.DisconnectTime.IsZero())
+
+	// Machines being replaced are left alone.
+	updateMachineStatusesOnce(conn)
+	assert.Equal(t, m, getMachine())
+
+	// Once the machine reaches its replacement limit, it's no longer replaced.
+	m.Status = db.Reconnecting
+	m.Replacements = 2
+	m.DisconnectTime = start
+	conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+		view.Commit(m)
+		return nil
+	})
+	updateMachineStatusesOnce(conn)
+	assert.Equal(t, m, getMachine())
+}
+
+func TestShouldReplace(t *testing.T) {
+	start := time.Now()
+	now = func() time.Time { return start.Add(time.Hour) }
+	defer func() { now = time.Now }()
+
+	m := db.Machine{Status: db.Reconnecting, DisconnectTime: start}
+	policy := blueprint.HealthPolicy{ReplaceAfter: 30}
+	assert.True(t, shouldReplace(m, policy))
+
+	// Replacement is disabled without a timeout.
+	assert.False(t, shouldReplace(m, blueprint.HealthPolicy{}))
+
+	// Only reconnecting machines are replaced.
+	m.Status = db.Connecting
+	assert.False(t, shouldReplace(m, policy))
+	m.Status = db.Reconnecting
+
+	// The number of replacements defaults to `defaultMaxReplacements`.
+	m.Replacements = defaultMaxReplacements - 1
+	assert.True(t, shouldReplace(m, policy))
+	m.Replacements = defaultMaxReplacements
+	assert.False(t, shouldReplace(m, policy))
+}
+
+func TestSetStatuses(t *testing.T) {
+	conn := db.New()
+
+	// Machines in the same scaling group share a BlueprintID.
+	var machines []db.Machine
+	conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+		for i := 0; i < 2; i++ {
+			m := view.InsertMachine()
+			m.BlueprintID = "group"
+			view.Commit(m)
+			machines = append(machines, m)
+		}
+		return nil
+	})
+
+	setStatuses(conn, machines[:1], db.Booting)
+	dbms := db.SortMachines(conn.SelectFromMachine(nil))
+	assert.Equal(t, db.Booting, dbms[0].Status)
+	assert.Equal(t, "", dbms[1].Status)
 }
@@ -20,16 +20,23 @@ const vagrantCmd = "vagrant"
 const shCmd = "sh"
 const cloudConfigPath = "/user-data"
 const sizePath = "/size"
+const bootHashPath = "/boot_hash"
 const vagrantFilePath = "/Vagrantfile"
 
 // Allow mocking out for unit tests
 var box = "ubuntu/xenial64"
 var boxVersion = "20170515.0.0"
 
-// createVagrantFile generates a VagrantFile for the machine.
-func createVagrantFile() string {
+// createVagrantFile generates a VagrantFile for the machine.  Custom boxes are
+// used at whatever version is available.
+func createVagrantFile(image string) string {
 	t := template.Must(template.New("VagrantFile").Parse(vagrantTemplate))
 
+	vmBox, vmBoxVersion := box, boxVersion
+	if image != "" {
+		vmBox, vmBoxVersion = image, ""
+	}
+
 	var vagrantFileBytes bytes.Buffer
 	err := t.Execute(&vagrantFileBytes, struct {
 		CloudConfigPath string
@@ -38,8 +45,8 @@ func createVagrantFile() string {
 		SizePath        string
 	}{
 		CloudConfigPath: cloudConfigPath,
-		Box:             box,
-		BoxVersion:      boxVersion,
+		Box:             vmBox,
+		BoxVersion:      vmBoxVersion,
 		SizePath:        sizePath,
 	})
 
@@ -51,7 +58,7 @@ func createVagrantFile() string {
 }
 
 // initMachine creates the files necessary to initialize a vagrant machine.
-func initMachine(cloudConfig string, size string, id string) error {
+func initMachine(cloudConfig, size, image, bootHash, id string) error {
 	c.Inc("Initialize Machine")
 	vdir, err := vagrantDir()
 	if err != nil {
@@ -71,7 +78,7 @@ func initMachine(cloudConfig string, size string, id string) error {
 		return err
 	}
 
-	vagrantFile := createVagrantFile()
+	vagrantFile := createVagrantFile(image)
 
 	err = util.WriteFile(path+vagrantFilePath, []byte(vagrantFile), 0644)
 	if err != nil {
@@ -85,6 +92,12 @@ func initMachine(cloudConfig string, size string, id string) error {
 		return err
 	}
 
+	err = util.WriteFile(path+bootHashPath, []byte(bootHash), 0644)
+	if err != nil {
+		destroy(id)
+		return err
+	}
+
 	return nil
 }
 
@@ -229,3 +242,11 @@ func size(id string) string {
 	}
 	return string(size)
 }
+
+func bootHash(id string) string {
+	hash, _, err := shell(id, "cat boot_hash")
+	if err != nil {
+		return ""
+	}
+	return string(hash)
+}
@@ -15,11 +15,17 @@ func TestVagrantFile(t *testing.T) {
 	box = "testBox"
 	boxVersion = "testVersion"
 
-	res := createVagrantFile()
+	res := createVagrantFile("")
 	exp := "(/user-data) (testBox) (testVersion) (/size)"
 	if res != exp {
 		t.Errorf("res: %s\nexp: %s", res, exp)
 	}
+
+	res = createVagrantFile("customBox")
+	exp = "(/user-data) (customBox) () (/size)"
+	if res != exp {
+		t.Errorf("res: %s\nexp: %s", res, exp)
+	}
 }
 
 func TestInitMachine(t *testing.T) {
@@ -31,7 +37,7 @@ func TestInitMachine(t *testing.T) {
 	size := "2,2"
 	id := "testing"
 
-	initMachine(cloudConfig, size, id)
+	initMachine(cloudConfig, size, "", "hash", id)
 
 	vdir, err := vagrantDir()
 
@@ -47,8 +53,12 @@ func TestInitMachine(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, size, resSize)
 
+	resBootHash, err := util.ReadFile(path + bootHashPath)
+	assert.Nil(t, err)
+	assert.Equal(t, "hash", resBootHash)
+
 	resVagrantFile, err := util.ReadFile(path + vagrantFilePath)
 	assert.Nil(t, err)
-	expFile := createVagrantFile()
+	expFile := createVagrantFile("")
 	assert.Equal(t, expFile, resVagrantFile)
 }
@@ -9,7 +9,9 @@ size = File.open(SIZE_PATH).read.strip.split(",")
 Vagrant.configure(2) do |config|
   config.vm.box = "{{.Box}}"
 
+{{- if .BoxVersion}}
 	config.vm.box_version = "{{.BoxVersion}}"
+{{- end}}
 
   config.vm.network "private_network", type: "dhcp"
 
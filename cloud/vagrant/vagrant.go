@@ -66,7 +66,8 @@ func (prvdr Provider) Boot(bootSet []db.Machine) error {
 func bootMachine(m db.Machine) error {
 	id := uuid.NewV4().String()
 
-	err := initMachine(cfg.Ubuntu(m, inboundPublicInterface), m.Size, id)
+	err := initMachine(cfg.Ubuntu(m, inboundPublicInterface), m.Size, m.Image,
+		cfg.BootHash(m), id)
 	if err == nil {
 		err = up(id)
 	}
@@ -101,6 +102,7 @@ func (prvdr Provider) List() ([]db.Machine, error) {
 			PublicIP:  ip,
 			PrivateIP: ip,
 			Size:      size(instanceID),
+			BootHash:  bootHash(instanceID),
 		}
 		machines = append(machines, instance)
 	}
@@ -0,0 +1,150 @@
+// Package auth implements role-based authorization for the grpc servers. The
+// certificate authority embeds a role in the subject of each certificate it
+// signs, and servers only allow each role to call the RPCs in their Policy.
+package auth
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	"golang.org/x/net/context"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// A Role describes what the holder of a certificate is allowed to do.
+type Role string
+
+const (
+	// ReadOnly certificates can query the state of the deployment.
+	ReadOnly Role = "read-only"
+
+	// Deployer certificates can also change the deployment.
+	Deployer Role = "deployer"
+
+	// Admin certificates can call every RPC. They're used by the daemon.
+	Admin Role = "admin"
+
+	// Minion certificates are installed on the machines in the cluster. Minions
+	// serve RPCs, but don't make them, so a compromised machine can't use its
+	// certificate to control the rest of the cluster.
+	Minion Role = "minion"
+)
+
+// Roles contains every valid role.
+var Roles = []Role{ReadOnly, Deployer, Admin, Minion}
+
+// ParseRole parses the role named by `str`.
+func ParseRole(str string) (Role, error) {
+	for _, role := range Roles {
+		if string(role) == str {
+			return role, nil
+		}
+	}
+	return "", fmt.Errorf("unknown role: %s", str)
+}
+
+// Subject returns the certificate subject that grants `role`.
+func Subject(role Role) pkix.Name {
+	return pkix.Name{OrganizationalUnit: []string{string(role)}}
+}
+
+// CertRole returns the role granted by the certificate. Certificates without a
+// role, such as those issued before roles were introduced, are only granted
+// ReadOnly, so that leaving out the role never grants more access.
+func CertRole(cert *x509.Certificate) Role {
+	if !HasRole(cert) {
+		return ReadOnly
+	}
+
+	role, err := ParseRole(cert.Subject.OrganizationalUnit[0])
+	if err != nil {
+		// Fail closed on certificates with roles we don't understand.
+		return ""
+	}
+	return role
+}
+
+// HasRole returns whether the certificate's subject names a role.
+func HasRole(cert *x509.Certificate) bool {
+	return len(cert.Subject.OrganizationalUnit) != 0
+}
+
+// A Policy maps the full name of each RPC (e.g. "/API/Deploy") to the roles that
+// are allowed to call it. RPCs that aren't in the policy can't be called.
+type Policy map[string][]Role
+
+// Allowed returns whether `role` may call `method`.
+func (policy Policy) Allowed(method string, role Role) bool {
+	for _, allowed := range policy[method] {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
+
+// ServerOpts returns the grpc options that enforce `policy` on a server. The
+// server's credentials must verify the peer's certificate, as TLS does.
+func ServerOpts(policy Policy) []grpc.ServerOption {
+	return []grpc.ServerOption{grpc.UnaryInterceptor(policy.Intercept)}
+}
+
+// Intercept is a grpc.UnaryServerInterceptor that enforces `policy`. It's
+// exported so that servers can chain it with their own interceptors.
+func (policy Policy) Intercept(ctx context.Context, req interface{},
+	info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+	role, err := peerRole(ctx)
+	if err != nil {
+		return nil, grpc.Errorf(codes.Unauthenticated, "%s", err)
+	}
+
+	if !policy.Allowed(info.FullMethod, role) {
+		log.WithFields(log.Fields{
+			"method": info.FullMethod,
+			"role":   role,
+		}).Warn("Denied unauthorized RPC")
+		return nil, grpc.Errorf(codes.PermissionDenied,
+			"role %q may not call %s", role, info.FullMethod)
+	}
+
+	return handler(ctx, req)
+}
+
+// peerRole returns the role of the peer that made the RPC in `ctx`.
+func peerRole(ctx context.Context) (Role, error) {
+	cert, err := PeerCert(ctx)
+	if err != nil {
+		return "", err
+	}
+	return CertRole(cert), nil
+}
+
+// PeerCert returns the certificate presented by the peer that made the RPC in
+// `ctx`. The TLS credentials only accept peers whose leaf certificate was signed
+// by the CA, so the certificate, and the role it grants, can be trusted.
+func PeerCert(ctx context.Context) (*x509.Certificate, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, errors.New("no peer information")
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil, errors.New("peer did not authenticate with TLS")
+	}
+
+	certs := tlsInfo.State.PeerCertificates
+	if len(certs) == 0 {
+		return nil, errors.New("peer did not present a certificate")
+	}
+	return certs[0], nil
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	"golang.org/x/net/context"
+)
+
+func TestParseRole(t *testing.T) {
+	t.Parallel()
+
+	for _, role := range Roles {
+		parsed, err := ParseRole(string(role))
+		assert.NoError(t, err)
+		assert.Equal(t, role, parsed)
+	}
+
+	_, err := ParseRole("root")
+	assert.EqualError(t, err, "unknown role: root")
+}
+
+func TestCertRole(t *testing.T) {
+	t.Parallel()
+
+	cert := &x509.Certificate{Subject: Subject(Deployer)}
+	assert.Equal(t, Deployer, CertRole(cert))
+
+	// Certificates without a role get the least access.
+	cert = &x509.Certificate{}
+	assert.False(t, HasRole(cert))
+	assert.Equal(t, ReadOnly, CertRole(cert))
+
+	cert = &x509.Certificate{
+		Subject: pkix.Name{OrganizationalUnit: []string{"root"}},
+	}
+	assert.Equal(t, Role(""), CertRole(cert))
+}
+
+func TestIntercept(t *testing.T) {
+	t.Parallel()
+
+	policy := Policy{
+		"/API/Query":  {ReadOnly, Deployer},
+		"/API/Deploy": {Deployer},
+	}
+
+	var called bool
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		called = true
+		return "reply", nil
+	}
+
+	call := func(ctx context.Context, method string) (interface{}, error) {
+		called = false
+		return policy.Intercept(ctx, nil,
+			&grpc.UnaryServerInfo{FullMethod: method}, handler)
+	}
+
+	reply, err := call(peerContext(ReadOnly), "/API/Query")
+	assert.NoError(t, err)
+	assert.Equal(t, "reply", reply)
+	assert.True(t, called)
+
+	_, err = call(peerContext(ReadOnly), "/API/Deploy")
+	assert.Equal(t, codes.PermissionDenied, grpc.Code(err))
+	assert.False(t, called)
+
+	_, err = call(peerContext(Deployer), "/API/Deploy")
+	assert.NoError(t, err)
+	assert.True(t, called)
+
+	// RPCs that aren't in the policy are denied.
+	_, err = call(peerContext(Admin), "/API/Unknown")
+	assert.Equal(t, codes.PermissionDenied, grpc.Code(err))
+	assert.False(t, called)
+
+	// Peers that didn't authenticate with a certificate are denied.
+	_, err = call(context.Background(), "/API/Query")
+	assert.Equal(t, codes.Unauthenticated, grpc.Code(err))
+
+	_, err = call(peer.NewContext(context.Background(), &peer.Peer{}),
+		"/API/Query")
+	assert.Equal(t, codes.Unauthenticated, grpc.Code(err))
+
+	noCerts := peer.NewContext(context.Background(),
+		&peer.Peer{AuthInfo: credentials.TLSInfo{}})
+	_, err = call(noCerts, "/API/Query")
+	assert.Equal(t, codes.Unauthenticated, grpc.Code(err))
+	assert.False(t, called)
+}
+
+func peerContext(role Role) context.Context {
+	cert := &x509.Certificate{Subject: Subject(role)}
+	return peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+		}},
+	})
+}
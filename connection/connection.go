@@ -5,6 +5,7 @@ import (
 	"time"
 
 	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 )
 
@@ -44,3 +45,23 @@ func Server(proto, addr string, opts []grpc.ServerOption) (net.Listener, *grpc.S
 		time.Sleep(30 * time.Second)
 	}
 }
+
+// ChainUnaryInterceptors combines `interceptors` into a single interceptor, as
+// grpc servers only accept one. The first interceptor is the outermost, so it
+// sees the result of the interceptors that follow it.
+func ChainUnaryInterceptors(
+	interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], handler
+			handler = func(ctx context.Context, req interface{}) (
+				interface{}, error) {
+				return interceptor(ctx, req, info, next)
+			}
+		}
+		return handler(ctx, req)
+	}
+}
@@ -2,9 +2,49 @@ package connection
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
 )
 
-func TestNone(t *testing.T) {
-	// This test is so that the build does not fail because there are no test
-	// files in this package.
+func TestChainUnaryInterceptors(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	interceptor := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req interface{},
+			info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (
+			interface{}, error) {
+
+			assert.Equal(t, "/API/Deploy", info.FullMethod)
+			calls = append(calls, name)
+			reply, err := handler(ctx, req)
+			return name + "(" + reply.(string) + ")", err
+		}
+	}
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls = append(calls, "handler")
+		return req, nil
+	}
+
+	chain := ChainUnaryInterceptors(interceptor("a"), interceptor("b"))
+	reply, err := chain(context.Background(), "req",
+		&grpc.UnaryServerInfo{FullMethod: "/API/Deploy"}, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "a(b(req))", reply)
+	assert.Equal(t, []string{"a", "b", "handler"}, calls)
+
+	// Interceptors may reject the call without calling the handler.
+	calls = nil
+	reject := func(context.Context, interface{}, *grpc.UnaryServerInfo,
+		grpc.UnaryHandler) (interface{}, error) {
+		return nil, assert.AnError
+	}
+	chain = ChainUnaryInterceptors(reject, interceptor("b"))
+	_, err = chain(context.Background(), "req",
+		&grpc.UnaryServerInfo{FullMethod: "/API/Deploy"}, handler)
+	assert.Equal(t, assert.AnError, err)
+	assert.Empty(t, calls)
 }
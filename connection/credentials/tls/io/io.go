@@ -28,22 +28,40 @@ type File struct {
 
 // ReadCredentials reads the TLS credentials contained within the directory.
 func ReadCredentials(dir string) (tls.TLS, error) {
-	caCert, err := util.ReadFile(caCertPath(dir))
+	caCert, signedCert, signedKey, err := readFiles(dir)
+	if err != nil {
+		return tls.TLS{}, err
+	}
+	return tls.New(caCert, signedCert, signedKey)
+}
+
+// ReloadCredentials updates `creds` with the TLS credentials contained within
+// the directory.
+func ReloadCredentials(dir string, creds tls.TLS) error {
+	caCert, signedCert, signedKey, err := readFiles(dir)
 	if err != nil {
-		return tls.TLS{}, fmt.Errorf("read CA: %s", err)
+		return err
 	}
+	return creds.Update(caCert, signedCert, signedKey)
+}
 
-	signedCert, err := util.ReadFile(signedCertPath(dir))
+func readFiles(dir string) (caCert, signedCert, signedKey string, err error) {
+	caCert, err = util.ReadFile(caCertPath(dir))
 	if err != nil {
-		return tls.TLS{}, fmt.Errorf("read signed cert: %s", err)
+		return "", "", "", fmt.Errorf("read CA: %s", err)
 	}
 
-	signedKey, err := util.ReadFile(signedKeyPath(dir))
+	signedCert, err = util.ReadFile(SignedCertPath(dir))
 	if err != nil {
-		return tls.TLS{}, fmt.Errorf("read signed key: %s", err)
+		return "", "", "", fmt.Errorf("read signed cert: %s", err)
 	}
 
-	return tls.New(caCert, signedCert, signedKey)
+	signedKey, err = util.ReadFile(signedKeyPath(dir))
+	if err != nil {
+		return "", "", "", fmt.Errorf("read signed key: %s", err)
+	}
+
+	return caCert, signedCert, signedKey, nil
 }
 
 // ReadCA reads the certificate authority contained with the directory.
@@ -64,9 +82,17 @@ func ReadCA(dir string) (rsa.KeyPair, error) {
 // MinionFiles defines how files should be written to disk for installation on
 // minions.
 func MinionFiles(dir string, ca, signed rsa.KeyPair) []File {
-	return []File{
+	return append([]File{
 		{Path: caCertPath(dir), Content: ca.CertString(), Mode: 0644},
-		{Path: signedCertPath(dir), Content: signed.CertString(), Mode: 0644},
+	}, SignedFiles(dir, signed)...)
+}
+
+// SignedFiles defines how a signed certificate and key should be written to
+// disk.  Unlike MinionFiles and DaemonFiles, it leaves the certificate authority
+// alone, so it's used to rotate certificates.
+func SignedFiles(dir string, signed rsa.KeyPair) []File {
+	return []File{
+		{Path: SignedCertPath(dir), Content: signed.CertString(), Mode: 0644},
 		{Path: signedKeyPath(dir), Content: signed.PrivateKeyString(),
 			Mode: 0600},
 	}
@@ -88,8 +114,8 @@ func caKeyPath(dir string) string {
 	return filepath.Join(dir, caKeyFilename)
 }
 
-// signedCertPath defines where to write the certificate for the signed certificate.
-func signedCertPath(dir string) string {
+// SignedCertPath defines where to write the certificate for the signed certificate.
+func SignedCertPath(dir string) string {
 	return filepath.Join(dir, signedCertFilename)
 }
 
@@ -6,6 +6,7 @@ import (
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/quilt/quilt/connection/auth"
 	"github.com/quilt/quilt/connection/credentials/tls/rsa"
 	"github.com/quilt/quilt/util"
 )
@@ -39,7 +40,7 @@ func TestWriteAndReadMinionCerts(t *testing.T) {
 	ca, err := rsa.NewCertificateAuthority()
 	assert.NoError(t, err)
 
-	signed, err := rsa.NewSigned(ca)
+	signed, err := rsa.NewSigned(ca, auth.Admin)
 	assert.NoError(t, err)
 
 	testDir := "/tls"
@@ -58,7 +59,7 @@ func TestReadDaemonCerts(t *testing.T) {
 	ca, err := rsa.NewCertificateAuthority()
 	assert.NoError(t, err)
 
-	signed, err := rsa.NewSigned(ca)
+	signed, err := rsa.NewSigned(ca, auth.Admin)
 	assert.NoError(t, err)
 
 	testDir := "/tls"
@@ -96,7 +97,7 @@ func TestReadCredentialsErrors(t *testing.T) {
 	// Missing CA certificate.
 	setupFilesystem([]File{
 		{Path: signedKeyPath(testDir), Mode: 0644},
-		{Path: signedCertPath(testDir), Mode: 0644},
+		{Path: SignedCertPath(testDir), Mode: 0644},
 	})
 	_, err := ReadCredentials(testDir)
 	assert.EqualError(t, err,
@@ -105,7 +106,7 @@ func TestReadCredentialsErrors(t *testing.T) {
 	// Missing signed key.
 	setupFilesystem([]File{
 		{Path: caCertPath(testDir), Mode: 0644},
-		{Path: signedCertPath(testDir), Mode: 0644},
+		{Path: SignedCertPath(testDir), Mode: 0644},
 	})
 	_, err = ReadCredentials(testDir)
 	assert.EqualError(t, err,
@@ -127,3 +128,39 @@ func setupFilesystem(files []File) {
 		util.WriteFile(f.Path, []byte(f.Content), f.Mode)
 	}
 }
+
+func TestReloadCredentials(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	ca, err := rsa.NewCertificateAuthority()
+	assert.NoError(t, err)
+
+	signed, err := rsa.NewSigned(ca, auth.Admin)
+	assert.NoError(t, err)
+
+	testDir := "/tls"
+	util.Mkdir(testDir, 0755)
+	for _, f := range MinionFiles(testDir, ca, signed) {
+		util.WriteFile(f.Path, []byte(f.Content), f.Mode)
+	}
+
+	creds, err := ReadCredentials(testDir)
+	assert.NoError(t, err)
+	assert.Equal(t, signed.NotAfter(), creds.CertExpiry())
+
+	// Rotating the signed certificate shouldn't touch the CA.
+	rotated, err := rsa.NewSigned(ca, auth.Admin)
+	assert.NoError(t, err)
+	for _, f := range SignedFiles(testDir, rotated) {
+		util.WriteFile(f.Path, []byte(f.Content), f.Mode)
+	}
+
+	assert.NoError(t, ReloadCredentials(testDir, creds))
+	assert.Equal(t, rotated.NotAfter(), creds.CertExpiry())
+	assert.Equal(t, ca.NotAfter(), creds.CAExpiry())
+
+	util.AppFs.Remove(signedKeyPath(testDir))
+	err = ReloadCredentials(testDir, creds)
+	assert.EqualError(t, err,
+		"read signed key: open /tls/quilt.key: file does not exist")
+}
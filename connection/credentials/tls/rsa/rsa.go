@@ -10,6 +10,21 @@ import (
 	"fmt"
 	"math/big"
 	"time"
+
+	"github.com/quilt/quilt/connection/auth"
+)
+
+const (
+	// Certificate authorities can't be rotated without reinstalling every
+	// certificate in the cluster, so they're long lived.
+	caValidity = 10 * 365 * 24 * time.Hour
+
+	// Signed certificates are rotated automatically, so they expire sooner.
+	signedValidity = 365 * 24 * time.Hour
+
+	// RenewBefore is how long before expiring that signed certificates should
+	// be reissued.
+	RenewBefore = 30 * 24 * time.Hour
 )
 
 // KeyPair represents an RSA private key and certificate. The private key is
@@ -40,6 +55,16 @@ func (keyPair KeyPair) CertString() string {
 	}))
 }
 
+// NotAfter returns when the certificate expires.
+func (keyPair KeyPair) NotAfter() time.Time {
+	return keyPair.cert.NotAfter
+}
+
+// Role returns the role granted by the certificate.
+func (keyPair KeyPair) Role() auth.Role {
+	return auth.CertRole(keyPair.cert)
+}
+
 // New loads the KeyPair defined by the given PEM-encoded cert and key.
 func New(certStr, keyStr string) (KeyPair, error) {
 	keyDER, err := getDER(keyStr)
@@ -86,7 +111,7 @@ func NewCertificateAuthority() (KeyPair, error) {
 		return KeyPair{}, fmt.Errorf("create key: %s", err)
 	}
 
-	template, err := certTemplate()
+	template, err := certTemplate(caValidity)
 	if err != nil {
 		return KeyPair{}, fmt.Errorf("create template: %s", err)
 	}
@@ -103,17 +128,18 @@ func NewCertificateAuthority() (KeyPair, error) {
 	return KeyPair{key, cert}, err
 }
 
-// NewSigned generates a KeyPair signed by `signer`.
-func NewSigned(signer KeyPair) (KeyPair, error) {
+// NewSigned generates a KeyPair signed by `signer`, which grants `role`.
+func NewSigned(signer KeyPair, role auth.Role) (KeyPair, error) {
 	key, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
 		return KeyPair{}, fmt.Errorf("create key: %s", err)
 	}
 
-	template, err := certTemplate()
+	template, err := certTemplate(signedValidity)
 	if err != nil {
 		return KeyPair{}, fmt.Errorf("create template: %s", err)
 	}
+	template.Subject = auth.Subject(role)
 	template.ExtKeyUsage = []x509.ExtKeyUsage{
 		x509.ExtKeyUsageClientAuth,
 		x509.ExtKeyUsageServerAuth,
@@ -129,7 +155,27 @@ func NewSigned(signer KeyPair) (KeyPair, error) {
 	return KeyPair{key, cert}, err
 }
 
-func certTemplate() (x509.Certificate, error) {
+// ParseCert parses the PEM-encoded certificate. It returns an error if the
+// certificate wasn't signed by `ca`.
+func ParseCert(ca KeyPair, certStr string) (*x509.Certificate, error) {
+	der, err := getDER(certStr)
+	if err != nil {
+		return nil, fmt.Errorf("read cert: %s", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse cert: %s", err)
+	}
+
+	if err := cert.CheckSignatureFrom(ca.cert); err != nil {
+		return nil, fmt.Errorf("check signature: %s", err)
+	}
+
+	return cert, nil
+}
+
+func certTemplate(validity time.Duration) (x509.Certificate, error) {
 	// Pick a random serial number.
 	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
 	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
@@ -142,8 +188,7 @@ func certTemplate() (x509.Certificate, error) {
 		SerialNumber:          serialNumber,
 		BasicConstraintsValid: true,
 		NotBefore:             now,
-		// Expire after one year.
-		NotAfter: now.Add(1 * 365 * 24 * time.Hour),
+		NotAfter:              now.Add(validity),
 	}
 
 	return template, nil
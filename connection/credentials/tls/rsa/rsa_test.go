@@ -4,6 +4,7 @@ import (
 	"crypto/x509"
 	"testing"
 
+	"github.com/quilt/quilt/connection/auth"
 	"github.com/quilt/quilt/connection/credentials/tls"
 
 	"github.com/stretchr/testify/assert"
@@ -53,6 +54,42 @@ func newCAAndSigned() (KeyPair, KeyPair, error) {
 		return KeyPair{}, KeyPair{}, err
 	}
 
-	signed, err := NewSigned(ca)
+	signed, err := NewSigned(ca, auth.Admin)
 	return ca, signed, err
 }
+
+func TestParseCert(t *testing.T) {
+	ca, signed, err := newCAAndSigned()
+	assert.NoError(t, err)
+
+	// The CA outlives the certificates it signs so that they can be rotated.
+	assert.True(t, ca.NotAfter().After(signed.NotAfter()))
+
+	cert, err := ParseCert(ca, signed.CertString())
+	assert.NoError(t, err)
+	assert.Equal(t, signed.NotAfter(), cert.NotAfter)
+
+	otherCA, err := NewCertificateAuthority()
+	assert.NoError(t, err)
+
+	_, err = ParseCert(otherCA, signed.CertString())
+	assert.Error(t, err)
+
+	_, err = ParseCert(ca, "garbage")
+	assert.EqualError(t, err, "read cert: no key PEM data found")
+}
+
+func TestRole(t *testing.T) {
+	ca, err := NewCertificateAuthority()
+	assert.NoError(t, err)
+
+	for _, role := range auth.Roles {
+		signed, err := NewSigned(ca, role)
+		assert.NoError(t, err)
+		assert.Equal(t, role, signed.Role())
+
+		cert, err := ParseCert(ca, signed.CertString())
+		assert.NoError(t, err)
+		assert.Equal(t, role, auth.CertRole(cert))
+	}
+}
@@ -3,9 +3,11 @@ package tls
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/pem"
 	"errors"
 	"fmt"
-	"strings"
+	"sync"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -19,18 +21,33 @@ import (
 // certificate authority.
 // The rsa subpackage contains code to generate certificates compatible with
 // this authentication scheme.
+// The credentials are loaded into a shared state so that they can be updated
+// with Update, without restarting the servers and clients that use them.
 type TLS struct {
-	keyPair tls.Certificate
-	caPool  *x509.CertPool
+	state *tlsState
+}
+
+type tlsState struct {
+	sync.RWMutex
+
+	keyPair  tls.Certificate
+	caPool   *x509.CertPool
+	caExpiry time.Time
 }
 
 // ServerOpts gets the grpc options for creating a server.
 func (tlsAuth TLS) ServerOpts() []grpc.ServerOption {
 	return []grpc.ServerOption{grpc.Creds(
 		credentials.NewTLS(&tls.Config{
-			Certificates: []tls.Certificate{tlsAuth.keyPair},
-			ClientCAs:    tlsAuth.caPool,
-			ClientAuth:   tls.RequireAndVerifyClientCert,
+			GetCertificate: func(*tls.ClientHelloInfo) (
+				*tls.Certificate, error) {
+				return tlsAuth.getKeyPair(), nil
+			},
+
+			// The client's certificate is verified by VerifyPeerCertificate
+			// rather than ClientCAs so that the CA can be updated.
+			ClientAuth:            tls.RequireAnyClientCert,
+			VerifyPeerCertificate: tlsAuth.verifySignedByCA,
 		}),
 	)}
 }
@@ -39,7 +56,10 @@ func (tlsAuth TLS) ServerOpts() []grpc.ServerOption {
 func (tlsAuth TLS) ClientOpts() []grpc.DialOption {
 	return []grpc.DialOption{grpc.WithTransportCredentials(
 		credentials.NewTLS(&tls.Config{
-			Certificates: []tls.Certificate{tlsAuth.keyPair},
+			GetClientCertificate: func(*tls.CertificateRequestInfo) (
+				*tls.Certificate, error) {
+				return tlsAuth.getKeyPair(), nil
+			},
 
 			// We use a custom VerifyPeerCertificate that only checks whether
 			// the certificate is signed by the expected CA, and ignores
@@ -54,46 +74,109 @@ func (tlsAuth TLS) ClientOpts() []grpc.DialOption {
 	)}
 }
 
-// verifySignedByCA verifies that at least one certificate is signed by the
-// expected CA. It is different from the default implementation because it does
-// verify the peer's hostname.
-func (tlsAuth TLS) verifySignedByCA(rawCertsSlice [][]byte,
-	_ [][]*x509.Certificate) error {
-	var verifyErrors []string
-	for _, rawCerts := range rawCertsSlice {
-		parsedCerts, err := x509.ParseCertificates(rawCerts)
-		if err != nil {
-			verifyErrors = append(verifyErrors, err.Error())
-			continue
-		}
+// CertExpiry returns when the signed certificate expires.
+func (tlsAuth TLS) CertExpiry() time.Time {
+	tlsAuth.state.RLock()
+	defer tlsAuth.state.RUnlock()
+	return tlsAuth.state.keyPair.Leaf.NotAfter
+}
 
-		for _, cert := range parsedCerts {
-			_, err = cert.Verify(x509.VerifyOptions{
-				Roots: tlsAuth.caPool,
-			})
-			if err == nil {
-				return nil
-			}
+// CAExpiry returns when the certificate authority's certificate expires.
+func (tlsAuth TLS) CAExpiry() time.Time {
+	tlsAuth.state.RLock()
+	defer tlsAuth.state.RUnlock()
+	return tlsAuth.state.caExpiry
+}
 
-			verifyErrors = append(verifyErrors, err.Error())
-		}
+// Update replaces the credentials with the given CA and signed certificate and
+// key. Connections made after the update use the new credentials.
+func (tlsAuth TLS) Update(ca, cert, key string) error {
+	newState, err := parse(ca, cert, key)
+	if err != nil {
+		return err
 	}
 
-	return fmt.Errorf("failed to verify peer certificates: [%s]",
-		strings.Join(verifyErrors, ", "))
+	tlsAuth.state.Lock()
+	defer tlsAuth.state.Unlock()
+	tlsAuth.state.keyPair = newState.keyPair
+	tlsAuth.state.caPool = newState.caPool
+	tlsAuth.state.caExpiry = newState.caExpiry
+	return nil
+}
+
+func (tlsAuth TLS) getKeyPair() *tls.Certificate {
+	tlsAuth.state.RLock()
+	defer tlsAuth.state.RUnlock()
+	keyPair := tlsAuth.state.keyPair
+	return &keyPair
+}
+
+func (tlsAuth TLS) getCAPool() *x509.CertPool {
+	tlsAuth.state.RLock()
+	defer tlsAuth.state.RUnlock()
+	return tlsAuth.state.caPool
+}
+
+// verifySignedByCA verifies that the peer's certificate is signed by the
+// expected CA. It is different from the default implementation because it
+// doesn't verify the peer's hostname. Only the leaf certificate is checked, as
+// the other certificates in the chain don't prove the peer's identity.
+func (tlsAuth TLS) verifySignedByCA(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return errors.New("no peer certificates")
+	}
+
+	leaf, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse peer certificate: %s", err)
+	}
+
+	_, err = leaf.Verify(x509.VerifyOptions{Roots: tlsAuth.getCAPool()})
+	if err != nil {
+		return fmt.Errorf("failed to verify peer certificate: %s", err)
+	}
+	return nil
 }
 
 // New creates a TLS instance from the given CA and signed certificate and key.
 func New(ca, cert, key string) (TLS, error) {
-	keyPair, err := tls.X509KeyPair([]byte(cert), []byte(key))
+	state, err := parse(ca, cert, key)
 	if err != nil {
 		return TLS{}, err
 	}
+	return TLS{state}, nil
+}
+
+func parse(ca, cert, key string) (*tlsState, error) {
+	keyPair, err := tls.X509KeyPair([]byte(cert), []byte(key))
+	if err != nil {
+		return nil, err
+	}
+
+	keyPair.Leaf, err = x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
 
 	caPool := x509.NewCertPool()
 	if !caPool.AppendCertsFromPEM([]byte(ca)) {
-		return TLS{}, errors.New("failed to create CA cert pool")
+		return nil, errors.New("failed to create CA cert pool")
+	}
+
+	var caExpiry time.Time
+	for rest := []byte(ca); ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		caCert, err := x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			caExpiry = caCert.NotAfter
+			break
+		}
 	}
 
-	return TLS{keyPair, caPool}, nil
+	return &tlsState{keyPair: keyPair, caPool: caPool, caExpiry: caExpiry}, nil
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/pem"
 	"testing"
 
+	"github.com/quilt/quilt/connection/auth"
 	"github.com/quilt/quilt/connection/credentials/tls/rsa"
 
 	"github.com/stretchr/testify/assert"
@@ -100,7 +101,7 @@ func TestVerifySignedByCA(t *testing.T) {
 	validCA, err := rsa.NewCertificateAuthority()
 	assert.NoError(t, err)
 
-	validClient, err := rsa.NewSigned(validCA)
+	validClient, err := rsa.NewSigned(validCA, auth.Admin)
 	assert.NoError(t, err)
 
 	tlsCred, err := New(validCA.CertString(), validClient.CertString(),
@@ -109,7 +110,7 @@ func TestVerifySignedByCA(t *testing.T) {
 
 	// Test that verification passes for servers with a certificate signed
 	// by the same CA.
-	validServer, err := rsa.NewSigned(validCA)
+	validServer, err := rsa.NewSigned(validCA, auth.Admin)
 	assert.NoError(t, err)
 	verifyErr := tryVerify(tlsCred, validServer.CertString())
 
@@ -117,13 +118,66 @@ func TestVerifySignedByCA(t *testing.T) {
 	otherCA, err := rsa.NewCertificateAuthority()
 	assert.NoError(t, err)
 
-	otherServer, err := rsa.NewSigned(otherCA)
+	otherServer, err := rsa.NewSigned(otherCA, auth.Admin)
 	assert.NoError(t, err)
 
 	verifyErr = tryVerify(tlsCred, otherServer.CertString())
 	assert.Error(t, verifyErr)
 	assert.Contains(t, verifyErr.Error(),
 		"x509: certificate signed by unknown authority")
+
+	// Test that a trusted certificate elsewhere in the chain doesn't vouch for
+	// an untrusted leaf.
+	selfSigned, err := rsa.NewCertificateAuthority()
+	assert.NoError(t, err)
+
+	leaf, _ := pem.Decode([]byte(selfSigned.CertString()))
+	trusted, _ := pem.Decode([]byte(validServer.CertString()))
+	verifyErr = tlsCred.verifySignedByCA([][]byte{leaf.Bytes, trusted.Bytes}, nil)
+	assert.Error(t, verifyErr)
+
+	verifyErr = tlsCred.verifySignedByCA(nil, nil)
+	assert.EqualError(t, verifyErr, "no peer certificates")
+}
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+
+	oldCA, err := rsa.NewCertificateAuthority()
+	assert.NoError(t, err)
+
+	oldSigned, err := rsa.NewSigned(oldCA, auth.Admin)
+	assert.NoError(t, err)
+
+	tlsCred, err := New(oldCA.CertString(), oldSigned.CertString(),
+		oldSigned.PrivateKeyString())
+	assert.NoError(t, err)
+	assert.Equal(t, oldSigned.NotAfter(), tlsCred.CertExpiry())
+	assert.Equal(t, oldCA.NotAfter(), tlsCred.CAExpiry())
+	copied := tlsCred
+
+	newCA, err := rsa.NewCertificateAuthority()
+	assert.NoError(t, err)
+
+	newSigned, err := rsa.NewSigned(newCA, auth.Admin)
+	assert.NoError(t, err)
+	assert.Error(t, tryVerify(tlsCred, newSigned.CertString()))
+
+	// Invalid credentials are rejected, and the old credentials are kept.
+	err = tlsCred.Update(newCA.CertString(), newSigned.CertString(), "key")
+	assert.EqualError(t, err, "tls: failed to find any PEM data in key input")
+	assert.NoError(t, tryVerify(tlsCred, oldSigned.CertString()))
+
+	err = tlsCred.Update(newCA.CertString(), newSigned.CertString(),
+		newSigned.PrivateKeyString())
+	assert.NoError(t, err)
+	assert.NoError(t, tryVerify(tlsCred, newSigned.CertString()))
+	assert.Error(t, tryVerify(tlsCred, oldSigned.CertString()))
+	assert.Equal(t, newSigned.NotAfter(), tlsCred.CertExpiry())
+	assert.Equal(t, newCA.NotAfter(), tlsCred.CAExpiry())
+
+	// Copies of the credentials share the update.
+	assert.Equal(t, newSigned.NotAfter(), copied.CertExpiry())
 }
 
 // tryVerify attempts to verify the given PEM-encoded certificate against
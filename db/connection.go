@@ -2,6 +2,10 @@ package db
 
 import (
 	"fmt"
+	"net"
+	"strings"
+
+	"github.com/quilt/quilt/blueprint"
 )
 
 // A Connection allows two hostnames to speak to each other on the port
@@ -13,6 +17,14 @@ type Connection struct {
 	To      string
 	MinPort int
 	MaxPort int
+
+	// The limit, in bits per second, on the traffic in each direction of the
+	// connection, or zero if it's unlimited.
+	Bandwidth int `json:",omitempty"`
+
+	// The addresses that the domain of a connection to a domain outside of Quilt
+	// resolves to.  The leader resolves them periodically.
+	ResolvedIPs []string `json:",omitempty"`
 }
 
 // InsertConnection creates a new connection row and inserts it into the database.
@@ -58,6 +70,28 @@ func (c Connection) String() string {
 	return fmt.Sprintf("Connection-%d{%s->%s:%s}", c.ID, c.From, c.To, port)
 }
 
+// ExternalCIDRs returns the CIDRs that a connection to an address outside of Quilt
+// allows traffic to, or nil if the connection isn't to such an address.  Domains
+// are represented by the addresses they resolved to.
+func (c Connection) ExternalCIDRs() []string {
+	switch {
+	case strings.HasPrefix(c.To, blueprint.CIDRLabelPrefix):
+		_, ipNet, err := net.ParseCIDR(
+			strings.TrimPrefix(c.To, blueprint.CIDRLabelPrefix))
+		if err != nil {
+			return nil
+		}
+		return []string{ipNet.String()}
+	case strings.HasPrefix(c.To, blueprint.DomainLabelPrefix):
+		var cidrs []string
+		for _, ip := range c.ResolvedIPs {
+			cidrs = append(cidrs, ip+"/32")
+		}
+		return cidrs
+	}
+	return nil
+}
+
 func (c Connection) less(r row) bool {
 	o := r.(Connection)
 
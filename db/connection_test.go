@@ -40,3 +40,19 @@ func TestConnection(t *testing.T) {
 	assert.True(t, connection.less(Connection{From: "foo", MinPort: 100}))
 	assert.True(t, connection.less(Connection{From: "foo", ID: id + 1}))
 }
+
+func TestConnectionExternalCIDRs(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, Connection{To: "foo"}.ExternalCIDRs())
+	assert.Equal(t, []string{"52.1.0.0/16"},
+		Connection{To: "cidr:52.1.0.0/16"}.ExternalCIDRs())
+	assert.Equal(t, []string{"52.1.0.0/16"},
+		Connection{To: "cidr:52.1.2.3/16"}.ExternalCIDRs())
+	assert.Nil(t, Connection{To: "cidr:52.1.0.0"}.ExternalCIDRs())
+	assert.Nil(t, Connection{To: "domain:example.com"}.ExternalCIDRs())
+	assert.Equal(t, []string{"1.2.3.4/32", "1.2.3.5/32"}, Connection{
+		To:          "domain:example.com",
+		ResolvedIPs: []string{"1.2.3.4", "1.2.3.5"},
+	}.ExternalCIDRs())
+}
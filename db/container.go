@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/quilt/quilt/blueprint"
 	"github.com/quilt/quilt/util"
 )
 
@@ -26,11 +27,32 @@ type Container struct {
 	Hostname          string            `json:",omitempty"`
 	Created           time.Time         `json:","`
 
+	// Set by the leader when the container doesn't fit on any worker.
+	Unschedulable bool `json:",omitempty"`
+
+	// The privileges the container runs with, or nil for the defaults.
+	Security *blueprint.Security `json:",omitempty"`
+
+	// The limits on the rates at which the container receives and sends
+	// traffic, or nil if it's unlimited.
+	Bandwidth *blueprint.Bandwidth `json:",omitempty"`
+
+	// The DSCP value that the container's packets are marked with, or zero.
+	TrafficClass int `json:",omitempty"`
+
+	// The result of probing the container on behalf of the load balancers in
+	// front of it.  It's empty if the container isn't probed, or hasn't passed
+	// a probe yet, ContainerHealthy, or otherwise describes the failure.
+	Health string `json:",omitempty"`
+
 	Image      string `json:",omitempty"`
 	ImageID    string `json:",omitempty"`
 	Dockerfile string `json:"-"`
 }
 
+// ContainerHealthy is the Health of containers that pass their load balancer probes.
+const ContainerHealthy = "healthy"
+
 // ContainerSlice is an alias for []Container to allow for joins
 type ContainerSlice []Container
 
@@ -105,6 +127,26 @@ func (c Container) String() string {
 		tags = append(tags, fmt.Sprintf("Status: %s", c.Status))
 	}
 
+	if c.Health != "" {
+		tags = append(tags, fmt.Sprintf("Health: %s", c.Health))
+	}
+
+	if c.Unschedulable {
+		tags = append(tags, "Unschedulable")
+	}
+
+	if c.Security.IsPrivileged() {
+		tags = append(tags, "Privileged")
+	}
+
+	if bw := c.Bandwidth.String(); bw != "" {
+		tags = append(tags, fmt.Sprintf("Bandwidth: %s", bw))
+	}
+
+	if c.TrafficClass != 0 {
+		tags = append(tags, fmt.Sprintf("TrafficClass: %d", c.TrafficClass))
+	}
+
 	if !c.Created.IsZero() {
 		tags = append(tags, fmt.Sprintf("Created: %s", c.Created.String()))
 	}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/quilt/quilt/blueprint"
 )
 
 func TestContainerString(t *testing.T) {
@@ -40,6 +42,23 @@ func TestContainerString(t *testing.T) {
 		"Status: testing, Created: " + fakeTimeString + "}"
 
 	assert.Equal(t, exp, c.String())
+
+	c = Container{ID: 2, Image: "test", Unschedulable: true}
+	exp = "Container-2{run test, Unschedulable}"
+
+	assert.Equal(t, exp, c.String())
+
+	c = Container{ID: 3, Image: "test",
+		Security: &blueprint.Security{Privileged: true}}
+	assert.Equal(t, "Container-3{run test, Privileged}", c.String())
+
+	c = Container{ID: 4, Image: "test",
+		Bandwidth: &blueprint.Bandwidth{Ingress: 10000000, Egress: 1500}}
+	assert.Equal(t, "Container-4{run test, Bandwidth: ingress 10mbit, "+
+		"egress 1500bit}", c.String())
+
+	c = Container{ID: 5, Image: "test", TrafficClass: 10}
+	assert.Equal(t, "Container-5{run test, TrafficClass: 10}", c.String())
 }
 
 func TestContainerHelpers(t *testing.T) {
@@ -13,6 +13,10 @@ type Etcd struct {
 
 	Leader   bool   // True if this Minion is the leader.
 	LeaderIP string // IP address of the current leader, or ""
+
+	// True if this minion should give up, and not campaign for, leadership,
+	// e.g. because it can't keep ovn-northd running.
+	StepDown bool
 }
 
 func (e Etcd) String() string {
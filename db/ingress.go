@@ -0,0 +1,96 @@
+package db
+
+import "github.com/quilt/quilt/blueprint"
+
+// An Ingress row is created for each ingress specified by the policy.
+type Ingress struct {
+	ID int
+
+	Name         string
+	Routes       []blueprint.IngressRoute
+	Certificates []blueprint.IngressCertificate `rowStringer:"omit"`
+	HTTPPort     int
+	HTTPSPort    int
+
+	// The IPs of the containers that each route's destination forwards to,
+	// mapped to the private IP of the minion each runs on.  The leader computes
+	// them when sharing the ingresses with the workers, whose routers use them to
+	// forward requests across the cluster.
+	Backends map[string]map[string]string `json:",omitempty" rowStringer:"omit"`
+}
+
+// IngressSlice is an alias for []Ingress to allow for joins
+type IngressSlice []Ingress
+
+// InsertIngress creates a new ingress row and inserts it into the database.
+func (db Database) InsertIngress() Ingress {
+	result := Ingress{ID: db.nextID()}
+	db.insert(result)
+	return result
+}
+
+// SelectFromIngress gets all ingresses in the database that satisfy 'check'.
+func (db Database) SelectFromIngress(check func(Ingress) bool) []Ingress {
+	var result []Ingress
+	for _, row := range db.selectRows(IngressTable) {
+		if check == nil || check(row.(Ingress)) {
+			result = append(result, row.(Ingress))
+		}
+	}
+
+	return result
+}
+
+// SelectFromIngress gets all ingresses in the database connection that satisfy
+// 'check'.
+func (conn Conn) SelectFromIngress(check func(Ingress) bool) []Ingress {
+	var result []Ingress
+	conn.Txn(IngressTable).Run(func(view Database) error {
+		result = view.SelectFromIngress(check)
+		return nil
+	})
+	return result
+}
+
+func (ing Ingress) getID() int {
+	return ing.ID
+}
+
+func (ing Ingress) tt() TableType {
+	return IngressTable
+}
+
+func (ing Ingress) String() string {
+	return defaultString(ing)
+}
+
+func (ing Ingress) less(r row) bool {
+	ing2 := r.(Ingress)
+
+	switch {
+	case ing.Name != ing2.Name:
+		return ing.Name < ing2.Name
+	default:
+		return ing.ID < ing2.ID
+	}
+}
+
+// Get returns the value contained at the given index
+func (slc IngressSlice) Get(i int) interface{} {
+	return slc[i]
+}
+
+// Len returns the number of items in the slice
+func (slc IngressSlice) Len() int {
+	return len(slc)
+}
+
+// Less implements less than for sort.Interface.
+func (slc IngressSlice) Less(i, j int) bool {
+	return slc[i].less(slc[j])
+}
+
+// Swap implements swapping for sort.Interface.
+func (slc IngressSlice) Swap(i, j int) {
+	slc[i], slc[j] = slc[j], slc[i]
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/quilt/quilt/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIngress(t *testing.T) {
+	t.Parallel()
+
+	conn := New()
+
+	var id int
+	conn.Txn(IngressTable).Run(func(view Database) error {
+		ing := view.InsertIngress()
+		id = ing.ID
+		ing.Name = "web"
+		ing.Routes = []blueprint.IngressRoute{
+			{Host: "example.com", Path: "/", To: "app", Port: 80}}
+		ing.Certificates = []blueprint.IngressCertificate{
+			{Host: "example.com", CertFile: "a.crt", KeyFile: "a.key"}}
+		ing.HTTPPort = 80
+		view.Commit(ing)
+		return nil
+	})
+
+	ingresses := IngressSlice(conn.SelectFromIngress(
+		func(i Ingress) bool { return true }))
+	assert.Equal(t, 1, ingresses.Len())
+
+	ing := ingresses[0]
+	assert.Equal(t, "web", ing.Name)
+	assert.Equal(t, id, ing.getID())
+	assert.Equal(t, IngressTable, ing.tt())
+
+	assert.Equal(t, "Ingress-1{Name=web, Routes=[{example.com / app 80}], "+
+		"HTTPPort=80}", ing.String())
+
+	assert.Equal(t, ing, ingresses.Get(0))
+
+	assert.True(t, ing.less(Ingress{Name: "z"}))
+	assert.True(t, ing.less(Ingress{Name: "web", ID: id + 1}))
+}
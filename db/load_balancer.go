@@ -1,5 +1,7 @@
 package db
 
+import "github.com/quilt/quilt/blueprint"
+
 // A LoadBalancer row is created for each load balancer specified by the policy.
 type LoadBalancer struct {
 	ID int
@@ -7,6 +9,16 @@ type LoadBalancer struct {
 	Name      string
 	IP        string
 	Hostnames []string
+
+	// The probe that determines which backends receive traffic, or nil if
+	// all of them do.
+	Probe *blueprint.Probe `json:",omitempty"`
+
+	// The IPs of the backends that should receive traffic, mapped to the
+	// private IP of the minion each runs on.  The leader computes them when
+	// sharing the load balancers with the workers, which use them to spread
+	// traffic from the public internet across the cluster.
+	Backends map[string]string `json:",omitempty" rowStringer:"omit"`
 }
 
 // LoadBalancerSlice is an alias for []LoadBalancer to allow for joins
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 )
 
 // Machine represents a physical or virtual machine operated by a cloud provider on
@@ -21,14 +22,27 @@ type Machine struct {
 	SSHKeys     []string `rowStringer:"omit"`
 	FloatingIP  string
 	Preemptible bool
+	Image       string
+	BootScript  string `rowStringer:"omit"`
 
 	/* Populated by the cloud provider. */
 	CloudID   string //Cloud Provider ID
 	PublicIP  string
 	PrivateIP string
+	Error     string // The last error the provider returned for the machine.
+
+	// A hash of the Image and BootScript the machine was booted with, or empty
+	// if it was booted with the provider's defaults.
+	BootHash string `rowStringer:"omit"`
 
 	/* Populated by the cluster. */
-	Status string
+	Status         string
+	DisconnectTime time.Time // When the machine's minion stopped responding.
+	Replacements   int       // How many times the machine has been replaced.
+
+	// The SHA256 fingerprints of the machine's SSH host keys. They're reported
+	// by its minion, or trusted on first use until the minion connects.
+	HostKeys []string `rowStringer:"omit"`
 }
 
 const (
@@ -46,6 +60,11 @@ const (
 	// Connected represents that we are currently connected to the machine's
 	// minion.
 	Connected = "connected"
+
+	// Replacing represents that the machine stayed disconnected for longer than
+	// the blueprint's health policy allows, and its cloud instance is about to
+	// be terminated and booted again.
+	Replacing = "replacing"
 )
 
 // InsertMachine creates a new Machine and inserts it into 'db'.
@@ -117,10 +136,18 @@ func (m Machine) String() string {
 		tags = append(tags, fmt.Sprintf("Disk=%dGB", m.DiskSize))
 	}
 
+	if m.Image != "" {
+		tags = append(tags, "Image="+m.Image)
+	}
+
 	if m.Status != "" {
 		tags = append(tags, m.Status)
 	}
 
+	if m.Replacements != 0 {
+		tags = append(tags, fmt.Sprintf("Replacements=%d", m.Replacements))
+	}
+
 	return fmt.Sprintf("Machine-%d{%s}", m.ID, strings.Join(tags, ", "))
 }
 
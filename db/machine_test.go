@@ -111,24 +111,26 @@ func TestMachineString(t *testing.T) {
 	}
 
 	m = Machine{
-		ID:          1,
-		BlueprintID: "1",
-		Role:        Worker,
-		Preemptible: true,
-		CloudID:     "CloudID1234",
-		Provider:    "Amazon",
-		Region:      "us-west-1",
-		Size:        "m4.large",
-		PublicIP:    "1.2.3.4",
-		PrivateIP:   "5.6.7.8",
-		FloatingIP:  "8.9.3.2",
-		DiskSize:    56,
-		Status:      Connected,
+		ID:           1,
+		BlueprintID:  "1",
+		Role:         Worker,
+		Preemptible:  true,
+		CloudID:      "CloudID1234",
+		Provider:     "Amazon",
+		Region:       "us-west-1",
+		Size:         "m4.large",
+		PublicIP:     "1.2.3.4",
+		PrivateIP:    "5.6.7.8",
+		FloatingIP:   "8.9.3.2",
+		DiskSize:     56,
+		Image:        "ami-1234",
+		Status:       Connected,
+		Replacements: 2,
 	}
 	got = m.String()
 	exp = "Machine-1{1, Worker, Amazon us-west-1 m4.large preemptible, " +
 		"CloudID1234, PublicIP=1.2.3.4, PrivateIP=5.6.7.8, FloatingIP=8.9.3.2," +
-		" Disk=56GB, connected}"
+		" Disk=56GB, Image=ami-1234, connected, Replacements=2}"
 	if got != exp {
 		t.Errorf("\nGot: %s\nExp: %s", got, exp)
 	}
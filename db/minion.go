@@ -1,5 +1,7 @@
 package db
 
+import "github.com/quilt/quilt/blueprint"
+
 // The Minion table is instantiated on the minions with one row.  That row contains the
 // configuration that minion needs to operate, including its ID, Role, and IP address
 type Minion struct {
@@ -9,6 +11,26 @@ type Minion struct {
 	Blueprint      string `json:"-" rowStringer:"omit"`
 	AuthorizedKeys string `json:"-" rowStringer:"omit"`
 
+	// Overrides of the images of the system containers, and whether cadvisor
+	// is disabled.
+	SystemImages    map[string]string `json:"-" rowStringer:"omit"`
+	DisableCadvisor bool              `json:"-" rowStringer:"omit"`
+
+	// The resolvers that DNS queries for external names are forwarded to.
+	DNSUpstream []string `json:"-" rowStringer:"omit"`
+
+	// The DNS domain of the deployment's hostnames.  Use Domain() rather than
+	// reading it directly, as it's empty until the daemon configures the minion.
+	DNSDomain string `json:"-" rowStringer:"omit"`
+
+	// The container network.  Use Network() rather than reading these
+	// directly, as they're empty until the daemon configures the minion.
+	ContainerSubnet string `json:"-" rowStringer:"omit"`
+	GatewayIP       string `json:"-" rowStringer:"omit"`
+	LoadBalancerIP  string `json:"-" rowStringer:"omit"`
+	MTU             int    `json:"-" rowStringer:"omit"`
+	Encapsulation   string `json:"-" rowStringer:"omit"`
+
 	// Below fields are included in the JSON encoding.
 	Role        Role
 	PrivateIP   string
@@ -79,6 +101,25 @@ func (conn Conn) SelectFromMinion(check func(Minion) bool) []Minion {
 	return minions
 }
 
+// Domain returns the DNS domain of the deployment's hostnames.
+func (m Minion) Domain() string {
+	if m.DNSDomain == "" {
+		return blueprint.DefaultDomain
+	}
+	return m.DNSDomain
+}
+
+// Network returns the container network the minion was configured with.
+func (m Minion) Network() blueprint.NetworkConfig {
+	return blueprint.NetworkConfig{
+		Subnet:         m.ContainerSubnet,
+		GatewayIP:      m.GatewayIP,
+		LoadBalancerIP: m.LoadBalancerIP,
+		MTU:            m.MTU,
+		Encapsulation:  m.Encapsulation,
+	}.WithDefaults()
+}
+
 func (m Minion) getID() int {
 	return m.ID
 }
@@ -3,6 +3,8 @@ package db
 import (
 	"testing"
 
+	"github.com/quilt/quilt/blueprint"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -36,3 +38,27 @@ func TestMinion(t *testing.T) {
 
 	assert.Equal(t, "foo", conn.MinionSelf().Blueprint)
 }
+
+func TestMinionDomain(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "q", Minion{}.Domain())
+	assert.Equal(t, "svc.example.com",
+		Minion{DNSDomain: "svc.example.com"}.Domain())
+}
+
+func TestMinionNetwork(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, blueprint.DefaultNetwork, Minion{}.Network())
+
+	m := Minion{ContainerSubnet: "172.30.0.0/16", MTU: 8950,
+		Encapsulation: blueprint.Geneve}
+	assert.Equal(t, blueprint.NetworkConfig{
+		Subnet:         "172.30.0.0/16",
+		GatewayIP:      "172.30.0.1",
+		LoadBalancerIP: "172.30.0.2",
+		MTU:            8950,
+		Encapsulation:  blueprint.Geneve,
+	}, m.Network())
+}
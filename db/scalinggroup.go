@@ -0,0 +1,97 @@
+package db
+
+import (
+	"time"
+)
+
+// A ScalingGroup row tracks an autoscaling group of worker machines.  The group's
+// machines all share the BlueprintID of the blueprint machine they are booted from.
+// Used only by the daemon.
+type ScalingGroup struct {
+	ID int
+
+	BlueprintID string
+	Min         int
+	Max         int
+
+	// The number of machines the group should currently boot.
+	Size int
+
+	// When the group was last resized, and a description of why.
+	LastScaled time.Time `rowStringer:"omit"`
+	LastEvent  string
+}
+
+// InsertScalingGroup creates a new scaling group row and inserts it into the
+// database.
+func (db Database) InsertScalingGroup() ScalingGroup {
+	result := ScalingGroup{ID: db.nextID()}
+	db.insert(result)
+	return result
+}
+
+// SelectFromScalingGroup gets all scaling groups in the database that satisfy
+// 'check'.
+func (db Database) SelectFromScalingGroup(
+	check func(ScalingGroup) bool) []ScalingGroup {
+
+	var result []ScalingGroup
+	for _, row := range db.selectRows(ScalingGroupTable) {
+		if check == nil || check(row.(ScalingGroup)) {
+			result = append(result, row.(ScalingGroup))
+		}
+	}
+	return result
+}
+
+// SelectFromScalingGroup gets all scaling groups in the database connection that
+// satisfy 'check'.
+func (conn Conn) SelectFromScalingGroup(
+	check func(ScalingGroup) bool) []ScalingGroup {
+
+	var result []ScalingGroup
+	conn.Txn(ScalingGroupTable).Run(func(view Database) error {
+		result = view.SelectFromScalingGroup(check)
+		return nil
+	})
+	return result
+}
+
+func (group ScalingGroup) getID() int {
+	return group.ID
+}
+
+func (group ScalingGroup) tt() TableType {
+	return ScalingGroupTable
+}
+
+func (group ScalingGroup) String() string {
+	return defaultString(group)
+}
+
+func (group ScalingGroup) less(r row) bool {
+	return group.ID < r.(ScalingGroup).ID
+}
+
+// ScalingGroupSlice is an alias for []ScalingGroup to allow for joins
+type ScalingGroupSlice []ScalingGroup
+
+// Get returns the value contained at the given index
+func (slc ScalingGroupSlice) Get(ii int) interface{} {
+	return slc[ii]
+}
+
+// Len returns the number of items in the slice.
+func (slc ScalingGroupSlice) Len() int {
+	return len(slc)
+}
+
+// Less implements less than for sort.Interface.
+func (slc ScalingGroupSlice) Less(i, j int) bool {
+	return slc[i].less(slc[j])
+}
+
+// Swap implements swapping for sort.Interface.
+func (slc ScalingGroupSlice) Swap(i, j int) {
+	slc[i], slc[j] = slc[j], slc[i]
+}
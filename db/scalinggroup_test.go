@@ -0,0 +1,40 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScalingGroup(t *testing.T) {
+	t.Parallel()
+
+	conn := New()
+
+	var id int
+	conn.Txn(ScalingGroupTable).Run(func(view Database) error {
+		group := view.InsertScalingGroup()
+		id = group.ID
+		group.BlueprintID = "foo"
+		group.Max = 3
+		group.Size = 1
+		view.Commit(group)
+		return nil
+	})
+
+	groups := ScalingGroupSlice(conn.SelectFromScalingGroup(
+		func(g ScalingGroup) bool { return true }))
+	assert.Equal(t, 1, groups.Len())
+
+	group := groups[0]
+	assert.Equal(t, "foo", group.BlueprintID)
+	assert.Equal(t, id, group.getID())
+	assert.Equal(t, ScalingGroupTable, group.tt())
+
+	assert.Equal(t, "ScalingGroup-1{BlueprintID=foo, Max=3, Size=1}",
+		group.String())
+
+	assert.Equal(t, group, groups.Get(0))
+
+	assert.True(t, group.less(ScalingGroup{ID: id + 1}))
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"time"
+)
+
+// A SystemContainer row tracks a container run by the minion's supervisor, such as
+// etcd or ovs-vswitchd, that has exited at least once.  Used only by the minion.
+type SystemContainer struct {
+	ID int
+
+	Name string
+
+	// The number of times the container has been restarted, and the number of
+	// those restarts that happened without the container first running stably.
+	// The supervisor backs off restarting containers with many failures.
+	Restarts int
+	Failures int
+
+	// When the container last exited, and why.
+	LastExit   time.Time `rowStringer:"omit"`
+	ExitReason string
+}
+
+// InsertSystemContainer creates a new system container row and inserts it into the
+// database.
+func (db Database) InsertSystemContainer() SystemContainer {
+	result := SystemContainer{ID: db.nextID()}
+	db.insert(result)
+	return result
+}
+
+// SelectFromSystemContainer gets all system containers in the database that
+// satisfy 'check'.
+func (db Database) SelectFromSystemContainer(
+	check func(SystemContainer) bool) []SystemContainer {
+
+	var result []SystemContainer
+	for _, row := range db.selectRows(SystemContainerTable) {
+		if check == nil || check(row.(SystemContainer)) {
+			result = append(result, row.(SystemContainer))
+		}
+	}
+	return result
+}
+
+// SelectFromSystemContainer gets all system containers in the database connection
+// that satisfy 'check'.
+func (conn Conn) SelectFromSystemContainer(
+	check func(SystemContainer) bool) []SystemContainer {
+
+	var result []SystemContainer
+	conn.Txn(SystemContainerTable).Run(func(view Database) error {
+		result = view.SelectFromSystemContainer(check)
+		return nil
+	})
+	return result
+}
+
+func (sc SystemContainer) getID() int {
+	return sc.ID
+}
+
+func (sc SystemContainer) tt() TableType {
+	return SystemContainerTable
+}
+
+func (sc SystemContainer) String() string {
+	return defaultString(sc)
+}
+
+func (sc SystemContainer) less(r row) bool {
+	return sc.ID < r.(SystemContainer).ID
+}
+
+// SystemContainerSlice is an alias for []SystemContainer to allow for joins
+type SystemContainerSlice []SystemContainer
+
+// Get returns the value contained at the given index
+func (slc SystemContainerSlice) Get(ii int) interface{} {
+	return slc[ii]
+}
+
+// Len returns the number of items in the slice.
+func (slc SystemContainerSlice) Len() int {
+	return len(slc)
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSystemContainer(t *testing.T) {
+	t.Parallel()
+
+	conn := New()
+
+	var id int
+	conn.Txn(SystemContainerTable).Run(func(view Database) error {
+		sc := view.InsertSystemContainer()
+		id = sc.ID
+		sc.Name = "etcd"
+		sc.Restarts = 2
+		sc.ExitReason = "exit code 1"
+		view.Commit(sc)
+		return nil
+	})
+
+	scs := SystemContainerSlice(conn.SelectFromSystemContainer(
+		func(sc SystemContainer) bool { return true }))
+	assert.Equal(t, 1, scs.Len())
+
+	sc := scs[0]
+	assert.Equal(t, "etcd", sc.Name)
+	assert.Equal(t, id, sc.getID())
+	assert.Equal(t, SystemContainerTable, sc.tt())
+
+	assert.Equal(t, "SystemContainer-1{Name=etcd, Restarts=2, "+
+		"ExitReason=exit code 1}", sc.String())
+
+	assert.Equal(t, sc, scs.Get(0))
+
+	assert.True(t, sc.less(SystemContainer{ID: id + 1}))
+}
@@ -26,6 +26,9 @@ var ConnectionTable = TableType(reflect.TypeOf(Connection{}).String())
 // LoadBalancerTable is the type of the load balancer table.
 var LoadBalancerTable = TableType(reflect.TypeOf(LoadBalancer{}).String())
 
+// IngressTable is the type of the ingress table.
+var IngressTable = TableType(reflect.TypeOf(Ingress{}).String())
+
 // EtcdTable is the type of the etcd table.
 var EtcdTable = TableType(reflect.TypeOf(Etcd{}).String())
 
@@ -38,12 +41,22 @@ var ImageTable = TableType(reflect.TypeOf(Image{}).String())
 // HostnameTable is the type of the Hostname table.
 var HostnameTable = TableType(reflect.TypeOf(Hostname{}).String())
 
+// ScalingGroupTable is the type of the scaling group table.
+var ScalingGroupTable = TableType(reflect.TypeOf(ScalingGroup{}).String())
+
+// SystemContainerTable is the type of the system container table.
+var SystemContainerTable = TableType(reflect.TypeOf(SystemContainer{}).String())
+
+// TrafficTable is the type of the traffic table.
+var TrafficTable = TableType(reflect.TypeOf(Traffic{}).String())
+
 // AllTables is a slice of all the db TableTypes. It is used primarily for tests,
 // where there is no reason to put lots of thought into which tables a Transaction
 // should use.
 var AllTables = []TableType{BlueprintTable, MachineTable, ContainerTable, MinionTable,
-	ConnectionTable, LoadBalancerTable, EtcdTable, PlacementTable, ImageTable,
-	HostnameTable}
+	ConnectionTable, LoadBalancerTable, IngressTable, EtcdTable, PlacementTable,
+	ImageTable, HostnameTable, ScalingGroupTable, SystemContainerTable,
+	TrafficTable}
 
 type table struct {
 	rows map[int]row
@@ -0,0 +1,94 @@
+package db
+
+// A Traffic row counts the packets and bytes sent from one endpoint of the
+// connection graph to another.  From and To are each a hostname, a load balancer,
+// `blueprint.PublicInternetLabel`, or the label of an external address.  Workers
+// track the traffic sent and received by their containers since they started,
+// and the leader sums them across the cluster.
+type Traffic struct {
+	ID int `json:"-"`
+
+	From, To string
+
+	// The traffic class that From marks its packets with, or zero.
+	TrafficClass int `json:",omitempty"`
+
+	Packets, Bytes uint64
+}
+
+// InsertTraffic creates a new traffic row and inserts it into the database.
+func (db Database) InsertTraffic() Traffic {
+	result := Traffic{ID: db.nextID()}
+	db.insert(result)
+	return result
+}
+
+// SelectFromTraffic gets all traffic rows in the database that satisfy 'check'.
+func (db Database) SelectFromTraffic(check func(Traffic) bool) []Traffic {
+	var result []Traffic
+	for _, row := range db.selectRows(TrafficTable) {
+		if check == nil || check(row.(Traffic)) {
+			result = append(result, row.(Traffic))
+		}
+	}
+	return result
+}
+
+// SelectFromTraffic gets all traffic rows in the database connection that
+// satisfy 'check'.
+func (conn Conn) SelectFromTraffic(check func(Traffic) bool) []Traffic {
+	var result []Traffic
+	conn.Txn(TrafficTable).Run(func(view Database) error {
+		result = view.SelectFromTraffic(check)
+		return nil
+	})
+	return result
+}
+
+func (t Traffic) getID() int {
+	return t.ID
+}
+
+func (t Traffic) tt() TableType {
+	return TrafficTable
+}
+
+func (t Traffic) String() string {
+	return defaultString(t)
+}
+
+func (t Traffic) less(r row) bool {
+	t2 := r.(Traffic)
+
+	switch {
+	case t.From != t2.From:
+		return t.From < t2.From
+	case t.To != t2.To:
+		return t.To < t2.To
+	default:
+		return t.ID < t2.ID
+	}
+}
+
+// TrafficSlice is an alias for []Traffic to allow for joins
+type TrafficSlice []Traffic
+
+// Get returns the value contained at the given index
+func (slc TrafficSlice) Get(ii int) interface{} {
+	return slc[ii]
+}
+
+// Len returns the number of items in the slice.
+func (slc TrafficSlice) Len() int {
+	return len(slc)
+}
+
+// Less implements less than for sort.Interface.
+func (slc TrafficSlice) Less(i, j int) bool {
+	return slc[i].less(slc[j])
+}
+
+// Swap implements swapping for sort.Interface.
+func (slc TrafficSlice) Swap(i, j int) {
+	slc[i], slc[j] = slc[j], slc[i]
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraffic(t *testing.T) {
+	t.Parallel()
+
+	conn := New()
+
+	var id int
+	conn.Txn(TrafficTable).Run(func(view Database) error {
+		traffic := view.InsertTraffic()
+		id = traffic.ID
+		traffic.From = "web"
+		traffic.To = "db"
+		traffic.Packets = 10
+		traffic.Bytes = 1000
+		view.Commit(traffic)
+		return nil
+	})
+
+	traffic := TrafficSlice(conn.SelectFromTraffic(
+		func(t Traffic) bool { return true }))
+	assert.Equal(t, 1, traffic.Len())
+
+	tr := traffic[0]
+	assert.Equal(t, "web", tr.From)
+	assert.Equal(t, id, tr.getID())
+	assert.Equal(t, TrafficTable, tr.tt())
+
+	assert.Equal(t, "Traffic-1{From=web, To=db, Packets=10, Bytes=1000}",
+		tr.String())
+
+	assert.Equal(t, tr, traffic.Get(0))
+
+	assert.True(t, tr.less(Traffic{From: "x"}))
+	assert.True(t, tr.less(Traffic{From: "web", To: "x"}))
+	assert.True(t, tr.less(Traffic{From: "web", To: "db", ID: id + 1}))
+}
@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/quilt/quilt/api/client"
+	"github.com/quilt/quilt/connection"
+	"github.com/quilt/quilt/db"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	autoscaleInterval = 30 * time.Second
+
+	// Booting a worker takes a few minutes, so wait for the new workers to pick
+	// up the load before growing again.
+	scaleUpCooldown = 5 * time.Minute
+
+	// Be slower to shrink than to grow so that bursty load doesn't cause
+	// workers to be repeatedly booted and terminated.
+	scaleDownCooldown = 15 * time.Minute
+)
+
+var now = time.Now
+
+// RunAutoscaler resizes the blueprint's scaling groups in response to the load on
+// the cluster.  Groups grow when the leader fails to place containers, and shrink
+// when their workers are idle.
+func RunAutoscaler(conn db.Conn, creds connection.Credentials) {
+	for range time.Tick(autoscaleInterval) {
+		autoscaleOnce(conn, creds)
+	}
+}
+
+func autoscaleOnce(conn db.Conn, creds connection.Credentials) {
+	if len(conn.SelectFromScalingGroup(nil)) == 0 {
+		return
+	}
+
+	containers, err := queryContainers(conn.SelectFromMachine(nil), creds)
+	if err != nil {
+		log.WithError(err).Debug("Failed to query the cluster's containers")
+		return
+	}
+
+	conn.Txn(db.MachineTable, db.ScalingGroupTable).Run(
+		func(view db.Database) error {
+			autoscaleTxn(view, containers)
+			return nil
+		})
+}
+
+func autoscaleTxn(view db.Database, containers []db.Container) {
+	var unschedulable int
+	busy := map[string]bool{}
+	for _, dbc := range containers {
+		if dbc.Unschedulable {
+			unschedulable++
+		}
+		if dbc.Minion != "" {
+			busy[dbc.Minion] = true
+		}
+	}
+
+	groups := view.SelectFromScalingGroup(nil)
+	sort.Sort(db.ScalingGroupSlice(groups))
+	for _, group := range groups {
+		members := view.SelectFromMachine(func(m db.Machine) bool {
+			return m.BlueprintID == group.BlueprintID
+		})
+
+		if unschedulable > 0 {
+			// Only grow one group at a time, so that a single unschedulable
+			// container doesn't boot a worker in every group.
+			if scaleUp(view, group, members, unschedulable) {
+				return
+			}
+		} else {
+			scaleDown(view, group, members, busy)
+		}
+	}
+}
+
+// scaleUp adds a worker to `group` if it has room, and its existing workers have
+// all connected.  It returns whether the group grew.
+func scaleUp(view db.Database, group db.ScalingGroup, members []db.Machine,
+	unschedulable int) bool {
+
+	if group.Size >= group.Max || len(members) != group.Size ||
+		now().Sub(group.LastScaled) < scaleUpCooldown {
+		return false
+	}
+
+	// Workers that are still booting may be able to run the unschedulable
+	// containers once they connect.
+	for _, m := range members {
+		if m.Status != db.Connected {
+			return false
+		}
+	}
+
+	c.Inc("Scale Up")
+	group.Size++
+	group.LastScaled = now()
+	group.LastEvent = fmt.Sprintf("grew to %d (%d unschedulable containers)",
+		group.Size, unschedulable)
+	view.Commit(group)
+
+	log.WithField("group", group).Info("Growing autoscaling group")
+	return true
+}
+
+// scaleDown terminates one of the idle workers in `group` if the group is larger
+// than its minimum.  `busy` contains the private IPs of workers running containers.
+func scaleDown(view db.Database, group db.ScalingGroup, members []db.Machine,
+	busy map[string]bool) {
+
+	if group.Size <= group.Min ||
+		now().Sub(group.LastScaled) < scaleDownCooldown {
+		return
+	}
+
+	for _, m := range db.SortMachines(members) {
+		if m.Status != db.Connected || m.PrivateIP == "" || busy[m.PrivateIP] {
+			continue
+		}
+
+		c.Inc("Scale Down")
+		view.Remove(m)
+
+		group.Size--
+		group.LastScaled = now()
+		group.LastEvent = fmt.Sprintf("shrank to %d (worker %s was idle)",
+			group.Size, m.PrivateIP)
+		view.Commit(group)
+
+		log.WithField("group", group).Info("Shrinking autoscaling group")
+		return
+	}
+}
+
+func queryContainersImpl(machines []db.Machine, creds connection.Credentials) (
+	[]db.Container, error) {
+
+	leader, err := client.Leader(machines, creds)
+	if err != nil {
+		return nil, err
+	}
+	defer leader.Close()
+
+	return leader.QueryContainers()
+}
+
+// Stored in a variable so that it may be mocked out in unit tests.
+var queryContainers = queryContainersImpl
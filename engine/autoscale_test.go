@@ -0,0 +1,165 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/quilt/quilt/connection"
+	"github.com/quilt/quilt/db"
+)
+
+func TestAutoscaleUp(t *testing.T) {
+	start := time.Now()
+	now = func() time.Time { return start }
+	defer func() { now = time.Now }()
+
+	conn := db.New()
+	addGroup(conn, "group", 0, 2, 1)
+	addWorker(conn, "group", "1.1.1.1", db.Connected)
+
+	busy := []db.Container{{Minion: "1.1.1.1"}, {Unschedulable: true}}
+	runAutoscaler(conn, busy)
+
+	group := getGroup(t, conn)
+	assert.Equal(t, 2, group.Size)
+	assert.Equal(t, start, group.LastScaled)
+	assert.Equal(t, "grew to 2 (1 unschedulable containers)", group.LastEvent)
+
+	// Don't grow again until the new worker has booted and connected.
+	addWorker(conn, "group", "", db.Booting)
+	now = func() time.Time { return start.Add(time.Hour) }
+	runAutoscaler(conn, busy)
+	assert.Equal(t, 2, getGroup(t, conn).Size)
+
+	// Groups never grow beyond their maximum.
+	conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+		for _, m := range view.SelectFromMachine(nil) {
+			m.Status = db.Connected
+			view.Commit(m)
+		}
+		return nil
+	})
+	runAutoscaler(conn, busy)
+	assert.Equal(t, 2, getGroup(t, conn).Size)
+}
+
+func TestAutoscaleCooldown(t *testing.T) {
+	start := time.Now()
+	now = func() time.Time { return start }
+	defer func() { now = time.Now }()
+
+	conn := db.New()
+	addGroup(conn, "group", 1, 2, 1)
+	addWorker(conn, "group", "1.1.1.1", db.Connected)
+	conn.Txn(db.ScalingGroupTable).Run(func(view db.Database) error {
+		group := view.SelectFromScalingGroup(nil)[0]
+		group.LastScaled = start.Add(-time.Minute)
+		view.Commit(group)
+		return nil
+	})
+
+	runAutoscaler(conn, []db.Container{{Unschedulable: true}})
+	assert.Equal(t, 1, getGroup(t, conn).Size)
+
+	now = func() time.Time { return start.Add(scaleUpCooldown) }
+	runAutoscaler(conn, []db.Container{{Unschedulable: true}})
+	assert.Equal(t, 2, getGroup(t, conn).Size)
+}
+
+func TestAutoscaleDown(t *testing.T) {
+	start := time.Now()
+	now = func() time.Time { return start }
+	defer func() { now = time.Now }()
+
+	conn := db.New()
+	addGroup(conn, "group", 1, 3, 3)
+	addWorker(conn, "group", "1.1.1.1", db.Connected)
+	addWorker(conn, "group", "2.2.2.2", db.Connected)
+	addWorker(conn, "group", "3.3.3.3", db.Connected)
+	addWorker(conn, "other", "4.4.4.4", db.Connected)
+
+	// Only the idle worker in the group is terminated.
+	containers := []db.Container{{Minion: "1.1.1.1"}, {Minion: "3.3.3.3"}}
+	runAutoscaler(conn, containers)
+
+	group := getGroup(t, conn)
+	assert.Equal(t, 2, group.Size)
+	assert.Equal(t, "shrank to 2 (worker 2.2.2.2 was idle)", group.LastEvent)
+
+	var ips []string
+	for _, m := range conn.SelectFromMachine(nil) {
+		ips = append(ips, m.PrivateIP)
+	}
+	assert.Len(t, ips, 3)
+	assert.NotContains(t, ips, "2.2.2.2")
+
+	// Busy workers are never terminated.
+	now = func() time.Time { return start.Add(time.Hour) }
+	runAutoscaler(conn, containers)
+	assert.Equal(t, 2, getGroup(t, conn).Size)
+	assert.Len(t, conn.SelectFromMachine(nil), 3)
+
+	// Without any load, the group shrinks to its minimum, but no further.
+	runAutoscaler(conn, nil)
+	assert.Equal(t, 1, getGroup(t, conn).Size)
+	now = func() time.Time { return start.Add(2 * time.Hour) }
+	runAutoscaler(conn, nil)
+	assert.Equal(t, 1, getGroup(t, conn).Size)
+	assert.Len(t, conn.SelectFromMachine(nil), 2)
+}
+
+func TestAutoscaleQueryError(t *testing.T) {
+	queryContainers = func(_ []db.Machine, _ connection.Credentials) (
+		[]db.Container, error) {
+		return nil, errors.New("no leader")
+	}
+	defer func() { queryContainers = queryContainersImpl }()
+
+	conn := db.New()
+	addGroup(conn, "group", 0, 2, 1)
+	addWorker(conn, "group", "1.1.1.1", db.Connected)
+	autoscaleOnce(conn, nil)
+	assert.Equal(t, 1, getGroup(t, conn).Size)
+}
+
+func runAutoscaler(conn db.Conn, containers []db.Container) {
+	queryContainers = func(_ []db.Machine, _ connection.Credentials) (
+		[]db.Container, error) {
+		return containers, nil
+	}
+	defer func() { queryContainers = queryContainersImpl }()
+	autoscaleOnce(conn, nil)
+}
+
+func addGroup(conn db.Conn, id string, min, max, size int) {
+	conn.Txn(db.ScalingGroupTable).Run(func(view db.Database) error {
+		group := view.InsertScalingGroup()
+		group.BlueprintID = id
+		group.Min = min
+		group.Max = max
+		group.Size = size
+		view.Commit(group)
+		return nil
+	})
+}
+
+func addWorker(conn db.Conn, id, privateIP, status string) {
+	conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+		m := view.InsertMachine()
+		m.BlueprintID = id
+		m.Role = db.Worker
+		m.PrivateIP = privateIP
+		m.Status = status
+		view.Commit(m)
+		return nil
+	})
+}
+
+func getGroup(t *testing.T, conn db.Conn) db.ScalingGroup {
+	groups := conn.SelectFromScalingGroup(nil)
+	assert.Len(t, groups, 1)
+	return groups[0]
+}
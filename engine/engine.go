@@ -1,10 +1,13 @@
 package engine
 
 import (
+	"time"
+
 	"github.com/quilt/quilt/blueprint"
 	"github.com/quilt/quilt/cloud"
 	"github.com/quilt/quilt/counter"
 	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/health"
 	"github.com/quilt/quilt/join"
 	"github.com/quilt/quilt/util"
 
@@ -16,13 +19,20 @@ var defaultDiskSize = 32
 
 var c = counter.New("Engine")
 
+// The engine runs at least every 30 seconds, so it's stuck if it hasn't reported in
+// a few minutes.
+var engineHealth = health.New("engine", 2*time.Minute)
+
 // Run updates the database in response to changes in the blueprint table.
 func Run(conn db.Conn, adminKey string) {
-	for range conn.TriggerTick(30, db.BlueprintTable, db.MachineTable).C {
-		conn.Txn(db.BlueprintTable, db.MachineTable).Run(
+	for range conn.TriggerTick(30, db.BlueprintTable, db.MachineTable,
+		db.ScalingGroupTable).C {
+		conn.Txn(db.BlueprintTable, db.MachineTable,
+			db.ScalingGroupTable).Run(
 			func(view db.Database) error {
 				return updateTxn(view, adminKey)
 			})
+		engineHealth.Report(nil)
 	}
 }
 
@@ -92,6 +102,8 @@ func toDBMachine(machines []blueprint.Machine, maxPrice float64,
 		m.BlueprintID = blueprintm.ID
 		m.Region = blueprintm.Region
 		m.FloatingIP = blueprintm.FloatingIP
+		m.Image = blueprintm.Image
+		m.BootScript = blueprintm.BootScript
 		dbMachines = append(dbMachines, cloud.DefaultRegion(m))
 	}
 
@@ -110,6 +122,8 @@ func machineTxn(view db.Database, bp blueprint.Blueprint, adminKey string) {
 	// XXX: How best to deal with machines that don't specify enough information?
 	maxPrice := bp.MaxPrice
 	blueprintMachines := toDBMachine(bp.Machines, maxPrice, adminKey)
+	blueprintMachines = expandScalingGroups(blueprintMachines,
+		updateScalingGroups(view, bp.Machines))
 
 	dbMachines := view.SelectFromMachine(nil)
 
@@ -136,6 +150,11 @@ func machineTxn(view db.Database, bp blueprint.Blueprint, adminKey string) {
 			return -1
 		case dbMachine.DiskSize != blueprintMachine.DiskSize:
 			return -1
+		case dbMachine.Image != blueprintMachine.Image ||
+			dbMachine.BootScript != blueprintMachine.BootScript:
+			// Machines can't be reconfigured after they boot, so they
+			// must be replaced.
+			return -1
 		case dbMachine.PrivateIP == "":
 			return 2
 		case dbMachine.PublicIP == "":
@@ -172,6 +191,88 @@ func machineTxn(view db.Database, bp blueprint.Blueprint, adminKey string) {
 		dbMachine.SSHKeys = blueprintMachine.SSHKeys
 		dbMachine.FloatingIP = blueprintMachine.FloatingIP
 		dbMachine.Preemptible = blueprintMachine.Preemptible
+		dbMachine.Image = blueprintMachine.Image
+		dbMachine.BootScript = blueprintMachine.BootScript
 		view.Commit(dbMachine)
 	}
 }
+
+// updateScalingGroups creates a ScalingGroup for each autoscaled machine in the
+// blueprint, and removes the groups whose machines are no longer in the blueprint.
+// It returns how many machines each group should boot, keyed by BlueprintID.
+func updateScalingGroups(view db.Database,
+	machines []blueprint.Machine) map[string]int {
+
+	groups := map[string]db.ScalingGroup{}
+	for _, group := range view.SelectFromScalingGroup(nil) {
+		groups[group.BlueprintID] = group
+	}
+
+	sizes := map[string]int{}
+	for _, m := range machines {
+		if m.Autoscale == nil {
+			continue
+		}
+
+		if role, _ := db.ParseRole(m.Role); role != db.Worker {
+			log.WithField("machine", m.ID).Error(
+				"Only workers may autoscale.")
+			continue
+		}
+
+		// Every machine in the group would claim the same IP.
+		if m.FloatingIP != "" {
+			log.WithField("machine", m.ID).Error(
+				"Autoscaled machines may not have a floating IP.")
+			continue
+		}
+
+		scale := *m.Autoscale
+		if scale.Max <= 0 || scale.Max < scale.Min || scale.Min < 0 {
+			log.WithField("autoscale", scale).Error(
+				"Invalid autoscaling bounds.")
+			continue
+		}
+
+		group, ok := groups[m.ID]
+		if !ok {
+			group = view.InsertScalingGroup()
+			group.BlueprintID = m.ID
+			group.Size = scale.Min
+		}
+		delete(groups, m.ID)
+
+		group.Min = scale.Min
+		group.Max = scale.Max
+		if group.Size < group.Min {
+			group.Size = group.Min
+		} else if group.Size > group.Max {
+			group.Size = group.Max
+		}
+		view.Commit(group)
+
+		sizes[m.ID] = group.Size
+	}
+
+	for _, group := range groups {
+		view.Remove(group)
+	}
+	return sizes
+}
+
+// expandScalingGroups replaces each machine that belongs to a scaling group with
+// as many copies as the group should currently boot.
+func expandScalingGroups(machines []db.Machine, sizes map[string]int) []db.Machine {
+	var expanded []db.Machine
+	for _, m := range machines {
+		n, ok := sizes[m.BlueprintID]
+		if !ok {
+			n = 1
+		}
+
+		for i := 0; i < n; i++ {
+			expanded = append(expanded, m)
+		}
+	}
+	return expanded
+}
@@ -208,6 +208,36 @@ func TestAdminKey(t *testing.T) {
 	}
 }
 
+func TestImageAndBootScript(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+
+	bp := blueprint.Blueprint{
+		Machines: []blueprint.Machine{
+			{Provider: "Amazon", Size: "m4.large", Role: "Master", ID: "1"},
+			{Provider: "Amazon", Size: "m4.large", Role: "Worker", ID: "2",
+				Image: "ami-1234", BootScript: "echo hello"},
+		},
+	}
+	updateBlueprint(t, conn, bp, "")
+
+	_, workers := selectMachines(conn)
+	assert.Len(t, workers, 1)
+	assert.Equal(t, "ami-1234", workers[0].Image)
+	assert.Equal(t, "echo hello", workers[0].BootScript)
+	origID := workers[0].ID
+
+	// Changing the boot script replaces the machine.
+	bp.Machines[1].BootScript = "echo goodbye"
+	updateBlueprint(t, conn, bp, "")
+
+	_, workers = selectMachines(conn)
+	assert.Len(t, workers, 1)
+	assert.Equal(t, "echo goodbye", workers[0].BootScript)
+	assert.NotEqual(t, origID, workers[0].ID)
+}
+
 func TestSort(t *testing.T) {
 	conn := db.New()
 
@@ -261,6 +291,77 @@ func TestSort(t *testing.T) {
 	})
 }
 
+func TestScalingGroups(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+
+	bp := blueprint.Blueprint{
+		Machines: []blueprint.Machine{
+			{Provider: "Amazon", Size: "m4.large", Role: "Master", ID: "1"},
+			{Provider: "Amazon", Size: "m4.large", Role: "Worker", ID: "2",
+				Autoscale: &blueprint.Autoscale{Min: 2, Max: 4}},
+		},
+	}
+	updateBlueprint(t, conn, bp, "")
+
+	// New groups start at their minimum size.
+	groups := conn.SelectFromScalingGroup(nil)
+	assert.Len(t, groups, 1)
+	assert.Equal(t, "2", groups[0].BlueprintID)
+	assert.Equal(t, 2, groups[0].Size)
+
+	masters, workers := selectMachines(conn)
+	assert.Len(t, masters, 1)
+	assert.Len(t, workers, 2)
+	for _, w := range workers {
+		assert.Equal(t, "2", w.BlueprintID)
+	}
+
+	// The engine boots as many workers as the group wants.
+	conn.Txn(db.ScalingGroupTable).Run(func(view db.Database) error {
+		group := view.SelectFromScalingGroup(nil)[0]
+		group.Size = 3
+		view.Commit(group)
+		return nil
+	})
+	updateBlueprint(t, conn, bp, "")
+	_, workers = selectMachines(conn)
+	assert.Len(t, workers, 3)
+
+	// Lowering the maximum shrinks the group.
+	bp.Machines[1].Autoscale = &blueprint.Autoscale{Min: 1, Max: 2}
+	updateBlueprint(t, conn, bp, "")
+	groups = conn.SelectFromScalingGroup(nil)
+	assert.Len(t, groups, 1)
+	assert.Equal(t, 2, groups[0].Size)
+	_, workers = selectMachines(conn)
+	assert.Len(t, workers, 2)
+
+	// Masters can't autoscale.
+	bp.Machines[0].Autoscale = &blueprint.Autoscale{Min: 1, Max: 2}
+	updateBlueprint(t, conn, bp, "")
+	assert.Len(t, conn.SelectFromScalingGroup(nil), 1)
+	masters, _ = selectMachines(conn)
+	assert.Len(t, masters, 1)
+
+	// Nor can machines with a floating IP, as every copy would claim it.
+	bp.Machines[1].FloatingIP = "8.8.8.8"
+	updateBlueprint(t, conn, bp, "")
+	assert.Empty(t, conn.SelectFromScalingGroup(nil))
+	_, workers = selectMachines(conn)
+	assert.Len(t, workers, 1)
+	assert.Equal(t, "8.8.8.8", workers[0].FloatingIP)
+	bp.Machines[1].FloatingIP = ""
+
+	// Groups are removed along with their machine.
+	bp.Machines[1].Autoscale = nil
+	updateBlueprint(t, conn, bp, "")
+	assert.Empty(t, conn.SelectFromScalingGroup(nil))
+	_, workers = selectMachines(conn)
+	assert.Len(t, workers, 1)
+}
+
 func selectMachines(conn db.Conn) (masters, workers []db.Machine) {
 	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
 		masters = view.SelectFromMachine(func(m db.Machine) bool {
@@ -0,0 +1,111 @@
+// Package health tracks the health of the subsystems running in a Quilt process.
+// Long running loops, such as the engine, report the result of each iteration,
+// and are considered unhealthy if they stop reporting. Other subsystems, such as
+// the Docker daemon, register functions that check their current state.
+package health
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/quilt/quilt/api/pb"
+)
+
+// A Check tracks the health of a subsystem that reports its own status.
+type Check struct {
+	name string
+	ttl  time.Duration
+}
+
+type report struct {
+	ttl  time.Duration
+	time time.Time
+	err  error
+}
+
+var lock sync.Mutex
+var reports = map[string]report{}
+var checkers = map[string]func() error{}
+
+// New creates a Check with the given name. The subsystem is unhealthy if it
+// doesn't report for longer than `ttl`.
+func New(name string, ttl time.Duration) Check {
+	return Check{name, ttl}
+}
+
+// Report records the result of the subsystem's latest run. A nil `err` means
+// that the subsystem is healthy.
+func (c Check) Report(err error) {
+	lock.Lock()
+	reports[c.name] = report{ttl: c.ttl, time: now(), err: err}
+	lock.Unlock()
+}
+
+// Remove stops tracking the check, e.g. because its subsystem was shut down.
+func (c Check) Remove() {
+	lock.Lock()
+	delete(reports, c.name)
+	lock.Unlock()
+}
+
+// Register adds a subsystem whose health is checked by calling `check` each time
+// the health of the process is queried.
+func Register(name string, check func() error) {
+	lock.Lock()
+	checkers[name] = check
+	lock.Unlock()
+}
+
+// Dump returns the health of every subsystem, sorted by name.
+func Dump() []*pb.HealthCheck {
+	lock.Lock()
+	var result []*pb.HealthCheck
+	for name, r := range reports {
+		err := r.err
+		if age := now().Sub(r.time); age > r.ttl {
+			err = fmt.Errorf("no report in %s", age/time.Second*time.Second)
+		}
+		result = append(result, newHealthCheck(name, err))
+	}
+
+	checks := map[string]func() error{}
+	for name, check := range checkers {
+		checks[name] = check
+	}
+	lock.Unlock()
+
+	// Checkers may be slow, so run them without holding the lock.
+	for name, check := range checks {
+		result = append(result, newHealthCheck(name, check()))
+	}
+
+	sort.Sort(byName(result))
+	return result
+}
+
+type byName []*pb.HealthCheck
+
+func (checks byName) Len() int {
+	return len(checks)
+}
+
+func (checks byName) Less(i, j int) bool {
+	return checks[i].Name < checks[j].Name
+}
+
+func (checks byName) Swap(i, j int) {
+	checks[i], checks[j] = checks[j], checks[i]
+}
+
+func newHealthCheck(name string, err error) *pb.HealthCheck {
+	check := &pb.HealthCheck{Name: name, Healthy: err == nil}
+	if err != nil {
+		check.Detail = err.Error()
+	}
+	return check
+}
+
+// Stored in a variable so that it may be mocked out in unit tests.
+var now = time.Now
@@ -0,0 +1,41 @@
+package health
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/quilt/quilt/api/pb"
+)
+
+func TestHealth(t *testing.T) {
+	defer func() { now = time.Now }()
+
+	start := time.Now()
+	now = func() time.Time { return start }
+
+	engine := New("engine", time.Minute)
+	engine.Report(nil)
+
+	cloud := New("cloud", time.Minute)
+	cloud.Report(errors.New("list failed"))
+
+	Register("docker", func() error { return nil })
+
+	assert.Equal(t, []*pb.HealthCheck{
+		{Name: "cloud", Healthy: false, Detail: "list failed"},
+		{Name: "docker", Healthy: true},
+		{Name: "engine", Healthy: true},
+	}, Dump())
+
+	// Subsystems that stop reporting are unhealthy.
+	now = func() time.Time { return start.Add(90*time.Second + time.Millisecond) }
+	cloud.Report(nil)
+	cloud.Remove()
+	assert.Equal(t, []*pb.HealthCheck{
+		{Name: "docker", Healthy: true},
+		{Name: "engine", Healthy: false, Detail: "no report in 1m30s"},
+	}, Dump())
+}
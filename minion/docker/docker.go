@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/quilt/quilt/minion/supervisor/images"
@@ -43,6 +44,13 @@ type Container struct {
 	Created time.Time
 }
 
+// An Exit describes a container that stopped running.
+type Exit struct {
+	ID     string
+	Name   string
+	Reason string
+}
+
 // ContainerSlice is an alias for []Container to allow for joins
 type ContainerSlice []Container
 
@@ -74,9 +82,15 @@ type RunOptions struct {
 	DNSSearch   []string
 
 	PidMode     string
-	Privileged  bool
 	VolumesFrom []string
 
+	Privileged     bool
+	CapAdd         []string
+	CapDrop        []string
+	User           string
+	ReadOnlyRootfs bool
+	SecurityOpt    []string
+
 	//Cadvisor Specific
 	Binds []string
 	ExternalPort string
@@ -89,6 +103,7 @@ type client interface {
 	StartContainer(id string, hostConfig *dkc.HostConfig) error
 	UploadToContainer(id string, opts dkc.UploadToContainerOptions) error
 	RemoveContainer(opts dkc.RemoveContainerOptions) error
+	KillContainer(opts dkc.KillContainerOptions) error
 	BuildImage(opts dkc.BuildImageOptions) error
 	PullImage(opts dkc.PullImageOptions, auth dkc.AuthConfiguration) error
 	PushImage(opts dkc.PushImageOptions, auth dkc.AuthConfiguration) error
@@ -98,6 +113,7 @@ type client interface {
 	CreateContainer(dkc.CreateContainerOptions) (*dkc.Container, error)
 	CreateNetwork(dkc.CreateNetworkOptions) (*dkc.Network, error)
 	ListNetworks() ([]dkc.Network, error)
+	AddEventListener(listener chan<- *dkc.APIEvents) error
 }
 
 var c = counter.New("Docker")
@@ -136,7 +152,6 @@ func (dk Client) Run(opts RunOptions) (string, error) {
 	   exposedPorts = map[dkc.Port]struct{}{"50000/tcp": {}}
 	}
 
-	network_admin_opt := []string{"NET_ADMIN"}
 	hc := &dkc.HostConfig{
 		NetworkMode: opts.NetworkMode,
 		PidMode:     opts.PidMode,
@@ -147,7 +162,11 @@ func (dk Client) Run(opts RunOptions) (string, error) {
 		PublishAllPorts: opts.PublishAllPorts,
 		PortBindings: portBinding,
 		Binds: opts.Binds,
-		CapAdd: network_admin_opt,
+
+		CapAdd:         opts.CapAdd,
+		CapDrop:        opts.CapDrop,
+		ReadonlyRootfs: opts.ReadOnlyRootfs,
+		SecurityOpt:    opts.SecurityOpt,
 	}
 
 	var nc *dkc.NetworkingConfig
@@ -163,8 +182,8 @@ func (dk Client) Run(opts RunOptions) (string, error) {
 		}
 	}
 
-	id, err := dk.create(opts.Name, opts.Image, opts.Hostname, opts.Args, exposedPorts, opts.Labels, env,
-		opts.FilepathToContent, hc, nc)
+	id, err := dk.create(opts.Name, opts.Image, opts.Hostname, opts.User, opts.Args,
+		exposedPorts, opts.Labels, env, opts.FilepathToContent, hc, nc)
 	if err != nil {
 		return "", err
 	}
@@ -225,6 +244,37 @@ func (dk Client) RemoveID(id string) error {
 	return nil
 }
 
+// WriteToContainer writes `files`, a map from absolute path to contents, into the
+// container with the given ID.
+func (dk Client) WriteToContainer(id string, files map[string]string) error {
+	c.Inc("Write To Container")
+	for path, content := range files {
+		relPath, _ := filepath.Rel("/", path)
+		tarBuf, err := util.ToTar(relPath, 0600, content)
+		if err != nil {
+			return err
+		}
+
+		err = dk.UploadToContainer(id, dkc.UploadToContainerOptions{
+			InputStream: tarBuf,
+			Path:        "/",
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Signal sends `sig` to the main process of the container with the given ID.
+func (dk Client) Signal(id string, sig syscall.Signal) error {
+	c.Inc("Signal")
+	return dk.KillContainer(dkc.KillContainerOptions{
+		ID:     id,
+		Signal: dkc.Signal(sig),
+	})
+}
+
 // Build builds an image with the given name and Dockerfile, and returns the
 // ID of the resulting image.
 func (dk Client) Build(name, dockerfile string, useCache bool) (id string, err error) {
@@ -405,7 +455,54 @@ func (dk Client) IsRunning(name string) (bool, error) {
 	return len(containers) != 0, nil
 }
 
-func (dk Client) create(name, image string, hostname string, args []string, exposedPorts map[dkc.Port]struct{},
+// WatchExits returns a channel that receives an Exit each time a container stops.
+func (dk Client) WatchExits() (<-chan Exit, error) {
+	events := make(chan *dkc.APIEvents, 32)
+	if err := dk.AddEventListener(events); err != nil {
+		return nil, err
+	}
+
+	exits := make(chan Exit, 32)
+	go func() {
+		defer close(exits)
+		for event := range events {
+			if event.Type == "container" && event.Action == "die" {
+				c.Inc("Exit")
+				exits <- dk.getExit(event)
+			}
+		}
+	}()
+	return exits, nil
+}
+
+func (dk Client) getExit(event *dkc.APIEvents) Exit {
+	exit := Exit{
+		ID:     event.Actor.ID,
+		Name:   event.Actor.Attributes["name"],
+		Reason: fmt.Sprintf("exit code %s", event.Actor.Attributes["exitCode"]),
+	}
+
+	// Older Docker daemons only report the container ID, and the exit code
+	// doesn't explain errors such as running out of memory.
+	container, err := dk.InspectContainer(exit.ID)
+	if err != nil {
+		return exit
+	}
+
+	exit.Name = strings.TrimPrefix(container.Name, "/")
+	switch {
+	case container.State.OOMKilled:
+		exit.Reason = "out of memory"
+	case container.State.Error != "":
+		exit.Reason = container.State.Error
+	default:
+		exit.Reason = fmt.Sprintf("exit code %d", container.State.ExitCode)
+	}
+	return exit
+}
+
+func (dk Client) create(name, image, hostname, user string, args []string,
+	exposedPorts map[dkc.Port]struct{},
 	labels map[string]string, env []string, filepathToContent map[string]string,
 	hc *dkc.HostConfig, nc *dkc.NetworkingConfig) (string, error) {
 
@@ -418,6 +515,7 @@ func (dk Client) create(name, image string, hostname string, args []string, expo
 		Config: &dkc.Config{
 			Image:  string(image),
 			Hostname: hostname,
+			User:     user,
 			Cmd:    args,
 			Labels: labels,
 			Env:    env,
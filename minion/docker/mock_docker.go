@@ -39,10 +39,12 @@ type MockClient struct {
 	Containers map[string]mockContainer
 	Networks   map[string]*dkc.Network
 	Uploads    map[UploadToContainerOptions]struct{}
+	Signals    map[string][]dkc.Signal
 	Images     map[string]*dkc.Image
 
 	createdExecs map[string]dkc.CreateExecOptions
 	Executions   map[string][]string
+	listeners    map[chan<- *dkc.APIEvents]struct{}
 
 	CreateError           bool
 	CreateNetworkError    bool
@@ -50,6 +52,7 @@ type MockClient struct {
 	CreateExecError       bool
 	InspectContainerError bool
 	InspectImageError     bool
+	KillError             bool
 	ListError             bool
 	BuildError            bool
 	PullError             bool
@@ -71,9 +74,11 @@ func NewMock() (*MockClient, Client) {
 		Containers:   map[string]mockContainer{},
 		Networks:     map[string]*dkc.Network{},
 		Uploads:      map[UploadToContainerOptions]struct{}{},
+		Signals:      map[string][]dkc.Signal{},
 		Images:       map[string]*dkc.Image{},
 		createdExecs: map[string]dkc.CreateExecOptions{},
 		Executions:   map[string][]string{},
+		listeners:    map[chan<- *dkc.APIEvents]struct{}{},
 	}
 	return md, Client{md, &sync.Mutex{}, map[string]*cacheEntry{}}
 }
@@ -94,13 +99,36 @@ func (dk MockClient) StartContainer(id string, hostConfig *dkc.HostConfig) error
 	return nil
 }
 
-// StopContainer stops the given docker container.
+// StopContainer stops the given docker container, and notifies the event
+// listeners that it exited with code 1.
 func (dk MockClient) StopContainer(id string) {
 	dk.Lock()
 	defer dk.Unlock()
-	container := dk.Containers[id]
+	container, ok := dk.Containers[id]
+	if !ok {
+		return
+	}
+
 	container.Running = false
+	container.State.ExitCode = 1
 	dk.Containers[id] = container
+
+	for listener := range dk.listeners {
+		listener <- &dkc.APIEvents{
+			Type:   "container",
+			Action: "die",
+			Actor: dkc.APIActor{ID: id, Attributes: map[string]string{
+				"name": container.Name, "exitCode": "1"}},
+		}
+	}
+}
+
+// AddEventListener registers a channel to receive the mock client's events.
+func (dk MockClient) AddEventListener(listener chan<- *dkc.APIEvents) error {
+	dk.Lock()
+	defer dk.Unlock()
+	dk.listeners[listener] = struct{}{}
+	return nil
 }
 
 // RemoveContainer removes the given docker container.
@@ -129,6 +157,23 @@ func readDockerfile(inp io.Reader) ([]byte, error) {
 	}
 }
 
+// KillContainer records the signal sent to the given docker container.
+func (dk MockClient) KillContainer(opts dkc.KillContainerOptions) error {
+	dk.Lock()
+	defer dk.Unlock()
+
+	if dk.KillError {
+		return errors.New("kill error")
+	}
+
+	if _, ok := dk.Containers[opts.ID]; !ok {
+		return ErrNoSuchContainer
+	}
+
+	dk.Signals[opts.ID] = append(dk.Signals[opts.ID], opts.Signal)
+	return nil
+}
+
 // BuildImage builds the requested image.
 func (dk MockClient) BuildImage(opts dkc.BuildImageOptions) error {
 	dk.Lock()
@@ -19,13 +19,17 @@ func updatePolicy(view db.Database, bp string) {
 	updateImages(view, compiled)
 	updateContainers(view, compiled)
 	updateLoadBalancers(view, compiled)
+	updateIngresses(view, compiled)
 	updateConnections(view, compiled)
 	updatePlacements(view, compiled)
 }
 
 // `portPlacements` creates exclusive placement rules such that no two containers
-// listening on the same public port get placed on the same machine.
-func portPlacements(connections []db.Connection, containers []db.Container) (
+// listening on the same public port get placed on the same machine.  Public load
+// balancers and ingresses accept traffic on every worker, so their backends can
+// be placed anywhere.
+func portPlacements(connections []db.Connection, containers []db.Container,
+	loadBalancers []db.LoadBalancer, ingresses []db.Ingress) (
 	placements []db.Placement) {
 
 	hostnameToContainer := map[string]db.Container{}
@@ -33,12 +37,24 @@ func portPlacements(connections []db.Connection, containers []db.Container) (
 		hostnameToContainer[c.Hostname] = c
 	}
 
+	lbNames := map[string]struct{}{}
+	for _, lb := range loadBalancers {
+		lbNames[lb.Name] = struct{}{}
+	}
+	for _, ing := range ingresses {
+		lbNames[ing.Name] = struct{}{}
+	}
+
 	ports := make(map[int][]string)
 	for _, conn := range connections {
 		if conn.From != blueprint.PublicInternetLabel {
 			continue
 		}
 
+		if _, ok := lbNames[conn.To]; ok {
+			continue
+		}
+
 		toContainer, ok := hostnameToContainer[conn.To]
 		if !ok {
 			log.WithField("connection", conn).
@@ -81,7 +97,10 @@ func portPlacements(connections []db.Connection, containers []db.Container) (
 func updatePlacements(view db.Database, bp blueprint.Blueprint) {
 	connections := view.SelectFromConnection(nil)
 	containers := view.SelectFromContainer(nil)
-	placements := db.PlacementSlice(portPlacements(connections, containers))
+	loadBalancers := view.SelectFromLoadBalancer(nil)
+	ingresses := view.SelectFromIngress(nil)
+	placements := db.PlacementSlice(portPlacements(connections, containers,
+		loadBalancers, ingresses))
 	for _, sp := range bp.Placements {
 		placements = append(placements, db.Placement{
 			TargetContainer: sp.TargetContainerID,
@@ -122,6 +141,7 @@ func updateLoadBalancers(view db.Database, bp blueprint.Blueprint) {
 		bpLoadBalancers = append(bpLoadBalancers, db.LoadBalancer{
 			Name:      lb.Name,
 			Hostnames: lb.Hostnames,
+			Probe:     lb.Probe,
 		})
 	}
 
@@ -149,10 +169,47 @@ func updateLoadBalancers(view db.Database, bp blueprint.Blueprint) {
 		// whatever IP the load balancer might have already been allocated.
 		dbLoadBalancer.Name = bpLoadBalancer.Name
 		dbLoadBalancer.Hostnames = bpLoadBalancer.Hostnames
+		dbLoadBalancer.Probe = bpLoadBalancer.Probe
 		view.Commit(dbLoadBalancer)
 	}
 }
 
+func updateIngresses(view db.Database, bp blueprint.Blueprint) {
+	key := func(intf interface{}) interface{} {
+		switch ing := intf.(type) {
+		case blueprint.Ingress:
+			return ing.Name
+		case db.Ingress:
+			return ing.Name
+		}
+		panic("unreachable")
+	}
+
+	dbIngresses := db.IngressSlice(view.SelectFromIngress(nil))
+	pairs, toAdd, toRemove := join.HashJoin(blueprintIngressSlice(bp.Ingresses),
+		dbIngresses, key, key)
+
+	for _, intf := range toRemove {
+		view.Remove(intf.(db.Ingress))
+	}
+
+	for _, intf := range toAdd {
+		pairs = append(pairs, join.Pair{L: intf, R: view.InsertIngress()})
+	}
+
+	for _, pair := range pairs {
+		bpIngress := pair.L.(blueprint.Ingress)
+		dbIngress := pair.R.(db.Ingress)
+
+		dbIngress.Name = bpIngress.Name
+		dbIngress.Routes = bpIngress.Routes
+		dbIngress.Certificates = bpIngress.Certificates
+		dbIngress.HTTPPort = bpIngress.HTTPPort
+		dbIngress.HTTPSPort = bpIngress.HTTPSPort
+		view.Commit(dbIngress)
+	}
+}
+
 func updateConnections(view db.Database, bp blueprint.Blueprint) {
 	scs := blueprint.ConnectionSlice(bp.Connections)
 
@@ -161,6 +218,8 @@ func updateConnections(view db.Database, bp blueprint.Blueprint) {
 	// balanced containers. This means allowing connections only to the load
 	// balancer IP address is insufficient -- the container must also be able
 	// to communicate directly with the containers behind the load balancer.
+	// Connections from the public internet are instead forwarded to the
+	// containers by the workers, so they aren't expanded.
 	loadBalancers := map[string]blueprint.LoadBalancer{}
 	for _, lb := range bp.LoadBalancers {
 		loadBalancers[lb.Name] = lb
@@ -168,7 +227,7 @@ func updateConnections(view db.Database, bp blueprint.Blueprint) {
 
 	for _, c := range scs {
 		lb, ok := loadBalancers[c.To]
-		if !ok {
+		if !ok || c.From == blueprint.PublicInternetLabel {
 			continue
 		}
 
@@ -185,10 +244,11 @@ func updateConnections(view db.Database, bp blueprint.Blueprint) {
 	dbcKey := func(val interface{}) interface{} {
 		c := val.(db.Connection)
 		return blueprint.Connection{
-			From:    c.From,
-			To:      c.To,
-			MinPort: c.MinPort,
-			MaxPort: c.MaxPort,
+			From:      c.From,
+			To:        c.To,
+			MinPort:   c.MinPort,
+			MaxPort:   c.MaxPort,
+			Bandwidth: c.Bandwidth,
 		}
 	}
 
@@ -213,6 +273,7 @@ func updateConnections(view db.Database, bp blueprint.Blueprint) {
 		dbc.To = blueprintc.To
 		dbc.MinPort = blueprintc.MinPort
 		dbc.MaxPort = blueprintc.MaxPort
+		dbc.Bandwidth = blueprintc.Bandwidth
 		view.Commit(dbc)
 	}
 }
@@ -228,6 +289,9 @@ func queryContainers(bp blueprint.Blueprint) []db.Container {
 			Image:             c.Image.Name,
 			Dockerfile:        c.Image.Dockerfile,
 			Hostname:          c.Hostname,
+			Security:          c.Security,
+			Bandwidth:         c.Bandwidth,
+			TrafficClass:      c.TrafficClass,
 		}
 	}
 
@@ -266,6 +330,9 @@ func updateContainers(view db.Database, bp blueprint.Blueprint) {
 		dbc.FilepathToContent = newc.FilepathToContent
 		dbc.BlueprintID = newc.BlueprintID
 		dbc.Hostname = newc.Hostname
+		dbc.Security = newc.Security
+		dbc.Bandwidth = newc.Bandwidth
+		dbc.TrafficClass = newc.TrafficClass
 		view.Commit(dbc)
 	}
 }
@@ -317,3 +384,13 @@ func (slc blueprintImageSlice) Get(ii int) interface{} {
 func (slc blueprintImageSlice) Len() int {
 	return len(slc)
 }
+
+type blueprintIngressSlice []blueprint.Ingress
+
+func (slc blueprintIngressSlice) Get(ii int) interface{} {
+	return slc[ii]
+}
+
+func (slc blueprintIngressSlice) Len() int {
+	return len(slc)
+}
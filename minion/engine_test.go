@@ -295,6 +295,26 @@ func TestConnectionTxn(t *testing.T) {
 	testConnectionTxn(t, conn, bp)
 	assert.False(t, fired(trigg))
 
+	bp.Connections[0].Bandwidth = 1000000
+	testConnectionTxn(t, conn, bp)
+	assert.True(t, fired(trigg))
+
+	testConnectionTxn(t, conn, bp)
+	assert.False(t, fired(trigg))
+
+	// Public connections to load balancers aren't expanded to the containers
+	// behind them.
+	bp.LoadBalancers = []blueprint.LoadBalancer{
+		{Name: "lb", Hostnames: []string{"a"}},
+	}
+	bp.Connections = []blueprint.Connection{
+		{From: blueprint.PublicInternetLabel, To: "lb", MinPort: 80,
+			MaxPort: 80},
+	}
+	testConnectionTxn(t, conn, bp)
+	assert.True(t, fired(trigg))
+
+	bp.LoadBalancers = nil
 	bp.Connections = nil
 	testConnectionTxn(t, conn, bp)
 	assert.True(t, fired(trigg))
@@ -316,7 +336,7 @@ func testConnectionTxn(t *testing.T, conn db.Conn, bp blueprint.Blueprint) {
 		found := false
 		for i, c := range connections {
 			if e.From == c.From && e.To == c.To && e.MinPort == c.MinPort &&
-				e.MaxPort == c.MaxPort {
+				e.MaxPort == c.MaxPort && e.Bandwidth == c.Bandwidth {
 				connections = append(
 					connections[:i], connections[i+1:]...)
 				found = true
@@ -441,6 +461,33 @@ func TestPlacementTxn(t *testing.T) {
 			Exclusive:       true,
 		},
 	)
+
+	// Backends of public load balancers can share machines.
+	bp.LoadBalancers = []blueprint.LoadBalancer{
+		{Name: "lb", Hostnames: []string{fooHostname, barHostname}},
+	}
+	bp.Connections = []blueprint.Connection{
+		{From: blueprint.PublicInternetLabel, To: "lb", MinPort: 80,
+			MaxPort: 80},
+		{From: blueprint.PublicInternetLabel, To: bazHostname, MinPort: 80,
+			MaxPort: 80},
+	}
+	checkPlacement(bp)
+
+	// So can the containers behind ingresses.
+	bp.LoadBalancers = nil
+	bp.Ingresses = []blueprint.Ingress{{
+		Name:     "ingress",
+		Routes:   []blueprint.IngressRoute{{To: fooHostname, Port: 8080}},
+		HTTPPort: 80,
+	}}
+	bp.Connections = []blueprint.Connection{
+		{From: blueprint.PublicInternetLabel, To: "ingress", MinPort: 80,
+			MaxPort: 80},
+		{From: blueprint.PublicInternetLabel, To: bazHostname, MinPort: 80,
+			MaxPort: 80},
+	}
+	checkPlacement(bp)
 }
 
 func checkImage(t *testing.T, conn db.Conn, bp blueprint.Blueprint, exp ...db.Image) {
@@ -624,3 +671,40 @@ func TestLoadBalancerTxn(t *testing.T) {
 		Hostnames: hostnamesB,
 	})
 }
+
+func TestIngressTxn(t *testing.T) {
+	t.Parallel()
+	conn := db.New()
+
+	check := func(bp blueprint.Blueprint, exp ...db.Ingress) {
+		var ingresses []db.Ingress
+		conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+			updatePolicy(view, bp.String())
+			ingresses = view.SelectFromIngress(nil)
+			return nil
+		})
+
+		for i := range ingresses {
+			ingresses[i].ID = 0
+		}
+		assert.Equal(t, exp, ingresses)
+	}
+
+	routes := []blueprint.IngressRoute{
+		{Host: "example.com", To: "web", Port: 80},
+	}
+	check(blueprint.Blueprint{Ingresses: []blueprint.Ingress{
+		{Name: "a", Routes: routes, HTTPPort: 80},
+	}}, db.Ingress{Name: "a", Routes: routes, HTTPPort: 80})
+
+	certs := []blueprint.IngressCertificate{
+		{Host: "example.com", CertFile: "a.crt", KeyFile: "a.key"},
+	}
+	check(blueprint.Blueprint{Ingresses: []blueprint.Ingress{
+		{Name: "a", Routes: routes, Certificates: certs, HTTPPort: 80,
+			HTTPSPort: 443},
+	}}, db.Ingress{Name: "a", Routes: routes, Certificates: certs,
+		HTTPPort: 80, HTTPSPort: 443})
+
+	check(blueprint.Blueprint{})
+}
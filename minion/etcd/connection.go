@@ -3,6 +3,7 @@ package etcd
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/quilt/quilt/db"
@@ -53,8 +54,13 @@ func runConnectionOnce(conn db.Conn, store Store) error {
 func joinConnections(view db.Database, etcdConns []db.Connection) {
 	key := func(iface interface{}) interface{} {
 		conn := iface.(db.Connection)
-		conn.ID = 0
-		return conn
+		return struct {
+			From, To         string
+			MinPort, MaxPort int
+			Bandwidth        int
+			ResolvedIPs      string
+		}{conn.From, conn.To, conn.MinPort, conn.MaxPort, conn.Bandwidth,
+			strings.Join(conn.ResolvedIPs, " ")}
 	}
 
 	_, connIfaces, etcdConnIfaces := join.HashJoin(
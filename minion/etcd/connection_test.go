@@ -1,6 +1,7 @@
 package etcd
 
 import (
+	"sort"
 	"testing"
 
 	"github.com/quilt/quilt/db"
@@ -30,6 +31,14 @@ func TestRunConnectionOnce(t *testing.T) {
 		conn.MinPort = 80
 		conn.MaxPort = 8080
 		view.Commit(conn)
+
+		conn = view.InsertConnection()
+		conn.From = "a"
+		conn.To = "domain:example.com"
+		conn.MinPort = 443
+		conn.MaxPort = 443
+		conn.ResolvedIPs = []string{"1.2.3.4"}
+		view.Commit(conn)
 		return nil
 	})
 
@@ -45,6 +54,15 @@ func TestRunConnectionOnce(t *testing.T) {
         "To": "b",
         "MinPort": 80,
         "MaxPort": 8080
+    },
+    {
+        "From": "a",
+        "To": "domain:example.com",
+        "MinPort": 443,
+        "MaxPort": 443,
+        "ResolvedIPs": [
+            "1.2.3.4"
+        ]
     }
 ]`
 	assert.Equal(t, expStr, str)
@@ -54,7 +72,9 @@ func TestRunConnectionOnce(t *testing.T) {
 		etcd.Leader = false
 		view.Commit(etcd)
 
-		conn := view.SelectFromConnection(nil)[0]
+		conn := view.SelectFromConnection(func(c db.Connection) bool {
+			return c.To == "b"
+		})[0]
 		conn.From = "1"
 		conn.To = "2"
 		conn.MinPort = 3
@@ -67,8 +87,12 @@ func TestRunConnectionOnce(t *testing.T) {
 	assert.NoError(t, err)
 
 	conns := conn.SelectFromConnection(nil)
-	assert.Len(t, conns, 1)
+	assert.Len(t, conns, 2)
+	sort.Sort(db.ConnectionSlice(conns))
 	conns[0].ID = 0
+	conns[1].ID = 0
 	assert.Equal(t, db.Connection{From: "a", To: "b", MinPort: 80, MaxPort: 8080},
 		conns[0])
+	assert.Equal(t, db.Connection{From: "a", To: "domain:example.com",
+		MinPort: 443, MaxPort: 443, ResolvedIPs: []string{"1.2.3.4"}}, conns[1])
 }
@@ -93,6 +93,7 @@ func joinContainers(view db.Database, etcdDBCs []db.Container) {
 			Command           string
 			Env               string
 			FilepathToContent string
+			Security          string
 		}{
 			Hostname:          dbc.Hostname,
 			IP:                dbc.IP,
@@ -102,6 +103,7 @@ func joinContainers(view db.Database, etcdDBCs []db.Container) {
 			Command:           fmt.Sprintf("%v", dbc.Command),
 			Env:               util.MapAsString(dbc.Env),
 			FilepathToContent: util.MapAsString(dbc.FilepathToContent),
+			Security:          dbc.Security.String(),
 		}
 	}
 
@@ -132,6 +134,9 @@ func joinContainers(view db.Database, etcdDBCs []db.Container) {
 		dbc.Env = edbc.Env
 		dbc.FilepathToContent = edbc.FilepathToContent
 		dbc.Hostname = edbc.Hostname
+		dbc.Security = edbc.Security
+		dbc.Bandwidth = edbc.Bandwidth
+		dbc.TrafficClass = edbc.TrafficClass
 		view.Commit(dbc)
 	}
 }
@@ -73,6 +73,24 @@ func campaign(conn db.Conn, store Store) {
 			continue
 		}
 
+		// Stepping down is pointless if there's no other master to take over.
+		if etcdRows[0].StepDown && len(etcdRows[0].EtcdIPs) > 1 {
+			if etcdRows[0].Leader {
+				c.Inc("Step Down")
+
+				// Only delete the key if it's still ours, as another
+				// master may have won an election since our lease
+				// expired.
+				err := store.CompareAndDelete(leaderKey, IP)
+				if err != nil {
+					log.WithError(err).Warn(
+						"Failed to give up leadership")
+				}
+				commitLeader(conn, false, "")
+			}
+			continue
+		}
+
 		ttl := electionTTL * time.Second
 
 		var err error
@@ -18,6 +18,7 @@ type Store interface {
 	GetTree(dir string) (Tree, error)
 	Get(path string) (string, error)
 	Delete(path string) error
+	CompareAndDelete(path, value string) error
 	Create(path, value string, ttl time.Duration) error
 	Set(path, value string, ttl time.Duration) error
 	Refresh(path, value string, ttl time.Duration) error
@@ -134,6 +135,13 @@ func (s store) Delete(path string) error {
 	return err
 }
 
+// CompareAndDelete deletes `path` only if it holds `value`.
+func (s store) CompareAndDelete(path, value string) error {
+	c.Inc("CompareAndDelete")
+	_, err := s.kapi.Delete(ctx(), path, &client.DeleteOptions{PrevValue: value})
+	return err
+}
+
 func (s store) Create(path, value string, ttl time.Duration) error {
 	c.Inc("Create")
 	_, err := s.kapi.Set(ctx(), path, value,
@@ -178,6 +178,22 @@ func (m mock) Delete(path string) error {
 	return nil
 }
 
+func (m mock) CompareAndDelete(path, value string) error {
+	m.Lock()
+	curValue, err := m.get(path)
+	m.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	if curValue != value {
+		return fmt.Errorf("key '%s' does not have value '%s'", path, value)
+	}
+
+	return m.Delete(path)
+}
+
 func (m mock) Set(path, value string, ttl time.Duration) error {
 	m.Lock()
 	defer m.Unlock()
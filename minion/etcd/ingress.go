@@ -0,0 +1,128 @@
+package etcd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/join"
+	"github.com/quilt/quilt/util"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const ingressPath = "/ingresses"
+
+// The leader shares the ingresses with the workers, along with the containers each
+// route forwards to, so that the router on every worker can accept requests for
+// backends running anywhere in the cluster.
+func runIngress(conn db.Conn, store Store) {
+	etcdWatch := store.Watch(ingressPath, 1*time.Second)
+	trigg := conn.TriggerTick(60, db.IngressTable, db.LoadBalancerTable,
+		db.ContainerTable)
+	for range util.JoinNotifiers(trigg.C, etcdWatch) {
+		if err := runIngressOnce(conn, store); err != nil {
+			log.WithError(err).Warn("Failed to sync ingresses with Etcd.")
+		}
+	}
+}
+
+func runIngressOnce(conn db.Conn, store Store) error {
+	etcdStr, err := readEtcdNode(store, ingressPath)
+	if err != nil {
+		return fmt.Errorf("etcd read error: %s", err)
+	}
+
+	if conn.EtcdLeader() {
+		c.Inc("Run Ingress Leader")
+		var ingresses []db.Ingress
+		var lbs []db.LoadBalancer
+		var dbcs []db.Container
+		conn.Txn(db.IngressTable, db.LoadBalancerTable,
+			db.ContainerTable).Run(func(view db.Database) error {
+			ingresses = view.SelectFromIngress(nil)
+			lbs = view.SelectFromLoadBalancer(nil)
+			dbcs = view.SelectFromContainer(nil)
+			return nil
+		})
+
+		for i := range ingresses {
+			ingresses[i].ID = 0
+			ingresses[i].Backends = ingressBackends(ingresses[i], lbs, dbcs)
+		}
+
+		err = writeEtcdSlice(store, ingressPath, etcdStr,
+			db.IngressSlice(ingresses))
+		if err != nil {
+			return fmt.Errorf("etcd write error: %s", err)
+		}
+	} else {
+		c.Inc("Run Ingress Worker")
+		var etcdIngresses []db.Ingress
+		json.Unmarshal([]byte(etcdStr), &etcdIngresses)
+		conn.Txn(db.IngressTable).Run(func(view db.Database) error {
+			joinIngresses(view, etcdIngresses)
+			return nil
+		})
+	}
+
+	return nil
+}
+
+func joinIngresses(view db.Database, etcdIngresses []db.Ingress) {
+	key := func(iface interface{}) interface{} {
+		ing := iface.(db.Ingress)
+		ing.ID = 0
+		str, _ := json.Marshal(ing)
+		return string(str)
+	}
+
+	_, dbIfaces, etcdIfaces := join.HashJoin(
+		db.IngressSlice(view.SelectFromIngress(nil)),
+		db.IngressSlice(etcdIngresses), key, key)
+
+	for _, iface := range dbIfaces {
+		view.Remove(iface.(db.Ingress))
+	}
+
+	for _, iface := range etcdIfaces {
+		etcdIngress := iface.(db.Ingress)
+		etcdIngress.ID = view.InsertIngress().ID
+		view.Commit(etcdIngress)
+	}
+}
+
+// ingressBackends maps the destination of each of the routes of `ing` to the
+// containers it forwards to.  Routes to a load balancer use the load balancer's
+// backends, and routes to a hostname use the container with that hostname.
+func ingressBackends(ing db.Ingress, lbs []db.LoadBalancer,
+	dbcs []db.Container) map[string]map[string]string {
+
+	lbMap := map[string]db.LoadBalancer{}
+	for _, lb := range lbs {
+		lbMap[lb.Name] = lb
+	}
+
+	backends := map[string]map[string]string{}
+	for _, route := range ing.Routes {
+		if lb, ok := lbMap[route.To]; ok {
+			lbBackends := loadBalancerBackends(lb, dbcs)
+			if lbBackends != nil {
+				backends[route.To] = lbBackends
+			}
+			continue
+		}
+
+		for _, dbc := range dbcs {
+			if dbc.Hostname == route.To && dbc.IP != "" && dbc.Minion != "" {
+				backends[route.To] = map[string]string{dbc.IP: dbc.Minion}
+			}
+		}
+	}
+
+	if len(backends) == 0 {
+		return nil
+	}
+	return backends
+}
@@ -0,0 +1,124 @@
+package etcd
+
+import (
+	"testing"
+
+	"github.com/quilt/quilt/blueprint"
+	"github.com/quilt/quilt/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunIngressOnce(t *testing.T) {
+	t.Parallel()
+
+	store := newTestMock()
+	conn := db.New()
+
+	err := runIngressOnce(conn, store)
+	assert.Error(t, err)
+
+	err = store.Set(ingressPath, "", 0)
+	assert.NoError(t, err)
+
+	routes := []blueprint.IngressRoute{
+		{Host: "example.com", To: "a", Port: 80},
+	}
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		etcd := view.InsertEtcd()
+		etcd.Leader = true
+		view.Commit(etcd)
+
+		ing := view.InsertIngress()
+		ing.Name = "ingress"
+		ing.Routes = routes
+		ing.HTTPPort = 80
+		view.Commit(ing)
+
+		dbc := view.InsertContainer()
+		dbc.Hostname = "a"
+		dbc.IP = "10.0.0.2"
+		dbc.Minion = "1.2.3.4"
+		view.Commit(dbc)
+		return nil
+	})
+
+	err = runIngressOnce(conn, store)
+	assert.NoError(t, err)
+
+	str, err := store.Get(ingressPath)
+	assert.NoError(t, err)
+
+	expStr := `[
+    {
+        "ID": 0,
+        "Name": "ingress",
+        "Routes": [
+            {
+                "Host": "example.com",
+                "To": "a",
+                "Port": 80
+            }
+        ],
+        "Certificates": null,
+        "HTTPPort": 80,
+        "HTTPSPort": 0,
+        "Backends": {
+            "a": {
+                "10.0.0.2": "1.2.3.4"
+            }
+        }
+    }
+]`
+	assert.Equal(t, expStr, str)
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		etcd := view.SelectFromEtcd(nil)[0]
+		etcd.Leader = false
+		view.Commit(etcd)
+
+		ing := view.SelectFromIngress(nil)[0]
+		ing.HTTPPort = 8000
+		view.Commit(ing)
+		return nil
+	})
+
+	err = runIngressOnce(conn, store)
+	assert.NoError(t, err)
+
+	ingresses := conn.SelectFromIngress(nil)
+	assert.Len(t, ingresses, 1)
+	ingresses[0].ID = 0
+	assert.Equal(t, db.Ingress{
+		Name:     "ingress",
+		Routes:   routes,
+		HTTPPort: 80,
+		Backends: map[string]map[string]string{
+			"a": {"10.0.0.2": "1.2.3.4"},
+		},
+	}, ingresses[0])
+}
+
+func TestIngressBackends(t *testing.T) {
+	t.Parallel()
+
+	dbcs := []db.Container{
+		{Hostname: "a", IP: "10.0.0.2", Minion: "1.2.3.4"},
+		{Hostname: "b", IP: "10.0.0.3", Minion: "1.2.3.5"},
+		{Hostname: "c", IP: "10.0.0.4", Minion: "1.2.3.5"},
+		{Hostname: "d", IP: "10.0.0.5"},
+	}
+	lbs := []db.LoadBalancer{{Name: "lb", Hostnames: []string{"b", "c"}}}
+
+	ing := db.Ingress{Routes: []blueprint.IngressRoute{
+		{Path: "/a", To: "a"},
+		{Path: "/lb", To: "lb"},
+		{Path: "/d", To: "d"},
+	}}
+	assert.Equal(t, map[string]map[string]string{
+		"a":  {"10.0.0.2": "1.2.3.4"},
+		"lb": {"10.0.0.3": "1.2.3.5", "10.0.0.4": "1.2.3.5"},
+	}, ingressBackends(ing, lbs, dbcs))
+
+	ing.Routes = []blueprint.IngressRoute{{To: "d"}}
+	assert.Nil(t, ingressBackends(ing, lbs, dbcs))
+}
@@ -0,0 +1,125 @@
+package etcd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/join"
+	"github.com/quilt/quilt/util"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const loadBalancerPath = "/loadBalancers"
+
+// The leader shares the load balancers with the workers, so that they can probe
+// the backends running on them, and forward public traffic to backends anywhere in
+// the cluster.
+func runLoadBalancer(conn db.Conn, store Store) {
+	etcdWatch := store.Watch(loadBalancerPath, 1*time.Second)
+	trigg := conn.TriggerTick(60, db.LoadBalancerTable, db.ContainerTable)
+	for range util.JoinNotifiers(trigg.C, etcdWatch) {
+		if err := runLoadBalancerOnce(conn, store); err != nil {
+			log.WithError(err).Warn(
+				"Failed to sync load balancers with Etcd.")
+		}
+	}
+}
+
+func runLoadBalancerOnce(conn db.Conn, store Store) error {
+	etcdStr, err := readEtcdNode(store, loadBalancerPath)
+	if err != nil {
+		return fmt.Errorf("etcd read error: %s", err)
+	}
+
+	if conn.EtcdLeader() {
+		c.Inc("Run Load Balancer Leader")
+		var lbs []db.LoadBalancer
+		var dbcs []db.Container
+		conn.Txn(db.LoadBalancerTable, db.ContainerTable).Run(
+			func(view db.Database) error {
+				lbs = view.SelectFromLoadBalancer(nil)
+				dbcs = view.SelectFromContainer(nil)
+				return nil
+			})
+
+		for i := range lbs {
+			lbs[i].ID = 0
+			lbs[i].Backends = loadBalancerBackends(lbs[i], dbcs)
+		}
+
+		err = writeEtcdSlice(store, loadBalancerPath, etcdStr,
+			db.LoadBalancerSlice(lbs))
+		if err != nil {
+			return fmt.Errorf("etcd write error: %s", err)
+		}
+	} else {
+		c.Inc("Run Load Balancer Worker")
+		var etcdLBs []db.LoadBalancer
+		json.Unmarshal([]byte(etcdStr), &etcdLBs)
+		conn.Txn(db.LoadBalancerTable).Run(func(view db.Database) error {
+			joinLoadBalancers(view, etcdLBs)
+			return nil
+		})
+	}
+
+	return nil
+}
+
+func joinLoadBalancers(view db.Database, etcdLBs []db.LoadBalancer) {
+	key := func(iface interface{}) interface{} {
+		lb := iface.(db.LoadBalancer)
+		return struct{ Name, IP, Hostnames, Probe, Backends string }{
+			Name:      lb.Name,
+			IP:        lb.IP,
+			Hostnames: strings.Join(lb.Hostnames, " "),
+			Probe:     lb.Probe.String(),
+			Backends:  util.MapAsString(lb.Backends),
+		}
+	}
+
+	_, dbIfaces, etcdIfaces := join.HashJoin(
+		db.LoadBalancerSlice(view.SelectFromLoadBalancer(nil)),
+		db.LoadBalancerSlice(etcdLBs), key, key)
+
+	for _, iface := range dbIfaces {
+		view.Remove(iface.(db.LoadBalancer))
+	}
+
+	for _, iface := range etcdIfaces {
+		etcdLB := iface.(db.LoadBalancer)
+		etcdLB.ID = view.InsertLoadBalancer().ID
+		view.Commit(etcdLB)
+	}
+}
+
+// loadBalancerBackends maps the IP of each container behind `lb` that should
+// receive traffic to the minion it's running on.  If `lb` has a probe, only the
+// healthy containers are included.
+func loadBalancerBackends(lb db.LoadBalancer, dbcs []db.Container) map[string]string {
+	hostnames := map[string]struct{}{}
+	for _, hostname := range lb.Hostnames {
+		hostnames[hostname] = struct{}{}
+	}
+
+	backends := map[string]string{}
+	for _, dbc := range dbcs {
+		if _, ok := hostnames[dbc.Hostname]; !ok ||
+			dbc.IP == "" || dbc.Minion == "" {
+			continue
+		}
+
+		if lb.Probe != nil && dbc.Health != db.ContainerHealthy {
+			continue
+		}
+		backends[dbc.IP] = dbc.Minion
+	}
+
+	if len(backends) == 0 {
+		return nil
+	}
+	return backends
+}
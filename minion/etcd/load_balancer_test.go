@@ -0,0 +1,126 @@
+package etcd
+
+import (
+	"testing"
+
+	"github.com/quilt/quilt/blueprint"
+	"github.com/quilt/quilt/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunLoadBalancerOnce(t *testing.T) {
+	t.Parallel()
+
+	store := newTestMock()
+	conn := db.New()
+
+	err := runLoadBalancerOnce(conn, store)
+	assert.Error(t, err)
+
+	err = store.Set(loadBalancerPath, "", 0)
+	assert.NoError(t, err)
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		etcd := view.InsertEtcd()
+		etcd.Leader = true
+		view.Commit(etcd)
+
+		lb := view.InsertLoadBalancer()
+		lb.Name = "lb"
+		lb.IP = "10.1.0.1"
+		lb.Hostnames = []string{"a", "b"}
+		lb.Probe = &blueprint.Probe{Port: 80, Path: "/health"}
+		view.Commit(lb)
+
+		dbc := view.InsertContainer()
+		dbc.Hostname = "a"
+		dbc.IP = "10.0.0.2"
+		dbc.Minion = "1.2.3.4"
+		dbc.Health = db.ContainerHealthy
+		view.Commit(dbc)
+
+		dbc = view.InsertContainer()
+		dbc.Hostname = "b"
+		dbc.IP = "10.0.0.3"
+		dbc.Minion = "1.2.3.5"
+		view.Commit(dbc)
+		return nil
+	})
+
+	err = runLoadBalancerOnce(conn, store)
+	assert.NoError(t, err)
+
+	str, err := store.Get(loadBalancerPath)
+	assert.NoError(t, err)
+
+	expStr := `[
+    {
+        "ID": 0,
+        "Name": "lb",
+        "IP": "10.1.0.1",
+        "Hostnames": [
+            "a",
+            "b"
+        ],
+        "Probe": {
+            "Port": 80,
+            "Path": "/health"
+        },
+        "Backends": {
+            "10.0.0.2": "1.2.3.4"
+        }
+    }
+]`
+	assert.Equal(t, expStr, str)
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		etcd := view.SelectFromEtcd(nil)[0]
+		etcd.Leader = false
+		view.Commit(etcd)
+
+		lb := view.SelectFromLoadBalancer(nil)[0]
+		lb.IP = "10.1.0.2"
+		lb.Probe = nil
+		view.Commit(lb)
+		return nil
+	})
+
+	err = runLoadBalancerOnce(conn, store)
+	assert.NoError(t, err)
+
+	lbs := conn.SelectFromLoadBalancer(nil)
+	assert.Len(t, lbs, 1)
+	lbs[0].ID = 0
+	assert.Equal(t, db.LoadBalancer{
+		Name:      "lb",
+		IP:        "10.1.0.1",
+		Hostnames: []string{"a", "b"},
+		Probe:     &blueprint.Probe{Port: 80, Path: "/health"},
+		Backends:  map[string]string{"10.0.0.2": "1.2.3.4"},
+	}, lbs[0])
+}
+
+func TestLoadBalancerBackends(t *testing.T) {
+	t.Parallel()
+
+	dbcs := []db.Container{
+		{Hostname: "a", IP: "10.0.0.2", Minion: "1.2.3.4"},
+		{Hostname: "b", IP: "10.0.0.3", Minion: "1.2.3.5",
+			Health: db.ContainerHealthy},
+		{Hostname: "c", IP: "10.0.0.4", Minion: "1.2.3.5"},
+		{Hostname: "d", IP: "10.0.0.5"},
+	}
+
+	lb := db.LoadBalancer{Hostnames: []string{"a", "b", "d"}}
+	assert.Equal(t, map[string]string{
+		"10.0.0.2": "1.2.3.4",
+		"10.0.0.3": "1.2.3.5",
+	}, loadBalancerBackends(lb, dbcs))
+
+	lb.Probe = &blueprint.Probe{Port: 80}
+	assert.Equal(t, map[string]string{"10.0.0.3": "1.2.3.5"},
+		loadBalancerBackends(lb, dbcs))
+
+	lb.Hostnames = []string{"c"}
+	assert.Nil(t, loadBalancerBackends(lb, dbcs))
+}
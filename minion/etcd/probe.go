@@ -0,0 +1,96 @@
+package etcd
+
+import (
+	"encoding/json"
+	"path"
+	"time"
+
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/util"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Workers publish the health of the load balancer backends they probe, and the
+// leader records it in its container table so that only healthy backends receive
+// load balanced traffic.  A worker's results expire if it stops publishing them.
+const (
+	probeTimeout = 30
+	probePath    = "/probes"
+)
+
+func runProbeSync(conn db.Conn, store Store) {
+	go func() {
+		for range conn.TriggerTick(probeTimeout/2, db.ContainerTable).C {
+			writeProbes(conn, store)
+		}
+	}()
+
+	etcdWatch := store.Watch(probePath, 1*time.Second)
+	trigg := conn.TriggerTick(probeTimeout/2, db.EtcdTable)
+	for range util.JoinNotifiers(trigg.C, etcdWatch) {
+		if conn.EtcdLeader() {
+			readProbes(conn, store)
+		}
+	}
+}
+
+// writeProbes publishes the Health of the containers running on this worker,
+// keyed by IP.
+func writeProbes(conn db.Conn, store Store) {
+	self := conn.MinionSelf()
+	if self.Role != db.Worker || self.PrivateIP == "" {
+		return
+	}
+
+	health := map[string]string{}
+	for _, dbc := range conn.SelectFromContainer(nil) {
+		if dbc.IP != "" && dbc.Health != "" {
+			health[dbc.IP] = dbc.Health
+		}
+	}
+
+	js, err := jsonMarshal(health)
+	if err != nil {
+		panic("Failed to convert probe results to JSON")
+	}
+
+	key := path.Join(probePath, self.PrivateIP)
+	if err := store.Set(key, string(js), probeTimeout*time.Second); err != nil {
+		log.WithError(err).Warnf("Failed to write %s", key)
+	}
+}
+
+// readProbes updates the Health of each container according to the results
+// published by the workers.
+func readProbes(conn db.Conn, store Store) {
+	tree, err := store.GetTree(probePath)
+	if err != nil {
+		log.WithError(err).Warning("Failed to get probe results from Etcd.")
+		return
+	}
+
+	health := map[string]string{}
+	for _, t := range tree.Children {
+		var workerHealth map[string]string
+		if err := json.Unmarshal([]byte(t.Value), &workerHealth); err != nil {
+			log.WithField("json", t.Value).Warning(
+				"Failed to parse probe results.")
+			continue
+		}
+
+		for ip, h := range workerHealth {
+			health[ip] = h
+		}
+	}
+
+	conn.Txn(db.ContainerTable).Run(func(view db.Database) error {
+		for _, dbc := range view.SelectFromContainer(nil) {
+			if h := health[dbc.IP]; dbc.Health != h {
+				dbc.Health = h
+				view.Commit(dbc)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,85 @@
+package etcd
+
+import (
+	"testing"
+
+	"github.com/quilt/quilt/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteProbes(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	store := newTestMock()
+	key := "/probes/1.2.3.4"
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.InsertMinion()
+		m.Self = true
+		m.Role = db.Master
+		m.PrivateIP = "1.2.3.4"
+		view.Commit(m)
+
+		dbc := view.InsertContainer()
+		dbc.IP = "10.0.0.2"
+		dbc.Health = db.ContainerHealthy
+		view.Commit(dbc)
+
+		dbc = view.InsertContainer()
+		dbc.IP = "10.0.0.3"
+		view.Commit(dbc)
+		return nil
+	})
+
+	// Only workers probe containers.
+	writeProbes(conn, store)
+	_, err := store.Get(key)
+	assert.NotNil(t, err)
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.MinionSelf()
+		m.Role = db.Worker
+		view.Commit(m)
+		return nil
+	})
+
+	writeProbes(conn, store)
+	val, err := store.Get(key)
+	assert.Nil(t, err)
+	assert.Equal(t, "{\n    \"10.0.0.2\": \"healthy\"\n}", val)
+}
+
+func TestReadProbes(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	store := newTestMock()
+
+	store.Mkdir(probePath, 0)
+	store.Set("/probes/1.2.3.4", `{"10.0.0.2": "healthy"}`, 0)
+	store.Set("/probes/1.2.3.5", `{"10.0.0.3": "unhealthy: refused"}`, 0)
+	store.Set("/probes/1.2.3.6", `malformed`, 0)
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		for _, ip := range []string{"10.0.0.2", "10.0.0.3", "10.0.0.4"} {
+			dbc := view.InsertContainer()
+			dbc.IP = ip
+			dbc.Health = "stale"
+			view.Commit(dbc)
+		}
+		return nil
+	})
+
+	readProbes(conn, store)
+
+	health := map[string]string{}
+	for _, dbc := range conn.SelectFromContainer(nil) {
+		health[dbc.IP] = dbc.Health
+	}
+	assert.Equal(t, map[string]string{
+		"10.0.0.2": db.ContainerHealthy,
+		"10.0.0.3": "unhealthy: refused",
+		"10.0.0.4": "",
+	}, health)
+}
@@ -1,10 +1,12 @@
 package etcd
 
 import (
+	"errors"
 	"time"
 
 	"github.com/coreos/etcd/client"
 	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/health"
 
 	log "github.com/Sirupsen/logrus"
 )
@@ -12,15 +14,31 @@ import (
 // Run synchronizes state in `conn` with the Etcd cluster.
 func Run(conn db.Conn) {
 	store := NewStore()
+	health.Register("etcd", func() error { return checkLeader(conn) })
 	makeEtcdDir(minionPath, store, 0)
+	makeEtcdDir(probePath, store, 0)
+	makeEtcdDir(trafficPath, store, 0)
 
 	go runElection(conn, store)
 	go runConnection(conn, store)
 	go runContainer(conn, store)
 	go runHostname(conn, store)
+	go runLoadBalancer(conn, store)
+	go runIngress(conn, store)
+	go runProbeSync(conn, store)
+	go runTrafficSync(conn, store)
 	runMinionSync(conn, store)
 }
 
+// checkLeader returns an error if the minion doesn't know the cluster's leader.
+func checkLeader(conn db.Conn) error {
+	etcdRows := conn.SelectFromEtcd(nil)
+	if len(etcdRows) != 1 || etcdRows[0].LeaderIP == "" {
+		return errors.New("no etcd leader")
+	}
+	return nil
+}
+
 func makeEtcdDir(dir string, store Store, ttl time.Duration) {
 	for {
 		err := createEtcdDir(dir, store, ttl)
@@ -0,0 +1,116 @@
+package etcd
+
+import (
+	"encoding/json"
+	"path"
+	"time"
+
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/join"
+	"github.com/quilt/quilt/util"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Workers publish the traffic sent and received by their containers, and the
+// leader records the sum in its traffic table, from which the traffic of the whole
+// cluster is served to the API.  A worker's traffic expires if it stops
+// publishing it.
+const (
+	trafficTimeout = 30
+	trafficPath    = "/traffic"
+)
+
+func runTrafficSync(conn db.Conn, store Store) {
+	go func() {
+		for range conn.TriggerTick(trafficTimeout/2, db.TrafficTable).C {
+			writeTraffic(conn, store)
+		}
+	}()
+
+	etcdWatch := store.Watch(trafficPath, 1*time.Second)
+	trigg := conn.TriggerTick(trafficTimeout/2, db.EtcdTable)
+	for range util.JoinNotifiers(trigg.C, etcdWatch) {
+		if conn.EtcdLeader() {
+			readTraffic(conn, store)
+		}
+	}
+}
+
+// writeTraffic publishes the traffic counted on this worker.
+func writeTraffic(conn db.Conn, store Store) {
+	self := conn.MinionSelf()
+	if self.Role != db.Worker || self.PrivateIP == "" {
+		return
+	}
+
+	js, err := jsonMarshal(conn.SelectFromTraffic(nil))
+	if err != nil {
+		panic("Failed to convert traffic to JSON")
+	}
+
+	key := path.Join(trafficPath, self.PrivateIP)
+	if err := store.Set(key, string(js), trafficTimeout*time.Second); err != nil {
+		log.WithError(err).Warnf("Failed to write %s", key)
+	}
+}
+
+// readTraffic sums the traffic published by the workers into the traffic table.
+func readTraffic(conn db.Conn, store Store) {
+	tree, err := store.GetTree(trafficPath)
+	if err != nil {
+		log.WithError(err).Warning("Failed to get traffic from Etcd.")
+		return
+	}
+
+	sums := map[[2]string]db.Traffic{}
+	for _, t := range tree.Children {
+		var workerTraffic []db.Traffic
+		if err := json.Unmarshal([]byte(t.Value), &workerTraffic); err != nil {
+			log.WithField("json", t.Value).Warning("Failed to parse traffic.")
+			continue
+		}
+
+		for _, wt := range workerTraffic {
+			key := [2]string{wt.From, wt.To}
+			sum := sums[key]
+			sum.From, sum.To = wt.From, wt.To
+			sum.TrafficClass = wt.TrafficClass
+			sum.Packets += wt.Packets
+			sum.Bytes += wt.Bytes
+			sums[key] = sum
+		}
+	}
+
+	var traffic db.TrafficSlice
+	for _, sum := range sums {
+		traffic = append(traffic, sum)
+	}
+
+	key := func(intf interface{}) interface{} {
+		t := intf.(db.Traffic)
+		return [2]string{t.From, t.To}
+	}
+
+	conn.Txn(db.TrafficTable).Run(func(view db.Database) error {
+		dbTraffic := db.TrafficSlice(view.SelectFromTraffic(nil))
+		pairs, toAdd, toRemove := join.HashJoin(traffic, dbTraffic, key, key)
+
+		for _, intf := range toRemove {
+			view.Remove(intf.(db.Traffic))
+		}
+
+		for _, intf := range toAdd {
+			pairs = append(pairs, join.Pair{L: intf, R: view.InsertTraffic()})
+		}
+
+		for _, pair := range pairs {
+			t := pair.L.(db.Traffic)
+			t.ID = pair.R.(db.Traffic).ID
+			if t != pair.R.(db.Traffic) {
+				view.Commit(t)
+			}
+		}
+		return nil
+	})
+}
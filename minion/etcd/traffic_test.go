@@ -0,0 +1,93 @@
+package etcd
+
+import (
+	"testing"
+
+	"github.com/quilt/quilt/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteTraffic(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	store := newTestMock()
+	key := "/traffic/1.2.3.4"
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.InsertMinion()
+		m.Self = true
+		m.Role = db.Master
+		m.PrivateIP = "1.2.3.4"
+		view.Commit(m)
+
+		traffic := view.InsertTraffic()
+		traffic.From = "web"
+		traffic.To = "db"
+		traffic.Packets = 1
+		traffic.Bytes = 100
+		view.Commit(traffic)
+		return nil
+	})
+
+	// Only workers count traffic.
+	writeTraffic(conn, store)
+	_, err := store.Get(key)
+	assert.NotNil(t, err)
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.MinionSelf()
+		m.Role = db.Worker
+		view.Commit(m)
+		return nil
+	})
+
+	writeTraffic(conn, store)
+	val, err := store.Get(key)
+	assert.Nil(t, err)
+	assert.Equal(t, `[
+    {
+        "From": "web",
+        "To": "db",
+        "Packets": 1,
+        "Bytes": 100
+    }
+]`, val)
+}
+
+func TestReadTraffic(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	store := newTestMock()
+
+	store.Mkdir(trafficPath, 0)
+	store.Set("/traffic/1.2.3.4", `[
+		{"From": "web", "To": "db", "TrafficClass": 10, "Packets": 1,
+			"Bytes": 100},
+		{"From": "public", "To": "web", "Packets": 2, "Bytes": 200}]`, 0)
+	store.Set("/traffic/1.2.3.5", `[
+		{"From": "web", "To": "db", "TrafficClass": 10, "Packets": 3,
+			"Bytes": 300}]`, 0)
+	store.Set("/traffic/1.2.3.6", `malformed`, 0)
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		traffic := view.InsertTraffic()
+		traffic.From = "stale"
+		traffic.To = "db"
+		view.Commit(traffic)
+		return nil
+	})
+
+	readTraffic(conn, store)
+
+	traffic := map[[2]string][3]uint64{}
+	for _, t := range conn.SelectFromTraffic(nil) {
+		traffic[[2]string{t.From, t.To}] = [3]uint64{
+			uint64(t.TrafficClass), t.Packets, t.Bytes}
+	}
+	assert.Equal(t, map[[2]string][3]uint64{
+		{"web", "db"}:     {10, 4, 400},
+		{"public", "web"}: {0, 2, 200},
+	}, traffic)
+}
@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"net"
 	"syscall"
+
+	"github.com/quilt/quilt/blueprint"
 )
 
+// The addresses of the container network default to those of
+// blueprint.DefaultNetwork, and are overridden by Configure.
 var (
 	// QuiltSubnet is the subnet under which Quilt containers and load balancers
 	// are given IP addresses.
@@ -36,6 +40,23 @@ var (
 	OvnBridge = "br-int"
 )
 
+// Configure sets the addresses of the container network to those of `nc`.  It
+// isn't safe to call once the modules that read them have started.
+func Configure(nc blueprint.NetworkConfig) error {
+	if err := nc.Validate(); err != nil {
+		return err
+	}
+
+	// Validate guarantees that these parse.
+	_, subnet, _ := net.ParseCIDR(nc.Subnet)
+	QuiltSubnet = *subnet
+	GatewayIP = net.ParseIP(nc.GatewayIP)
+	GatewayMac = IPToMac(GatewayIP)
+	LoadBalancerIP = net.ParseIP(nc.LoadBalancerIP)
+	LoadBalancerMac = IPToMac(LoadBalancerIP)
+	return nil
+}
+
 // IPStrToMac converts the given IP address string into a MAC address.
 func IPStrToMac(ipStr string) string {
 	parsedIP := net.ParseIP(ipStr)
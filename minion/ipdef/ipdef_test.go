@@ -6,6 +6,8 @@ import (
 	"net"
 	"testing"
 
+	"github.com/quilt/quilt/blueprint"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -27,3 +29,23 @@ func TestIFName(t *testing.T) {
 	assert.Equal(t, IFName("1"), "1")
 	assert.Equal(t, IFName(""), "")
 }
+
+func TestConfigure(t *testing.T) {
+	defer func() {
+		assert.NoError(t, Configure(blueprint.DefaultNetwork))
+		assert.Equal(t, "10.0.0.0/8", QuiltSubnet.String())
+		assert.Equal(t, "02:00:0a:00:00:01", GatewayMac)
+	}()
+
+	nc := blueprint.NetworkConfig{Subnet: "172.30.0.0/16"}.WithDefaults()
+	assert.NoError(t, Configure(nc))
+	assert.Equal(t, "172.30.0.0/16", QuiltSubnet.String())
+	assert.Equal(t, "172.30.0.1", GatewayIP.String())
+	assert.Equal(t, "02:00:ac:1e:00:01", GatewayMac)
+	assert.Equal(t, "172.30.0.2", LoadBalancerIP.String())
+	assert.Equal(t, "02:00:ac:1e:00:02", LoadBalancerMac)
+
+	nc.Subnet = "bad"
+	assert.Error(t, Configure(nc))
+	assert.Equal(t, "172.30.0.0/16", QuiltSubnet.String())
+}
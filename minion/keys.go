@@ -2,16 +2,27 @@ package minion
 
 import (
 	"os"
+	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/util"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/spf13/afero"
+	"golang.org/x/crypto/ssh"
 )
 
 const authorizedKeysFile = "/home/quilt/.ssh/authorized_keys"
 
+// The directory containing the host's SSH keys, which is mounted into the minion
+// container, and the pattern matching the names of the public keys.
+const (
+	hostKeysDir     = "/etc/ssh"
+	hostKeysPattern = "ssh_host_*_key.pub"
+)
+
 func syncAuthorizedKeys(conn db.Conn) {
 	// XXX: If we immediately started syncing the SSH keys, there would be a
 	// brief period where no keys are installed. This is because
@@ -56,3 +67,38 @@ func waitForConfig(conn db.Conn) {
 		time.Sleep(1 * time.Second)
 	}
 }
+
+// hostKeyFingerprints returns the SHA256 fingerprints of the host's SSH keys, so
+// that the daemon and CLI can verify that they're connecting to the right machine.
+func hostKeyFingerprints() []string {
+	files, err := afero.ReadDir(util.AppFs, hostKeysDir)
+	if err != nil {
+		log.WithError(err).Error("Failed to list SSH host keys")
+		return nil
+	}
+
+	var fingerprints []string
+	for _, file := range files {
+		if match, _ := filepath.Match(hostKeysPattern, file.Name()); !match {
+			continue
+		}
+
+		path := filepath.Join(hostKeysDir, file.Name())
+		keyStr, err := util.ReadFile(path)
+		if err != nil {
+			log.WithError(err).WithField("path", path).Warn(
+				"Failed to read SSH host key")
+			continue
+		}
+
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(keyStr))
+		if err != nil {
+			log.WithError(err).WithField("path", path).Warn(
+				"Failed to parse SSH host key")
+			continue
+		}
+		fingerprints = append(fingerprints, ssh.FingerprintSHA256(key))
+	}
+	sort.Strings(fingerprints)
+	return fingerprints
+}
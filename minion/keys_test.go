@@ -86,3 +86,25 @@ func TestSyncKeysError(t *testing.T) {
 	err = runOnce(conn)
 	assert.EqualError(t, err, "operation not permitted")
 }
+
+func TestHostKeyFingerprints(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+	assert.Empty(t, hostKeyFingerprints())
+
+	hostKey := "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQC0XGWf3uoKJwIAh" +
+		"g3jg1oNfyxav55IKDEq2W72CL2SRiMRtmVs6fPeaem6HNMvWFrb0pqnguQyHo59RT" +
+		"4Hs/VJrbqkfR3wGWtxWL/TlVN0D/jSpOZP+/tuNz/qusow4PRlwvpV3Ic7JGNgcRP" +
+		"vseR1mimM4PXAbqPfgay9OZ8WweaartZHT9iStSo64DYArDgJ3dV6M8RFqXPkbijT" +
+		"8EfFuRj9PxH+S9NIzQF/T6rOemgMIBXDX9PA0DR/rGwYyaqHPMVsh+dw6Nsq/l21v" +
+		"noURtc9U7AvrkL/42DKMnv/p16w6DZJuTqh/CzU29fI0PvfeTqhv0aF5mjYtkEo0Tb7" +
+		" root@host"
+	util.WriteFile("/etc/ssh/ssh_host_rsa_key.pub", []byte(hostKey), 0644)
+
+	// Malformed keys and private keys are ignored.
+	util.WriteFile("/etc/ssh/ssh_host_dsa_key.pub", []byte("malformed"), 0644)
+	util.WriteFile("/etc/ssh/ssh_host_rsa_key", []byte("private"), 0600)
+
+	assert.Equal(t,
+		[]string{"SHA256:ZP0Pw4NfMqJCGce0gu0XaUazjGgDvJ9n+KgWf2AeMIE"},
+		hostKeyFingerprints())
+}
@@ -49,7 +49,8 @@ func updateACLs(ovsdbClient ovsdb.Client, connections []db.Connection,
 
 	for _, conn := range connections {
 		if conn.From == blueprint.PublicInternetLabel ||
-			conn.To == blueprint.PublicInternetLabel {
+			conn.To == blueprint.PublicInternetLabel ||
+			blueprint.IsExternalLabel(conn.To) {
 			continue
 		}
 
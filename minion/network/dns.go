@@ -3,9 +3,11 @@ package network
 import (
 	"fmt"
 	"net"
+	"sort"
 	"strings"
 	"sync"
 
+	"github.com/quilt/quilt/blueprint"
 	"github.com/quilt/quilt/counter"
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/join"
@@ -17,11 +19,22 @@ import (
 
 const dnsTTL = 60 // Seconds
 
+// SRV records are only generated for connections that allow fewer than this many
+// ports, to keep responses a reasonable size.
+const maxSRVPorts = 32
+
+const resolvConf = "/etc/resolv.conf"
+
 type dnsTable struct {
 	server dns.Server
+	cache  *dnsCache
 
 	recordLock sync.Mutex
+	domain     string
 	records    map[string]net.IP
+	ptrs       map[string]string
+	ports      map[string][]uint16
+	upstreams  []string
 }
 
 var table *dnsTable
@@ -41,7 +54,8 @@ func syncHostnames(conn db.Conn) {
 }
 
 func serveDNS(conn db.Conn) {
-	for range conn.Trigger(db.HostnameTable, db.MinionTable).C {
+	for range conn.Trigger(db.HostnameTable, db.ConnectionTable,
+		db.MinionTable).C {
 		serveDNSOnce(conn)
 	}
 }
@@ -112,19 +126,30 @@ func serveDNSOnce(conn db.Conn) {
 		return
 	}
 
-	table = updateTable(table, conn.SelectFromHostname(nil))
+	table = updateTable(table, self.Domain(), conn.SelectFromHostname(nil),
+		conn.SelectFromConnection(nil), upstreamServers(self.DNSUpstream))
 }
 
-func updateTable(table *dnsTable, hostnames []db.Hostname) *dnsTable {
+func updateTable(table *dnsTable, domain string, hostnames []db.Hostname,
+	connections []db.Connection, upstreams []string) *dnsTable {
 	dnsC.Inc("Update Server")
-	records := hostnamesToDNS(hostnames)
+	records := hostnamesToDNS(hostnames, domain)
+	ptrs := hostnamesToPTR(hostnames, domain)
+	ports := connectionsToPorts(connections, domain)
 	if table != nil {
 		table.recordLock.Lock()
+		table.domain = domain
 		table.records = records
+		table.ptrs = ptrs
+		table.ports = ports
+		table.upstreams = upstreams
 		table.recordLock.Unlock()
 		return table
 	}
-	table = makeTable(records)
+	table = makeTable(domain, records)
+	table.ptrs = ptrs
+	table.ports = ports
+	table.upstreams = upstreams
 
 	// There could be multiple messages depending on how listenAndServe is
 	// implemented.  We don't want anyone to block, so we make a bit of a buffer.
@@ -148,9 +173,6 @@ func (table *dnsTable) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
 	log.Debug("DNS Request: ", req)
 
 	resp := table.genResponse(req)
-	if resp == nil {
-		return
-	}
 	log.Debug("DNS Response: ", resp)
 
 	if err := w.WriteMsg(resp); err != nil {
@@ -164,70 +186,171 @@ func (table *dnsTable) genResponse(req *dns.Msg) *dns.Msg {
 		return resp.SetRcode(req, dns.RcodeNotImplemented)
 	}
 	q := req.Question[0]
-	if q.Qclass != dns.ClassINET || q.Qtype != dns.TypeA {
+	if q.Qclass != dns.ClassINET {
 		return resp.SetRcode(req, dns.RcodeNotImplemented)
 	}
 
-	ips := table.lookupA(q.Name)
-	if len(ips) == 0 {
-		// Even though the client asked for a hostname within `.q` that we know
-		// nothing about, it's possible we'll learn about it in the future.  For
-		// now, we'll just not respond, the client will time out, and try again
-		// later.  Hopefully by then we have a response for them -- or if not,
-		// eventually they'll give up.
-		//
-		// XXX: The above logic is correct for things in the .q domain name, but
-		// we're also doing the same thing for failures to resolve external
-		// hosts.  This isn't entirely correct, it would be much better to return
-		// whatever upstream gave us in case of a failure.
-		return nil
+	name := strings.ToLower(q.Name)
+	table.recordLock.Lock()
+	domain := table.domain
+	table.recordLock.Unlock()
+	if !isLocalName(name, domain) {
+		return table.forward(req)
 	}
 
-	resp.SetReply(req)
-	for _, ip := range ips {
-		resp.Answer = append(resp.Answer, &dns.A{
-			Hdr: dns.RR_Header{
-				Name:   q.Name,
-				Rrtype: dns.TypeA,
-				Class:  dns.ClassINET,
-				Ttl:    dnsTTL,
-			},
-			A: ip,
-		})
+	answer, ok := table.lookup(name, q.Qtype)
+	if !ok {
+		// Without an SOA record, resolvers won't cache the failure, so the
+		// name resolves as soon as it's added to the deployment.
+		resp.SetRcode(req, dns.RcodeNameError)
+		resp.Authoritative = true
+		return resp
 	}
+
+	resp.SetReply(req)
+	resp.Authoritative = true
+	resp.Answer = answer
 	return resp
 }
 
-func (table *dnsTable) lookupA(name string) []net.IP {
+// lookup returns the records of type `qtype` for `name`, which must be a name
+// Quilt is authoritative for.  The boolean is false if `name` doesn't exist, in
+// which case the query should fail with NXDOMAIN.  If `name` exists but has no
+// records of type `qtype`, the answer is empty.
+func (table *dnsTable) lookup(name string, qtype uint16) ([]dns.RR, bool) {
+	dnsC.Inc("Lookup Internal")
+	table.recordLock.Lock()
+	defer table.recordLock.Unlock()
+
+	if target, ok := table.ptrs[name]; ok {
+		if qtype != dns.TypePTR {
+			return nil, true
+		}
+		return []dns.RR{&dns.PTR{Hdr: rrHeader(name, dns.TypePTR),
+			Ptr: target}}, true
+	}
+
+	// SRV queries may be prefixed with service and protocol labels, such as
+	// _http._tcp.foo.q, which don't affect the answer.  The answer is still owned
+	// by the queried name, so that resolvers match it to the question.
+	host := name
+	if qtype == dns.TypeSRV {
+		host = trimServiceLabels(name)
+	}
+
+	ip := table.records[host]
+	if ip == nil {
+		return nil, false
+	}
+
+	switch {
+	case qtype == dns.TypeA && ip.To4() != nil:
+		return []dns.RR{&dns.A{Hdr: rrHeader(name, dns.TypeA), A: ip}}, true
+	case qtype == dns.TypeAAAA && ip.To4() == nil:
+		return []dns.RR{&dns.AAAA{Hdr: rrHeader(name, dns.TypeAAAA),
+			AAAA: ip}}, true
+	case qtype == dns.TypeSRV:
+		var rrs []dns.RR
+		for _, port := range table.ports[host] {
+			rrs = append(rrs, &dns.SRV{
+				Hdr:      rrHeader(name, dns.TypeSRV),
+				Priority: 0,
+				Weight:   1,
+				Port:     port,
+				Target:   host,
+			})
+		}
+		return rrs, true
+	}
+	return nil, true
+}
+
+// forward resolves `req` using the upstream resolvers, and relays their response.
+func (table *dnsTable) forward(req *dns.Msg) *dns.Msg {
 	dnsC.Inc("Lookup External")
-	if strings.HasSuffix(name, ".q.") {
-		table.recordLock.Lock()
-		ip := table.records[name]
-		table.recordLock.Unlock()
-		if ip == nil {
-			return nil
+	q := req.Question[0]
+	if resp := table.cache.get(q); resp != nil {
+		dnsC.Inc("Cache Hit")
+		resp.Id = req.Id
+		return resp
+	}
+
+	table.recordLock.Lock()
+	upstreams := table.upstreams
+	table.recordLock.Unlock()
+
+	for _, upstream := range upstreams {
+		resp, err := exchange(req, upstream)
+		if err != nil {
+			log.WithError(err).WithField("upstream", upstream).Debug(
+				"Failed to forward DNS request: ", q.Name)
+			continue
 		}
-		return []net.IP{ip}
+
+		table.cache.set(q, resp)
+		resp.Id = req.Id
+		return resp
 	}
 
-	ipStrs, err := lookupHost(strings.TrimRight(name, "."))
-	if err != nil {
-		log.WithError(err).Debug("Failed to lookup external record: ", name)
+	dnsC.Inc("Lookup External Failed")
+	return (&dns.Msg{}).SetRcode(req, dns.RcodeServerFailure)
+}
+
+func rrHeader(name string, rrtype uint16) dns.RR_Header {
+	return dns.RR_Header{
+		Name:   name,
+		Rrtype: rrtype,
+		Class:  dns.ClassINET,
+		Ttl:    dnsTTL,
+	}
+}
+
+// isLocalName returns true if Quilt is authoritative for `name`, i.e. it's a
+// hostname within `domain`, or the reverse name of an address in the Quilt subnet.
+func isLocalName(name, domain string) bool {
+	if name == domain+"." || strings.HasSuffix(name, "."+domain+".") {
+		return true
+	}
+
+	ip := reverseToIP(name)
+	return ip != nil && ipdef.QuiltSubnet.Contains(ip)
+}
+
+// reverseToIP parses an in-addr.arpa name, such as 4.3.2.10.in-addr.arpa., into
+// the IP address it refers to.  It returns nil if `name` isn't such a name.
+func reverseToIP(name string) net.IP {
+	const suffix = ".in-addr.arpa."
+	if !strings.HasSuffix(name, suffix) {
 		return nil
 	}
 
-	var ips []net.IP
-	for _, ipStr := range ipStrs {
-		if ip := net.ParseIP(ipStr); ip != nil && ip.To4() != nil {
-			ips = append(ips, ip)
+	octets := strings.Split(strings.TrimSuffix(name, suffix), ".")
+	if len(octets) != 4 {
+		return nil
+	}
+
+	for i, j := 0, len(octets)-1; i < j; i, j = i+1, j-1 {
+		octets[i], octets[j] = octets[j], octets[i]
+	}
+	return net.ParseIP(strings.Join(octets, ".")).To4()
+}
+
+func trimServiceLabels(name string) string {
+	for strings.HasPrefix(name, "_") {
+		i := strings.Index(name, ".")
+		if i < 0 {
+			break
 		}
+		name = name[i+1:]
 	}
-	return ips
+	return name
 }
 
-func makeTable(records map[string]net.IP) *dnsTable {
+func makeTable(domain string, records map[string]net.IP) *dnsTable {
 	tbl := &dnsTable{
+		domain:  domain,
 		records: records,
+		cache:   newDNSCache(),
 		server: dns.Server{
 			Addr: fmt.Sprintf("%s:53", ipdef.GatewayIP),
 			Net:  "udp",
@@ -237,18 +360,129 @@ func makeTable(records map[string]net.IP) *dnsTable {
 	return tbl
 }
 
-func hostnamesToDNS(hostnames []db.Hostname) map[string]net.IP {
+func hostnamesToDNS(hostnames []db.Hostname, domain string) map[string]net.IP {
 	records := map[string]net.IP{}
 	for _, hn := range hostnames {
 		if ip := net.ParseIP(hn.IP); ip != nil {
-			records[hn.Hostname+".q."] = ip
+			records[fqdn(hn.Hostname, domain)] = ip
 		}
 	}
 	return records
 }
 
+// hostnamesToPTR maps the reverse name of each hostname's IP to the hostname.  If
+// several hostnames share an IP, the alphabetically first one is used.
+func hostnamesToPTR(hostnames []db.Hostname, domain string) map[string]string {
+	ptrs := map[string]string{}
+	for _, hn := range hostnames {
+		if net.ParseIP(hn.IP) == nil {
+			continue
+		}
+
+		arpa, err := dns.ReverseAddr(hn.IP)
+		if err != nil {
+			continue
+		}
+
+		target := fqdn(hn.Hostname, domain)
+		if old, ok := ptrs[arpa]; !ok || target < old {
+			ptrs[arpa] = target
+		}
+	}
+	return ptrs
+}
+
+// connectionsToPorts maps each hostname to the sorted ports that connections
+// allow into it.
+func connectionsToPorts(connections []db.Connection,
+	domain string) map[string][]uint16 {
+	portSets := map[string]map[uint16]struct{}{}
+	for _, conn := range connections {
+		if conn.To == blueprint.PublicInternetLabel ||
+			blueprint.IsExternalLabel(conn.To) ||
+			conn.MinPort <= 0 || conn.MaxPort > 65535 ||
+			conn.MaxPort-conn.MinPort >= maxSRVPorts {
+			continue
+		}
+
+		name := fqdn(conn.To, domain)
+		if portSets[name] == nil {
+			portSets[name] = map[uint16]struct{}{}
+		}
+		for port := conn.MinPort; port <= conn.MaxPort; port++ {
+			portSets[name][uint16(port)] = struct{}{}
+		}
+	}
+
+	ports := map[string][]uint16{}
+	for name, set := range portSets {
+		for port := range set {
+			ports[name] = append(ports[name], port)
+		}
+		sort.Sort(portSlice(ports[name]))
+	}
+	return ports
+}
+
+// fqdn returns the fully qualified name of `hostname` within `domain`.
+func fqdn(hostname, domain string) string {
+	return strings.ToLower(hostname + "." + domain + ".")
+}
+
+// upstreamServers returns the addresses of the resolvers that external queries
+// are forwarded to.  If the deployment doesn't configure any, the minion's own
+// resolvers are used.
+func upstreamServers(configured []string) []string {
+	servers := configured
+	if len(servers) == 0 {
+		cfg, err := clientConfigFromFile(resolvConf)
+		if err != nil {
+			log.WithError(err).Warn("Failed to read upstream DNS servers")
+			return nil
+		}
+		servers = cfg.Servers
+	}
+
+	var addrs []string
+	for _, server := range servers {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		addrs = append(addrs, server)
+	}
+	return addrs
+}
+
+type portSlice []uint16
+
+func (ports portSlice) Len() int {
+	return len(ports)
+}
+
+func (ports portSlice) Less(i, j int) bool {
+	return ports[i] < ports[j]
+}
+
+func (ports portSlice) Swap(i, j int) {
+	ports[i], ports[j] = ports[j], ports[i]
+}
+
+// defaultExchange sends `req` to the resolver at `addr`, retrying over TCP if the
+// UDP response was truncated.
+func defaultExchange(req *dns.Msg, addr string) (*dns.Msg, error) {
+	client := dns.Client{Net: "udp"}
+	resp, _, err := client.Exchange(req, addr)
+	if err == nil && resp.Truncated {
+		client.Net = "tcp"
+		resp, _, err = client.Exchange(req, addr)
+	}
+	return resp, err
+}
+
+// Stored in variables so that they may be mocked out in unit tests.
 var listenAndServe = func(table *dnsTable) error {
 	return table.server.ListenAndServe()
 }
 
-var lookupHost = net.LookupHost
+var exchange = defaultExchange
+var clientConfigFromFile = dns.ClientConfigFromFile
@@ -0,0 +1,128 @@
+package network
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Upstream responses are cached for the smallest TTL among their records, capped
+// at `maxCacheTTL`.  Responses without records, such as NXDOMAIN without an SOA,
+// are cached for `negativeCacheTTL`.
+const maxCacheTTL = time.Hour
+const negativeCacheTTL = 30 * time.Second
+const maxCacheEntries = 4096
+
+type dnsCache struct {
+	sync.Mutex
+	entries map[dns.Question]cacheEntry
+}
+
+type cacheEntry struct {
+	resp    *dns.Msg
+	stored  time.Time
+	expires time.Time
+}
+
+func newDNSCache() *dnsCache {
+	return &dnsCache{entries: map[dns.Question]cacheEntry{}}
+}
+
+// get returns a copy of the cached response to `q`, with its TTLs reduced by the
+// time it spent in the cache, or nil if there is no such response.
+func (cache *dnsCache) get(q dns.Question) *dns.Msg {
+	cache.Lock()
+	entry, ok := cache.entries[cacheKey(q)]
+	cache.Unlock()
+
+	currTime := now()
+	if !ok || !currTime.Before(entry.expires) {
+		return nil
+	}
+
+	resp := entry.resp.Copy()
+	elapsed := uint32(currTime.Sub(entry.stored) / time.Second)
+	for _, section := range [][]dns.RR{resp.Answer, resp.Ns, resp.Extra} {
+		for _, rr := range section {
+			if hdr := rr.Header(); hdr.Rrtype != dns.TypeOPT {
+				hdr.Ttl -= elapsed
+			}
+		}
+	}
+	return resp
+}
+
+// set caches `resp` as the answer to `q`, if it's a successful answer or a
+// definitive NXDOMAIN.
+func (cache *dnsCache) set(q dns.Question, resp *dns.Msg) {
+	if resp.Truncated || (resp.Rcode != dns.RcodeSuccess &&
+		resp.Rcode != dns.RcodeNameError) {
+		return
+	}
+
+	ttl := negativeCacheTTL
+	if len(resp.Answer)+len(resp.Ns) > 0 {
+		ttl = maxCacheTTL
+	}
+	for _, section := range [][]dns.RR{resp.Answer, resp.Ns} {
+		for _, rr := range section {
+			rrTTL := time.Duration(rr.Header().Ttl) * time.Second
+			if soa, ok := rr.(*dns.SOA); ok {
+				// Negative answers are cached for the SOA's minimum TTL.
+				minTTL := time.Duration(soa.Minttl) * time.Second
+				if minTTL < rrTTL {
+					rrTTL = minTTL
+				}
+			}
+
+			if rrTTL < ttl {
+				ttl = rrTTL
+			}
+		}
+	}
+
+	if ttl <= 0 {
+		return
+	}
+
+	cache.Lock()
+	defer cache.Unlock()
+
+	currTime := now()
+	if len(cache.entries) >= maxCacheEntries {
+		cache.evict(currTime)
+	}
+	cache.entries[cacheKey(q)] = cacheEntry{
+		resp:    resp.Copy(),
+		stored:  currTime,
+		expires: currTime.Add(ttl),
+	}
+}
+
+// evict makes room in the cache by removing expired entries, or if there are
+// none, an arbitrary entry.  The caller must hold the cache's lock.
+func (cache *dnsCache) evict(currTime time.Time) {
+	for q, entry := range cache.entries {
+		if !currTime.Before(entry.expires) {
+			delete(cache.entries, q)
+		}
+	}
+
+	for q := range cache.entries {
+		if len(cache.entries) < maxCacheEntries {
+			break
+		}
+		delete(cache.entries, q)
+	}
+}
+
+// DNS names are case insensitive.
+func cacheKey(q dns.Question) dns.Question {
+	q.Name = strings.ToLower(q.Name)
+	return q
+}
+
+// Stored in a variable so that it may be mocked out in unit tests.
+var now = time.Now
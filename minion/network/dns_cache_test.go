@@ -0,0 +1,94 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDNSCache(t *testing.T) {
+	defer func() { now = time.Now }()
+	timestamp := time.Now()
+	now = func() time.Time { return timestamp }
+
+	cache := newDNSCache()
+	q := dns.Question{Name: "quilt.io.", Qtype: dns.TypeA, Qclass: dns.ClassINET}
+	assert.Nil(t, cache.get(q))
+
+	resp := &dns.Msg{}
+	resp.Answer = []dns.RR{
+		&dns.A{Hdr: dns.RR_Header{Name: "quilt.io.", Rrtype: dns.TypeA,
+			Class: dns.ClassINET, Ttl: 300}, A: net.IPv4(1, 2, 3, 4)},
+		&dns.A{Hdr: dns.RR_Header{Name: "quilt.io.", Rrtype: dns.TypeA,
+			Class: dns.ClassINET, Ttl: 60}, A: net.IPv4(5, 6, 7, 8)},
+	}
+	cache.set(q, resp)
+
+	// Lookups are case insensitive, and don't share the cached response.
+	upperQ := q
+	upperQ.Name = "QUILT.io."
+	cached := cache.get(upperQ)
+	assert.Equal(t, resp, cached)
+	assert.False(t, resp == cached)
+
+	timestamp = timestamp.Add(20 * time.Second)
+	cached = cache.get(q)
+	assert.Equal(t, uint32(280), cached.Answer[0].Header().Ttl)
+	assert.Equal(t, uint32(40), cached.Answer[1].Header().Ttl)
+
+	// The response expires with its shortest TTL.
+	timestamp = timestamp.Add(40 * time.Second)
+	assert.Nil(t, cache.get(q))
+
+	// Failures aren't cached.
+	resp = &dns.Msg{}
+	resp.Rcode = dns.RcodeServerFailure
+	cache.set(q, resp)
+	assert.Nil(t, cache.get(q))
+
+	// Negative answers are cached for the SOA's minimum TTL.
+	resp = &dns.Msg{}
+	resp.Rcode = dns.RcodeNameError
+	resp.Ns = []dns.RR{&dns.SOA{Hdr: dns.RR_Header{Name: "io.",
+		Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 900}, Minttl: 10}}
+	cache.set(q, resp)
+	assert.NotNil(t, cache.get(q))
+	timestamp = timestamp.Add(10 * time.Second)
+	assert.Nil(t, cache.get(q))
+
+	// Or a default if there's no SOA.
+	resp = &dns.Msg{}
+	resp.Rcode = dns.RcodeNameError
+	cache.set(q, resp)
+	timestamp = timestamp.Add(negativeCacheTTL - time.Second)
+	assert.NotNil(t, cache.get(q))
+	timestamp = timestamp.Add(time.Second)
+	assert.Nil(t, cache.get(q))
+}
+
+func TestDNSCacheEvict(t *testing.T) {
+	defer func() { now = time.Now }()
+	timestamp := time.Now()
+	now = func() time.Time { return timestamp }
+
+	cache := newDNSCache()
+	resp := &dns.Msg{}
+	resp.Rcode = dns.RcodeNameError
+	for i := 0; i < maxCacheEntries; i++ {
+		cache.set(dns.Question{Name: fmt.Sprintf("%d.", i)}, resp)
+	}
+	assert.Equal(t, maxCacheEntries, len(cache.entries))
+
+	cache.set(dns.Question{Name: "new"}, resp)
+	assert.Equal(t, maxCacheEntries, len(cache.entries))
+	assert.NotNil(t, cache.get(dns.Question{Name: "new"}))
+
+	// Expired entries are evicted first.
+	timestamp = timestamp.Add(negativeCacheTTL)
+	cache.set(dns.Question{Name: "newer"}, resp)
+	assert.Equal(t, 1, len(cache.entries))
+}
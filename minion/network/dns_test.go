@@ -3,6 +3,7 @@ package network
 import (
 	"net"
 	"testing"
+	"time"
 
 	"github.com/miekg/dns"
 	"github.com/quilt/quilt/db"
@@ -13,50 +14,65 @@ func TestUpdateTable(t *testing.T) {
 	t.Parallel()
 
 	listenAndServe = func(table *dnsTable) error { return assert.AnError }
-	assert.Nil(t, updateTable(nil, nil))
+	assert.Nil(t, updateTable(nil, "q", nil, nil, nil))
 
 	listenAndServe = func(table *dnsTable) error {
 		table.server.NotifyStartedFunc()
 		return nil
 	}
 
-	table := updateTable(nil, []db.Hostname{{Hostname: "foo", IP: "1.2.3.4"}})
+	table := updateTable(nil, "q", []db.Hostname{{Hostname: "foo", IP: "1.2.3.4"}},
+		[]db.Connection{{From: "bar", To: "foo", MinPort: 80, MaxPort: 80}},
+		[]string{"8.8.8.8:53"})
 	assert.NotNil(t, table)
 	assert.Equal(t, map[string]net.IP{"foo.q.": net.IPv4(1, 2, 3, 4)}, table.records)
+	assert.Equal(t, map[string]string{"4.3.2.1.in-addr.arpa.": "foo.q."},
+		table.ptrs)
+	assert.Equal(t, map[string][]uint16{"foo.q.": {80}}, table.ports)
+	assert.Equal(t, []string{"8.8.8.8:53"}, table.upstreams)
 
-	newTable := updateTable(table, []db.Hostname{{Hostname: "foo", IP: "5.6.7.8"}})
+	newTable := updateTable(table, "svc.example.com",
+		[]db.Hostname{{Hostname: "foo", IP: "5.6.7.8"}}, nil, nil)
 	assert.NotNil(t, newTable)
 	assert.True(t, table == newTable) // Pointer Equality.
-	assert.Equal(t, map[string]net.IP{"foo.q.": net.IPv4(5, 6, 7, 8)},
-		newTable.records)
+	assert.Equal(t, "svc.example.com", newTable.domain)
+	assert.Equal(t, map[string]net.IP{
+		"foo.svc.example.com.": net.IPv4(5, 6, 7, 8)}, newTable.records)
+	assert.Equal(t, map[string]string{
+		"8.7.6.5.in-addr.arpa.": "foo.svc.example.com."}, newTable.ptrs)
+	assert.Empty(t, newTable.ports)
+	assert.Empty(t, newTable.upstreams)
 }
 
 func TestGenResponse(t *testing.T) {
-	t.Parallel()
-
-	table := makeTable(map[string]net.IP{
+	table := makeTable("q", map[string]net.IP{
 		"a.q.": net.IPv4(1, 2, 3, 4),
 	})
 
 	req := &dns.Msg{}
-	req.SetQuestion("foo.", dns.TypeAAAA)
+	req.Question = nil
 	resp := table.genResponse(req)
 	assert.Equal(t, req.Id, resp.Id)
-	assert.Equal(t, resp.Rcode, dns.RcodeNotImplemented)
+	assert.Equal(t, dns.RcodeNotImplemented, resp.Rcode)
 
-	req.Question = nil
+	req.SetQuestion("a.q.", dns.TypeA)
+	req.Question[0].Qclass = dns.ClassCHAOS
 	resp = table.genResponse(req)
-	assert.Equal(t, req.Id, resp.Id)
 	assert.Equal(t, dns.RcodeNotImplemented, resp.Rcode)
 
 	req.SetQuestion("bad.q.", dns.TypeA)
 	resp = table.genResponse(req)
-	assert.Nil(t, resp)
+	assert.Equal(t, req.Id, resp.Id)
+	assert.Equal(t, dns.RcodeNameError, resp.Rcode)
+	assert.True(t, resp.Authoritative)
+	assert.Empty(t, resp.Answer)
 
-	req.SetQuestion("a.q.", dns.TypeA)
+	// Names are case insensitive.
+	req.SetQuestion("A.q.", dns.TypeA)
 	resp = table.genResponse(req)
 	exp := *req
 	exp.Response = true
+	exp.Authoritative = true
 	exp.Rcode = dns.RcodeSuccess
 	exp.Answer = []dns.RR{&dns.A{
 		Hdr: dns.RR_Header{
@@ -69,41 +85,187 @@ func TestGenResponse(t *testing.T) {
 	}}
 	assert.Equal(t, &exp, resp)
 
+	// Known names without records of the requested type have an empty answer.
+	req.SetQuestion("a.q.", dns.TypeAAAA)
+	resp = table.genResponse(req)
+	assert.Equal(t, dns.RcodeSuccess, resp.Rcode)
+	assert.Empty(t, resp.Answer)
+
+	// Other names are forwarded upstream.
+	defer func() { exchange = defaultExchange }()
+	table.upstreams = []string{"8.8.8.8:53"}
+	exchange = func(req *dns.Msg, addr string) (*dns.Msg, error) {
+		resp := &dns.Msg{}
+		return resp.SetRcode(req, dns.RcodeNameError), nil
+	}
+	req.SetQuestion("foo.", dns.TypeAAAA)
+	resp = table.genResponse(req)
+	assert.Equal(t, req.Id, resp.Id)
+	assert.Equal(t, dns.RcodeNameError, resp.Rcode)
 }
 
-func TestLookupA(t *testing.T) {
+func TestLookup(t *testing.T) {
 	t.Parallel()
 
-	table := makeTable(map[string]net.IP{
-		"a.q.": net.IPv4(1, 2, 3, 4),
+	ipv6 := net.ParseIP("fd00::1")
+	table := makeTable("q", map[string]net.IP{
+		"a.q.":  net.IPv4(1, 2, 3, 4),
+		"lb.q.": net.IPv4(5, 6, 7, 8),
+		"v6.q.": ipv6,
 	})
+	table.ptrs = map[string]string{"4.3.2.1.in-addr.arpa.": "a.q."}
+	table.ports = map[string][]uint16{"lb.q.": {80, 443}}
+
+	rrs, ok := table.lookup("bad.q.", dns.TypeA)
+	assert.False(t, ok)
+	assert.Empty(t, rrs)
+
+	rrs, ok = table.lookup("a.q.", dns.TypeA)
+	assert.True(t, ok)
+	assert.Equal(t, []dns.RR{&dns.A{Hdr: rrHeader("a.q.", dns.TypeA),
+		A: net.IPv4(1, 2, 3, 4)}}, rrs)
+
+	rrs, ok = table.lookup("a.q.", dns.TypeAAAA)
+	assert.True(t, ok)
+	assert.Empty(t, rrs)
+
+	rrs, ok = table.lookup("v6.q.", dns.TypeAAAA)
+	assert.True(t, ok)
+	assert.Equal(t, []dns.RR{&dns.AAAA{Hdr: rrHeader("v6.q.", dns.TypeAAAA),
+		AAAA: ipv6}}, rrs)
+
+	rrs, ok = table.lookup("v6.q.", dns.TypeA)
+	assert.True(t, ok)
+	assert.Empty(t, rrs)
+
+	expSRV := []dns.RR{&dns.SRV{
+		Hdr:    rrHeader("lb.q.", dns.TypeSRV),
+		Weight: 1,
+		Port:   80,
+		Target: "lb.q.",
+	}, &dns.SRV{
+		Hdr:    rrHeader("lb.q.", dns.TypeSRV),
+		Weight: 1,
+		Port:   443,
+		Target: "lb.q.",
+	}}
+	rrs, ok = table.lookup("lb.q.", dns.TypeSRV)
+	assert.True(t, ok)
+	assert.Equal(t, expSRV, rrs)
+
+	rrs, ok = table.lookup("_http._tcp.lb.q.", dns.TypeSRV)
+	assert.True(t, ok)
+	for _, rr := range expSRV {
+		rr.Header().Name = "_http._tcp.lb.q."
+	}
+	assert.Equal(t, expSRV, rrs)
+
+	rrs, ok = table.lookup("a.q.", dns.TypeSRV)
+	assert.True(t, ok)
+	assert.Empty(t, rrs)
+
+	rrs, ok = table.lookup("4.3.2.1.in-addr.arpa.", dns.TypePTR)
+	assert.True(t, ok)
+	assert.Equal(t, []dns.RR{&dns.PTR{
+		Hdr: rrHeader("4.3.2.1.in-addr.arpa.", dns.TypePTR),
+		Ptr: "a.q.",
+	}}, rrs)
+
+	rrs, ok = table.lookup("5.3.2.1.in-addr.arpa.", dns.TypePTR)
+	assert.False(t, ok)
+	assert.Empty(t, rrs)
+}
 
-	assert.Empty(t, table.lookupA("bad.q."))
-	assert.Equal(t, []net.IP{net.IPv4(1, 2, 3, 4)}, table.lookupA("a.q."))
+func TestForward(t *testing.T) {
+	defer func() {
+		exchange = defaultExchange
+		now = time.Now
+	}()
+
+	timestamp := time.Now()
+	now = func() time.Time { return timestamp }
+
+	table := makeTable("q", nil)
+	table.upstreams = []string{"1.1.1.1:53", "8.8.8.8:53"}
+
+	var queried []string
+	exchange = func(req *dns.Msg, addr string) (*dns.Msg, error) {
+		queried = append(queried, addr)
+		if addr == "1.1.1.1:53" {
+			return nil, assert.AnError
+		}
+
+		resp := &dns.Msg{}
+		resp.SetReply(req)
+		resp.Answer = []dns.RR{&dns.CNAME{
+			Hdr: dns.RR_Header{Name: "quilt.io.", Rrtype: dns.TypeCNAME,
+				Class: dns.ClassINET, Ttl: 100},
+			Target: "www.quilt.io.",
+		}}
+		return resp, nil
+	}
 
-	lookupHost = func(string) ([]string, error) { return nil, assert.AnError }
-	assert.Empty(t, table.lookupA("quilt.io."))
+	req := &dns.Msg{}
+	req.SetQuestion("quilt.io.", dns.TypeCNAME)
+	resp := table.forward(req)
+	assert.Equal(t, []string{"1.1.1.1:53", "8.8.8.8:53"}, queried)
+	assert.Equal(t, req.Id, resp.Id)
+	assert.Equal(t, dns.RcodeSuccess, resp.Rcode)
+	assert.Len(t, resp.Answer, 1)
+
+	// The second request is answered from the cache.
+	queried = nil
+	timestamp = timestamp.Add(10 * time.Second)
+	req.Id++
+	resp = table.forward(req)
+	assert.Empty(t, queried)
+	assert.Equal(t, req.Id, resp.Id)
+	assert.Equal(t, uint32(90), resp.Answer[0].Header().Ttl)
+
+	// Once the response expires, the upstreams are queried again, and if all
+	// of them fail, so does the request.
+	timestamp = timestamp.Add(90 * time.Second)
+	exchange = func(req *dns.Msg, addr string) (*dns.Msg, error) {
+		queried = append(queried, addr)
+		return nil, assert.AnError
+	}
+	resp = table.forward(req)
+	assert.Equal(t, []string{"1.1.1.1:53", "8.8.8.8:53"}, queried)
+	assert.Equal(t, req.Id, resp.Id)
+	assert.Equal(t, dns.RcodeServerFailure, resp.Rcode)
+}
 
-	lookupHost = func(string) ([]string, error) { return []string{"bad"}, nil }
-	assert.Empty(t, table.lookupA("quilt.io."))
+func TestIsLocalName(t *testing.T) {
+	t.Parallel()
 
-	lookupHost = func(string) ([]string, error) {
-		return []string{"2601:644:380:cde:fc06:2533:adf9:2891"}, nil
-	}
-	assert.Empty(t, table.lookupA("quilt.io."))
+	assert.True(t, isLocalName("q.", "q"))
+	assert.True(t, isLocalName("foo.q.", "q"))
+	assert.True(t, isLocalName("4.3.2.10.in-addr.arpa.", "q"))
+	assert.False(t, isLocalName("quilt.io.", "q"))
+	assert.False(t, isLocalName("foo.q.io.", "q"))
+	assert.False(t, isLocalName("8.8.8.8.in-addr.arpa.", "q"))
+	assert.False(t, isLocalName("10.in-addr.arpa.", "q"))
+
+	assert.True(t, isLocalName("foo.svc.example.com.", "svc.example.com"))
+	assert.False(t, isLocalName("foo.q.", "svc.example.com"))
+	assert.False(t, isLocalName("foo.example.com.", "svc.example.com"))
+}
 
-	lookupHost = func(string) ([]string, error) {
-		return []string{"1.2.3.4", "5.6.7.8"}, nil
-	}
-	assert.Equal(t, []net.IP{net.IPv4(1, 2, 3, 4), net.IPv4(5, 6, 7, 8)},
-		table.lookupA("quilt.io."))
+func TestReverseToIP(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, net.IPv4(10, 2, 3, 4).To4(),
+		reverseToIP("4.3.2.10.in-addr.arpa."))
+	assert.Nil(t, reverseToIP("3.2.10.in-addr.arpa."))
+	assert.Nil(t, reverseToIP("a.3.2.10.in-addr.arpa."))
+	assert.Nil(t, reverseToIP("foo.q."))
 }
 
 func TestMakeTable(t *testing.T) {
 	t.Parallel()
 
 	records := map[string]net.IP{"a": net.IPv4(1, 2, 3, 4)}
-	tbl := makeTable(records)
+	tbl := makeTable("q", records)
 	assert.Equal(t, tbl.records, records)
 	assert.Equal(t, tbl.server.Addr, "10.0.0.1:53")
 	assert.Equal(t, tbl.server.Net, "udp")
@@ -129,7 +291,7 @@ func TestHostnamesToDNS(t *testing.T) {
 	}, {
 		Hostname: "2.h4",
 		IP:       "2.2.2.2",
-	}})
+	}}, "q")
 	exp := map[string]net.IP{
 		"h3.q.":   net.IPv4(1, 2, 3, 4),
 		"h4.q.":   net.IPv4(5, 6, 7, 8),
@@ -139,6 +301,54 @@ func TestHostnamesToDNS(t *testing.T) {
 	assert.Equal(t, exp, res)
 }
 
+func TestHostnamesToPTR(t *testing.T) {
+	t.Parallel()
+
+	res := hostnamesToPTR([]db.Hostname{
+		{Hostname: "h1", IP: "badIP"},
+		{Hostname: "h2", IP: "1.2.3.4"},
+		{Hostname: "h4", IP: "5.6.7.8"},
+		{Hostname: "h3", IP: "5.6.7.8"},
+	}, "q")
+	assert.Equal(t, map[string]string{
+		"4.3.2.1.in-addr.arpa.": "h2.q.",
+		"8.7.6.5.in-addr.arpa.": "h3.q.",
+	}, res)
+}
+
+func TestConnectionsToPorts(t *testing.T) {
+	t.Parallel()
+
+	res := connectionsToPorts([]db.Connection{
+		{From: "a", To: "b", MinPort: 443, MaxPort: 443},
+		{From: "c", To: "b", MinPort: 80, MaxPort: 81},
+		{From: "public", To: "b", MinPort: 80, MaxPort: 80},
+		{From: "a", To: "public", MinPort: 53, MaxPort: 53},
+		{From: "a", To: "c", MinPort: 1, MaxPort: 65535},
+		{From: "a", To: "cidr:8.8.8.8/32", MinPort: 53, MaxPort: 53},
+		{From: "a", To: "domain:example.com", MinPort: 443, MaxPort: 443},
+	}, "q")
+	assert.Equal(t, map[string][]uint16{"b.q.": {80, 81, 443}}, res)
+}
+
+func TestUpstreamServers(t *testing.T) {
+	defer func() { clientConfigFromFile = dns.ClientConfigFromFile }()
+
+	assert.Equal(t, []string{"8.8.8.8:53", "10.1.0.2:5353", "[fd00::1]:53"},
+		upstreamServers([]string{"8.8.8.8", "10.1.0.2:5353", "fd00::1"}))
+
+	clientConfigFromFile = func(path string) (*dns.ClientConfig, error) {
+		assert.Equal(t, "/etc/resolv.conf", path)
+		return &dns.ClientConfig{Servers: []string{"1.1.1.1"}}, nil
+	}
+	assert.Equal(t, []string{"1.1.1.1:53"}, upstreamServers(nil))
+
+	clientConfigFromFile = func(path string) (*dns.ClientConfig, error) {
+		return nil, assert.AnError
+	}
+	assert.Nil(t, upstreamServers(nil))
+}
+
 func TestSyncHostnamesWorker(t *testing.T) {
 	conn := db.New()
 	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
@@ -0,0 +1,117 @@
+package network
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/quilt/quilt/blueprint"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/util"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// How often the domains that connections allow traffic to are resolved again.
+const resolveInterval = time.Minute
+
+// domainResolver tracks the addresses that each domain last resolved to, and when.
+type domainResolver struct {
+	ips          map[string][]string
+	lastResolved map[string]time.Time
+}
+
+// The leader periodically resolves the domains that connections allow traffic to,
+// and records the addresses in the connections.  The workers only allow the
+// containers' traffic to those addresses.
+func runResolveDomains(conn db.Conn) {
+	r := domainResolver{
+		ips:          map[string][]string{},
+		lastResolved: map[string]time.Time{},
+	}
+	for range conn.TriggerTick(30, db.ConnectionTable, db.EtcdTable).C {
+		if conn.EtcdLeader() {
+			r.runOnce(conn)
+		}
+	}
+}
+
+func (r *domainResolver) runOnce(conn db.Conn) {
+	domains := map[string]struct{}{}
+	for _, dbc := range conn.SelectFromConnection(nil) {
+		if domain := connectionDomain(dbc); domain != "" {
+			domains[domain] = struct{}{}
+		}
+	}
+
+	for domain := range r.lastResolved {
+		if _, ok := domains[domain]; !ok {
+			delete(r.lastResolved, domain)
+			delete(r.ips, domain)
+		}
+	}
+
+	// The domains are resolved outside of the transaction, as lookups may be slow.
+	for domain := range domains {
+		last, ok := r.lastResolved[domain]
+		if ok && time.Since(last) < resolveInterval {
+			continue
+		}
+		r.lastResolved[domain] = time.Now()
+
+		ips, err := resolveDomain(domain)
+		if err != nil {
+			// Keep allowing the previous addresses until the lookup succeeds.
+			log.WithError(err).WithField("domain", domain).Warn(
+				"Failed to resolve domain.")
+			continue
+		}
+		r.ips[domain] = ips
+	}
+
+	conn.Txn(db.ConnectionTable).Run(func(view db.Database) error {
+		for _, dbc := range view.SelectFromConnection(nil) {
+			ips, ok := r.ips[connectionDomain(dbc)]
+			if ok && !util.StrSliceEqual(ips, dbc.ResolvedIPs) {
+				dbc.ResolvedIPs = ips
+				view.Commit(dbc)
+			}
+		}
+		return nil
+	})
+}
+
+// resolveDomain returns the sorted IPv4 addresses that `domain` resolves to.
+func resolveDomain(domain string) ([]string, error) {
+	c.Inc("Resolve Domain")
+	addrs, err := lookupHost(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	ipSet := map[string]struct{}{}
+	for _, addr := range addrs {
+		if ip := net.ParseIP(addr); ip != nil && ip.To4() != nil {
+			ipSet[ip.String()] = struct{}{}
+		}
+	}
+
+	ips := []string{}
+	for ip := range ipSet {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	return ips, nil
+}
+
+// connectionDomain returns the domain that `dbc` allows traffic to, or "" if it
+// isn't a connection to a domain.
+func connectionDomain(dbc db.Connection) string {
+	if !strings.HasPrefix(dbc.To, blueprint.DomainLabelPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(dbc.To, blueprint.DomainLabelPrefix)
+}
+
+var lookupHost = net.LookupHost
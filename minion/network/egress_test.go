@@ -0,0 +1,82 @@
+package network
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/quilt/quilt/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveDomains(t *testing.T) {
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		dbc := view.InsertConnection()
+		dbc.From = "a"
+		dbc.To = "domain:example.com"
+		view.Commit(dbc)
+
+		dbc = view.InsertConnection()
+		dbc.From = "a"
+		dbc.To = "cidr:52.1.0.0/16"
+		view.Commit(dbc)
+		return nil
+	})
+
+	var lookups []string
+	addrs := []string{"1.2.3.5", "1.2.3.4", "::1", "1.2.3.4"}
+	var lookupErr error
+	lookupHost = func(domain string) ([]string, error) {
+		lookups = append(lookups, domain)
+		return addrs, lookupErr
+	}
+
+	resolvedIPs := func() map[string][]string {
+		res := map[string][]string{}
+		for _, dbc := range conn.SelectFromConnection(nil) {
+			res[dbc.To] = dbc.ResolvedIPs
+		}
+		return res
+	}
+
+	r := domainResolver{
+		ips:          map[string][]string{},
+		lastResolved: map[string]time.Time{},
+	}
+	r.runOnce(conn)
+	assert.Equal(t, []string{"example.com"}, lookups)
+	assert.Equal(t, map[string][]string{
+		"domain:example.com": {"1.2.3.4", "1.2.3.5"},
+		"cidr:52.1.0.0/16":   nil,
+	}, resolvedIPs())
+
+	// Domains aren't resolved again until the interval has passed.
+	addrs = []string{"1.2.3.6"}
+	r.runOnce(conn)
+	assert.Len(t, lookups, 1)
+
+	// Failed lookups keep the previous addresses.
+	r.lastResolved["example.com"] = time.Now().Add(-resolveInterval)
+	lookupErr = errors.New("lookup error")
+	r.runOnce(conn)
+	assert.Len(t, lookups, 2)
+	assert.Equal(t, []string{"1.2.3.4", "1.2.3.5"},
+		resolvedIPs()["domain:example.com"])
+
+	r.lastResolved["example.com"] = time.Now().Add(-resolveInterval)
+	lookupErr = nil
+	r.runOnce(conn)
+	assert.Equal(t, []string{"1.2.3.6"}, resolvedIPs()["domain:example.com"])
+
+	// Domains that are no longer used are forgotten.
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		view.Remove(view.SelectFromConnection(func(dbc db.Connection) bool {
+			return dbc.To == "domain:example.com"
+		})[0])
+		return nil
+	})
+	r.runOnce(conn)
+	assert.Empty(t, r.ips)
+	assert.Empty(t, r.lastResolved)
+}
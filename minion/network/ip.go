@@ -7,6 +7,7 @@ import (
 	"math/rand"
 	"net"
 
+	"github.com/quilt/quilt/blueprint"
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/minion/ipdef"
 
@@ -56,6 +57,10 @@ func makeIPContext(view db.Database, subnetBlacklist []net.IPNet) ipContext {
 			// While not strictly required, it would be odd to allocate
 			// 10.0.0.0.
 			ipdef.QuiltSubnet.IP.String(): {},
+
+			// Packets to the broadcast address aren't delivered to a
+			// single container.
+			blueprint.BroadcastIP(ipdef.QuiltSubnet).String(): {},
 		},
 	}
 
@@ -112,7 +117,9 @@ func updateIPsOnce(view db.Database) error {
 
 // makeSubnetBlacklist returns all subnets that are governed by routes in a
 // worker machine's network stack, and intersect with the Quilt container
-// subnet.
+// subnet.  It fails if a route conflicts with the container network so badly
+// that blacklisting can't work around it, i.e. if it covers the whole subnet or
+// the gateway.  The container network has to be reconfigured in that case.
 func makeSubnetBlacklist(view db.Database) ([]net.IPNet, error) {
 	isWorker := func(m db.Minion) bool {
 		return m.Role == db.Worker
@@ -120,18 +127,23 @@ func makeSubnetBlacklist(view db.Database) ([]net.IPNet, error) {
 
 	subnets := map[string]struct{}{}
 	for _, m := range view.SelectFromMinion(isWorker) {
-		for _, subnet := range m.HostSubnets {
-			subnets[subnet] = struct{}{}
+		for _, subnetStr := range m.HostSubnets {
+			_, subnet, err := net.ParseCIDR(subnetStr)
+			if err != nil {
+				return nil, fmt.Errorf("parse subnet %s: %s", subnetStr,
+					err)
+			}
+
+			if err := checkHostSubnet(*subnet); err != nil {
+				return nil, fmt.Errorf("worker %s: %s", m.PrivateIP, err)
+			}
+			subnets[subnet.String()] = struct{}{}
 		}
 	}
 
 	var subnetBlacklist []net.IPNet
 	for subnetStr := range subnets {
-		_, subnet, err := net.ParseCIDR(subnetStr)
-		if err != nil {
-			return nil, fmt.Errorf("parse subnet %s: %s", subnetStr, err)
-		}
-
+		_, subnet, _ := net.ParseCIDR(subnetStr)
 		if subnetIntersects(ipdef.QuiltSubnet, *subnet) {
 			subnetBlacklist = append(subnetBlacklist, *subnet)
 		}
@@ -139,6 +151,23 @@ func makeSubnetBlacklist(view db.Database) ([]net.IPNet, error) {
 	return subnetBlacklist, nil
 }
 
+// checkHostSubnet returns an error if a host route to `subnet` leaves no room
+// for the container network.
+func checkHostSubnet(subnet net.IPNet) error {
+	hostOnes, _ := subnet.Mask.Size()
+	quiltOnes, _ := ipdef.QuiltSubnet.Mask.Size()
+	if subnet.Contains(ipdef.QuiltSubnet.IP) && hostOnes <= quiltOnes {
+		return fmt.Errorf("route to %s covers the container subnet %s",
+			subnet.String(), ipdef.QuiltSubnet.String())
+	}
+
+	if subnet.Contains(ipdef.GatewayIP) {
+		return fmt.Errorf("route to %s covers the container gateway %s",
+			subnet.String(), ipdef.GatewayIP)
+	}
+	return nil
+}
+
 func subnetIntersects(a net.IPNet, b net.IPNet) bool {
 	return a.Contains(b.IP) || b.Contains(a.IP)
 }
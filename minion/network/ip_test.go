@@ -30,6 +30,32 @@ func TestMakeSubnetBlacklistError(t *testing.T) {
 	})
 }
 
+func TestMakeSubnetBlacklistConflict(t *testing.T) {
+	t.Parallel()
+
+	check := func(hostSubnet, expErr string) {
+		conn := db.New()
+		conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+			m := view.InsertMinion()
+			m.Role = db.Worker
+			m.PrivateIP = "1.2.3.4"
+			m.HostSubnets = []string{"172.31.0.0/16", hostSubnet}
+			view.Commit(m)
+
+			_, err := makeSubnetBlacklist(view)
+			assert.EqualError(t, err, expErr)
+			return nil
+		})
+	}
+
+	check("10.0.0.0/8", "worker 1.2.3.4: route to 10.0.0.0/8 covers "+
+		"the container subnet 10.0.0.0/8")
+	check("8.0.0.0/6", "worker 1.2.3.4: route to 8.0.0.0/6 covers "+
+		"the container subnet 10.0.0.0/8")
+	check("10.0.0.0/16", "worker 1.2.3.4: route to 10.0.0.0/16 covers "+
+		"the container gateway 10.0.0.1")
+}
+
 func TestMakeSubnetBlacklist(t *testing.T) {
 	t.Parallel()
 
@@ -127,10 +153,11 @@ func TestMakeIPContext(t *testing.T) {
 	})
 
 	assert.Equal(t, map[string]struct{}{
-		"10.0.0.0": {},
-		"10.0.0.1": {},
-		"10.0.0.2": {},
-		"10.0.0.3": {},
+		"10.0.0.0":       {},
+		"10.0.0.1":       {},
+		"10.0.0.2":       {},
+		"10.0.0.3":       {},
+		"10.255.255.255": {},
 	}, ctx.reserved)
 
 	assert.Len(t, ctx.unassignedContainers, 2)
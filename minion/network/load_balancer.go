@@ -46,13 +46,15 @@ unchanged.
 8. 10.2.0.1 receives the packet from the router.
 */
 func updateLoadBalancers(client ovsdb.Client, loadBalancers []db.LoadBalancer,
-	hostnameToIP map[string]string) {
-	updateLoadBalancerIPs(client, loadBalancers, hostnameToIP)
+	hostnameToIP map[string]string, healthy map[string]bool) {
+	updateLoadBalancerIPs(client, loadBalancers, hostnameToIP, healthy)
 	updateLoadBalancerARP(client, loadBalancers)
 }
 
+// Load balancers with a Probe only forward traffic to the backend IPs in
+// `healthy`.
 func updateLoadBalancerIPs(client ovsdb.Client, loadBalancers []db.LoadBalancer,
-	hostnameToIP map[string]string) {
+	hostnameToIP map[string]string, healthy map[string]bool) {
 	curr, err := client.ListLoadBalancers()
 	if err != nil {
 		log.WithError(err).Error("Failed to get load balancers")
@@ -64,7 +66,7 @@ func updateLoadBalancerIPs(client ovsdb.Client, loadBalancers []db.LoadBalancer,
 		var ips []string
 		for _, hostname := range lb.Hostnames {
 			ip := hostnameToIP[hostname]
-			if ip != "" {
+			if ip != "" && (lb.Probe == nil || healthy[ip]) {
 				ips = append(ips, ip)
 			}
 		}
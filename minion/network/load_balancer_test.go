@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/quilt/quilt/blueprint"
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/minion/ipdef"
 	"github.com/quilt/quilt/minion/ovsdb"
@@ -17,7 +18,7 @@ func TestUpdateLoadBalancerIPs(t *testing.T) {
 
 	// Test error handling.
 	client.On("ListLoadBalancers").Return(nil, assert.AnError).Once()
-	updateLoadBalancerIPs(client, nil, nil)
+	updateLoadBalancerIPs(client, nil, nil, nil)
 	client.AssertNotCalled(t, "CreateLoadBalancer",
 		mock.Anything, mock.Anything, mock.Anything)
 	client.AssertNotCalled(t, "DeleteLoadBalancer", mock.Anything, mock.Anything)
@@ -51,7 +52,24 @@ func TestUpdateLoadBalancerIPs(t *testing.T) {
 		"red":    "10.0.0.4",
 		"blue":   "10.0.0.3",
 		"yellow": "10.0.0.11",
-	})
+	}, nil)
+	client.AssertExpectations(t)
+
+	// Test that probed load balancers only include healthy backends.
+	client.On("ListLoadBalancers").Return(nil, nil).Once()
+	client.On("CreateLoadBalancer", lSwitch, "probed",
+		map[string]string{"10.0.0.20": "10.0.0.22"}).Return(nil).Once()
+	updateLoadBalancerIPs(client, []db.LoadBalancer{
+		{
+			Name:      "probed",
+			IP:        "10.0.0.20",
+			Hostnames: []string{"sick", "well"},
+			Probe:     &blueprint.Probe{Port: 80},
+		},
+	}, map[string]string{
+		"sick": "10.0.0.21",
+		"well": "10.0.0.22",
+	}, map[string]bool{"10.0.0.22": true})
 	client.AssertExpectations(t)
 }
 
@@ -50,6 +50,20 @@ func (_m *IPTables) AppendUnique(_a0 string, _a1 string, _a2 ...string) error {
 	return r0
 }
 
+// ClearChain provides a mock function with given fields: _a0, _a1
+func (_m *IPTables) ClearChain(_a0 string, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Delete provides a mock function with given fields: _a0, _a1, _a2
 func (_m *IPTables) Delete(_a0 string, _a1 string, _a2 ...string) error {
 	_va := make([]interface{}, len(_a2))
@@ -93,3 +107,40 @@ func (_m *IPTables) List(_a0 string, _a1 string) ([]string, error) {
 
 	return r0, r1
 }
+
+// ListChains provides a mock function with given fields: _a0
+func (_m *IPTables) ListChains(_a0 string) ([]string, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(string) []string); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewChain provides a mock function with given fields: _a0, _a1
+func (_m *IPTables) NewChain(_a0 string, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/quilt/quilt/blueprint"
@@ -21,14 +22,18 @@ import (
 type IPTables interface {
 	Append(string, string, ...string) error
 	AppendUnique(string, string, ...string) error
+	ClearChain(string, string) error
 	Delete(string, string, ...string) error
 	List(string, string) ([]string, error)
+	ListChains(string) ([]string, error)
+	NewChain(string, string) error
 }
 
 var iptC = counter.New("Network IP Tables")
 
 func runNat(conn db.Conn, inboundPubIntf, outboundPubIntf string) {
-	tables := []db.TableType{db.ContainerTable, db.ConnectionTable, db.MinionTable}
+	tables := []db.TableType{db.ContainerTable, db.ConnectionTable, db.MinionTable,
+		db.LoadBalancerTable}
 	for range conn.TriggerTick(30, tables...).C {
 		minion := conn.MinionSelf()
 		if minion.Role != db.Worker {
@@ -39,6 +44,17 @@ func runNat(conn db.Conn, inboundPubIntf, outboundPubIntf string) {
 		containers := conn.SelectFromContainer(func(c db.Container) bool {
 			return c.IP != ""
 		})
+		publicLBs := publicLoadBalancers(minion.PrivateIP,
+			conn.SelectFromLoadBalancer(nil), connections)
+
+		var peers []string
+		for _, m := range conn.SelectFromMinion(nil) {
+			if m.Role == db.Worker && m.PrivateIP != "" &&
+				m.PrivateIP != minion.PrivateIP {
+				peers = append(peers, m.PrivateIP)
+			}
+		}
+		sort.Strings(peers)
 
 		ipt, err := iptables.New()
 		if err != nil {
@@ -46,8 +62,8 @@ func runNat(conn db.Conn, inboundPubIntf, outboundPubIntf string) {
 			continue
 		}
 
-		err = updateNAT(ipt, containers, connections, inboundPubIntf,
-			outboundPubIntf)
+		err = updateNAT(ipt, containers, connections, publicLBs, peers,
+			inboundPubIntf, outboundPubIntf)
 		if err != nil {
 			log.WithError(err).Error("Failed to update NAT rules")
 		}
@@ -86,8 +102,11 @@ func pickIntfs(inboundPubIntf, outboundPubIntf string) (string, string, error) {
 // containers. They overwrite any pre-existing or outdated rules.
 // "postrouting rules" are responsible for routing traffic from containers
 // to the public internet. They overwrite any pre-existing or outdated rules.
+// Traffic to public load balancers is handled by dedicated chains, which are
+// described in nat_load_balancer.go.
 func updateNAT(ipt IPTables, containers []db.Container,
-	connections []db.Connection, inboundPubIntf, outboundPubIntf string) (err error) {
+	connections []db.Connection, publicLBs []publicLB, peers []string,
+	inboundPubIntf, outboundPubIntf string) (err error) {
 
 	inboundPubIntf, outboundPubIntf, err = pickIntfs(inboundPubIntf, outboundPubIntf)
 	if err != nil {
@@ -98,12 +117,19 @@ func updateNAT(ipt IPTables, containers []db.Container,
 		return err
 	}
 
-	prerouting := preroutingRules(inboundPubIntf, containers, connections)
+	err = syncLoadBalancerChains(ipt, inboundPubIntf, publicLBs, peers)
+	if err != nil {
+		return err
+	}
+
+	prerouting := append(preroutingRules(inboundPubIntf, containers, connections),
+		"-m addrtype --dst-type LOCAL -j "+lbChain)
 	if err := syncChain(ipt, "nat", "PREROUTING", prerouting); err != nil {
 		return err
 	}
 
-	postrouting := postroutingRules(outboundPubIntf, containers, connections)
+	postrouting := append(postroutingRules(outboundPubIntf, containers, connections),
+		lbPostroutingRules(publicLBs)...)
 	return syncChain(ipt, "nat", "POSTROUTING", postrouting)
 }
 
@@ -257,6 +283,36 @@ func postroutingRules(publicInterface string, containers []db.Container,
 		}
 	}
 
+	return append(rules, externalRules(publicInterface, containers,
+		connections)...)
+}
+
+// externalRules masquerades the traffic of connections to addresses outside of
+// Quilt, but only when it's destined for the connection's CIDRs.
+func externalRules(publicInterface string, containers []db.Container,
+	connections []db.Connection) (rules []string) {
+
+	hostnameToIP := map[string]string{}
+	for _, dbc := range containers {
+		hostnameToIP[dbc.Hostname] = dbc.IP
+	}
+
+	for _, conn := range connections {
+		ip := hostnameToIP[conn.From]
+		if ip == "" || conn.MinPort != conn.MaxPort {
+			continue
+		}
+
+		for _, cidr := range conn.ExternalCIDRs() {
+			for _, protocol := range []string{"tcp", "udp"} {
+				rules = append(rules, fmt.Sprintf(
+					"-s %[1]s/32 -d %[2]s -p %[3]s -m %[3]s "+
+						"--dport %[4]d -o %[5]s -j MASQUERADE",
+					ip, cidr, protocol, conn.MinPort,
+					publicInterface))
+			}
+		}
+	}
 	return rules
 }
 
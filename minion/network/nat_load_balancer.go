@@ -0,0 +1,252 @@
+package network
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/quilt/quilt/blueprint"
+	"github.com/quilt/quilt/db"
+)
+
+/*
+Load balancers that accept connections from the public internet are implemented
+with iptables on every worker, so that public traffic arriving at any of them is
+spread across all backends in the cluster.
+
+New connections to a public load balancer port are DNATed to one of the backends,
+picked at random.  Backends running on the same worker are reached directly, while
+connections to backends on other workers are DNATed to the private IP of that
+worker, and masqueraded so that responses return through the worker that accepted
+the connection.  Each worker is picked once per backend running on it, so that the
+backends all receive the same share of traffic.
+
+Workers then spread connections forwarded by their peers across their local
+backends only, so that traffic never makes more than one hop between workers.
+*/
+
+const (
+	// lbChain contains the rules for connections to public load balancers.
+	lbChain = "QUILT-LB"
+
+	// lbLocalChain contains the rules for connections to public load balancers
+	// that were forwarded by another worker.
+	lbLocalChain = "QUILT-LB-LOCAL"
+)
+
+// A publicLB is a load balancer that accepts connections from the public internet.
+type publicLB struct {
+	name  string
+	ports []int
+
+	// The IPs of the backends running on this worker.
+	local []string
+
+	// The private IP of the worker running each backend on other workers.  Workers
+	// appear once for each backend running on them.
+	remote []string
+}
+
+// publicLoadBalancers returns the load balancers that accept connections from the
+// public internet, sorted by name.
+func publicLoadBalancers(myIP string, loadBalancers []db.LoadBalancer,
+	connections []db.Connection) (publicLBs []publicLB) {
+
+	ports := map[string]map[int]struct{}{}
+	for _, conn := range connections {
+		if conn.From != blueprint.PublicInternetLabel {
+			continue
+		}
+
+		if _, ok := ports[conn.To]; !ok {
+			ports[conn.To] = map[int]struct{}{}
+		}
+		ports[conn.To][conn.MinPort] = struct{}{}
+	}
+
+	for _, lb := range loadBalancers {
+		if len(ports[lb.Name]) == 0 {
+			continue
+		}
+
+		plb := publicLB{name: lb.Name}
+		for port := range ports[lb.Name] {
+			plb.ports = append(plb.ports, port)
+		}
+
+		for ip, minion := range lb.Backends {
+			if minion == myIP {
+				plb.local = append(plb.local, ip)
+			} else {
+				plb.remote = append(plb.remote, minion)
+			}
+		}
+
+		sort.Ints(plb.ports)
+		sort.Strings(plb.local)
+		sort.Strings(plb.remote)
+		publicLBs = append(publicLBs, plb)
+	}
+
+	sort.Sort(publicLBSlice(publicLBs))
+	return publicLBs
+}
+
+// syncLoadBalancerChains creates the load balancer chains if necessary, and
+// replaces their contents with the rules for `publicLBs`.
+func syncLoadBalancerChains(ipt IPTables, publicInterface string,
+	publicLBs []publicLB, peers []string) error {
+
+	chains, err := ipt.ListChains("nat")
+	if err != nil {
+		return fmt.Errorf("iptables list chains: %s", err)
+	}
+
+	for _, chain := range []string{lbChain, lbLocalChain} {
+		exists := false
+		for _, c := range chains {
+			exists = exists || c == chain
+		}
+
+		if exists {
+			continue
+		}
+
+		iptC.Inc("New Chain")
+		if err := ipt.NewChain("nat", chain); err != nil {
+			return fmt.Errorf("iptables new chain: %s", err)
+		}
+	}
+
+	err = syncOrderedChain(ipt, "nat", lbLocalChain, lbLocalRules(publicLBs))
+	if err != nil {
+		return err
+	}
+
+	return syncOrderedChain(ipt, "nat", lbChain,
+		lbRules(publicInterface, publicLBs, peers))
+}
+
+// lbRules returns the rules that spread connections from the public internet
+// across all backends in the cluster.  Connections from `peers` were already
+// forwarded by another worker, so they're only spread across the local backends.
+func lbRules(publicInterface string, publicLBs []publicLB,
+	peers []string) (rules []string) {
+
+	for _, peer := range peers {
+		rules = append(rules,
+			fmt.Sprintf("-s %s/32 -j %s", peer, lbLocalChain),
+			fmt.Sprintf("-s %s/32 -j RETURN", peer))
+	}
+
+	for _, lb := range publicLBs {
+		targets := append(append([]string{}, lb.local...), lb.remote...)
+		for _, port := range lb.ports {
+			rules = append(rules, dnatRules(
+				"-i "+publicInterface+" ", port, targets)...)
+		}
+	}
+	return rules
+}
+
+// lbLocalRules returns the rules that spread connections across the backends
+// running on this worker.
+func lbLocalRules(publicLBs []publicLB) (rules []string) {
+	for _, lb := range publicLBs {
+		for _, port := range lb.ports {
+			rules = append(rules, dnatRules("", port, lb.local)...)
+		}
+	}
+	return rules
+}
+
+// dnatRules returns rules that DNAT connections to `port` to a random one of
+// `targets`.  The rules must be installed in order: each rule matches a share of
+// the connections that weren't matched by the rules before it, such that every
+// target receives the same share overall.
+func dnatRules(match string, port int, targets []string) (rules []string) {
+	for _, protocol := range []string{"tcp", "udp"} {
+		for i, target := range targets {
+			statistic := ""
+			if remaining := len(targets) - i; remaining > 1 {
+				statistic = fmt.Sprintf("-m statistic --mode random "+
+					"--probability %.11f ", 1/float64(remaining))
+			}
+
+			rules = append(rules, fmt.Sprintf(
+				"%[1]s-p %[2]s -m %[2]s --dport %[3]d %[4]s"+
+					"-j DNAT --to-destination %[5]s:%[3]d",
+				match, protocol, port, statistic, target))
+		}
+	}
+	return rules
+}
+
+// lbPostroutingRules masquerades connections forwarded to other workers, so that
+// their responses return through this worker.
+func lbPostroutingRules(publicLBs []publicLB) (rules []string) {
+	added := map[string]struct{}{}
+	for _, lb := range publicLBs {
+		for _, peer := range lb.remote {
+			for _, port := range lb.ports {
+				for _, protocol := range []string{"tcp", "udp"} {
+					rule := fmt.Sprintf("-d %[1]s/32 -p %[2]s "+
+						"-m %[2]s --dport %[3]d -j MASQUERADE",
+						peer, protocol, port)
+					if _, ok := added[rule]; !ok {
+						added[rule] = struct{}{}
+						rules = append(rules, rule)
+					}
+				}
+			}
+		}
+	}
+	return rules
+}
+
+// syncOrderedChain replaces the rules in `chain` with `target` if they differ.
+// Unlike syncChain, it preserves the order of the rules.
+func syncOrderedChain(ipt IPTables, table, chain string, target []string) error {
+	curr, err := getRules(ipt, table, chain)
+	if err != nil {
+		return fmt.Errorf("iptables get: %s", err.Error())
+	}
+
+	if len(curr) == len(target) {
+		equal := true
+		for i := range curr {
+			equal = equal && ruleKey(curr[i]) == ruleKey(target[i])
+		}
+
+		if equal {
+			return nil
+		}
+	}
+
+	iptC.Inc("Clear Chain")
+	if err := ipt.ClearChain(table, chain); err != nil {
+		return fmt.Errorf("iptables clear chain: %s", err)
+	}
+
+	for _, r := range target {
+		iptC.Inc("Append")
+		if err := ipt.Append(table, chain, strings.Split(r, " ")...); err != nil {
+			return fmt.Errorf("iptables append: %s", err)
+		}
+	}
+	return nil
+}
+
+type publicLBSlice []publicLB
+
+func (lbs publicLBSlice) Len() int {
+	return len(lbs)
+}
+
+func (lbs publicLBSlice) Less(i, j int) bool {
+	return lbs[i].name < lbs[j].name
+}
+
+func (lbs publicLBSlice) Swap(i, j int) {
+	lbs[i], lbs[j] = lbs[j], lbs[i]
+}
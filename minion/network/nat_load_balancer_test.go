@@ -0,0 +1,168 @@
+package network
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/quilt/quilt/blueprint"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/minion/network/mocks"
+)
+
+func TestPublicLoadBalancers(t *testing.T) {
+	t.Parallel()
+
+	lbs := []db.LoadBalancer{
+		{
+			Name: "web",
+			Backends: map[string]string{
+				"10.0.0.2": "1.2.3.4",
+				"10.0.0.3": "1.2.3.5",
+				"10.0.0.4": "1.2.3.5",
+				"10.0.0.5": "1.2.3.4",
+			},
+		},
+		{
+			Name:     "api",
+			Backends: map[string]string{"10.0.0.6": "1.2.3.6"},
+		},
+		{
+			Name:     "private",
+			Backends: map[string]string{"10.0.0.7": "1.2.3.4"},
+		},
+	}
+
+	conns := []db.Connection{
+		{From: blueprint.PublicInternetLabel, To: "web", MinPort: 443,
+			MaxPort: 443},
+		{From: blueprint.PublicInternetLabel, To: "web", MinPort: 80,
+			MaxPort: 80},
+		{From: blueprint.PublicInternetLabel, To: "api", MinPort: 8080,
+			MaxPort: 8080},
+		{From: "web", To: "private", MinPort: 80, MaxPort: 80},
+	}
+
+	assert.Equal(t, []publicLB{
+		{
+			name:   "api",
+			ports:  []int{8080},
+			remote: []string{"1.2.3.6"},
+		},
+		{
+			name:   "web",
+			ports:  []int{80, 443},
+			local:  []string{"10.0.0.2", "10.0.0.5"},
+			remote: []string{"1.2.3.5", "1.2.3.5"},
+		},
+	}, publicLoadBalancers("1.2.3.4", lbs, conns))
+}
+
+func TestLBRules(t *testing.T) {
+	t.Parallel()
+
+	lbs := []publicLB{
+		{
+			name:   "web",
+			ports:  []int{80},
+			local:  []string{"10.0.0.2"},
+			remote: []string{"1.2.3.5", "1.2.3.5"},
+		},
+		{
+			name:  "empty",
+			ports: []int{81},
+		},
+	}
+
+	assert.Equal(t, []string{
+		"-s 1.2.3.5/32 -j QUILT-LB-LOCAL",
+		"-s 1.2.3.5/32 -j RETURN",
+		"-s 1.2.3.6/32 -j QUILT-LB-LOCAL",
+		"-s 1.2.3.6/32 -j RETURN",
+		"-i eth0 -p tcp -m tcp --dport 80 -m statistic --mode random " +
+			"--probability 0.33333333333 " +
+			"-j DNAT --to-destination 10.0.0.2:80",
+		"-i eth0 -p tcp -m tcp --dport 80 -m statistic --mode random " +
+			"--probability 0.50000000000 -j DNAT --to-destination 1.2.3.5:80",
+		"-i eth0 -p tcp -m tcp --dport 80 " +
+			"-j DNAT --to-destination 1.2.3.5:80",
+		"-i eth0 -p udp -m udp --dport 80 -m statistic --mode random " +
+			"--probability 0.33333333333 " +
+			"-j DNAT --to-destination 10.0.0.2:80",
+		"-i eth0 -p udp -m udp --dport 80 -m statistic --mode random " +
+			"--probability 0.50000000000 -j DNAT --to-destination 1.2.3.5:80",
+		"-i eth0 -p udp -m udp --dport 80 " +
+			"-j DNAT --to-destination 1.2.3.5:80",
+	}, lbRules("eth0", lbs, []string{"1.2.3.5", "1.2.3.6"}))
+
+	assert.Equal(t, []string{
+		"-p tcp -m tcp --dport 80 -j DNAT --to-destination 10.0.0.2:80",
+		"-p udp -m udp --dport 80 -j DNAT --to-destination 10.0.0.2:80",
+	}, lbLocalRules(lbs))
+
+	assert.Equal(t, []string{
+		"-d 1.2.3.5/32 -p tcp -m tcp --dport 80 -j MASQUERADE",
+		"-d 1.2.3.5/32 -p udp -m udp --dport 80 -j MASQUERADE",
+	}, lbPostroutingRules(lbs))
+}
+
+func TestSyncLoadBalancerChains(t *testing.T) {
+	lbs := []publicLB{{name: "web", ports: []int{80}, local: []string{"10.0.0.2"}}}
+
+	ipt := &mocks.IPTables{}
+	ipt.On("ListChains", "nat").Return([]string{"PREROUTING", lbChain}, nil)
+	ipt.On("NewChain", "nat", lbLocalChain).Return(nil).Once()
+	ipt.On("List", "nat", lbLocalChain).Return(nil, nil)
+	ipt.On("ClearChain", "nat", lbLocalChain).Return(nil).Once()
+	ipt.On("Append", "nat", lbLocalChain, "-p", "tcp", "-m", "tcp", "--dport",
+		"80", "-j", "DNAT", "--to-destination", "10.0.0.2:80").Return(nil).Once()
+	ipt.On("Append", "nat", lbLocalChain, "-p", "udp", "-m", "udp", "--dport",
+		"80", "-j", "DNAT", "--to-destination", "10.0.0.2:80").Return(nil).Once()
+
+	// The chain is already up to date, so it isn't modified.
+	ipt.On("List", "nat", lbChain).Return([]string{
+		"-A QUILT-LB -i eth0 -p tcp -m tcp --dport 80 " +
+			"-j DNAT --to-destination 10.0.0.2:80",
+		"-A QUILT-LB -i eth0 -p udp -m udp --dport 80 " +
+			"-j DNAT --to-destination 10.0.0.2:80",
+	}, nil)
+
+	assert.NoError(t, syncLoadBalancerChains(ipt, "eth0", lbs, nil))
+	ipt.AssertExpectations(t)
+
+	anErr := errors.New("err")
+	ipt = &mocks.IPTables{}
+	ipt.On("ListChains", "nat").Return(nil, nil)
+	ipt.On("NewChain", "nat", mock.Anything).Return(anErr)
+	assert.EqualError(t, syncLoadBalancerChains(ipt, "eth0", lbs, nil),
+		"iptables new chain: err")
+}
+
+func TestSyncOrderedChain(t *testing.T) {
+	ipt := &mocks.IPTables{}
+	ipt.On("List", "nat", lbChain).Return([]string{
+		"-A QUILT-LB -s 1.2.3.5/32 -j RETURN",
+		"-A QUILT-LB -s 1.2.3.5/32 -j QUILT-LB-LOCAL",
+	}, nil)
+	ipt.On("ClearChain", "nat", lbChain).Return(nil).Once()
+	ipt.On("Append", "nat", lbChain, "-s", "1.2.3.5/32", "-j",
+		"QUILT-LB-LOCAL").Return(nil).Once()
+	ipt.On("Append", "nat", lbChain, "-s", "1.2.3.5/32", "-j",
+		"RETURN").Return(nil).Once()
+
+	// The rules are the same, but in the wrong order.
+	assert.NoError(t, syncOrderedChain(ipt, "nat", lbChain, []string{
+		"-s 1.2.3.5/32 -j QUILT-LB-LOCAL",
+		"-s 1.2.3.5/32 -j RETURN",
+	}))
+	ipt.AssertExpectations(t)
+
+	anErr := errors.New("err")
+	ipt = &mocks.IPTables{}
+	ipt.On("List", "nat", lbChain).Return(nil, nil)
+	ipt.On("ClearChain", "nat", lbChain).Return(anErr)
+	assert.EqualError(t, syncOrderedChain(ipt, "nat", lbChain, []string{"rule"}),
+		"iptables clear chain: err")
+}
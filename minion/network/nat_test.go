@@ -26,7 +26,7 @@ func TestUpdateNATErrors(t *testing.T) {
 	getDefaultRouteIntf = func() (string, error) {
 		return "", anErr
 	}
-	assert.NotNil(t, updateNAT(ipt, nil, nil, "", ""))
+	assert.NotNil(t, updateNAT(ipt, nil, nil, nil, nil, "", ""))
 
 	ipt = &mocks.IPTables{}
 	ipt.On("AppendUnique", mock.Anything, mock.Anything, mock.Anything,
@@ -34,20 +34,32 @@ func TestUpdateNATErrors(t *testing.T) {
 	getDefaultRouteIntf = func() (string, error) {
 		return "eth0", nil
 	}
-	assert.NotNil(t, updateNAT(ipt, nil, nil, "", ""))
+	assert.NotNil(t, updateNAT(ipt, nil, nil, nil, nil, "", ""))
 
 	ipt = &mocks.IPTables{}
 	ipt.On("AppendUnique", mock.Anything, mock.Anything, mock.Anything,
 		mock.Anything).Return(nil)
+	ipt.On("ListChains", "nat").Return(nil, anErr)
+	assert.NotNil(t, updateNAT(ipt, nil, nil, nil, nil, "", ""))
+
+	ipt = &mocks.IPTables{}
+	ipt.On("AppendUnique", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything).Return(nil)
+	ipt.On("ListChains", "nat").Return([]string{lbChain, lbLocalChain}, nil)
 	ipt.On("List", mock.Anything, mock.Anything).Return(nil, anErr)
-	assert.NotNil(t, updateNAT(ipt, nil, nil, "", ""))
+	assert.NotNil(t, updateNAT(ipt, nil, nil, nil, nil, "", ""))
 
 	ipt = &mocks.IPTables{}
 	ipt.On("AppendUnique", mock.Anything, mock.Anything, mock.Anything,
 		mock.Anything).Return(nil)
+	ipt.On("ListChains", "nat").Return([]string{lbChain, lbLocalChain}, nil)
+	ipt.On("List", "nat", lbChain).Return(nil, nil)
+	ipt.On("List", "nat", lbLocalChain).Return(nil, nil)
 	ipt.On("List", "nat", "PREROUTING").Return(nil, nil)
+	ipt.On("Append", "nat", "PREROUTING", "-m", "addrtype", "--dst-type",
+		"LOCAL", "-j", lbChain).Return(nil)
 	ipt.On("List", "nat", "POSTROUTING").Return(nil, anErr)
-	assert.NotNil(t, updateNAT(ipt, nil, nil, "", ""))
+	assert.NotNil(t, updateNAT(ipt, nil, nil, nil, nil, "", ""))
 }
 
 func TestPreroutingRules(t *testing.T) {
@@ -128,6 +140,43 @@ func TestPostroutingRules(t *testing.T) {
 	actual := postroutingRules("eth0", containers, connections)
 	sort.Strings(actual)
 	assert.Equal(t, exp, actual)
+
+	// Traffic to external addresses is only masqueraded to their CIDRs.
+	connections = []db.Connection{
+		{
+			From:    "red",
+			To:      "cidr:52.1.0.0/16",
+			MinPort: 5432,
+			MaxPort: 5432,
+		},
+		{
+			From:        "purple",
+			To:          "domain:example.com",
+			MinPort:     443,
+			MaxPort:     443,
+			ResolvedIPs: []string{"1.2.3.4"},
+		},
+		{
+			From:    "purple",
+			To:      "domain:unresolved.com",
+			MinPort: 443,
+			MaxPort: 443,
+		},
+	}
+
+	exp = []string{
+		"-s 8.8.8.8/32 -d 52.1.0.0/16 -p tcp -m tcp --dport 5432 -o eth0 " +
+			"-j MASQUERADE",
+		"-s 8.8.8.8/32 -d 52.1.0.0/16 -p udp -m udp --dport 5432 -o eth0 " +
+			"-j MASQUERADE",
+		"-s 9.9.9.9/32 -d 1.2.3.4/32 -p tcp -m tcp --dport 443 -o eth0 " +
+			"-j MASQUERADE",
+		"-s 9.9.9.9/32 -d 1.2.3.4/32 -p udp -m udp --dport 443 -o eth0 " +
+			"-j MASQUERADE",
+	}
+	actual = postroutingRules("eth0", containers, connections)
+	sort.Strings(actual)
+	assert.Equal(t, exp, actual)
 }
 
 func TestGetRules(t *testing.T) {
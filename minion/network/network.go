@@ -8,6 +8,7 @@ package network
 import (
 	"github.com/quilt/quilt/counter"
 	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/health"
 	"github.com/quilt/quilt/join"
 	"github.com/quilt/quilt/minion/ipdef"
 	"github.com/quilt/quilt/minion/ovsdb"
@@ -26,9 +27,14 @@ var c = counter.New("Network")
 
 // Run blocks implementing the network services.
 func Run(conn db.Conn, inboundPubIntf, outboundPubIntf string) {
+	health.Register("ovsdb", checkOVSDB)
+
 	go runNat(conn, inboundPubIntf, outboundPubIntf)
 	go runDNS(conn)
 	go runUpdateIPs(conn)
+	go runProbes(conn)
+	go runResolveDomains(conn)
+	go runTraffic(conn)
 
 	for range conn.TriggerTick(30, db.ContainerTable, db.HostnameTable,
 		db.ConnectionTable, db.LoadBalancerTable, db.EtcdTable).C {
@@ -38,6 +44,16 @@ func Run(conn db.Conn, inboundPubIntf, outboundPubIntf string) {
 	}
 }
 
+// checkOVSDB returns an error if the minion can't connect to ovsdb-server.
+func checkOVSDB() error {
+	client, err := ovsdb.Open()
+	if err != nil {
+		return err
+	}
+	client.Disconnect()
+	return nil
+}
+
 // The leader of the cluster is responsible for properly configuring OVN northd
 // for container networking and load balancing.  This means creating a logical
 // port for each container, creating ACLs, creating the load balancer router,
@@ -76,10 +92,23 @@ func runMaster(conn db.Conn) {
 
 	updateLogicalSwitch(ovsdbClient, containers)
 	updateLoadBalancerRouter(ovsdbClient)
-	updateLoadBalancers(ovsdbClient, loadBalancers, hostnameToIP)
+	updateLoadBalancers(ovsdbClient, loadBalancers, hostnameToIP,
+		healthyIPs(containers))
 	updateACLs(ovsdbClient, connections, hostnameToIP)
 }
 
+// healthyIPs returns the set of container IPs that are passing their load balancer
+// probes.
+func healthyIPs(containers []db.Container) map[string]bool {
+	healthy := map[string]bool{}
+	for _, dbc := range containers {
+		if dbc.Health == db.ContainerHealthy {
+			healthy[dbc.IP] = true
+		}
+	}
+	return healthy
+}
+
 func updateLogicalSwitch(ovsdbClient ovsdb.Client, containers []db.Container) {
 	switchExists, err := ovsdbClient.LogicalSwitchExists(lSwitch)
 	if err != nil {
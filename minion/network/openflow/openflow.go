@@ -3,6 +3,8 @@ package openflow
 import (
 	"fmt"
 	"os/exec"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/quilt/quilt/counter"
@@ -46,11 +48,28 @@ Tables
 // Table_0 initializes the registers and forwards to Table_1.
 Table_0 { // Initial Table
 	for each db.Container {
+		// IP packets of containers with a traffic class are marked with it.
+		if dbc.TrafficClass && ip && in_port=dbc.VethPort && dl_src=dbc.Mac {
+			nw_tos <- dbc.TrafficClass << 2
+			reg0 <- dbc.PatchPort
+			goto Table_1
+		}
+
 		if in_port=dbc.VethPort && dl_src=dbc.Mac {
 			reg0 <- dbc.PatchPort
 			goto Table_1
 		}
 
+		// Traffic of connections with a bandwidth limit is enqueued in the
+		// queue that shapes it.
+		for each queue {
+			if [tcp|udp] && in_port=dbc.PatchPort && nw_src=queue.peer &&
+				[tp_src=queue.srcPort|tp_dst=queue.dstPort] {
+				set_queue:queue.id
+				output:dbc.VethPort
+			}
+		}
+
 		if in_port=dbc.PatchPort {
 			output:dbc.VethPort
 		}
@@ -73,6 +92,16 @@ Table_1 {
 		goto Table_3
 	}
 
+	// Packets to peers are handled by OVN, but have their own flows so that
+	// their traffic is counted.
+	for each db.Container {
+		for each peer {
+			if ip && dl_src=dbc.mac && nw_src=dbc.ip && nw_dst=peer {
+				output:reg0
+			}
+		}
+	}
+
 	// Everything else can be handled by OVN.
 	output:reg0
 }
@@ -106,6 +135,14 @@ Table_2 {
 			[tcp|udp],dl_dst=dbc.mac,ip_dst=dbc.ip,tp_dst=fromPub,
 				actions=output:veth
 		}
+
+		for each toExternal {
+			// Response packets come from the external CIDR and port.  These
+			// take precedence over toPub, so that their traffic is counted
+			// separately.
+			[tcp|udp],dl_dst=dbc.mac,ip_dst=dbc.ip,ip_src=toExternal.cidr,
+				tp_src=toExternal.port,actions=output:veth
+		}
         }
 }
 
@@ -135,6 +172,13 @@ Table_3 {
 			[tcp|udp],dl_src=dbc.mac,ip_src=dbc.ip,tp_src=fromPub,
 				actions=output:LOCAL
 		}
+
+		for each toExternal {
+			// Outbound packets are only allowed to the external CIDR and
+			// port.
+			[tcp|udp],dl_src=dbc.mac,ip_src=dbc.ip,ip_dst=toExternal.cidr,
+				tp_dst=toExternal.port,actions=output:LOCAL
+		}
 	}
 }
 
@@ -150,6 +194,37 @@ type Container struct {
 	// Set of ports going to and from the public internet.
 	ToPub   map[int]struct{}
 	FromPub map[int]struct{}
+
+	// Set of addresses outside of Quilt that the container may connect to.
+	ToExternal map[External]struct{}
+
+	// Set of IPs of the containers and load balancers that the container
+	// exchanges traffic with.
+	Peers map[string]struct{}
+
+	// Set of queues that the traffic the container receives from its peers is
+	// enqueued in.
+	Queues map[Queue]struct{}
+
+	// The DSCP value that the container's IP packets are marked with, or zero.
+	TrafficClass int
+}
+
+// A Queue of the container's OVS port.  Traffic from Peer is enqueued in it if it's
+// sent from SrcPort, or to DstPort, whichever is nonzero.  Rate, in bits per
+// second, is what the port's QoS shapes the queue to, and doesn't affect the flows.
+type Queue struct {
+	ID      int
+	Peer    string
+	SrcPort int
+	DstPort int
+	Rate    int
+}
+
+// An External address, and the port on it, that a container may connect to.
+type External struct {
+	CIDR string
+	Port int
 }
 
 type container struct {
@@ -220,6 +295,140 @@ func AddFlows(containers []Container) error {
 	return nil
 }
 
+// Traffic is the number of packets and bytes sent from Src to Dst, as counted by
+// one of the flows of the containers on this host.  Src and Dst are each the IP of
+// a container or load balancer, the CIDR of an external address, or empty for the
+// public internet.
+type Traffic struct {
+	Src, Dst string
+
+	// Flow identifies the flow that counted the traffic, and Duration is the
+	// number of seconds since it was installed.  Reinstalled flows restart
+	// their counters, and their duration.
+	Flow     string
+	Duration float64
+
+	Packets, Bytes uint64
+}
+
+// DumpTraffic returns the traffic counted by each of the flows of the containers.
+func DumpTraffic() ([]Traffic, error) {
+	c.Inc("Dump Traffic")
+	dump, err := dumpFlows()
+	if err != nil {
+		return nil, fmt.Errorf("ovs-ofctl: %s", err)
+	}
+	return parseTraffic(dump), nil
+}
+
+func parseTraffic(dump string) []Traffic {
+	var traffic []Traffic
+	for _, line := range strings.Split(dump, "\n") {
+		fields := parseFlow(line)
+
+		var src, dst string
+		switch fields["table"] + "," + fields["priority"] {
+		case "1,850":
+			src, dst = fields["nw_src"], fields["nw_dst"]
+		case "2,500":
+			dst = fields["nw_dst"]
+		case "2,600":
+			src, dst = toCIDR(fields["nw_src"]), fields["nw_dst"]
+		case "3,500":
+			src = fields["nw_src"]
+		case "3,600":
+			src, dst = fields["nw_src"], toCIDR(fields["nw_dst"])
+		default:
+			continue
+		}
+
+		t := Traffic{Src: src, Dst: dst, Flow: flowMatch(fields)}
+		t.Duration, _ = strconv.ParseFloat(
+			strings.TrimSuffix(fields["duration"], "s"), 64)
+		t.Packets, _ = strconv.ParseUint(fields["n_packets"], 10, 64)
+		t.Bytes, _ = strconv.ParseUint(fields["n_bytes"], 10, 64)
+		traffic = append(traffic, t)
+	}
+
+	sort.Sort(trafficSlice(traffic))
+	return traffic
+}
+
+// flowStats are the fields of a dumped flow that describe its state, rather than
+// which packets it matches.
+var flowStats = map[string]bool{"cookie": true, "duration": true,
+	"n_packets": true, "n_bytes": true, "idle_age": true, "hard_age": true}
+
+// flowMatch returns the table, priority and match of the parsed flow `fields`,
+// which identify the flow.
+func flowMatch(fields map[string]string) string {
+	var match []string
+	for key, value := range fields {
+		if flowStats[key] {
+			continue
+		}
+
+		if value == "" {
+			match = append(match, key)
+		} else {
+			match = append(match, key+"="+value)
+		}
+	}
+	sort.Strings(match)
+	return strings.Join(match, ",")
+}
+
+// parseFlow parses a flow printed by `ovs-ofctl dump-flows` into its fields.  For
+// example, " cookie=0x0, table=1, n_packets=2, priority=850,ip,nw_src=10.0.0.2
+// actions=output:NXM_NX_REG0[]" has an "ip" field with an empty value.  The
+// actions are ignored.
+func parseFlow(line string) map[string]string {
+	line = strings.TrimSpace(line)
+	if i := strings.Index(line, " actions="); i >= 0 {
+		line = line[:i]
+	}
+
+	fields := map[string]string{}
+	for _, field := range strings.Split(line, ",") {
+		kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(kv) == 2 {
+			fields[kv[0]] = kv[1]
+		} else {
+			fields[kv[0]] = ""
+		}
+	}
+	return fields
+}
+
+// toCIDR converts the addresses printed by ovs-ofctl, which omit the prefix length
+// of single hosts, to CIDR notation.
+func toCIDR(addr string) string {
+	if addr == "" || strings.Contains(addr, "/") {
+		return addr
+	}
+	return addr + "/32"
+}
+
+type trafficSlice []Traffic
+
+func (slc trafficSlice) Len() int {
+	return len(slc)
+}
+
+func (slc trafficSlice) Less(i, j int) bool {
+	if slc[i].Src != slc[j].Src {
+		return slc[i].Src < slc[j].Src
+	}
+	if slc[i].Dst != slc[j].Dst {
+		return slc[i].Dst < slc[j].Dst
+	}
+	return slc[i].Flow < slc[j].Flow
+}
+
+func (slc trafficSlice) Swap(i, j int) {
+	slc[i], slc[j] = slc[j], slc[i]
+}
+
 func allContainerFlows(containers []container) []string {
 	var flows []string
 	for _, c := range containers {
@@ -244,6 +453,14 @@ func containerFlows(c container) []string {
 			"action=output:%d", c.Mac, ipdef.GatewayIP, c.vethPort),
 	}
 
+	if c.TrafficClass != 0 {
+		// The DSCP value is the upper six bits of the TOS field.
+		flows = append(flows, fmt.Sprintf("table=0,priority=40000,ip,"+
+			"in_port=%d,dl_src=%s,actions=mod_nw_tos:%d,"+
+			"load:0x%x->NXM_NX_REG0[],resubmit(,1)",
+			c.vethPort, c.Mac, c.TrafficClass<<2, c.patchPort))
+	}
+
 	table2 := "table=2,priority=500,%s,dl_dst=%s,ip_dst=%s,tp_src=%d," +
 		"actions=output:%d"
 	table3 := "table=3,priority=500,%s,dl_src=%s,ip_src=%s,tp_dst=%d," +
@@ -270,6 +487,40 @@ func containerFlows(c container) []string {
 			fmt.Sprintf(table3, "udp", c.Mac, c.IP, from))
 	}
 
+	table2 = "table=2,priority=600,%s,dl_dst=%s,ip_dst=%s,ip_src=%s,tp_src=%d," +
+		"actions=output:%d"
+	table3 = "table=3,priority=600,%s,dl_src=%s,ip_src=%s,ip_dst=%s,tp_dst=%d," +
+		"actions=output:LOCAL"
+	for ext := range c.Container.ToExternal {
+		flows = append(flows,
+			fmt.Sprintf(table2, "tcp", c.Mac, c.IP, ext.CIDR, ext.Port,
+				c.vethPort),
+			fmt.Sprintf(table2, "udp", c.Mac, c.IP, ext.CIDR, ext.Port,
+				c.vethPort),
+
+			fmt.Sprintf(table3, "tcp", c.Mac, c.IP, ext.CIDR, ext.Port),
+			fmt.Sprintf(table3, "udp", c.Mac, c.IP, ext.CIDR, ext.Port))
+	}
+
+	for q := range c.Container.Queues {
+		port := fmt.Sprintf("tp_dst=%d", q.DstPort)
+		if q.SrcPort != 0 {
+			port = fmt.Sprintf("tp_src=%d", q.SrcPort)
+		}
+
+		for _, proto := range []string{"tcp", "udp"} {
+			flows = append(flows, fmt.Sprintf("table=0,priority=40000,%s,"+
+				"in_port=%d,nw_src=%s,%s,actions=set_queue:%d,output:%d",
+				proto, c.patchPort, q.Peer, port, q.ID, c.vethPort))
+		}
+	}
+
+	for peer := range c.Container.Peers {
+		flows = append(flows, fmt.Sprintf("table=1,priority=850,ip,dl_src=%s,"+
+			"nw_src=%s,nw_dst=%s,actions=output:NXM_NX_REG0[]",
+			c.Mac, c.IP, peer))
+	}
+
 	return flows
 }
 
@@ -334,3 +585,10 @@ var ofctl = func(action string, flows []string) error {
 
 	return cmd.Wait()
 }
+
+var dumpFlows = func() (string, error) {
+	c.Inc("ovs-ofctl dump-flows")
+	out, err := exec.Command("ovs-ofctl", "-O", "OpenFlow13", "dump-flows",
+		ipdef.QuiltBridge).Output()
+	return string(out), err
+}
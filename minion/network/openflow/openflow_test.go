@@ -73,16 +73,22 @@ func TestAllFlows(t *testing.T) {
 		patchPort: 4,
 		vethPort:  5,
 		Container: Container{
-			IP:    "6.7.8.9",
-			Mac:   "66:66:66:66:66:66",
-			ToPub: map[int]struct{}{5: {}}},
+			IP:           "6.7.8.9",
+			Mac:          "66:66:66:66:66:66",
+			ToPub:        map[int]struct{}{5: {}},
+			Peers:        map[string]struct{}{"9.8.7.6": {}},
+			TrafficClass: 10},
 	}, {
 		patchPort: 9,
 		vethPort:  8,
 		Container: Container{
 			IP:      "9.8.7.6",
 			Mac:     "99:99:99:99:99:99",
-			FromPub: map[int]struct{}{8: {}}}}})
+			FromPub: map[int]struct{}{8: {}},
+			ToExternal: map[External]struct{}{
+				{CIDR: "52.1.0.0/16", Port: 5432}: {}},
+			Queues: map[Queue]struct{}{
+				{ID: 1, Peer: "6.7.8.9", DstPort: 80}: {}}}}})
 	exp := append(staticFlows,
 		"table=0,in_port=5,dl_src=66:66:66:66:66:66,"+
 			"actions=load:0x4->NXM_NX_REG0[],resubmit(,1)",
@@ -90,6 +96,8 @@ func TestAllFlows(t *testing.T) {
 		"table=2,priority=900,arp,dl_dst=66:66:66:66:66:66,action=output:5",
 		"table=2,priority=800,ip,dl_dst=66:66:66:66:66:66,nw_src=10.0.0.1,"+
 			"action=output:5",
+		"table=0,priority=40000,ip,in_port=5,dl_src=66:66:66:66:66:66,"+
+			"actions=mod_nw_tos:40,load:0x4->NXM_NX_REG0[],resubmit(,1)",
 		"table=2,priority=500,tcp,dl_dst=66:66:66:66:66:66,ip_dst=6.7.8.9,"+
 			"tp_src=5,actions=output:5",
 		"table=2,priority=500,udp,dl_dst=66:66:66:66:66:66,ip_dst=6.7.8.9,"+
@@ -98,6 +106,8 @@ func TestAllFlows(t *testing.T) {
 			"tp_dst=5,actions=output:LOCAL",
 		"table=3,priority=500,udp,dl_src=66:66:66:66:66:66,ip_src=6.7.8.9,"+
 			"tp_dst=5,actions=output:LOCAL",
+		"table=1,priority=850,ip,dl_src=66:66:66:66:66:66,nw_src=6.7.8.9,"+
+			"nw_dst=9.8.7.6,actions=output:NXM_NX_REG0[]",
 		"table=0,in_port=8,dl_src=99:99:99:99:99:99,"+
 			"actions=load:0x9->NXM_NX_REG0[],resubmit(,1)",
 		"table=0,in_port=9,actions=output:8",
@@ -112,11 +122,90 @@ func TestAllFlows(t *testing.T) {
 			"tp_src=8,actions=output:LOCAL",
 		"table=3,priority=500,udp,dl_src=99:99:99:99:99:99,ip_src=9.8.7.6,"+
 			"tp_src=8,actions=output:LOCAL",
+		"table=2,priority=600,tcp,dl_dst=99:99:99:99:99:99,ip_dst=9.8.7.6,"+
+			"ip_src=52.1.0.0/16,tp_src=5432,actions=output:8",
+		"table=2,priority=600,udp,dl_dst=99:99:99:99:99:99,ip_dst=9.8.7.6,"+
+			"ip_src=52.1.0.0/16,tp_src=5432,actions=output:8",
+		"table=3,priority=600,tcp,dl_src=99:99:99:99:99:99,ip_src=9.8.7.6,"+
+			"ip_dst=52.1.0.0/16,tp_dst=5432,actions=output:LOCAL",
+		"table=3,priority=600,udp,dl_src=99:99:99:99:99:99,ip_src=9.8.7.6,"+
+			"ip_dst=52.1.0.0/16,tp_dst=5432,actions=output:LOCAL",
+		"table=0,priority=40000,tcp,in_port=9,nw_src=6.7.8.9,tp_dst=80,"+
+			"actions=set_queue:1,output:8",
+		"table=0,priority=40000,udp,in_port=9,nw_src=6.7.8.9,tp_dst=80,"+
+			"actions=set_queue:1,output:8",
 		"table=2,priority=1000,dl_dst=ff:ff:ff:ff:ff:ff,"+
 			"actions=output:5,output:8")
 	assert.Equal(t, exp, flows)
 }
 
+func TestDumpTraffic(t *testing.T) {
+	dumpFlows = func() (string, error) {
+		return "", errors.New("err")
+	}
+	_, err := DumpTraffic()
+	assert.EqualError(t, err, "ovs-ofctl: err")
+
+	dumpFlows = func() (string, error) {
+		return `OFPST_FLOW reply (OF1.3) (xid=0x2):
+ cookie=0x0, duration=9.1s, table=0, n_packets=50, n_bytes=5000, ` +
+			`in_port=4 actions=output:5
+ cookie=0x0, duration=2.5s, table=1, n_packets=3, n_bytes=300, ` +
+			`priority=850,ip,dl_src=02:00:0a:00:00:02,nw_src=10.0.0.2,` +
+			`nw_dst=10.0.0.3 actions=output:NXM_NX_REG0[]
+ cookie=0x0, duration=9.1s, table=2, n_packets=1, n_bytes=60, ` +
+			`priority=500,tcp,dl_dst=02:00:0a:00:00:02,nw_dst=10.0.0.2,` +
+			`tp_dst=80 actions=output:5
+ cookie=0x0, duration=9.1s, table=3, n_packets=2, n_bytes=120, ` +
+			`priority=500,tcp,dl_src=02:00:0a:00:00:02,nw_src=10.0.0.2,` +
+			`tp_src=80 actions=LOCAL
+ cookie=0x0, duration=9.1s, table=3, n_packets=4, n_bytes=400, ` +
+			`priority=500,udp,dl_src=02:00:0a:00:00:02,nw_src=10.0.0.2,` +
+			`tp_src=80 actions=LOCAL
+ cookie=0x0, duration=9.1s, table=2, n_packets=5, n_bytes=500, ` +
+			`priority=600,tcp,dl_dst=02:00:0a:00:00:02,nw_src=52.1.0.0/16,` +
+			`nw_dst=10.0.0.2,tp_src=5432 actions=output:5
+ cookie=0x0, duration=9.1s, table=3, n_packets=6, n_bytes=600, ` +
+			`priority=600,tcp,dl_src=02:00:0a:00:00:02,nw_src=10.0.0.2,` +
+			`nw_dst=1.2.3.4,tp_dst=5432 actions=LOCAL
+`, nil
+	}
+
+	traffic, err := DumpTraffic()
+	assert.NoError(t, err)
+	assert.Equal(t, []Traffic{{
+		Src: "", Dst: "10.0.0.2",
+		Flow: "dl_dst=02:00:0a:00:00:02,nw_dst=10.0.0.2,priority=500," +
+			"table=2,tcp,tp_dst=80",
+		Duration: 9.1, Packets: 1, Bytes: 60,
+	}, {
+		Src: "10.0.0.2", Dst: "",
+		Flow: "dl_src=02:00:0a:00:00:02,nw_src=10.0.0.2,priority=500," +
+			"table=3,tcp,tp_src=80",
+		Duration: 9.1, Packets: 2, Bytes: 120,
+	}, {
+		Src: "10.0.0.2", Dst: "",
+		Flow: "dl_src=02:00:0a:00:00:02,nw_src=10.0.0.2,priority=500," +
+			"table=3,tp_src=80,udp",
+		Duration: 9.1, Packets: 4, Bytes: 400,
+	}, {
+		Src: "10.0.0.2", Dst: "1.2.3.4/32",
+		Flow: "dl_src=02:00:0a:00:00:02,nw_dst=1.2.3.4,nw_src=10.0.0.2," +
+			"priority=600,table=3,tcp,tp_dst=5432",
+		Duration: 9.1, Packets: 6, Bytes: 600,
+	}, {
+		Src: "10.0.0.2", Dst: "10.0.0.3",
+		Flow: "dl_src=02:00:0a:00:00:02,ip,nw_dst=10.0.0.3,nw_src=10.0.0.2," +
+			"priority=850,table=1",
+		Duration: 2.5, Packets: 3, Bytes: 300,
+	}, {
+		Src: "52.1.0.0/16", Dst: "10.0.0.2",
+		Flow: "dl_dst=02:00:0a:00:00:02,nw_dst=10.0.0.2,nw_src=52.1.0.0/16," +
+			"priority=600,table=2,tcp,tp_src=5432",
+		Duration: 9.1, Packets: 5, Bytes: 500,
+	}}, traffic)
+}
+
 func TestResolveContainers(t *testing.T) {
 	t.Parallel()
 
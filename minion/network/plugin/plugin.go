@@ -34,11 +34,14 @@ type driver struct{}
 
 var c = counter.New("Network Plugin")
 
-const mtu int = 1400
+// The MTU of container interfaces.  It's set by Run.
+var mtu int
 
 // Run runs the network driver and starts the server to listen for requests. It will
-// block until the server socket has been created.
-func Run() {
+// block until the server socket has been created.  Container interfaces are
+// created with the given MTU.
+func Run(containerMTU int) {
+	mtu = containerMTU
 	h := dnet.NewHandler(driver{})
 
 	go ofctlRun()
@@ -0,0 +1,137 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/quilt/quilt/blueprint"
+	"github.com/quilt/quilt/db"
+)
+
+const (
+	probeInterval = 5 * time.Second
+	probeTimeout  = 2 * time.Second
+
+	// The number of consecutive failed probes after which a backend is
+	// considered unhealthy.
+	probeFailureThreshold = 3
+)
+
+// prober tracks the consecutive probe failures of each container, keyed by IP.
+type prober struct {
+	failures map[string]int
+}
+
+// Workers probe the load balancer backends running on them, and record the
+// results in the Health of each container.  The results are shared with the
+// leader, which only load balances across healthy backends.
+func runProbes(conn db.Conn) {
+	p := prober{failures: map[string]int{}}
+	for range time.Tick(probeInterval) {
+		if conn.MinionSelf().Role == db.Worker {
+			p.runOnce(conn)
+		}
+	}
+}
+
+func (p *prober) runOnce(conn db.Conn) {
+	probes := map[string][]blueprint.Probe{}
+	for _, lb := range conn.SelectFromLoadBalancer(nil) {
+		if lb.Probe == nil {
+			continue
+		}
+		for _, hostname := range lb.Hostnames {
+			probes[hostname] = append(probes[hostname], *lb.Probe)
+		}
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	results := map[string]error{}
+	for _, dbc := range conn.SelectFromContainer(nil) {
+		if dbc.IP == "" || len(probes[dbc.Hostname]) == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(ip string, ps []blueprint.Probe) {
+			defer wg.Done()
+
+			var err error
+			for _, pr := range ps {
+				if err = probe(ip, pr); err != nil {
+					break
+				}
+			}
+
+			lock.Lock()
+			results[ip] = err
+			lock.Unlock()
+		}(dbc.IP, probes[dbc.Hostname])
+	}
+	wg.Wait()
+
+	conn.Txn(db.ContainerTable).Run(func(view db.Database) error {
+		for _, dbc := range view.SelectFromContainer(nil) {
+			if health := p.health(dbc, results); dbc.Health != health {
+				dbc.Health = health
+				view.Commit(dbc)
+			}
+		}
+		return nil
+	})
+
+	for ip := range p.failures {
+		if _, ok := results[ip]; !ok {
+			delete(p.failures, ip)
+		}
+	}
+}
+
+// health computes the new Health of `dbc` given the results of this round of
+// probes.  Containers that haven't yet passed a probe have no health, and healthy
+// containers only become unhealthy after several consecutive failures.
+func (p *prober) health(dbc db.Container, results map[string]error) string {
+	err, ok := results[dbc.IP]
+	if !ok {
+		return ""
+	}
+
+	if err == nil {
+		p.failures[dbc.IP] = 0
+		return db.ContainerHealthy
+	}
+
+	p.failures[dbc.IP]++
+	if p.failures[dbc.IP] < probeFailureThreshold {
+		return dbc.Health
+	}
+	return fmt.Sprintf("unhealthy: %s", err)
+}
+
+var probe = func(ip string, p blueprint.Probe) error {
+	addr := net.JoinHostPort(ip, strconv.Itoa(p.Port))
+	if p.Path == "" {
+		conn, err := net.DialTimeout("tcp", addr, probeTimeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	client := http.Client{Timeout: probeTimeout}
+	resp, err := client.Get("http://" + addr + p.Path)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("HTTP status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package network
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/quilt/quilt/blueprint"
+	"github.com/quilt/quilt/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProberRunOnce(t *testing.T) {
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		lb := view.InsertLoadBalancer()
+		lb.Hostnames = []string{"a", "b"}
+		lb.Probe = &blueprint.Probe{Port: 80}
+		view.Commit(lb)
+
+		lb = view.InsertLoadBalancer()
+		lb.Hostnames = []string{"c"}
+		view.Commit(lb)
+
+		for i, hostname := range []string{"a", "b", "c"} {
+			dbc := view.InsertContainer()
+			dbc.Hostname = hostname
+			dbc.IP = "10.0.0." + strconv.Itoa(i+2)
+			view.Commit(dbc)
+		}
+		return nil
+	})
+
+	oldProbe := probe
+	defer func() { probe = oldProbe }()
+
+	failing := map[string]bool{}
+	var probedLock sync.Mutex
+	var probed []string
+	probe = func(ip string, p blueprint.Probe) error {
+		assert.Equal(t, blueprint.Probe{Port: 80}, p)
+		probedLock.Lock()
+		probed = append(probed, ip)
+		probedLock.Unlock()
+		if failing[ip] {
+			return errors.New("refused")
+		}
+		return nil
+	}
+
+	health := func() map[string]string {
+		res := map[string]string{}
+		for _, dbc := range conn.SelectFromContainer(nil) {
+			res[dbc.Hostname] = dbc.Health
+		}
+		return res
+	}
+
+	p := prober{failures: map[string]int{}}
+	failing["10.0.0.3"] = true
+	p.runOnce(conn)
+	assert.Len(t, probed, 2)
+	assert.Equal(t, map[string]string{
+		"a": db.ContainerHealthy, "b": "", "c": ""}, health())
+
+	// Healthy backends tolerate a few failures before being marked unhealthy.
+	failing["10.0.0.2"] = true
+	for i := 1; i < probeFailureThreshold; i++ {
+		p.runOnce(conn)
+		assert.Equal(t, db.ContainerHealthy, health()["a"])
+	}
+	p.runOnce(conn)
+	assert.Equal(t, map[string]string{
+		"a": "unhealthy: refused",
+		"b": "unhealthy: refused",
+		"c": ""}, health())
+
+	// A single success restores health.
+	failing = map[string]bool{}
+	p.runOnce(conn)
+	assert.Equal(t, map[string]string{
+		"a": db.ContainerHealthy, "b": db.ContainerHealthy, "c": ""}, health())
+
+	// Containers that are no longer load balanced lose their health.
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		for _, lb := range view.SelectFromLoadBalancer(nil) {
+			view.Remove(lb)
+		}
+		return nil
+	})
+	p.runOnce(conn)
+	assert.Equal(t, map[string]string{"a": "", "b": "", "c": ""}, health())
+	assert.Empty(t, p.failures)
+}
+
+func TestProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/healthz" {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	defer server.Close()
+
+	host, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+	assert.NoError(t, err)
+	port, _ := strconv.Atoi(portStr)
+
+	assert.NoError(t, probe(host, blueprint.Probe{Port: port}))
+	assert.NoError(t, probe(host, blueprint.Probe{Port: port, Path: "/healthz"}))
+	assert.EqualError(t, probe(host, blueprint.Probe{Port: port, Path: "/bad"}),
+		"HTTP status 404")
+
+	server.Close()
+	assert.Error(t, probe(host, blueprint.Probe{Port: port}))
+}
@@ -0,0 +1,167 @@
+package network
+
+import (
+	"time"
+
+	"github.com/quilt/quilt/blueprint"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/join"
+	"github.com/quilt/quilt/minion/network/openflow"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const trafficInterval = 10 * time.Second
+
+// trafficCounter tracks the traffic counted by the OpenFlow flows of each source
+// and destination.  The flows' counters restart whenever they are reinstalled, so
+// the totals are accumulated from the increase of each flow between successive
+// dumps.
+type trafficCounter struct {
+	last  map[string]openflow.Traffic
+	total map[[2]string]openflow.Traffic
+}
+
+// Workers count the traffic sent and received by their containers, and record it
+// in the traffic table, keyed by the endpoints of the connection graph.  The
+// leader sums the traffic of all the workers.
+func runTraffic(conn db.Conn) {
+	tc := trafficCounter{
+		last:  map[string]openflow.Traffic{},
+		total: map[[2]string]openflow.Traffic{},
+	}
+	for range time.Tick(trafficInterval) {
+		if conn.MinionSelf().Role == db.Worker {
+			tc.runOnce(conn)
+		}
+	}
+}
+
+func (tc *trafficCounter) runOnce(conn db.Conn) {
+	dump, err := dumpTraffic()
+	if err != nil {
+		log.WithError(err).Warning("Failed to count traffic.")
+		return
+	}
+	tc.update(dump)
+
+	conn.Txn(db.ConnectionTable, db.ContainerTable, db.HostnameTable,
+		db.LoadBalancerTable, db.TrafficTable).Run(func(view db.Database) error {
+
+		// The traffic is sent either by this worker's containers, or from
+		// outside of Quilt, so their classes are all known.
+		classes := map[string]int{}
+		for _, dbc := range view.SelectFromContainer(nil) {
+			classes[dbc.IP] = dbc.TrafficClass
+		}
+
+		// Flows may briefly disappear while they're reinstalled, so their
+		// totals are only forgotten once their connection is removed.
+		// Responses flow in the opposite direction to the connection.
+		connected := map[[2]string]bool{}
+		for _, dbc := range view.SelectFromConnection(nil) {
+			connected[[2]string{dbc.From, dbc.To}] = true
+			connected[[2]string{dbc.To, dbc.From}] = true
+		}
+
+		labels := trafficLabels(view)
+		traffic := map[[2]string]db.Traffic{}
+		for ips, t := range tc.total {
+			from, okFrom := labels[t.Src]
+			to, okTo := labels[t.Dst]
+			if !okFrom || !okTo || !connected[[2]string{from, to}] {
+				delete(tc.total, ips)
+				continue
+			}
+
+			key := [2]string{from, to}
+			row := traffic[key]
+			row.From, row.To = from, to
+			row.TrafficClass = classes[t.Src]
+			row.Packets += t.Packets
+			row.Bytes += t.Bytes
+			traffic[key] = row
+		}
+
+		var rows db.TrafficSlice
+		for _, row := range traffic {
+			rows = append(rows, row)
+		}
+		updateTraffic(view, rows)
+		return nil
+	})
+}
+
+// update accumulates the traffic in `dump` into the totals.
+func (tc *trafficCounter) update(dump []openflow.Traffic) {
+	current := map[string]openflow.Traffic{}
+	for _, t := range dump {
+		current[t.Flow] = t
+
+		// A flow that's new, or whose duration went down because it was
+		// reinstalled, counted all of its traffic since the last dump.
+		last, ok := tc.last[t.Flow]
+		if !ok || t.Duration < last.Duration {
+			last = openflow.Traffic{}
+		}
+
+		key := [2]string{t.Src, t.Dst}
+		total := tc.total[key]
+		total.Src, total.Dst = t.Src, t.Dst
+		total.Packets += t.Packets - last.Packets
+		total.Bytes += t.Bytes - last.Bytes
+		tc.total[key] = total
+	}
+	tc.last = current
+}
+
+// trafficLabels maps the addresses counted by OpenFlow to the endpoints of the
+// connection graph they belong to.
+func trafficLabels(view db.Database) map[string]string {
+	labels := map[string]string{"": blueprint.PublicInternetLabel}
+	for _, hn := range view.SelectFromHostname(nil) {
+		labels[hn.IP] = hn.Hostname
+	}
+
+	for _, lb := range view.SelectFromLoadBalancer(nil) {
+		if lb.IP != "" {
+			labels[lb.IP] = lb.Name
+		}
+	}
+
+	for _, dbc := range view.SelectFromConnection(nil) {
+		for _, cidr := range dbc.ExternalCIDRs() {
+			labels[cidr] = dbc.To
+		}
+	}
+	return labels
+}
+
+// updateTraffic replaces the rows of the traffic table with `rows`.
+func updateTraffic(view db.Database, rows db.TrafficSlice) {
+	key := func(intf interface{}) interface{} {
+		t := intf.(db.Traffic)
+		return [2]string{t.From, t.To}
+	}
+
+	dbTraffic := db.TrafficSlice(view.SelectFromTraffic(nil))
+	pairs, toAdd, toRemove := join.HashJoin(rows, dbTraffic, key, key)
+
+	for _, intf := range toRemove {
+		view.Remove(intf.(db.Traffic))
+	}
+
+	for _, intf := range toAdd {
+		pairs = append(pairs, join.Pair{L: intf, R: view.InsertTraffic()})
+	}
+
+	for _, pair := range pairs {
+		t := pair.L.(db.Traffic)
+		t.ID = pair.R.(db.Traffic).ID
+		if t != pair.R.(db.Traffic) {
+			view.Commit(t)
+		}
+	}
+}
+
+var dumpTraffic = openflow.DumpTraffic
@@ -0,0 +1,118 @@
+package network
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/minion/network/openflow"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunTraffic(t *testing.T) {
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		for hostname, ip := range map[string]string{
+			"web": "10.0.0.2", "db": "10.0.0.3", "other": "10.0.0.4"} {
+			hn := view.InsertHostname()
+			hn.Hostname, hn.IP = hostname, ip
+			view.Commit(hn)
+		}
+
+		lb := view.InsertLoadBalancer()
+		lb.Name, lb.IP = "lb", "10.0.0.5"
+		view.Commit(lb)
+
+		for _, pair := range [][2]string{{"public", "web"}, {"web", "db"},
+			{"web", "lb"}, {"other", "db"}, {"web", "cidr:52.1.0.0/16"}} {
+			dbc := view.InsertConnection()
+			dbc.From, dbc.To = pair[0], pair[1]
+			view.Commit(dbc)
+		}
+
+		container := view.InsertContainer()
+		container.IP, container.TrafficClass = "10.0.0.2", 10
+		view.Commit(container)
+		return nil
+	})
+
+	var dump []openflow.Traffic
+	var dumpErr error
+	dumpTraffic = func() ([]openflow.Traffic, error) {
+		return dump, dumpErr
+	}
+
+	traffic := func() map[[2]string][2]uint64 {
+		res := map[[2]string][2]uint64{}
+		for _, t := range conn.SelectFromTraffic(nil) {
+			res[[2]string{t.From, t.To}] = [2]uint64{t.Packets, t.Bytes}
+		}
+		return res
+	}
+
+	tc := trafficCounter{
+		last:  map[string]openflow.Traffic{},
+		total: map[[2]string]openflow.Traffic{},
+	}
+
+	flow := func(src, dst, flow string, duration float64, packets,
+		bytes uint64) openflow.Traffic {
+		return openflow.Traffic{Src: src, Dst: dst, Flow: flow,
+			Duration: duration, Packets: packets, Bytes: bytes}
+	}
+
+	dump = []openflow.Traffic{
+		flow("", "10.0.0.2", "a", 5, 1, 100),
+		flow("10.0.0.2", "10.0.0.3", "b", 5, 1, 150),
+		flow("10.0.0.2", "10.0.0.3", "c", 5, 1, 50),
+		flow("10.0.0.2", "10.0.0.5", "d", 5, 3, 300),
+		flow("10.0.0.2", "52.1.0.0/16", "e", 5, 4, 400),
+		flow("10.0.0.2", "10.0.0.9", "f", 5, 5, 500),
+	}
+	tc.runOnce(conn)
+	assert.Equal(t, map[[2]string][2]uint64{
+		{"public", "web"}:           {1, 100},
+		{"web", "db"}:               {2, 200},
+		{"web", "lb"}:               {3, 300},
+		{"web", "cidr:52.1.0.0/16"}: {4, 400},
+	}, traffic())
+
+	// Traffic is tagged with the class of the container that sent it.
+	for _, row := range conn.SelectFromTraffic(nil) {
+		if row.From == "web" {
+			assert.Equal(t, 10, row.TrafficClass)
+		} else {
+			assert.Zero(t, row.TrafficClass)
+		}
+	}
+
+	// Flows that were reinstalled restart their counters, even if they've since
+	// counted more than before.  The traffic of flows that disappear is only
+	// forgotten once their connection is removed.
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		for _, dbc := range view.SelectFromConnection(nil) {
+			if dbc.From == "web" && dbc.To == "cidr:52.1.0.0/16" {
+				view.Remove(dbc)
+			}
+		}
+		return nil
+	})
+	dump = []openflow.Traffic{
+		flow("", "10.0.0.2", "a", 15, 3, 300),
+		flow("10.0.0.2", "10.0.0.3", "b", 2, 2, 200),
+		flow("10.0.0.2", "10.0.0.3", "c", 15, 1, 50),
+		flow("10.0.0.4", "10.0.0.3", "g", 15, 1, 10),
+	}
+	tc.runOnce(conn)
+	assert.Equal(t, map[[2]string][2]uint64{
+		{"public", "web"}: {3, 300},
+		{"web", "db"}:     {4, 400},
+		{"web", "lb"}:     {3, 300},
+		{"other", "db"}:   {1, 10},
+	}, traffic())
+
+	// Failed dumps leave the traffic untouched.
+	dumpErr = errors.New("err")
+	tc.runOnce(conn)
+	assert.Len(t, traffic(), 4)
+}
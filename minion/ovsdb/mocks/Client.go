@@ -200,6 +200,29 @@ func (_m *Client) ListLoadBalancers() ([]ovsdb.LoadBalancer, error) {
 	return r0, r1
 }
 
+// ListPortQoS provides a mock function with given fields:
+func (_m *Client) ListPortQoS() (map[string]ovsdb.PortQoS, error) {
+	ret := _m.Called()
+
+	var r0 map[string]ovsdb.PortQoS
+	if rf, ok := ret.Get(0).(func() map[string]ovsdb.PortQoS); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]ovsdb.PortQoS)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListRouterPorts provides a mock function with given fields:
 func (_m *Client) ListRouterPorts() ([]ovsdb.RouterPort, error) {
 	ret := _m.Called()
@@ -332,6 +355,20 @@ func (_m *Client) OpenFlowPorts() (map[string]int, error) {
 	return r0, r1
 }
 
+// SetPortQoS provides a mock function with given fields: name, qos
+func (_m *Client) SetPortQoS(name string, qos ovsdb.PortQoS) error {
+	ret := _m.Called(name, qos)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, ovsdb.PortQoS) error); ok {
+		r0 = rf(name, qos)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdateSwitchPortAddresses provides a mock function with given fields: name, addresses
 func (_m *Client) UpdateSwitchPortAddresses(name string, addresses []string) error {
 	ret := _m.Called(name, addresses)
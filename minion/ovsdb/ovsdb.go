@@ -7,6 +7,8 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sort"
+	"strconv"
 
 	"github.com/quilt/quilt/counter"
 	ovs "github.com/socketplane/libovsdb"
@@ -42,6 +44,8 @@ type Client interface {
 	DeleteLoadBalancer(lswitch string, lb LoadBalancer) error
 
 	OpenFlowPorts() (map[string]int, error)
+	ListPortQoS() (map[string]PortQoS, error)
+	SetPortQoS(name string, qos PortQoS) error
 
 	Disconnect()
 }
@@ -108,6 +112,20 @@ type LoadBalancer struct {
 	VIPs map[string]string
 }
 
+// PortQoS limits the rates, in bits per second, of the traffic that an OVS port
+// receives and sends.  A zero rate is unlimited.
+type PortQoS struct {
+	// The rate at which traffic received from the port is policed.
+	PolicingRate int
+
+	// The rate to which the traffic sent to the port is shaped.
+	MaxRate int
+
+	// Maps queue IDs, greater than zero, to the rate of the traffic that
+	// OpenFlow enqueues in them.
+	QueueRates map[int]int
+}
+
 type row map[string]interface{}
 type mutation interface{}
 
@@ -554,6 +572,182 @@ func (ovsdb client) OpenFlowPorts() (map[string]int, error) {
 	return ifaceMap, nil
 }
 
+// ListPortQoS returns the QoS of each OVS port, keyed by name, as it was set by
+// SetPortQoS.  Policing rates are only stored to the kbps.
+func (ovsdb client) ListPortQoS() (map[string]PortQoS, error) {
+	c.Inc("List Port QoS")
+	var ops []ovs.Operation
+	for _, table := range []string{"Interface", "Port", "QoS", "Queue"} {
+		ops = append(ops, ovs.Operation{
+			Op:    "select",
+			Table: table,
+			Where: noCondition,
+		})
+	}
+
+	reply, err := ovsdb.Transact("Open_vSwitch", ops...)
+	if err != nil {
+		return nil, fmt.Errorf("transaction error: listing port QoS: %s", err)
+	}
+	if err := errorCheck(reply, len(ops)); err != nil {
+		return nil, err
+	}
+	ifaceRows, portRows, qosRows, queueRows := reply[0].Rows, reply[1].Rows,
+		reply[2].Rows, reply[3].Rows
+
+	queueRates := map[string]int{}
+	for _, queue := range queueRows {
+		rate, err := maxRate(queue)
+		if err != nil {
+			return nil, fmt.Errorf("malformed queue: %s", err)
+		}
+		queueRates[ovsUUIDFromRow(queue).GoUUID] = rate
+	}
+
+	qosByUUID := map[string]PortQoS{}
+	for _, qosRow := range qosRows {
+		var qos PortQoS
+		rate, err := maxRate(qosRow)
+		if err != nil {
+			return nil, fmt.Errorf("malformed QoS: %s", err)
+		}
+		if rate != unlimitedRate {
+			qos.MaxRate = rate
+		}
+
+		queues, err := ovsUUIDMapToMap(qosRow["queues"])
+		if err != nil {
+			return nil, fmt.Errorf("malformed QoS queues: %s", err)
+		}
+		for id, uuid := range queues {
+			if id == 0 {
+				continue
+			}
+			if qos.QueueRates == nil {
+				qos.QueueRates = map[int]int{}
+			}
+			qos.QueueRates[id] = queueRates[uuid]
+		}
+		qosByUUID[ovsUUIDFromRow(qosRow).GoUUID] = qos
+	}
+
+	result := map[string]PortQoS{}
+	for _, port := range portRows {
+		name, ok := port["name"].(string)
+		if !ok {
+			continue
+		}
+
+		// A port's qos is either a UUID, or the empty set.
+		var qos PortQoS
+		if ref, ok := port["qos"].([]interface{}); ok && len(ref) == 2 &&
+			ref[0] == "uuid" {
+			uuid, _ := ref[1].(string)
+			qos = qosByUUID[uuid]
+		}
+		result[name] = qos
+	}
+
+	for _, iface := range ifaceRows {
+		name, ok := iface["name"].(string)
+		if !ok {
+			continue
+		}
+
+		if kbps, ok := iface["ingress_policing_rate"].(float64); ok && kbps > 0 {
+			qos := result[name]
+			qos.PolicingRate = int(kbps) * 1000
+			result[name] = qos
+		}
+	}
+	return result, nil
+}
+
+// The rate that linux-htb shapes traffic to if it isn't told one.  Without it, the
+// queues of ports without a MaxRate would be limited to 100mbit.
+const unlimitedRate = 10 * 1000 * 1000 * 1000
+
+// SetPortQoS polices and shapes the traffic of the interface and port called `name`
+// according to `qos`, replacing whatever QoS they had before.  The QoS and Queue
+// rows that the port stops referencing are garbage collected by ovsdb.
+func (ovsdb client) SetPortQoS(name string, qos PortQoS) error {
+	c.Inc("Set Port QoS")
+	// OVS polices in kbps, and treats a rate of zero as unlimited, so partial
+	// kbps are rounded up rather than dropped.
+	policingKbps := (qos.PolicingRate + 999) / 1000
+	ops := []ovs.Operation{{
+		Op:    "update",
+		Table: "Interface",
+		Row: map[string]interface{}{
+			"ingress_policing_rate":  policingKbps,
+			"ingress_policing_burst": policingKbps / 10,
+		},
+		Where: newCondition("name", "==", name),
+	}}
+
+	var qosRef interface{} = ovs.OvsSet{GoSet: []interface{}{}}
+	if qos.MaxRate > 0 || len(qos.QueueRates) > 0 {
+		maxRate := qos.MaxRate
+		if maxRate <= 0 {
+			maxRate = unlimitedRate
+		}
+
+		// Queue 0 carries the traffic that OpenFlow doesn't enqueue elsewhere.
+		rates := map[int]int{0: maxRate}
+		var ids []int
+		for id, rate := range qos.QueueRates {
+			rates[id] = rate
+		}
+		for id := range rates {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+
+		queues := map[int]ovs.UUID{}
+		for _, id := range ids {
+			uuidName := fmt.Sprintf("qqueue%d", id)
+			queues[id] = ovs.UUID{GoUUID: uuidName}
+			ops = append(ops, ovs.Operation{
+				Op:    "insert",
+				Table: "Queue",
+				Row: map[string]interface{}{
+					"other_config": newOvsMap(map[string]string{
+						"max-rate": strconv.Itoa(rates[id]),
+					}),
+				},
+				UUIDName: uuidName,
+			})
+		}
+
+		ops = append(ops, ovs.Operation{
+			Op:    "insert",
+			Table: "QoS",
+			Row: map[string]interface{}{
+				"type": "linux-htb",
+				"other_config": newOvsMap(map[string]string{
+					"max-rate": strconv.Itoa(maxRate),
+				}),
+				"queues": newOvsMap(queues),
+			},
+			UUIDName: "qqosadd",
+		})
+		qosRef = ovs.UUID{GoUUID: "qqosadd"}
+	}
+
+	ops = append(ops, ovs.Operation{
+		Op:    "update",
+		Table: "Port",
+		Row:   map[string]interface{}{"qos": qosRef},
+		Where: newCondition("name", "==", name),
+	})
+
+	results, err := ovsdb.Transact("Open_vSwitch", ops...)
+	if err != nil {
+		return fmt.Errorf("transaction error: setting QoS of %s: %s", name, err)
+	}
+	return errorCheck(results, len(ops))
+}
+
 func (ovsdb client) CreateLoadBalancer(lswitch, name string,
 	vips map[string]string) error {
 	c.Inc("Create Load Balancer")
@@ -692,6 +886,51 @@ func ovsStringMapToMap(oMap interface{}) (map[string]string, error) {
 	return ret, nil
 }
 
+// maxRate parses the "max-rate" of the `other_config` of a QoS or Queue row.
+func maxRate(r row) (int, error) {
+	config, err := ovsStringMapToMap(r["other_config"])
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(config["max-rate"])
+}
+
+// ovsUUIDMapToMap converts an ovsdb map from integers to UUIDs, such as the queues
+// of a QoS row.
+func ovsUUIDMapToMap(oMap interface{}) (map[int]string, error) {
+	wrap, ok := oMap.([]interface{})
+	if !ok || len(wrap) != 2 || wrap[0] != "map" {
+		return nil, errors.New("ovs map outermost layer invalid")
+	}
+
+	brokenMap, ok := wrap[1].([]interface{})
+	if !ok {
+		return nil, errors.New("ovs map content invalid")
+	}
+
+	ret := map[int]string{}
+	for _, kvPair := range brokenMap {
+		kvSlice, ok := kvPair.([]interface{})
+		if !ok || len(kvSlice) != 2 {
+			return nil, errors.New("ovs map block must be a pair")
+		}
+		key, ok := kvSlice[0].(float64)
+		if !ok {
+			return nil, errors.New("ovs map key must be an integer")
+		}
+		uuid, ok := kvSlice[1].([]interface{})
+		if !ok || len(uuid) != 2 || uuid[0] != "uuid" {
+			return nil, errors.New("ovs map value must be a UUID")
+		}
+		val, ok := uuid[1].(string)
+		if !ok {
+			return nil, errors.New("ovs map value must be a UUID")
+		}
+		ret[int(key)] = val
+	}
+	return ret, nil
+}
+
 func ovsUUIDFromRow(row row) ovs.UUID {
 	uuid := ovs.UUID{}
 	block, ok := row["_uuid"].([]interface{})
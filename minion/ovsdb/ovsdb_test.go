@@ -2,6 +2,7 @@ package ovsdb
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	ovs "github.com/socketplane/libovsdb"
@@ -670,6 +671,161 @@ func TestDeleteLoadBalancer(t *testing.T) {
 	api.AssertExpectations(t)
 }
 
+func TestSetPortQoS(t *testing.T) {
+	t.Parallel()
+
+	api := new(mockTransact)
+	odb := Client(client{api})
+
+	ifaceOp := func(kbps int) ovs.Operation {
+		return ovs.Operation{
+			Op:    "update",
+			Table: "Interface",
+			Row: map[string]interface{}{
+				"ingress_policing_rate":  kbps,
+				"ingress_policing_burst": kbps / 10,
+			},
+			Where: newCondition("name", "==", "veth"),
+		}
+	}
+	portOp := func(qos interface{}) ovs.Operation {
+		return ovs.Operation{
+			Op:    "update",
+			Table: "Port",
+			Row:   map[string]interface{}{"qos": qos},
+			Where: newCondition("name", "==", "veth"),
+		}
+	}
+	queueOp := func(id int, rate string) ovs.Operation {
+		return ovs.Operation{
+			Op:    "insert",
+			Table: "Queue",
+			Row: map[string]interface{}{
+				"other_config": newOvsMap(map[string]string{
+					"max-rate": rate,
+				}),
+			},
+			UUIDName: fmt.Sprintf("qqueue%d", id),
+		}
+	}
+
+	noQoS := ovs.OvsSet{GoSet: []interface{}{}}
+	api.On("Transact", "Open_vSwitch", ifaceOp(0), portOp(noQoS)).Return(
+		nil, errors.New("err")).Once()
+	err := odb.SetPortQoS("veth", PortQoS{})
+	assert.EqualError(t, err, "transaction error: setting QoS of veth: err")
+
+	api.On("Transact", "Open_vSwitch", ifaceOp(5000), portOp(noQoS)).Return(
+		[]ovs.OperationResult{{}, {}}, nil).Once()
+	err = odb.SetPortQoS("veth", PortQoS{PolicingRate: 5000000})
+	assert.NoError(t, err)
+
+	// Rates below a kbps aren't truncated to zero, which would be unlimited.
+	api.On("Transact", "Open_vSwitch", ifaceOp(1), portOp(noQoS)).Return(
+		[]ovs.OperationResult{{}, {}}, nil).Once()
+	err = odb.SetPortQoS("veth", PortQoS{PolicingRate: 500})
+	assert.NoError(t, err)
+
+	qosOp := ovs.Operation{
+		Op:    "insert",
+		Table: "QoS",
+		Row: map[string]interface{}{
+			"type": "linux-htb",
+			"other_config": newOvsMap(map[string]string{
+				"max-rate": "10000000000",
+			}),
+			"queues": newOvsMap(map[int]ovs.UUID{
+				0: {GoUUID: "qqueue0"},
+				1: {GoUUID: "qqueue1"},
+				2: {GoUUID: "qqueue2"},
+			}),
+		},
+		UUIDName: "qqosadd",
+	}
+	api.On("Transact", "Open_vSwitch", ifaceOp(0), queueOp(0, "10000000000"),
+		queueOp(1, "1000000"), queueOp(2, "2000000"), qosOp,
+		portOp(ovs.UUID{GoUUID: "qqosadd"})).Return(
+		[]ovs.OperationResult{{}, {}, {}, {}, {}, {Error: "err"}}, nil).Once()
+	err = odb.SetPortQoS("veth", PortQoS{
+		QueueRates: map[int]int{2: 2000000, 1: 1000000}})
+	assert.EqualError(t, err, "operation 5 failed due to error: err: ")
+
+	api.AssertExpectations(t)
+}
+
+func TestListPortQoS(t *testing.T) {
+	t.Parallel()
+
+	api := new(mockTransact)
+	odb := Client(client{api})
+
+	var ops []interface{}
+	for _, table := range []string{"Interface", "Port", "QoS", "Queue"} {
+		ops = append(ops, ovs.Operation{
+			Op:    "select",
+			Table: table,
+			Where: noCondition,
+		})
+	}
+	args := append([]interface{}{"Open_vSwitch"}, ops...)
+
+	api.On("Transact", args...).Return(nil, errors.New("err")).Once()
+	_, err := odb.ListPortQoS()
+	assert.EqualError(t, err, "transaction error: listing port QoS: err")
+
+	uuid := func(id string) []interface{} {
+		return []interface{}{"uuid", id}
+	}
+	maxRate := func(rate string) []interface{} {
+		return []interface{}{"map", []interface{}{
+			[]interface{}{"max-rate", rate}}}
+	}
+	res := []ovs.OperationResult{
+		{Rows: []map[string]interface{}{
+			{"name": "a", "ingress_policing_rate": float64(5)},
+			{"name": "b", "ingress_policing_rate": float64(0)},
+			{"name": "c", "ingress_policing_rate": float64(0)},
+		}},
+		{Rows: []map[string]interface{}{
+			{"name": "a", "qos": []interface{}{"set", []interface{}{}}},
+			{"name": "b", "qos": uuid("qos1")},
+			{"name": "c", "qos": uuid("qos2")},
+		}},
+		{Rows: []map[string]interface{}{
+			{"_uuid": uuid("qos1"), "other_config": maxRate("10000000000"),
+				"queues": []interface{}{"map", []interface{}{
+					[]interface{}{float64(0), uuid("queue0")},
+					[]interface{}{float64(1), uuid("queue1")},
+				}}},
+			{"_uuid": uuid("qos2"), "other_config": maxRate("3000"),
+				"queues": []interface{}{"map", []interface{}{
+					[]interface{}{float64(0), uuid("queue2")},
+				}}},
+		}},
+		{Rows: []map[string]interface{}{
+			{"_uuid": uuid("queue0"), "other_config": maxRate("10000000000")},
+			{"_uuid": uuid("queue1"), "other_config": maxRate("7000")},
+			{"_uuid": uuid("queue2"), "other_config": maxRate("3000")},
+		}},
+	}
+	api.On("Transact", args...).Return(res, nil).Once()
+	qos, err := odb.ListPortQoS()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]PortQoS{
+		"a": {PolicingRate: 5000},
+		"b": {QueueRates: map[int]int{1: 7000}},
+		"c": {MaxRate: 3000},
+	}, qos)
+
+	res[3].Rows[0]["other_config"] = []interface{}{"map", []interface{}{}}
+	api.On("Transact", args...).Return(res, nil).Once()
+	_, err = odb.ListPortQoS()
+	assert.EqualError(t, err, `malformed queue: strconv.Atoi: parsing "": `+
+		"invalid syntax")
+
+	api.AssertExpectations(t)
+}
+
 func TestOvsStringSetToSlice(t *testing.T) {
 	t.Parallel()
 	assert.Equal(t, []string{"b"}, ovsStringSetToSlice("b"))
@@ -70,6 +70,21 @@ type MinionConfig struct {
 	EtcdMembers    []string          `protobuf:"bytes,9,rep,name=EtcdMembers" json:"EtcdMembers,omitempty"`
 	AuthorizedKeys []string          `protobuf:"bytes,10,rep,name=AuthorizedKeys" json:"AuthorizedKeys,omitempty"`
 	DiskSize       int32             `protobuf:"varint,11,opt,name=DiskSize" json:"DiskSize,omitempty"`
+	HostKeys       []string          `protobuf:"bytes,12,rep,name=HostKeys" json:"HostKeys,omitempty"`
+	// Overrides the images of system containers.  The keys are "etcd", "ovs",
+	// "registry", "cadvisor" and "router".
+	SystemImages    map[string]string `protobuf:"bytes,13,rep,name=SystemImages" json:"SystemImages,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	DisableCadvisor bool              `protobuf:"varint,14,opt,name=DisableCadvisor" json:"DisableCadvisor,omitempty"`
+	// The resolvers that DNS queries for external names are forwarded to.
+	DNSUpstream []string `protobuf:"bytes,15,rep,name=DNSUpstream" json:"DNSUpstream,omitempty"`
+	// The DNS domain of the deployment's hostnames.
+	DNSDomain string `protobuf:"bytes,16,opt,name=DNSDomain" json:"DNSDomain,omitempty"`
+	// The container network.  See blueprint.NetworkConfig.
+	ContainerSubnet string `protobuf:"bytes,17,opt,name=ContainerSubnet" json:"ContainerSubnet,omitempty"`
+	GatewayIP       string `protobuf:"bytes,18,opt,name=GatewayIP" json:"GatewayIP,omitempty"`
+	LoadBalancerIP  string `protobuf:"bytes,19,opt,name=LoadBalancerIP" json:"LoadBalancerIP,omitempty"`
+	MTU             int32  `protobuf:"varint,20,opt,name=MTU" json:"MTU,omitempty"`
+	Encapsulation   string `protobuf:"bytes,21,opt,name=Encapsulation" json:"Encapsulation,omitempty"`
 }
 
 func (m *MinionConfig) Reset()                    { *m = MinionConfig{} }
@@ -154,6 +169,76 @@ func (m *MinionConfig) GetDiskSize() int32 {
 	return 0
 }
 
+func (m *MinionConfig) GetHostKeys() []string {
+	if m != nil {
+		return m.HostKeys
+	}
+	return nil
+}
+
+func (m *MinionConfig) GetSystemImages() map[string]string {
+	if m != nil {
+		return m.SystemImages
+	}
+	return nil
+}
+
+func (m *MinionConfig) GetDisableCadvisor() bool {
+	if m != nil {
+		return m.DisableCadvisor
+	}
+	return false
+}
+
+func (m *MinionConfig) GetDNSUpstream() []string {
+	if m != nil {
+		return m.DNSUpstream
+	}
+	return nil
+}
+
+func (m *MinionConfig) GetDNSDomain() string {
+	if m != nil {
+		return m.DNSDomain
+	}
+	return ""
+}
+
+func (m *MinionConfig) GetContainerSubnet() string {
+	if m != nil {
+		return m.ContainerSubnet
+	}
+	return ""
+}
+
+func (m *MinionConfig) GetGatewayIP() string {
+	if m != nil {
+		return m.GatewayIP
+	}
+	return ""
+}
+
+func (m *MinionConfig) GetLoadBalancerIP() string {
+	if m != nil {
+		return m.LoadBalancerIP
+	}
+	return ""
+}
+
+func (m *MinionConfig) GetMTU() int32 {
+	if m != nil {
+		return m.MTU
+	}
+	return 0
+}
+
+func (m *MinionConfig) GetEncapsulation() string {
+	if m != nil {
+		return m.Encapsulation
+	}
+	return ""
+}
+
 type Reply struct {
 }
 
@@ -285,27 +370,40 @@ var _Minion_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("minion/pb/pb.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 345 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x5c, 0x91, 0x51, 0x6b, 0xe2, 0x40,
-	0x14, 0x85, 0x4d, 0x8c, 0x31, 0xb9, 0xee, 0xaa, 0xdc, 0x87, 0x65, 0x90, 0x65, 0x09, 0x79, 0x90,
-	0xb0, 0x2c, 0x11, 0xdc, 0x5f, 0x60, 0x6b, 0x5a, 0x82, 0xa8, 0x61, 0x2c, 0xf4, 0xd9, 0xd4, 0xa9,
-	0x1d, 0x1a, 0x33, 0xe9, 0x64, 0x14, 0xf4, 0xa7, 0xf7, 0xa9, 0x64, 0x14, 0xab, 0xbe, 0xcd, 0xfd,
-	0xce, 0x39, 0xc3, 0xe5, 0x5c, 0xc0, 0x0d, 0xcf, 0xb9, 0xc8, 0x07, 0x45, 0x3a, 0x28, 0xd2, 0xb0,
-	0x90, 0x42, 0x09, 0xff, 0xd3, 0x84, 0x1f, 0x53, 0x8d, 0xef, 0x45, 0xfe, 0xca, 0xd7, 0xd8, 0x06,
-	0x33, 0x1e, 0x13, 0xc3, 0x33, 0x02, 0x97, 0x9a, 0xf1, 0x18, 0xfb, 0x60, 0x49, 0x91, 0x31, 0x62,
-	0x7a, 0x46, 0xd0, 0x1e, 0x62, 0x78, 0x69, 0x0e, 0xa9, 0xc8, 0x18, 0xd5, 0x3a, 0xfe, 0x06, 0x37,
-	0x91, 0x7c, 0xb7, 0x54, 0x2c, 0x4e, 0x48, 0x5d, 0xc7, 0xbf, 0x41, 0xa5, 0xde, 0x65, 0x5b, 0x56,
-	0x48, 0x9e, 0x2b, 0x62, 0x1d, 0xd5, 0x33, 0xc0, 0x1e, 0x38, 0x89, 0x14, 0x3b, 0xbe, 0x62, 0x92,
-	0x34, 0xb4, 0x78, 0x9e, 0x11, 0xc1, 0x5a, 0xf0, 0x03, 0x23, 0xb6, 0xe6, 0xfa, 0x8d, 0xbf, 0xc0,
-	0xa6, 0x6c, 0xcd, 0x45, 0x4e, 0x9a, 0x9a, 0x9e, 0x26, 0xfc, 0x03, 0xf0, 0x90, 0x89, 0xa5, 0xe2,
-	0xf9, 0x3a, 0x4e, 0x88, 0xa3, 0xb5, 0x0b, 0x82, 0x1e, 0xb4, 0x22, 0xf5, 0xb2, 0x9a, 0xb2, 0x4d,
-	0xca, 0x64, 0x49, 0x5c, 0xaf, 0x1e, 0xb8, 0xf4, 0x12, 0x61, 0x1f, 0xda, 0xa3, 0xad, 0x7a, 0x13,
-	0x92, 0x1f, 0xd8, 0x6a, 0xc2, 0xf6, 0x25, 0x01, 0x6d, 0xba, 0xa1, 0xd5, 0xc6, 0x63, 0x5e, 0xbe,
-	0xeb, 0xcd, 0x5a, 0x9e, 0x11, 0x34, 0xe8, 0x79, 0xf6, 0x03, 0xb0, 0xaa, 0x5e, 0xd0, 0x01, 0x6b,
-	0x36, 0x9f, 0x45, 0xdd, 0x1a, 0x02, 0xd8, 0xcf, 0x73, 0x3a, 0x89, 0x68, 0xd7, 0xa8, 0xde, 0xd3,
-	0xd1, 0xe2, 0x29, 0xa2, 0x5d, 0xd3, 0x6f, 0x42, 0x83, 0xb2, 0x22, 0xdb, 0xfb, 0x2e, 0x34, 0x29,
-	0xfb, 0xd8, 0xb2, 0x52, 0x0d, 0x53, 0xb0, 0x8f, 0x15, 0xe3, 0x5f, 0xe8, 0x2c, 0x98, 0xba, 0x3a,
-	0xce, 0xcf, 0xab, 0xfa, 0x7b, 0x76, 0x78, 0x8c, 0xd7, 0xf0, 0x1f, 0x74, 0x1e, 0x6f, 0xbc, 0x4e,
-	0x78, 0xfa, 0xb2, 0x77, 0x9d, 0xf2, 0x6b, 0xa9, 0xad, 0x6f, 0xff, 0xff, 0x2b, 0x00, 0x00, 0xff,
-	0xff, 0xe3, 0x82, 0xdc, 0x40, 0x11, 0x02, 0x00, 0x00,
+	// 551 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x64, 0x93, 0x6f, 0x6f, 0xda, 0x3e,
+	0x10, 0xc7, 0x1b, 0xfe, 0xa4, 0x70, 0x6d, 0x81, 0xfa, 0xd7, 0xdf, 0x64, 0xa1, 0x69, 0x8b, 0xd0,
+	0x54, 0x45, 0xd3, 0x44, 0xa5, 0xee, 0xc9, 0xb4, 0x27, 0x53, 0x4b, 0x58, 0x17, 0x75, 0x50, 0xe4,
+	0xb4, 0xda, 0x63, 0xa7, 0xdc, 0x98, 0xd5, 0x60, 0x67, 0x8e, 0x61, 0xa2, 0x2f, 0x6a, 0xaf, 0x71,
+	0xb2, 0x83, 0x18, 0xb0, 0x67, 0x77, 0x9f, 0xfb, 0x7e, 0x2f, 0xf1, 0x9d, 0x0d, 0x64, 0x2e, 0xa4,
+	0x50, 0xf2, 0x22, 0x4f, 0x2f, 0xf2, 0xb4, 0x9f, 0x6b, 0x65, 0x54, 0xef, 0xb7, 0x0f, 0xc7, 0x23,
+	0x87, 0x07, 0x4a, 0x7e, 0x17, 0x33, 0xd2, 0x82, 0x4a, 0x1c, 0x51, 0x2f, 0xf0, 0xc2, 0x26, 0xab,
+	0xc4, 0x11, 0x39, 0x87, 0x9a, 0x56, 0x19, 0xd2, 0x4a, 0xe0, 0x85, 0xad, 0x4b, 0xd2, 0xdf, 0x16,
+	0xf7, 0x99, 0xca, 0x90, 0xb9, 0x3a, 0x79, 0x09, 0xcd, 0x89, 0x16, 0x4b, 0x6e, 0x30, 0x9e, 0xd0,
+	0xaa, 0xb3, 0xff, 0x05, 0xb6, 0x7a, 0x9d, 0x2d, 0x30, 0xd7, 0x42, 0x1a, 0x5a, 0x2b, 0xab, 0x1b,
+	0x40, 0xba, 0xd0, 0x98, 0x68, 0xb5, 0x14, 0x53, 0xd4, 0xb4, 0xee, 0x8a, 0x9b, 0x9c, 0x10, 0xa8,
+	0x25, 0xe2, 0x19, 0xa9, 0xef, 0xb8, 0x8b, 0xc9, 0x0b, 0xf0, 0x19, 0xce, 0x84, 0x92, 0xf4, 0xd0,
+	0xd1, 0x75, 0x46, 0x5e, 0x01, 0x7c, 0xce, 0x14, 0x37, 0x42, 0xce, 0xe2, 0x09, 0x6d, 0xb8, 0xda,
+	0x16, 0x21, 0x01, 0x1c, 0x0d, 0xcd, 0xe3, 0x74, 0x84, 0xf3, 0x14, 0x75, 0x41, 0x9b, 0x41, 0x35,
+	0x6c, 0xb2, 0x6d, 0x44, 0xce, 0xa1, 0x75, 0xb5, 0x30, 0x3f, 0x94, 0x16, 0xcf, 0x38, 0xbd, 0xc5,
+	0x55, 0x41, 0xc1, 0x89, 0xf6, 0xa8, 0xfd, 0xe3, 0x48, 0x14, 0x4f, 0xee, 0xcf, 0x8e, 0x02, 0x2f,
+	0xac, 0xb3, 0x4d, 0x6e, 0x6b, 0x5f, 0x54, 0x61, 0x9c, 0xfb, 0xd8, 0xb9, 0x37, 0x39, 0x19, 0xc0,
+	0x71, 0xb2, 0x2a, 0x0c, 0xce, 0xe3, 0x39, 0x9f, 0x61, 0x41, 0x4f, 0x82, 0x6a, 0x78, 0x74, 0xf9,
+	0x7a, 0x77, 0xaa, 0xdb, 0x8a, 0xa1, 0x34, 0x7a, 0xc5, 0x76, 0x4c, 0x24, 0x84, 0x76, 0x24, 0x0a,
+	0x9e, 0x66, 0x38, 0xe0, 0xd3, 0xa5, 0x28, 0x94, 0xa6, 0xad, 0xc0, 0x0b, 0x1b, 0x6c, 0x1f, 0xdb,
+	0x03, 0x47, 0xe3, 0xe4, 0x21, 0x2f, 0x8c, 0x46, 0x3e, 0xa7, 0xed, 0xf2, 0xc0, 0x5b, 0xc8, 0x2e,
+	0x26, 0x1a, 0x27, 0x91, 0x9a, 0x73, 0x21, 0x69, 0xa7, 0x5c, 0xcc, 0x06, 0xd8, 0x2f, 0x0d, 0x94,
+	0x34, 0x5c, 0x48, 0xd4, 0xc9, 0x22, 0x95, 0x68, 0xe8, 0xa9, 0xd3, 0xec, 0x63, 0xdb, 0xe7, 0x86,
+	0x1b, 0xfc, 0xc5, 0x57, 0xf1, 0x84, 0x92, 0xb2, 0xcf, 0x06, 0xd8, 0xb1, 0x7e, 0x55, 0x7c, 0x7a,
+	0xcd, 0x33, 0x2e, 0x1f, 0x51, 0xc7, 0x13, 0xfa, 0x9f, 0x93, 0xec, 0x51, 0xd2, 0x81, 0xea, 0xe8,
+	0xfe, 0x81, 0x9e, 0xb9, 0x89, 0xda, 0x90, 0xbc, 0x81, 0x93, 0xa1, 0x7c, 0xe4, 0x79, 0xb1, 0xc8,
+	0xb8, 0xb1, 0x1b, 0xff, 0xdf, 0x19, 0x77, 0x61, 0xf7, 0x13, 0x9c, 0xfe, 0x33, 0x34, 0xdb, 0xec,
+	0x09, 0x57, 0xeb, 0xab, 0x6c, 0x43, 0x72, 0x06, 0xf5, 0x25, 0xcf, 0x16, 0xe5, 0x65, 0x6e, 0xb2,
+	0x32, 0xf9, 0x58, 0xf9, 0xe0, 0xf5, 0x42, 0xa8, 0xd9, 0xbb, 0x4c, 0x1a, 0x50, 0x1b, 0xdf, 0x8d,
+	0x87, 0x9d, 0x03, 0x02, 0xe0, 0x7f, 0xbb, 0x63, 0xb7, 0x43, 0xd6, 0xf1, 0x6c, 0x3c, 0xba, 0x4a,
+	0xee, 0x87, 0xac, 0x53, 0xe9, 0x1d, 0x42, 0x9d, 0x61, 0x9e, 0xad, 0x7a, 0x4d, 0x38, 0x64, 0xf8,
+	0x73, 0x81, 0x85, 0xb9, 0x4c, 0xc1, 0x2f, 0x17, 0x48, 0xde, 0x42, 0x3b, 0x41, 0xb3, 0xf3, 0xa0,
+	0x4e, 0x76, 0x96, 0xdb, 0xf5, 0xfb, 0xa5, 0xfd, 0x80, 0xbc, 0x83, 0xf6, 0xcd, 0x9e, 0xb6, 0xd1,
+	0x5f, 0xb7, 0xec, 0xee, 0xba, 0x7a, 0x07, 0xa9, 0xef, 0xde, 0xeb, 0xfb, 0x3f, 0x03, 0x00, 0x91,
+	0xd1, 0xee, 0x08, 0xc5, 0x03, 0x00, 0x00,
 }
@@ -8,12 +8,16 @@ import (
 
 	"github.com/quilt/quilt/api"
 	apiServer "github.com/quilt/quilt/api/server"
+	"github.com/quilt/quilt/blueprint"
 	"github.com/quilt/quilt/cli/command/credentials"
 	"github.com/quilt/quilt/connection"
+	"github.com/quilt/quilt/connection/credentials/tls"
+	tlsIO "github.com/quilt/quilt/connection/credentials/tls/io"
 	"github.com/quilt/quilt/counter"
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/minion/docker"
 	"github.com/quilt/quilt/minion/etcd"
+	"github.com/quilt/quilt/minion/ipdef"
 	"github.com/quilt/quilt/minion/network"
 	"github.com/quilt/quilt/minion/network/plugin"
 	"github.com/quilt/quilt/minion/pprofile"
@@ -53,17 +57,6 @@ func Run(role db.Role, inboundPubIntf, outboundPubIntf, tlsDir string) {
 		go network.WriteSubnets(conn)
 	}
 
-	// Not in a goroutine, want the plugin to start before the scheduler
-	plugin.Run()
-
-	supervisor.Run(conn, dk, role)
-
-	go scheduler.Run(conn, dk)
-	go network.Run(conn, inboundPubIntf, outboundPubIntf)
-	go registry.Run(conn, dk)
-	go etcd.Run(conn)
-	go syncAuthorizedKeys(conn)
-
 	// Block until the credentials are in place on the local filesystem. We
 	// can't simply fail if the first read fails because the daemon might still
 	// be generating and copying keys onto the local filesystem. The key
@@ -83,17 +76,50 @@ func Run(role db.Role, inboundPubIntf, outboundPubIntf, tlsDir string) {
 		return
 	}
 
+	if tlsCreds, ok := creds.(tls.TLS); ok {
+		go reloadCredentials(tlsDir, tlsCreds)
+	}
+
 	go minionServerRun(conn, creds)
-	go apiServer.Run(conn, fmt.Sprintf("tcp://0.0.0.0:%d", api.DefaultRemotePort),
-		false, creds)
+	go apiServer.Run(conn, []apiServer.Listener{{
+		Address: fmt.Sprintf("tcp://0.0.0.0:%d", api.DefaultRemotePort),
+		Creds:   creds,
+	}}, false, creds, "")
+
+	// The container network is configured by the daemon, so the modules that
+	// depend on it can't start until the daemon has connected.
+	containerNet := waitForNetwork(conn)
+	if err := ipdef.Configure(containerNet); err != nil {
+		log.WithError(err).Error("Invalid container network")
+		return
+	}
+
+	// Not in a goroutine, want the plugin to start before the scheduler
+	plugin.Run(containerNet.MTU)
+
+	supervisor.Run(conn, dk, role)
+
+	go scheduler.Run(conn, dk)
+	go network.Run(conn, inboundPubIntf, outboundPubIntf)
+	go registry.Run(conn, dk)
+	go etcd.Run(conn)
+	go syncAuthorizedKeys(conn)
 
 	loopLog := util.NewEventTimer("Minion-Update")
 
+	lastNet := containerNet
 	for range conn.Trigger(db.MinionTable, db.EtcdTable).C {
 		loopLog.LogStart()
+		if self := conn.MinionSelf(); self.Network() != lastNet {
+			lastNet = self.Network()
+			log.WithField("network", lastNet).Warn("The container " +
+				"network changed, but only its encapsulation is " +
+				"updated in place. The rest applies to new machines.")
+		}
+
 		txn := conn.Txn(db.ConnectionTable, db.ContainerTable, db.MinionTable,
 			db.EtcdTable, db.PlacementTable, db.ImageTable,
-			db.LoadBalancerTable)
+			db.LoadBalancerTable, db.IngressTable)
 		txn.Run(func(view db.Database) error {
 			minion := view.MinionSelf()
 			if view.EtcdLeader() {
@@ -105,6 +131,36 @@ func Run(role db.Role, inboundPubIntf, outboundPubIntf, tlsDir string) {
 	}
 }
 
+// waitForNetwork blocks until the daemon has configured the container network,
+// and returns it.
+func waitForNetwork(conn db.Conn) blueprint.NetworkConfig {
+	for range conn.TriggerTick(30, db.MinionTable).C {
+		if self := conn.MinionSelf(); self.ContainerSubnet != "" {
+			return self.Network()
+		}
+		log.Debug("Waiting for the daemon to configure the container network")
+	}
+	panic("unreached")
+}
+
+// reloadCredentials periodically rereads the credentials in `tlsDir` so that the
+// daemon can rotate them without restarting the minion.
+func reloadCredentials(tlsDir string, creds tls.TLS) {
+	for range time.Tick(time.Minute) {
+		expiry := creds.CertExpiry()
+		if err := tlsIO.ReloadCredentials(tlsDir, creds); err != nil {
+			// The daemon may be in the middle of writing new credentials.
+			log.WithError(err).Debug("Failed to reload TLS credentials")
+			continue
+		}
+
+		if newExpiry := creds.CertExpiry(); !newExpiry.Equal(expiry) {
+			log.WithField("expiry", newExpiry).Info(
+				"Reloaded TLS credentials")
+		}
+	}
+}
+
 func runProfiler(duration time.Duration) {
 	go func() {
 		p := pprofile.New("minion")
@@ -77,6 +77,7 @@ Outer:
 			if validPlacement(ctx.constraints, *m, m.containers, dbc) {
 				c.Inc("Place Container")
 				dbc.Minion = m.PrivateIP
+				dbc.Unschedulable = false
 				ctx.changed = append(ctx.changed, dbc)
 				m.containers = append(m.containers, dbc)
 				heap.Fix(&minions, i)
@@ -85,6 +86,12 @@ Outer:
 			}
 		}
 		log.WithField("container", dbc).Warning("Failed to place container.")
+
+		// Let the daemon know that the cluster needs more workers.
+		if !dbc.Unschedulable {
+			dbc.Unschedulable = true
+			ctx.changed = append(ctx.changed, dbc)
+		}
 	}
 }
 
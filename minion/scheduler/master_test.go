@@ -240,12 +240,25 @@ func TestPlaceUnassigned(t *testing.T) {
 	placeUnassigned(ctx)
 	assert.Nil(t, ctx.changed)
 
+	// Containers that can't be placed are marked as unschedulable, once.
 	placements[0].Exclusive = false
 	placements[0].Region = "Nowhere"
 	containers[0].Minion = ""
+	ctx = makeContext(minions, placements, containers, nil)
+	placeUnassigned(ctx)
+	assert.Equal(t, []*db.Container{{ID: 1, BlueprintID: "1",
+		Unschedulable: true}}, ctx.changed)
+
 	ctx = makeContext(minions, placements, containers, nil)
 	placeUnassigned(ctx)
 	assert.Nil(t, ctx.changed)
+
+	// Once the container can be placed, it's no longer unschedulable.
+	placements[0].Region = "Region3"
+	ctx = makeContext(minions, placements, containers, nil)
+	placeUnassigned(ctx)
+	assert.Equal(t, []*db.Container{{ID: 1, BlueprintID: "1",
+		Minion: "3"}}, ctx.changed)
 }
 
 func TestMakeContext(t *testing.T) {
@@ -29,13 +29,14 @@ func Run(conn db.Conn, dk docker.Client) {
 
 	loopLog := util.NewEventTimer("Scheduler")
 	trig := conn.TriggerTick(60, db.MinionTable, db.ContainerTable,
-		db.PlacementTable, db.EtcdTable, db.ImageTable).C
+		db.PlacementTable, db.EtcdTable, db.ImageTable,
+		db.LoadBalancerTable, db.ConnectionTable, db.HostnameTable).C
 	for range trig {
 		loopLog.LogStart()
 		minion := conn.MinionSelf()
 
 		if minion.Role == db.Worker {
-			runWorker(conn, dk, minion.PrivateIP)
+			runWorker(conn, dk, minion.PrivateIP, minion.Domain())
 		} else if minion.Role == db.Master {
 			runMaster(conn)
 		}
@@ -3,6 +3,12 @@ package scheduler
 import (
 	"crypto/sha1"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,6 +20,7 @@ import (
 	"github.com/quilt/quilt/minion/ipdef"
 	"github.com/quilt/quilt/minion/network/openflow"
 	"github.com/quilt/quilt/minion/network/plugin"
+	"github.com/quilt/quilt/minion/ovsdb"
 	"github.com/quilt/quilt/util"
 )
 
@@ -21,11 +28,13 @@ const labelKey = "quilt"
 const labelValue = "scheduler"
 const labelPair = labelKey + "=" + labelValue
 const filesKey = "files"
+const securityKey = "security"
+const domainKey = "domain"
 const concurrencyLimit = 32
 
 var once sync.Once
 
-func runWorker(conn db.Conn, dk docker.Client, myIP string) {
+func runWorker(conn db.Conn, dk docker.Client, myIP, domain string) {
 	if myIP == "" {
 		return
 	}
@@ -53,7 +62,7 @@ func runWorker(conn db.Conn, dk docker.Client, myIP string) {
 			})
 
 			var changed []db.Container
-			changed, toBoot, toKill = syncWorker(dbcs, dkcs)
+			changed, toBoot, toKill = syncWorker(dbcs, dkcs, domain)
 			for _, dbc := range changed {
 				view.Commit(dbc)
 			}
@@ -66,7 +75,9 @@ func runWorker(conn db.Conn, dk docker.Client, myIP string) {
 		}
 
 		start := time.Now()
-		doContainers(dk, toBoot, dockerRun)
+		doContainers(dk, toBoot, func(dk docker.Client, iface interface{}) {
+			dockerRun(dk, iface, domain)
+		})
 		doContainers(dk, toKill, dockerKill)
 		log.Infof("Scheduler spent %v starting/stopping containers",
 			time.Since(start))
@@ -75,10 +86,18 @@ func runWorker(conn db.Conn, dk docker.Client, myIP string) {
 	updateOpenflow(conn, myIP)
 }
 
-func syncWorker(dbcs []db.Container, dkcs []docker.Container) (
+func syncWorker(dbcs []db.Container, dkcs []docker.Container, domain string) (
 	changed []db.Container, toBoot, toKill []interface{}) {
 
-	pairs, dbci, dkci := join.Join(dbcs, dkcs, syncJoinScore)
+	// Containers must be restarted if the DNS domain changes, as it determines
+	// their hostname and DNS search path.
+	score := func(left, right interface{}) int {
+		if containerDomain(right.(docker.Container)) != domain {
+			return -1
+		}
+		return syncJoinScore(left, right)
+	}
+	pairs, dbci, dkci := join.Join(dbcs, dkcs, score)
 
 	for _, i := range dkci {
 		toKill = append(toKill, i.(docker.Container))
@@ -121,32 +140,34 @@ func doContainers(dk docker.Client, ifaces []interface{},
 	}
 }
 
-func dockerRun(dk docker.Client, iface interface{}) {
+func dockerRun(dk docker.Client, iface interface{}, domain string) {
 	dbc := iface.(db.Container)
 	log.WithField("container", dbc).Info("Start container")
 	hostname := dbc.Hostname
 	if hostname != "" {
-		hostname += ".q"
+		hostname += "." + domain
 	}
-  
-	//Setting Privileged of the applications to be true just for the purpose of stress testing
-	//Otherwise very very dangerous!!! Not for production
-	_, err := dk.Run(docker.RunOptions{
-	        Hostname:          hostname,
+
+	ro := docker.RunOptions{
+		Hostname:          hostname,
 		Image:             dbc.Image,
 		Args:              dbc.Command,
 		Env:               dbc.Env,
 		FilepathToContent: dbc.FilepathToContent,
 		Labels: map[string]string{
-			labelKey: labelValue,
-			filesKey: filesHash(dbc.FilepathToContent),
+			labelKey:    labelValue,
+			filesKey:    filesHash(dbc.FilepathToContent),
+			securityKey: securityHash(dbc.Security),
+			domainKey:   domain,
 		},
 		IP:          dbc.IP,
 		NetworkMode: plugin.NetworkName,
 		DNS:         []string{ipdef.GatewayIP.String()},
-		DNSSearch:   []string{"q"},
-		Privileged: true,
-	})
+		DNSSearch:   []string{domain},
+	}
+	setSecurity(&ro, dbc.Security)
+
+	_, err := dk.Run(ro)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error":     err,
@@ -170,7 +191,9 @@ func syncJoinScore(left, right interface{}) int {
 	dbc := left.(db.Container)
 	dkc := right.(docker.Container)
 
-	if dbc.IP != dkc.IP || filesHash(dbc.FilepathToContent) != dkc.Labels[filesKey] {
+	if dbc.IP != dkc.IP ||
+		filesHash(dbc.FilepathToContent) != dkc.Labels[filesKey] ||
+		securityHash(dbc.Security) != dkc.Labels[securityKey] {
 		return -1
 	}
 
@@ -206,33 +229,282 @@ func filesHash(filepathToContent map[string]string) string {
 	return fmt.Sprintf("%x", sha1.Sum([]byte(toHash)))
 }
 
+// containerDomain returns the DNS domain `dkc` was started with.  Containers
+// started before the domain was configurable are in the default domain.
+func containerDomain(dkc docker.Container) string {
+	if domain := dkc.Labels[domainKey]; domain != "" {
+		return domain
+	}
+	return blueprint.DefaultDomain
+}
+
+// securityHash identifies the privileges a container was started with, so that it
+// can be restarted if they change.
+func securityHash(sec *blueprint.Security) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(sec.String())))
+}
+
+// setSecurity configures `ro` to run the container with the privileges described
+// by `sec`.  If `sec` is nil, the container runs with Docker's defaults.
+func setSecurity(ro *docker.RunOptions, sec *blueprint.Security) {
+	if sec == nil {
+		return
+	}
+
+	ro.Privileged = sec.Privileged
+	ro.CapAdd = sec.CapAdd
+	ro.CapDrop = sec.CapDrop
+	ro.User = sec.User
+	ro.ReadOnlyRootfs = sec.ReadOnlyRootfs
+	// Docker applies its default seccomp profile unless told otherwise.
+	if sec.Seccomp == "unconfined" {
+		ro.SecurityOpt = append(ro.SecurityOpt, "seccomp=unconfined")
+	}
+	if sec.AppArmor != "" {
+		ro.SecurityOpt = append(ro.SecurityOpt, "apparmor="+sec.AppArmor)
+	}
+}
+
 func updateOpenflow(conn db.Conn, myIP string) {
 	var dbcs []db.Container
 	var conns []db.Connection
+	var lbs []db.LoadBalancer
+	var hostnames []db.Hostname
 
 	txn := func(view db.Database) error {
 		conns = view.SelectFromConnection(nil)
+		lbs = view.SelectFromLoadBalancer(nil)
+		hostnames = view.SelectFromHostname(nil)
 		dbcs = view.SelectFromContainer(func(dbc db.Container) bool {
 			return dbc.EndpointID != "" && dbc.IP != "" && dbc.Minion == myIP
 		})
 		return nil
 	}
-	conn.Txn(db.ConnectionTable, db.ContainerTable).Run(txn)
+	conn.Txn(db.ConnectionTable, db.ContainerTable, db.HostnameTable,
+		db.LoadBalancerTable).Run(txn)
 
-	ofcs := openflowContainers(dbcs, conns)
+	ofcs := openflowContainers(dbcs, conns, lbs, hostnames)
 	if err := replaceFlows(ofcs); err != nil {
 		log.WithError(err).Warning("Failed to update OpenFlow")
 	}
+
+	updateQoS(dbcs, ofcs)
+	updateClassIDs(dbcs)
+}
+
+// The net_cls cgroups of the containers started by docker.  The host's cgroups are
+// mounted into the minion container at /host.
+var netClsDir = "/host/sys/fs/cgroup/net_cls/docker"
+
+// The major number of the net_cls classids of containers with a traffic class.
+const classIDMajor = 0x10
+
+// updateClassIDs sets the net_cls classid of each container in `dbcs` to
+// `classIDMajor:TrafficClass`, so that its packets can be classified by the host
+// without the container needing any privileges.  Containers without a traffic
+// class have a classid of zero.
+func updateClassIDs(dbcs []db.Container) {
+	for _, dbc := range dbcs {
+		if dbc.DockerID == "" {
+			continue
+		}
+
+		classID := 0
+		if validTrafficClass(dbc.TrafficClass) {
+			classID = classIDMajor<<16 | dbc.TrafficClass
+		}
+
+		path := filepath.Join(netClsDir, dbc.DockerID, "net_cls.classid")
+		current, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.WithError(err).WithField("container", dbc.DockerID).Debug(
+				"Failed to read net_cls classid")
+			continue
+		}
+
+		if strings.TrimSpace(string(current)) == strconv.Itoa(classID) {
+			continue
+		}
+
+		err = ioutil.WriteFile(path, []byte(strconv.Itoa(classID)), 0644)
+		if err != nil {
+			log.WithError(err).WithField("container", dbc.DockerID).Warning(
+				"Failed to set net_cls classid")
+		}
+	}
 }
 
-func openflowContainers(dbcs []db.Container,
-	conns []db.Connection) []openflow.Container {
+// The QoS last set on each veth, so that ovsdb is only written when it changes.
+// It's nil until it has been read from ovsdb, so that the QoS set before the minion
+// restarted is neither rewritten nor left stale.
+var vethQoS map[string]ovsdb.PortQoS
+
+// updateQoS limits the bandwidth of the veth of each container in `dbcs` to its
+// blueprint's, and shapes the queues of its bandwidth limited connections.
+func updateQoS(dbcs []db.Container, ofcs []openflow.Container) {
+	bandwidths := map[string]*blueprint.Bandwidth{}
+	for _, dbc := range dbcs {
+		bandwidths[dbc.IP] = dbc.Bandwidth
+	}
+
+	var odb ovsdb.Client
+	openOvsdb := func() bool {
+		if odb != nil {
+			return true
+		}
+
+		var err error
+		if odb, err = ovsdb.Open(); err != nil {
+			log.WithError(err).Warning("Failed to connect to ovsdb")
+			odb = nil
+			return false
+		}
+		return true
+	}
+	defer func() {
+		if odb != nil {
+			odb.Disconnect()
+		}
+	}()
+
+	if vethQoS == nil {
+		if !openOvsdb() {
+			return
+		}
+
+		current, err := odb.ListPortQoS()
+		if err != nil {
+			log.WithError(err).Warning("Failed to list QoS")
+			return
+		}
+		vethQoS = current
+	}
+
+	seen := map[string]struct{}{}
+	for _, ofc := range ofcs {
+		seen[ofc.Veth] = struct{}{}
+
+		qos := portQoS(bandwidths[ofc.IP], ofc.Queues)
+		if reflect.DeepEqual(qos, vethQoS[ofc.Veth]) {
+			continue
+		}
+
+		if !openOvsdb() {
+			return
+		}
+
+		if err := odb.SetPortQoS(ofc.Veth, qos); err != nil {
+			log.WithError(err).WithField("veth", ofc.Veth).Warning(
+				"Failed to set QoS")
+			continue
+		}
+		vethQoS[ofc.Veth] = qos
+	}
+
+	for veth := range vethQoS {
+		if _, ok := seen[veth]; !ok {
+			delete(vethQoS, veth)
+		}
+	}
+}
+
+// portQoS polices the traffic a container sends to its Egress bandwidth, and
+// shapes the traffic it receives to its Ingress bandwidth and `queues`.
+func portQoS(bw *blueprint.Bandwidth, queues map[openflow.Queue]struct{}) ovsdb.PortQoS {
+	var qos ovsdb.PortQoS
+	if bw != nil {
+		qos.PolicingRate = bw.Egress
+		qos.MaxRate = bw.Ingress
+	}
+
+	for q := range queues {
+		if qos.QueueRates == nil {
+			qos.QueueRates = map[int]int{}
+		}
+		qos.QueueRates[q.ID] = q.Rate
+	}
+	return qos
+}
+
+func openflowContainers(dbcs []db.Container, conns []db.Connection,
+	lbs []db.LoadBalancer, hostnames []db.Hostname) []openflow.Container {
+
+	// Public traffic to a load balancer is forwarded to its backends.
+	lbHostnames := map[string][]string{}
+	lbIPs := map[string]string{}
+	for _, lb := range lbs {
+		lbHostnames[lb.Name] = lb.Hostnames
+		lbIPs[lb.Name] = lb.IP
+	}
+
+	hostnameIPs := map[string]string{}
+	for _, hn := range hostnames {
+		hostnameIPs[hn.Hostname] = hn.IP
+	}
+
+	// Containers exchange traffic with the containers and load balancers they
+	// connect to, and with the containers that connect to them.  Load balancer
+	// backends reply directly to the clients.
+	peers := map[string][]string{}
+	addPeer := func(hostname, ip string) {
+		if ip != "" {
+			peers[hostname] = append(peers[hostname], ip)
+		}
+	}
+
+	// Both directions of a connection with a bandwidth limit are shaped as they
+	// leave OVS: the requests at the receiver, and the responses at the sender.
+	// Connections that match the same traffic share the lowest of their rates.
+	queues := map[string]map[openflow.Queue]int{}
+	addQueue := func(hostname string, q openflow.Queue, rate int) {
+		if q.Peer == "" {
+			return
+		}
+
+		if queues[hostname] == nil {
+			queues[hostname] = map[openflow.Queue]int{}
+		}
+		if old, ok := queues[hostname][q]; !ok || rate < old {
+			queues[hostname][q] = rate
+		}
+	}
 
 	fromPubPorts := map[string][]int{}
 	toPubPorts := map[string][]int{}
+	toExternal := map[string][]openflow.External{}
 	for _, conn := range conns {
+		if cidrs := conn.ExternalCIDRs(); cidrs != nil &&
+			conn.MinPort == conn.MaxPort {
+			for _, cidr := range cidrs {
+				toExternal[conn.From] = append(toExternal[conn.From],
+					openflow.External{CIDR: cidr, Port: conn.MinPort})
+			}
+			continue
+		}
+
 		if conn.From != blueprint.PublicInternetLabel &&
 			conn.To != blueprint.PublicInternetLabel {
+			if lbIP, ok := lbIPs[conn.To]; ok {
+				addPeer(conn.From, lbIP)
+				for _, hostname := range lbHostnames[conn.To] {
+					addPeer(hostname, hostnameIPs[conn.From])
+				}
+			} else {
+				addPeer(conn.From, hostnameIPs[conn.To])
+				addPeer(conn.To, hostnameIPs[conn.From])
+			}
+
+			if _, ok := lbIPs[conn.To]; !ok && conn.Bandwidth > 0 &&
+				conn.MinPort == conn.MaxPort {
+				addQueue(conn.To, openflow.Queue{
+					Peer:    hostnameIPs[conn.From],
+					DstPort: conn.MinPort,
+				}, conn.Bandwidth)
+				addQueue(conn.From, openflow.Queue{
+					Peer:    hostnameIPs[conn.To],
+					SrcPort: conn.MinPort,
+				}, conn.Bandwidth)
+			}
 			continue
 		}
 
@@ -246,6 +518,10 @@ func openflowContainers(dbcs []db.Container,
 		if conn.From == blueprint.PublicInternetLabel {
 			fromPubPorts[conn.To] = append(fromPubPorts[conn.To],
 				conn.MinPort)
+			for _, hostname := range lbHostnames[conn.To] {
+				fromPubPorts[hostname] = append(
+					fromPubPorts[hostname], conn.MinPort)
+			}
 		}
 
 		if conn.To == blueprint.PublicInternetLabel {
@@ -264,8 +540,15 @@ func openflowContainers(dbcs []db.Container,
 			Mac:   ipdef.IPStrToMac(dbc.IP),
 			IP:    dbc.IP,
 
-			ToPub:   map[int]struct{}{},
-			FromPub: map[int]struct{}{},
+			ToPub:      map[int]struct{}{},
+			FromPub:    map[int]struct{}{},
+			ToExternal: map[openflow.External]struct{}{},
+			Peers:      map[string]struct{}{},
+			Queues:     numberQueues(queues[dbc.Hostname]),
+		}
+
+		if validTrafficClass(dbc.TrafficClass) {
+			ofc.TrafficClass = dbc.TrafficClass
 		}
 
 		for _, p := range toPubPorts[dbc.Hostname] {
@@ -276,9 +559,66 @@ func openflowContainers(dbcs []db.Container,
 			ofc.FromPub[p] = struct{}{}
 		}
 
+		for _, ext := range toExternal[dbc.Hostname] {
+			ofc.ToExternal[ext] = struct{}{}
+		}
+
+		for _, ip := range peers[dbc.Hostname] {
+			if ip != dbc.IP {
+				ofc.Peers[ip] = struct{}{}
+			}
+		}
+
 		ofcs = append(ofcs, ofc)
 	}
 	return ofcs
 }
 
+// validTrafficClass returns whether `class` fits in the DSCP field.  Invalid
+// classes are ignored, as they would break the OpenFlow tables of every container.
+func validTrafficClass(class int) bool {
+	return class > 0 && class <= blueprint.MaxTrafficClass
+}
+
+// numberQueues assigns IDs, starting from 1, to `queues` in a stable order, and
+// sets their rates.
+func numberQueues(queues map[openflow.Queue]int) map[openflow.Queue]struct{} {
+	var sorted []openflow.Queue
+	for q := range queues {
+		sorted = append(sorted, q)
+	}
+	sort.Sort(queueSlice(sorted))
+
+	numbered := map[openflow.Queue]struct{}{}
+	for i, q := range sorted {
+		rate := queues[q]
+		q.ID = i + 1
+		q.Rate = rate
+		numbered[q] = struct{}{}
+	}
+	return numbered
+}
+
+type queueSlice []openflow.Queue
+
+func (slc queueSlice) Len() int {
+	return len(slc)
+}
+
+func (slc queueSlice) Less(i, j int) bool {
+	l, r := slc[i], slc[j]
+	switch {
+	case l.Peer != r.Peer:
+		return l.Peer < r.Peer
+	case l.SrcPort != r.SrcPort:
+		return l.SrcPort < r.SrcPort
+	default:
+		return l.DstPort < r.DstPort
+	}
+}
+
+func (slc queueSlice) Swap(i, j int) {
+	slc[i], slc[j] = slc[j], slc[i]
+}
+
 var replaceFlows = openflow.ReplaceFlows
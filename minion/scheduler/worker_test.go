@@ -2,6 +2,9 @@ package scheduler
 
 import (
 	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
@@ -9,6 +12,8 @@ import (
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/minion/docker"
 	"github.com/quilt/quilt/minion/network/openflow"
+	"github.com/quilt/quilt/minion/ovsdb"
+	"github.com/quilt/quilt/minion/ovsdb/mocks"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -34,20 +39,20 @@ func TestRunWorker(t *testing.T) {
 	})
 
 	// Wrong Minion IP, should do nothing.
-	runWorker(conn, dk, "1.2.3.5")
+	runWorker(conn, dk, "1.2.3.5", "q")
 	dkcs, err := dk.List(nil)
 	assert.NoError(t, err)
 	assert.Len(t, dkcs, 0)
 
 	// Run with a list error, should do nothing.
 	md.ListError = true
-	runWorker(conn, dk, "1.2.3.4")
+	runWorker(conn, dk, "1.2.3.4", "q")
 	md.ListError = false
 	dkcs, err = dk.List(nil)
 	assert.NoError(t, err)
 	assert.Len(t, dkcs, 0)
 
-	runWorker(conn, dk, "1.2.3.4")
+	runWorker(conn, dk, "1.2.3.4", "q")
 	dkcs, err = dk.List(nil)
 	assert.NoError(t, err)
 	assert.Len(t, dkcs, 1)
@@ -57,9 +62,11 @@ func TestRunWorker(t *testing.T) {
 func runSync(dk docker.Client, dbcs []db.Container,
 	dkcs []docker.Container) []db.Container {
 
-	changes, tdbcs, tdkcs := syncWorker(dbcs, dkcs)
+	changes, tdbcs, tdkcs := syncWorker(dbcs, dkcs, "q")
 	doContainers(dk, tdkcs, dockerKill)
-	doContainers(dk, tdbcs, dockerRun)
+	doContainers(dk, tdbcs, func(dk docker.Client, iface interface{}) {
+		dockerRun(dk, iface, "q")
+	})
 	return changes
 }
 
@@ -83,7 +90,7 @@ func TestSyncWorker(t *testing.T) {
 
 	runSync(dk, dbcs, nil)
 	dkcs, err := dk.List(nil)
-	changed, _, _ = syncWorker(dbcs, dkcs)
+	changed, _, _ = syncWorker(dbcs, dkcs, "q")
 	assert.NoError(t, err)
 
 	if changed[0].DockerID != dkcs[0].ID {
@@ -132,6 +139,34 @@ func TestSyncWorker(t *testing.T) {
 	assert.Len(t, dkcs, 0)
 }
 
+func TestSyncWorkerDomain(t *testing.T) {
+	t.Parallel()
+
+	dbcs := []db.Container{{ID: 1, Image: "Image"}}
+	dkcs := []docker.Container{{
+		ID:    "1",
+		Image: "Image",
+		Labels: map[string]string{
+			filesKey:    filesHash(nil),
+			securityKey: securityHash(nil),
+		},
+	}}
+
+	// Containers without a domain label are in the default domain.
+	changed, toBoot, toKill := syncWorker(dbcs, dkcs, "q")
+	assert.Len(t, changed, 1)
+	assert.Empty(t, toBoot)
+	assert.Empty(t, toKill)
+
+	_, toBoot, toKill = syncWorker(dbcs, dkcs, "svc.example.com")
+	assert.Len(t, toBoot, 1)
+	assert.Len(t, toKill, 1)
+
+	dkcs[0].Labels[domainKey] = "svc.example.com"
+	changed, _, _ = syncWorker(dbcs, dkcs, "svc.example.com")
+	assert.Len(t, changed, 1)
+}
+
 func TestInitsFiles(t *testing.T) {
 	t.Parallel()
 
@@ -172,12 +207,15 @@ func TestSyncJoinScore(t *testing.T) {
 		DockerID:          "DockerID",
 	}
 	dkc := docker.Container{
-		IP:     "1.2.3.4",
-		Image:  dbc.Image,
-		Args:   dbc.Command,
-		Env:    dbc.Env,
-		Labels: map[string]string{filesKey: filesHash(dbc.FilepathToContent)},
-		ID:     dbc.DockerID,
+		IP:    "1.2.3.4",
+		Image: dbc.Image,
+		Args:  dbc.Command,
+		Env:   dbc.Env,
+		Labels: map[string]string{
+			filesKey:    filesHash(dbc.FilepathToContent),
+			securityKey: securityHash(nil),
+		},
+		ID: dbc.DockerID,
 	}
 
 	score := syncJoinScore(dbc, dkc)
@@ -231,6 +269,43 @@ func TestSyncJoinScore(t *testing.T) {
 	dbc.ImageID = "wrong"
 	score = syncJoinScore(dbc, dkc)
 	assert.Equal(t, -1, score)
+
+	dbc.ImageID = dkc.ImageID
+	dbc.Security = &blueprint.Security{Privileged: true}
+	score = syncJoinScore(dbc, dkc)
+	assert.Equal(t, -1, score)
+}
+
+func TestSetSecurity(t *testing.T) {
+	t.Parallel()
+
+	var ro docker.RunOptions
+	setSecurity(&ro, nil)
+	assert.Equal(t, docker.RunOptions{}, ro)
+
+	setSecurity(&ro, &blueprint.Security{
+		Privileged:     true,
+		CapAdd:         []string{"NET_ADMIN"},
+		CapDrop:        []string{"MKNOD"},
+		User:           "nobody",
+		ReadOnlyRootfs: true,
+		Seccomp:        "unconfined",
+		AppArmor:       "docker-default",
+	})
+	assert.Equal(t, docker.RunOptions{
+		Privileged:     true,
+		CapAdd:         []string{"NET_ADMIN"},
+		CapDrop:        []string{"MKNOD"},
+		User:           "nobody",
+		ReadOnlyRootfs: true,
+		SecurityOpt: []string{"seccomp=unconfined",
+			"apparmor=docker-default"},
+	}, ro)
+
+	// The default seccomp profile needs no security option.
+	ro = docker.RunOptions{}
+	setSecurity(&ro, &blueprint.Security{Seccomp: "default"})
+	assert.Empty(t, ro.SecurityOpt)
 }
 
 func TestOpenFlowContainers(t *testing.T) {
@@ -241,15 +316,187 @@ func TestOpenFlowContainers(t *testing.T) {
 		{MinPort: 4, MaxPort: 4, To: blueprint.PublicInternetLabel, From: "blue"}}
 
 	res := openflowContainers([]db.Container{
-		{EndpointID: "f", IP: "1.2.3.4", Hostname: "red"}},
-		conns)
+		{EndpointID: "f", IP: "1.2.3.4", Hostname: "red", TrafficClass: 10}},
+		conns, nil, nil)
 	exp := []openflow.Container{{
-		Veth:    "f",
-		Patch:   "q_f",
-		IP:      "1.2.3.4",
-		Mac:     "02:00:01:02:03:04",
-		ToPub:   map[int]struct{}{3: {}},
-		FromPub: map[int]struct{}{2: {}},
+		Veth:       "f",
+		Patch:      "q_f",
+		IP:         "1.2.3.4",
+		Mac:        "02:00:01:02:03:04",
+		ToPub:      map[int]struct{}{3: {}},
+		FromPub:    map[int]struct{}{2: {}},
+		ToExternal: map[openflow.External]struct{}{},
+		Peers:      map[string]struct{}{},
+		Queues:     map[openflow.Queue]struct{}{},
+
+		TrafficClass: 10,
 	}}
 	assert.Equal(t, exp, res)
+	exp[0].TrafficClass = 0
+
+	// Backends accept public traffic to their load balancers.
+	conns = append(conns, db.Connection{MinPort: 5, MaxPort: 5,
+		From: blueprint.PublicInternetLabel, To: "lb"})
+	res = openflowContainers([]db.Container{
+		{EndpointID: "f", IP: "1.2.3.4", Hostname: "red"}},
+		conns, []db.LoadBalancer{{Name: "lb", Hostnames: []string{"red"}}}, nil)
+	exp[0].FromPub = map[int]struct{}{2: {}, 5: {}}
+	assert.Equal(t, exp, res)
+
+	// Connections to external addresses only allow traffic to their CIDRs.
+	conns = append(conns,
+		db.Connection{MinPort: 5432, MaxPort: 5432, From: "red",
+			To: "cidr:52.1.0.0/16"},
+		db.Connection{MinPort: 443, MaxPort: 443, From: "red",
+			To: "domain:example.com", ResolvedIPs: []string{"1.2.3.5"}},
+		db.Connection{MinPort: 443, MaxPort: 443, From: "blue",
+			To: "domain:example.com", ResolvedIPs: []string{"1.2.3.5"}})
+	res = openflowContainers([]db.Container{
+		{EndpointID: "f", IP: "1.2.3.4", Hostname: "red"}},
+		conns, []db.LoadBalancer{{Name: "lb", Hostnames: []string{"red"}}}, nil)
+	exp[0].ToExternal = map[openflow.External]struct{}{
+		{CIDR: "52.1.0.0/16", Port: 5432}: {},
+		{CIDR: "1.2.3.5/32", Port: 443}:   {},
+	}
+	assert.Equal(t, exp, res)
+
+	// Containers count the traffic to the containers and load balancers they
+	// are connected with.
+	conns = append(conns,
+		db.Connection{MinPort: 80, MaxPort: 80, From: "red", To: "blue"},
+		db.Connection{MinPort: 80, MaxPort: 80, From: "green", To: "red"},
+		db.Connection{MinPort: 80, MaxPort: 80, From: "green", To: "lb"},
+		db.Connection{MinPort: 80, MaxPort: 80, From: "red", To: "lb2"})
+	res = openflowContainers([]db.Container{
+		{EndpointID: "f", IP: "1.2.3.4", Hostname: "red"}},
+		conns, []db.LoadBalancer{
+			{Name: "lb", IP: "10.0.0.5", Hostnames: []string{"red"}},
+			{Name: "lb2", IP: "10.0.0.6", Hostnames: []string{"blue"}},
+		}, []db.Hostname{
+			{Hostname: "red", IP: "1.2.3.4"},
+			{Hostname: "blue", IP: "10.0.0.2"},
+			{Hostname: "green", IP: "10.0.0.3"},
+		})
+	exp[0].Peers = map[string]struct{}{
+		"10.0.0.2": {}, "10.0.0.3": {}, "10.0.0.6": {},
+	}
+	assert.Equal(t, exp, res)
+
+	// Both directions of connections with a bandwidth limit are enqueued.  The
+	// connections to load balancers aren't.
+	conns = append(conns,
+		db.Connection{MinPort: 22, MaxPort: 22, From: "red", To: "blue",
+			Bandwidth: 2000000},
+		db.Connection{MinPort: 22, MaxPort: 22, From: "red", To: "blue",
+			Bandwidth: 1000000},
+		db.Connection{MinPort: 53, MaxPort: 53, From: "green", To: "red",
+			Bandwidth: 5000000},
+		db.Connection{MinPort: 1, MaxPort: 2, From: "green", To: "red",
+			Bandwidth: 5000000},
+		db.Connection{MinPort: 443, MaxPort: 443, From: "red", To: "lb2",
+			Bandwidth: 5000000})
+	res = openflowContainers([]db.Container{
+		{EndpointID: "f", IP: "1.2.3.4", Hostname: "red"}},
+		conns, []db.LoadBalancer{
+			{Name: "lb", IP: "10.0.0.5", Hostnames: []string{"red"}},
+			{Name: "lb2", IP: "10.0.0.6", Hostnames: []string{"blue"}},
+		}, []db.Hostname{
+			{Hostname: "red", IP: "1.2.3.4"},
+			{Hostname: "blue", IP: "10.0.0.2"},
+			{Hostname: "green", IP: "10.0.0.3"},
+		})
+	exp[0].Queues = map[openflow.Queue]struct{}{
+		{ID: 1, Peer: "10.0.0.2", SrcPort: 22, Rate: 1000000}: {},
+		{ID: 2, Peer: "10.0.0.3", DstPort: 53, Rate: 5000000}: {},
+	}
+	assert.Equal(t, exp, res)
+}
+
+func TestUpdateQoS(t *testing.T) {
+	client := new(mocks.Client)
+	ovsdb.Open = func() (ovsdb.Client, error) { return client, nil }
+	vethQoS = map[string]ovsdb.PortQoS{}
+
+	dbcs := []db.Container{
+		{IP: "1.2.3.4", Bandwidth: &blueprint.Bandwidth{Ingress: 10, Egress: 5}},
+		{IP: "1.2.3.5"},
+	}
+	ofcs := []openflow.Container{
+		{Veth: "a", IP: "1.2.3.4"},
+		{Veth: "b", IP: "1.2.3.5", Queues: map[openflow.Queue]struct{}{
+			{ID: 1, Peer: "1.2.3.4", DstPort: 80, Rate: 7}: {}}},
+	}
+
+	aQoS := ovsdb.PortQoS{PolicingRate: 5, MaxRate: 10}
+	bQoS := ovsdb.PortQoS{QueueRates: map[int]int{1: 7}}
+	client.On("SetPortQoS", "a", aQoS).Return(nil).Once()
+	client.On("SetPortQoS", "b", bQoS).Return(assert.AnError).Once()
+	client.On("Disconnect").Return()
+	updateQoS(dbcs, ofcs)
+	client.AssertExpectations(t)
+	assert.Equal(t, map[string]ovsdb.PortQoS{"a": aQoS}, vethQoS)
+
+	// Only the QoS that failed, or changed, is set again.
+	client.On("SetPortQoS", "b", bQoS).Return(nil).Once()
+	updateQoS(dbcs, ofcs)
+	client.AssertExpectations(t)
+	assert.Equal(t, map[string]ovsdb.PortQoS{"a": aQoS, "b": bQoS}, vethQoS)
+
+	// Veths that disappear are forgotten.
+	updateQoS(dbcs, ofcs[1:])
+	assert.Equal(t, map[string]ovsdb.PortQoS{"b": bQoS}, vethQoS)
+	client.AssertNumberOfCalls(t, "SetPortQoS", 3)
+
+	// After a restart, the QoS is read from ovsdb before anything is set.
+	vethQoS = nil
+	client.On("ListPortQoS").Return(nil, assert.AnError).Once()
+	updateQoS(dbcs, ofcs)
+	client.AssertExpectations(t)
+	assert.Nil(t, vethQoS)
+
+	client.On("ListPortQoS").Return(map[string]ovsdb.PortQoS{
+		"a":      aQoS,
+		"b":      {MaxRate: 3},
+		"br-int": {},
+	}, nil).Once()
+	client.On("SetPortQoS", "b", bQoS).Return(nil).Once()
+	updateQoS(dbcs, ofcs)
+	client.AssertExpectations(t)
+	assert.Equal(t, map[string]ovsdb.PortQoS{"a": aQoS, "b": bQoS}, vethQoS)
+	client.AssertNumberOfCalls(t, "SetPortQoS", 4)
+}
+
+func TestUpdateClassIDs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "net_cls")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	netClsDir = dir
+
+	classIDPath := func(id string) string {
+		return filepath.Join(dir, id, "net_cls.classid")
+	}
+	for _, id := range []string{"a", "b"} {
+		assert.NoError(t, os.Mkdir(filepath.Join(dir, id), 0755))
+		assert.NoError(t, ioutil.WriteFile(classIDPath(id), []byte("0\n"), 0644))
+	}
+
+	readClassID := func(id string) string {
+		classID, err := ioutil.ReadFile(classIDPath(id))
+		assert.NoError(t, err)
+		return string(classID)
+	}
+
+	// Containers that aren't running yet, or whose cgroup is missing, are
+	// skipped.
+	updateClassIDs([]db.Container{
+		{DockerID: "a", TrafficClass: 10},
+		{DockerID: "missing", TrafficClass: 10},
+		{TrafficClass: 10},
+		{DockerID: "b", TrafficClass: 64},
+	})
+	assert.Equal(t, "1048586", readClassID("a"))
+	assert.Equal(t, "0\n", readClassID("b"))
+
+	updateClassIDs([]db.Container{{DockerID: "a"}})
+	assert.Equal(t, "0", readClassID("a"))
 }
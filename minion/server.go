@@ -5,6 +5,8 @@ import (
 	"strings"
 
 	"github.com/quilt/quilt/connection"
+	"github.com/quilt/quilt/connection/auth"
+	"github.com/quilt/quilt/connection/credentials/tls"
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/minion/pb"
 
@@ -17,8 +19,19 @@ type server struct {
 	db.Conn
 }
 
+// The minion server is only used by the daemon to configure the minion.
+var policy = auth.Policy{
+	"/Minion/SetMinionConfig": {auth.Admin},
+	"/Minion/GetMinionConfig": {auth.Admin},
+}
+
 func minionServerRun(conn db.Conn, creds connection.Credentials) {
-	sock, s := connection.Server("tcp", ":9999", creds.ServerOpts())
+	opts := creds.ServerOpts()
+	if _, isTLS := creds.(tls.TLS); isTLS {
+		opts = append(opts, auth.ServerOpts(policy)...)
+	}
+
+	sock, s := connection.Server("tcp", ":9999", opts)
 	server := server{conn}
 	pb.RegisterMinionServer(s, server)
 	s.Serve(sock)
@@ -42,6 +55,16 @@ func (s server) GetMinionConfig(cts context.Context,
 	cfg.DiskSize = int32(m.DiskSize)
 	log.WithField("DISKSIZE", cfg.DiskSize).Info("ANSON: GET TAKEN.")
 	cfg.AuthorizedKeys = strings.Split(m.AuthorizedKeys, "\n")
+	cfg.HostKeys = hostKeyFingerprints()
+	cfg.SystemImages = m.SystemImages
+	cfg.DisableCadvisor = m.DisableCadvisor
+	cfg.DNSUpstream = m.DNSUpstream
+	cfg.DNSDomain = m.DNSDomain
+	cfg.ContainerSubnet = m.ContainerSubnet
+	cfg.GatewayIP = m.GatewayIP
+	cfg.LoadBalancerIP = m.LoadBalancerIP
+	cfg.MTU = int32(m.MTU)
+	cfg.Encapsulation = m.Encapsulation
 
 	s.Txn(db.EtcdTable).Run(func(view db.Database) error {
 		if etcdRow, err := view.GetEtcd(); err == nil {
@@ -69,6 +92,15 @@ func (s server) SetMinionConfig(ctx context.Context,
 		minion.DiskSize = int(msg.DiskSize)
 		log.WithField("DISKSIZE", minion.DiskSize).Info("ANSON: SET TAKEN.")
 		minion.AuthorizedKeys = strings.Join(msg.AuthorizedKeys, "\n")
+		minion.SystemImages = msg.SystemImages
+		minion.DisableCadvisor = msg.DisableCadvisor
+		minion.DNSUpstream = msg.DNSUpstream
+		minion.DNSDomain = msg.DNSDomain
+		minion.ContainerSubnet = msg.ContainerSubnet
+		minion.GatewayIP = msg.GatewayIP
+		minion.LoadBalancerIP = msg.LoadBalancerIP
+		minion.MTU = int(msg.MTU)
+		minion.Encapsulation = msg.Encapsulation
 		minion.Self = true
 		view.Commit(minion)
 
@@ -5,10 +5,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/minion/pb"
+	"github.com/quilt/quilt/util"
 )
 
 func TestSetMinionConfig(t *testing.T) {
@@ -24,23 +26,33 @@ func TestSetMinionConfig(t *testing.T) {
 	})
 
 	cfg := pb.MinionConfig{
-		PrivateIP:      "priv",
-		Blueprint:      "blueprint",
-		Provider:       "provider",
-		Size:           "size",
-		Region:         "region",
-		EtcdMembers:    []string{"etcd1", "etcd2"},
-		AuthorizedKeys: []string{"key1", "key2"},
+		PrivateIP:       "priv",
+		Blueprint:       "blueprint",
+		Provider:        "provider",
+		Size:            "size",
+		Region:          "region",
+		EtcdMembers:     []string{"etcd1", "etcd2"},
+		AuthorizedKeys:  []string{"key1", "key2"},
+		ContainerSubnet: "172.30.0.0/16",
+		GatewayIP:       "172.30.0.1",
+		LoadBalancerIP:  "172.30.0.2",
+		MTU:             8950,
+		Encapsulation:   "vxlan",
 	}
 	expMinion := db.Minion{
-		Self:           true,
-		Blueprint:      "blueprint",
-		PrivateIP:      "priv",
-		Provider:       "provider",
-		Role:           db.Master,
-		Size:           "size",
-		Region:         "region",
-		AuthorizedKeys: "key1\nkey2",
+		Self:            true,
+		Blueprint:       "blueprint",
+		PrivateIP:       "priv",
+		Provider:        "provider",
+		Role:            db.Master,
+		Size:            "size",
+		Region:          "region",
+		AuthorizedKeys:  "key1\nkey2",
+		ContainerSubnet: "172.30.0.0/16",
+		GatewayIP:       "172.30.0.1",
+		LoadBalancerIP:  "172.30.0.2",
+		MTU:             8950,
+		Encapsulation:   "vxlan",
 	}
 	_, err := s.SetMinionConfig(nil, &cfg)
 	assert.NoError(t, err)
@@ -103,7 +115,7 @@ func checkEtcdEquals(t *testing.T, conn db.Conn, exp db.Etcd) {
 }
 
 func TestGetMinionConfig(t *testing.T) {
-	t.Parallel()
+	util.AppFs = afero.NewMemMapFs()
 	s := server{db.New()}
 
 	s.Conn.Txn(db.AllTables...).Run(func(view db.Database) error {
@@ -165,4 +177,36 @@ func TestGetMinionConfig(t *testing.T) {
 		EtcdMembers:    []string{"etcd1", "etcd2"},
 		AuthorizedKeys: []string{"key1", "key2"},
 	}, *cfg)
+
+	// Test reporting the container network.
+	s.Conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.MinionSelf()
+		m.ContainerSubnet = "172.30.0.0/16"
+		m.GatewayIP = "172.30.0.1"
+		m.LoadBalancerIP = "172.30.0.2"
+		m.MTU = 8950
+		m.Encapsulation = "vxlan"
+		view.Commit(m)
+		return nil
+	})
+	cfg, err = s.GetMinionConfig(nil, &pb.Request{})
+	assert.NoError(t, err)
+	assert.Equal(t, "172.30.0.0/16", cfg.ContainerSubnet)
+	assert.Equal(t, "172.30.0.1", cfg.GatewayIP)
+	assert.Equal(t, "172.30.0.2", cfg.LoadBalancerIP)
+	assert.Equal(t, int32(8950), cfg.MTU)
+	assert.Equal(t, "vxlan", cfg.Encapsulation)
+
+	// Test reporting the host's SSH keys.
+	hostKey := "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQC0XGWf3uoKJwIAh" +
+		"g3jg1oNfyxav55IKDEq2W72CL2SRiMRtmVs6fPeaem6HNMvWFrb0pqnguQyHo59RT" +
+		"4Hs/VJrbqkfR3wGWtxWL/TlVN0D/jSpOZP+/tuNz/qusow4PRlwvpV3Ic7JGNgcRP" +
+		"vseR1mimM4PXAbqPfgay9OZ8WweaartZHT9iStSo64DYArDgJ3dV6M8RFqXPkbijT" +
+		"8EfFuRj9PxH+S9NIzQF/T6rOemgMIBXDX9PA0DR/rGwYyaqHPMVsh+dw6Nsq/l21v" +
+		"noURtc9U7AvrkL/42DKMnv/p16w6DZJuTqh/CzU29fI0PvfeTqhv0aF5mjYtkEo0Tb7"
+	util.WriteFile("/etc/ssh/ssh_host_rsa_key.pub", []byte(hostKey), 0644)
+	cfg, err = s.GetMinionConfig(nil, &pb.Request{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"SHA256:ZP0Pw4NfMqJCGce0gu0XaUazjGgDvJ9n+KgWf2AeMIE"},
+		cfg.HostKeys)
 }
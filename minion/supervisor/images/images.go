@@ -21,4 +21,7 @@ const (
 
 	// Cadvisor is the name of the cadvisor container that monitors the traffic
 	Monitor = "cadvisor"
+
+	// Router is the name of the container that routes HTTP requests to ingresses.
+	Router = "router"
 )	    
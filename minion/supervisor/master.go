@@ -61,9 +61,7 @@ func runMasterOnce() {
 	run(images.Registry)
 
 	if leader {
-		/* XXX: If we fail to boot ovn-northd, we should give up
-		* our leadership somehow.  This ties into the general
-		* problem of monitoring health. */
+		// If ovn-northd keeps exiting, handleExit steps down from leadership.
 		run(images.Ovnnorthd, "ovn-northd")
 	} else {
 		Remove(images.Ovnnorthd)
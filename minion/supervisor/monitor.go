@@ -0,0 +1,174 @@
+package supervisor
+
+import (
+	"time"
+
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/minion/docker"
+	"github.com/quilt/quilt/minion/supervisor/images"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// The supervisor restarts containers that exit, doubling the delay after each
+// consecutive failure.  A container that stays up for `stableRunTime` is
+// considered healthy again, and its next restart isn't delayed.
+const minRestartDelay = time.Second
+const maxRestartDelay = 2 * time.Minute
+const stableRunTime = 10 * time.Minute
+
+// A master that fails to keep ovn-northd running this many times in a row steps
+// down from leadership, and doesn't campaign again for `stepDownTime`, to give a
+// healthier master the chance to lead.
+const maxNorthdFailures = 5
+const stepDownTime = 10 * time.Minute
+
+// watchExits restarts the supervisor's containers when Docker reports that they
+// exited.
+func watchExits() {
+	for {
+		exits, err := dk.WatchExits()
+		if err != nil {
+			log.WithError(err).Warn("Failed to watch Docker events.")
+			time.Sleep(10 * time.Second)
+			continue
+		}
+
+		for exit := range exits {
+			handleExit(exit)
+		}
+	}
+}
+
+func handleExit(exit docker.Exit) {
+	if _, ok := getDesired(exit.Name); !ok {
+		// Either the container isn't managed by the supervisor, or it was
+		// removed on purpose.
+		return
+	}
+
+	// The exit may be reported after the container was replaced, e.g. because
+	// the etcd membership or its image changed.  Waiting for startLock ensures
+	// that the replacement has been started.
+	startLock.Lock()
+	running, err := dk.IsRunning(exit.Name)
+	startLock.Unlock()
+	if err == nil && running {
+		return
+	}
+
+	c.Inc("Exited " + exit.Name)
+	sc := recordExit(exit.Name, exit.Reason)
+
+	logger := log.WithFields(log.Fields{
+		"name":     exit.Name,
+		"reason":   exit.Reason,
+		"restarts": sc.Restarts,
+	})
+
+	if exit.Name == images.Ovnnorthd && sc.Failures >= maxNorthdFailures {
+		logger.Error("System container exited.")
+		stepDown()
+		return
+	}
+
+	delay := restartDelay(sc.Failures)
+	logger.Warnf("System container exited. Restarting in %s.", delay)
+	afterFunc(delay, func() { restart(exit.Name) })
+}
+
+// restart starts the container specified by name, if the supervisor still wants it
+// to be running.
+func restart(name string) {
+	args, ok := getDesired(name)
+	if !ok {
+		return
+	}
+
+	c.Inc("Restart " + name)
+	if err := start(name, args); err != nil {
+		// Docker won't report an exit for a container that never started,
+		// so treat the failure as one.
+		handleExit(docker.Exit{Name: name, Reason: err.Error()})
+	}
+}
+
+// recordExit updates the SystemContainer row of the container specified by name,
+// and returns it.
+func recordExit(name, reason string) db.SystemContainer {
+	var sc db.SystemContainer
+	conn.Txn(db.SystemContainerTable).Run(func(view db.Database) error {
+		rows := view.SelectFromSystemContainer(func(sc db.SystemContainer) bool {
+			return sc.Name == name
+		})
+
+		if len(rows) == 0 {
+			sc = view.InsertSystemContainer()
+			sc.Name = name
+		} else {
+			sc = rows[0]
+		}
+
+		if now().Sub(sc.LastExit) > stableRunTime {
+			sc.Failures = 0
+		}
+
+		sc.Restarts++
+		sc.Failures++
+		sc.LastExit = now()
+		sc.ExitReason = reason
+		view.Commit(sc)
+		return nil
+	})
+	return sc
+}
+
+func restartDelay(failures int) time.Duration {
+	delay := minRestartDelay
+	for i := 1; i < failures && delay < maxRestartDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > maxRestartDelay {
+		return maxRestartDelay
+	}
+	return delay
+}
+
+// stepDown gives up this minion's etcd leadership for `stepDownTime`.  Once it's no
+// longer the leader, the supervisor removes its ovn-northd container.
+func stepDown() {
+	log.Errorf("Failed to keep %s running. Stepping down as leader for %s.",
+		images.Ovnnorthd, stepDownTime)
+	c.Inc("Step Down")
+
+	setStepDown(true)
+	afterFunc(stepDownTime, func() { setStepDown(false) })
+}
+
+func setStepDown(stepDown bool) {
+	conn.Txn(db.EtcdTable,
+		db.SystemContainerTable).Run(func(view db.Database) error {
+		if etcdRows := view.SelectFromEtcd(nil); len(etcdRows) == 1 {
+			etcdRows[0].StepDown = stepDown
+			view.Commit(etcdRows[0])
+		}
+
+		// Give ovn-northd a fresh start if this minion leads again.
+		for _, sc := range view.SelectFromSystemContainer(nil) {
+			if sc.Name == images.Ovnnorthd {
+				sc.Failures = 0
+				view.Commit(sc)
+			}
+		}
+		return nil
+	})
+}
+
+// Stored in variables so that they may be mocked out in unit tests.
+var now = time.Now
+var afterFunc = timeAfterFunc
+
+func timeAfterFunc(d time.Duration, f func()) {
+	time.AfterFunc(d, f)
+}
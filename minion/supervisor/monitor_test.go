@@ -0,0 +1,106 @@
+package supervisor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/minion/docker"
+	"github.com/quilt/quilt/minion/supervisor/images"
+)
+
+func TestHandleExit(t *testing.T) {
+	ctx := initTest(db.Master)
+	defer func() { afterFunc = timeAfterFunc }()
+
+	var delays []time.Duration
+	afterFunc = func(d time.Duration, f func()) {
+		delays = append(delays, d)
+		f()
+	}
+
+	run(images.Etcd, "arg")
+	exit := docker.Exit{Name: images.Etcd, Reason: "exit code 1"}
+
+	// Exits reported for running containers are stale.
+	handleExit(exit)
+	assert.Empty(t, ctx.conn.SelectFromSystemContainer(nil))
+
+	ctx.fd.stop(images.Etcd)
+	assert.Empty(t, ctx.fd.running())
+
+	handleExit(exit)
+	assert.Equal(t, map[string][]string{images.Etcd: {"arg"}}, ctx.fd.running())
+	assert.Equal(t, []time.Duration{time.Second}, delays)
+
+	sc := ctx.conn.SelectFromSystemContainer(nil)
+	assert.Len(t, sc, 1)
+	assert.Equal(t, images.Etcd, sc[0].Name)
+	assert.Equal(t, 1, sc[0].Restarts)
+	assert.Equal(t, 1, sc[0].Failures)
+	assert.Equal(t, "exit code 1", sc[0].ExitReason)
+
+	// Containers that were removed on purpose aren't restarted.
+	Remove(images.Etcd)
+	handleExit(exit)
+	assert.Empty(t, ctx.fd.running())
+	assert.Len(t, delays, 1)
+}
+
+func TestNorthdStepDown(t *testing.T) {
+	ctx := initTest(db.Master)
+	defer func() {
+		afterFunc = timeAfterFunc
+		now = time.Now
+	}()
+
+	timestamp := time.Now()
+	now = func() time.Time { return timestamp }
+
+	var delays []time.Duration
+	var scheduled []func()
+	afterFunc = func(d time.Duration, f func()) {
+		delays = append(delays, d)
+		scheduled = append(scheduled, f)
+	}
+
+	run(images.Ovnnorthd, "ovn-northd")
+	ctx.fd.stop(images.Ovnnorthd)
+
+	exit := docker.Exit{Name: images.Ovnnorthd, Reason: "out of memory"}
+	for i := 0; i < maxNorthdFailures; i++ {
+		handleExit(exit)
+	}
+
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second,
+		4 * time.Second, 8 * time.Second, stepDownTime}, delays)
+
+	etcdRow := ctx.conn.SelectFromEtcd(nil)[0]
+	assert.True(t, etcdRow.StepDown)
+
+	sc := ctx.conn.SelectFromSystemContainer(nil)[0]
+	assert.Equal(t, maxNorthdFailures, sc.Restarts)
+	assert.Equal(t, 0, sc.Failures)
+
+	// The minion may campaign again once the step down expires.
+	scheduled[len(scheduled)-1]()
+	etcdRow = ctx.conn.SelectFromEtcd(nil)[0]
+	assert.False(t, etcdRow.StepDown)
+
+	// Containers that ran stably are restarted without delay.
+	timestamp = timestamp.Add(stableRunTime + time.Second)
+	handleExit(exit)
+	assert.Equal(t, time.Second, delays[len(delays)-1])
+}
+
+func TestRestartDelay(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, time.Second, restartDelay(1))
+	assert.Equal(t, 2*time.Second, restartDelay(2))
+	assert.Equal(t, 64*time.Second, restartDelay(7))
+	assert.Equal(t, maxRestartDelay, restartDelay(8))
+	assert.Equal(t, maxRestartDelay, restartDelay(100))
+}
@@ -0,0 +1,313 @@
+package supervisor
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/quilt/quilt/blueprint"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/minion/supervisor/images"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// The port on which routers accept requests forwarded by the routers of other
+// workers.  They only forward these to the backends running on their own worker.
+const routerPeerPort = 9080
+
+// The header in which routers tell their peers which upstream a forwarded request
+// is destined for.
+const routerUpstreamHeader = "X-Quilt-Upstream"
+
+const routerConfigPath = "/etc/nginx/nginx.conf"
+
+// The directory in the router container that blueprint.IngressCertificateDir is
+// mounted at.
+const routerCertDir = "/etc/nginx/certs"
+
+// The router container, and the configuration last written into it.
+var routerID string
+var routerConfig string
+
+// runRouter runs the router container on workers while the blueprint has
+// ingresses, and keeps its configuration in sync with them.  Nginx reloads its
+// configuration gracefully, so open connections aren't dropped.
+func runRouter() {
+	for range conn.TriggerTick(30, db.IngressTable, db.MinionTable).C {
+		runRouterOnce()
+	}
+}
+
+func runRouterOnce() {
+	ingresses := conn.SelectFromIngress(nil)
+	if len(ingresses) == 0 {
+		if _, ok := getDesired(images.Router); ok {
+			Remove(images.Router)
+		}
+		routerID, routerConfig = "", ""
+		return
+	}
+
+	run(images.Router)
+
+	router, err := getRunning(images.Router)
+	if err != nil || router.ID == "" {
+		log.WithError(err).Debug("Router not running yet.")
+		return
+	}
+
+	config := makeRouterConfig(conn.MinionSelf().PrivateIP, ingresses)
+	if router.ID == routerID && config == routerConfig {
+		return
+	}
+
+	c.Inc("Update Router")
+	files := map[string]string{routerConfigPath: config}
+	if err := dk.WriteToContainer(router.ID, files); err != nil {
+		log.WithError(err).Warn("Failed to write the router configuration.")
+		return
+	}
+
+	if err := dk.Signal(router.ID, syscall.SIGHUP); err != nil {
+		log.WithError(err).Warn("Failed to reload the router.")
+		return
+	}
+
+	routerID, routerConfig = router.ID, config
+}
+
+// makeRouterConfig returns the nginx configuration of the router.
+func makeRouterConfig(myIP string, ingresses []db.Ingress) string {
+	sort.Sort(db.IngressSlice(ingresses))
+
+	upstreams := map[string]string{}
+	var localUpstreams []string
+	var servers bytes.Buffer
+	for _, ing := range ingresses {
+		var certPaths [][2]string
+		for _, cert := range ing.Certificates {
+			certPaths = append(certPaths, [2]string{
+				routerCertDir + "/" + cert.CertFile,
+				routerCertDir + "/" + cert.KeyFile,
+			})
+		}
+
+		hostRoutes := map[string][]blueprint.IngressRoute{}
+		for _, route := range ing.Routes {
+			hostRoutes[route.Host] = append(hostRoutes[route.Host], route)
+		}
+
+		var hosts []string
+		for host := range hostRoutes {
+			if host != "" {
+				hosts = append(hosts, host)
+			}
+		}
+		sort.Strings(hosts)
+
+		// Requests for unknown hosts are handled by the routes without a host.
+		// The default HTTPS server presents the first certificate, as nginx
+		// requires one.
+		var defaultCert *[2]string
+		if len(certPaths) > 0 {
+			defaultCert = &certPaths[0]
+		}
+		writeServer(&servers, ing, "", defaultCert, hostRoutes[""])
+
+		for _, host := range hosts {
+			var cert *[2]string
+			if i := matchCertificate(host, ing.Certificates); i >= 0 {
+				cert = &certPaths[i]
+			}
+			writeServer(&servers, ing, host, cert, hostRoutes[host])
+		}
+
+		for _, route := range ing.Routes {
+			name := upstreamName(route)
+			if _, ok := upstreams[name]; ok {
+				continue
+			}
+
+			var local bool
+			upstreams[name], local = makeUpstreams(name, route.Port, myIP,
+				ing.Backends[route.To])
+			if local {
+				localUpstreams = append(localUpstreams, name)
+			}
+		}
+	}
+
+	var names []string
+	for name := range upstreams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sort.Strings(localUpstreams)
+
+	var config bytes.Buffer
+	config.WriteString("worker_processes auto;\n\nevents {}\n\nhttp {\n")
+	config.WriteString("    proxy_http_version 1.1;\n")
+	config.WriteString("    proxy_set_header Host $host;\n")
+	config.WriteString("    proxy_set_header X-Forwarded-For " +
+		"$proxy_add_x_forwarded_for;\n\n")
+	for _, name := range names {
+		config.WriteString(upstreams[name])
+	}
+
+	// Peers only forward requests to the upstreams with backends on this worker.
+	// Requests without the header, or for other upstreams, are refused rather
+	// than passed to nginx as a hostname to resolve.
+	fmt.Fprintf(&config, "    map $http_%s $quilt_upstream {\n",
+		strings.ToLower(strings.Replace(routerUpstreamHeader, "-", "_", -1)))
+	config.WriteString("        default \"\";\n")
+	for _, name := range localUpstreams {
+		fmt.Fprintf(&config, "        %s local-%s;\n", name, name)
+	}
+	config.WriteString("    }\n\n")
+
+	fmt.Fprintf(&config, "    server {\n        listen %d;\n", routerPeerPort)
+	config.WriteString("        location / {\n" +
+		"            if ($quilt_upstream = \"\") {\n" +
+		"                return 404;\n" +
+		"            }\n" +
+		"            proxy_pass http://$quilt_upstream;\n" +
+		"        }\n    }\n")
+	config.Write(servers.Bytes())
+	config.WriteString("}\n")
+	return config.String()
+}
+
+// writeServer writes a server block that routes the requests for `host` on the
+// ports of `ing`.  An empty `host` denotes the default server.
+func writeServer(buf *bytes.Buffer, ing db.Ingress, host string, cert *[2]string,
+	routes []blueprint.IngressRoute) {
+
+	if ing.HTTPPort == 0 && (ing.HTTPSPort == 0 || cert == nil) {
+		return
+	}
+
+	defaultServer := ""
+	if host == "" {
+		defaultServer = " default_server"
+	}
+
+	buf.WriteString("\n    server {\n")
+	if ing.HTTPPort != 0 {
+		fmt.Fprintf(buf, "        listen %d%s;\n", ing.HTTPPort, defaultServer)
+	}
+	if ing.HTTPSPort != 0 && cert != nil {
+		fmt.Fprintf(buf, "        listen %d ssl%s;\n", ing.HTTPSPort,
+			defaultServer)
+		fmt.Fprintf(buf, "        ssl_certificate %s;\n", cert[0])
+		fmt.Fprintf(buf, "        ssl_certificate_key %s;\n", cert[1])
+	}
+	if host != "" {
+		fmt.Fprintf(buf, "        server_name %s;\n", host)
+	}
+
+	if len(routes) == 0 {
+		buf.WriteString("        return 404;\n    }\n")
+		return
+	}
+
+	for _, route := range routes {
+		path := route.Path
+		if path == "" {
+			path = "/"
+		}
+
+		fmt.Fprintf(buf, "        location %s {\n", path)
+		if len(ing.Backends[route.To]) == 0 {
+			buf.WriteString("            return 503;\n        }\n")
+			continue
+		}
+
+		// Setting a header in a location drops the ones set in the http block,
+		// so they have to be repeated.
+		name := upstreamName(route)
+		buf.WriteString("            proxy_set_header Host $host;\n")
+		buf.WriteString("            proxy_set_header X-Forwarded-For " +
+			"$proxy_add_x_forwarded_for;\n")
+		fmt.Fprintf(buf, "            proxy_set_header %s %s;\n",
+			routerUpstreamHeader, name)
+		fmt.Fprintf(buf, "            proxy_pass http://%s;\n        }\n", name)
+	}
+	buf.WriteString("    }\n")
+}
+
+// makeUpstreams returns the upstream that balances requests across `backends`,
+// and the upstream that peers forward requests to, which only contains the
+// backends running on this worker.  Requests for backends on other workers are
+// forwarded to those workers' routers, weighted by the number of backends there.
+// `local` reports whether there's an upstream for peers, which is only the case
+// if some backends run on this worker.
+func makeUpstreams(name string, port int, myIP string,
+	backends map[string]string) (upstreams string, local bool) {
+
+	if len(backends) == 0 {
+		return "", false
+	}
+
+	var localIPs []string
+	remote := map[string]int{}
+	for ip, minion := range backends {
+		if minion == myIP {
+			localIPs = append(localIPs, ip)
+		} else {
+			remote[minion]++
+		}
+	}
+	sort.Strings(localIPs)
+
+	var peers []string
+	for minion := range remote {
+		peers = append(peers, minion)
+	}
+	sort.Strings(peers)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "    upstream %s {\n", name)
+	for _, ip := range localIPs {
+		fmt.Fprintf(&buf, "        server %s:%d;\n", ip, port)
+	}
+	for _, peer := range peers {
+		fmt.Fprintf(&buf, "        server %s:%d weight=%d;\n", peer,
+			routerPeerPort, remote[peer])
+	}
+	buf.WriteString("    }\n\n")
+
+	if len(localIPs) != 0 {
+		fmt.Fprintf(&buf, "    upstream local-%s {\n", name)
+		for _, ip := range localIPs {
+			fmt.Fprintf(&buf, "        server %s:%d;\n", ip, port)
+		}
+		buf.WriteString("    }\n\n")
+	}
+	return buf.String(), len(localIPs) != 0
+}
+
+func upstreamName(route blueprint.IngressRoute) string {
+	return fmt.Sprintf("%s-%d", route.To, route.Port)
+}
+
+// matchCertificate returns the index of the certificate for `host`, or -1 if there
+// is none.  Certificates for wildcard hosts, e.g. "*.example.com", match any
+// subdomain.
+func matchCertificate(host string, certs []blueprint.IngressCertificate) int {
+	for i, cert := range certs {
+		if cert.Host == host {
+			return i
+		}
+	}
+
+	for i, cert := range certs {
+		if strings.HasPrefix(cert.Host, "*.") &&
+			strings.HasSuffix(host, cert.Host[1:]) {
+			return i
+		}
+	}
+	return -1
+}
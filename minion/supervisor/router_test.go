@@ -0,0 +1,198 @@
+package supervisor
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/quilt/quilt/blueprint"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/minion/docker"
+	"github.com/quilt/quilt/minion/supervisor/images"
+	"github.com/stretchr/testify/assert"
+
+	dkc "github.com/fsouza/go-dockerclient"
+)
+
+func TestRunRouterOnce(t *testing.T) {
+	ctx := initTest(db.Worker)
+	routerID, routerConfig = "", ""
+
+	runRouterOnce()
+	assert.Empty(t, ctx.fd.running())
+
+	ctx.conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.MinionSelf()
+		m.PrivateIP = "1.2.3.4"
+		view.Commit(m)
+
+		ing := view.InsertIngress()
+		ing.Name = "ingress"
+		ing.Routes = []blueprint.IngressRoute{{To: "web", Port: 8080}}
+		ing.HTTPPort = 80
+		ing.Backends = map[string]map[string]string{
+			"web": {"10.0.0.2": "1.2.3.4"},
+		}
+		view.Commit(ing)
+		return nil
+	})
+
+	runRouterOnce()
+	assert.Equal(t, map[string][]string{images.Router: nil}, ctx.fd.running())
+
+	router, err := getRunning(images.Router)
+	assert.NoError(t, err)
+
+	// The certificates are read from the worker, rather than the blueprint.
+	assert.Equal(t, []string{"/etc/quilt/certs:/etc/nginx/certs:ro"},
+		ctx.fd.md.Containers[router.ID].HostConfig.Binds)
+
+	config := makeRouterConfig("1.2.3.4", ctx.conn.SelectFromIngress(nil))
+	assert.Equal(t, map[docker.UploadToContainerOptions]struct{}{
+		{
+			ContainerID: router.ID,
+			UploadPath:  "/",
+			TarPath:     "etc/nginx/nginx.conf",
+			Contents:    config,
+		}: {},
+	}, ctx.fd.md.Uploads)
+	assert.Equal(t, []dkc.Signal{dkc.Signal(syscall.SIGHUP)},
+		ctx.fd.md.Signals[router.ID])
+
+	// Nothing changed, so the router isn't reloaded.
+	runRouterOnce()
+	assert.Len(t, ctx.fd.md.Signals[router.ID], 1)
+
+	ctx.conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		view.Remove(view.SelectFromIngress(nil)[0])
+		return nil
+	})
+
+	runRouterOnce()
+	assert.Empty(t, ctx.fd.running())
+}
+
+func TestMakeRouterFiles(t *testing.T) {
+	t.Parallel()
+
+	ingresses := []db.Ingress{{
+		Name: "ingress",
+		Routes: []blueprint.IngressRoute{
+			{Host: "example.com", Path: "/", To: "web", Port: 80},
+			{Host: "example.com", Path: "/api", To: "api", Port: 8080},
+			{Host: "other.com", To: "web", Port: 80},
+		},
+		Certificates: []blueprint.IngressCertificate{
+			{Host: "*.com", CertFile: "com.crt", KeyFile: "com.key"},
+		},
+		HTTPPort:  80,
+		HTTPSPort: 443,
+		Backends: map[string]map[string]string{
+			"web": {
+				"10.0.0.2": "1.2.3.4",
+				"10.0.0.3": "1.2.3.5",
+				"10.0.0.4": "1.2.3.5",
+			},
+		},
+	}}
+
+	config := makeRouterConfig("1.2.3.4", ingresses)
+	exp := `worker_processes auto;
+
+events {}
+
+http {
+    proxy_http_version 1.1;
+    proxy_set_header Host $host;
+    proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+
+    upstream web-80 {
+        server 10.0.0.2:80;
+        server 1.2.3.5:9080 weight=2;
+    }
+
+    upstream local-web-80 {
+        server 10.0.0.2:80;
+    }
+
+    map $http_x_quilt_upstream $quilt_upstream {
+        default "";
+        web-80 local-web-80;
+    }
+
+    server {
+        listen 9080;
+        location / {
+            if ($quilt_upstream = "") {
+                return 404;
+            }
+            proxy_pass http://$quilt_upstream;
+        }
+    }
+
+    server {
+        listen 80 default_server;
+        listen 443 ssl default_server;
+        ssl_certificate /etc/nginx/certs/com.crt;
+        ssl_certificate_key /etc/nginx/certs/com.key;
+        return 404;
+    }
+
+    server {
+        listen 80;
+        listen 443 ssl;
+        ssl_certificate /etc/nginx/certs/com.crt;
+        ssl_certificate_key /etc/nginx/certs/com.key;
+        server_name example.com;
+        location / {
+            proxy_set_header Host $host;
+            proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+            proxy_set_header X-Quilt-Upstream web-80;
+            proxy_pass http://web-80;
+        }
+        location /api {
+            return 503;
+        }
+    }
+
+    server {
+        listen 80;
+        listen 443 ssl;
+        ssl_certificate /etc/nginx/certs/com.crt;
+        ssl_certificate_key /etc/nginx/certs/com.key;
+        server_name other.com;
+        location / {
+            proxy_set_header Host $host;
+            proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+            proxy_set_header X-Quilt-Upstream web-80;
+            proxy_pass http://web-80;
+        }
+    }
+}
+`
+	assert.Equal(t, exp, config)
+
+	// The peers of a worker without local backends only forward to each other.
+	// Requests that peers forward to it are refused.
+	config = makeRouterConfig("1.2.3.6", ingresses)
+	assert.Contains(t, config, `    upstream web-80 {
+        server 1.2.3.4:9080 weight=1;
+        server 1.2.3.5:9080 weight=2;
+    }
+
+    map $http_x_quilt_upstream $quilt_upstream {
+        default "";
+    }`)
+}
+
+func TestMatchCertificate(t *testing.T) {
+	t.Parallel()
+
+	certs := []blueprint.IngressCertificate{
+		{Host: "*.example.com"},
+		{Host: "a.example.com"},
+	}
+	assert.Equal(t, 1, matchCertificate("a.example.com", certs))
+	assert.Equal(t, 0, matchCertificate("b.example.com", certs))
+	assert.Equal(t, -1, matchCertificate("example.com", certs))
+	assert.Equal(t, -1, matchCertificate("a.example.org", certs))
+}
@@ -3,10 +3,14 @@ package supervisor
 import (
 	"fmt"
 	"os/exec"
+	"sort"
 	"strings"
+	"sync"
 
+	"github.com/quilt/quilt/blueprint"
 	"github.com/quilt/quilt/counter"
 	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/health"
 	"github.com/quilt/quilt/minion/docker"
 	"github.com/quilt/quilt/minion/supervisor/images"
 
@@ -15,10 +19,7 @@ import (
 
 const ovsImage = "quilt/ovs"
 
-// The tunneling protocol to use between machines.
-// "stt" and "geneve" are supported.
-const tunnelingProtocol = "stt"
-
+// The default image of each system container.
 var imageMap = map[string]string{
 	images.Etcd:          "quay.io/coreos/etcd:v3.0.2",
 	images.Ovncontroller: ovsImage,
@@ -27,6 +28,20 @@ var imageMap = map[string]string{
 	images.Ovsvswitchd:   ovsImage,
 	images.Registry:      "registry:2",
 	images.Monitor:       "google/cadvisor:v0.24.1",
+	images.Router:        "nginx:1.13",
+}
+
+// The keys under which the deployment may override the image of each system
+// container.
+var imageKeys = map[string]string{
+	images.Etcd:          "etcd",
+	images.Ovncontroller: "ovs",
+	images.Ovnnorthd:     "ovs",
+	images.Ovsdb:         "ovs",
+	images.Ovsvswitchd:   "ovs",
+	images.Registry:      "registry",
+	images.Monitor:       "cadvisor",
+	images.Router:        "router",
 }
 
 const etcdHeartbeatInterval = "500"
@@ -40,21 +55,37 @@ var role db.Role
 var oldEtcdIPs []string
 var oldIP string
 
+// The containers the supervisor has started, and not yet removed, mapped to their
+// arguments.
+var desiredLock sync.Mutex
+var desired = map[string][]string{}
+
+// Held while starting containers, so that the supervisor loops and the restarts of
+// exited containers don't race.
+var startLock sync.Mutex
+
 // Run blocks implementing the supervisor module.
 func Run(_conn db.Conn, _dk docker.Client, _role db.Role) {
 	conn = _conn
 	dk = _dk
 	role = _role
 
+	// Pull the images the minion is configured to run, rather than the defaults,
+	// which are unused if they're overridden.
+	minion := conn.MinionSelf()
 	imageSet := map[string]struct{}{}
-	for _, image := range imageMap {
-		imageSet[image] = struct{}{}
+	for name := range imageMap {
+		imageSet[getImage(name, minion)] = struct{}{}
 	}
 
 	for image := range imageSet {
 		go dk.Pull(image)
 	}
 
+	health.Register("docker", checkDocker)
+	health.Register("supervisor", checkContainers)
+	go watchExits()
+
 	switch role {
 	case db.Master:
 		runMaster()
@@ -66,18 +97,44 @@ func Run(_conn db.Conn, _dk docker.Client, _role db.Role) {
 // run calls out to the Docker client to run the container specified by name.
 func run(name string, args ...string) {
 	c.Inc("Docker Run " + name)
-	isRunning, err := dk.IsRunning(name)
+	setDesired(name, args)
+	if err := start(name, args); err != nil {
+		log.WithError(err).Warnf("Failed to run %s.", name)
+	}
+}
+
+// start starts the container specified by name, unless it's already running the
+// configured image.  Exited and outdated containers are removed first so that
+// their name may be reused.
+func start(name string, args []string) error {
+	startLock.Lock()
+	defer startLock.Unlock()
+
+	image := getImage(name, conn.MinionSelf())
+	runningImage, err := getRunningImage(name)
 	if err != nil {
-		log.WithError(err).Warnf("could not check running status of %s.", name)
-		return
+		return fmt.Errorf("could not check running status: %s", err)
 	}
-	if isRunning {
-		return
+
+	if runningImage == image {
+		return nil
+	} else if runningImage != "" {
+		c.Inc("Upgrade " + name)
+		log.WithFields(log.Fields{
+			"name": name,
+			"old":  runningImage,
+			"new":  image,
+		}).Info("Upgrading system container")
+	}
+
+	err = dk.Remove(name)
+	if err != nil && err != docker.ErrNoSuchContainer {
+		return fmt.Errorf("could not remove old container: %s", err)
 	}
 
 	ro := docker.RunOptions{
 		Name:        name,
-		Image:       imageMap[name],
+		Image:       image,
 		Args:        args,
 		NetworkMode: "host",
 		VolumesFrom: []string{"minion"},
@@ -86,6 +143,11 @@ func run(name string, args ...string) {
 	if name == images.Ovsvswitchd {
 		ro.Privileged = true
 	}
+
+	if name == images.Router {
+		ro.Binds = []string{
+			blueprint.IngressCertificateDir + ":" + routerCertDir + ":ro"}
+	}
 	
 	if name == images.Monitor {
 		ro.Binds =  []string{"/:/rootfs:ro",
@@ -99,25 +161,112 @@ func run(name string, args ...string) {
 		ro.Privileged = true
 	}
 
-	//DANGER!!! Included here just for research purposes so that we can tag outgoing packets using net_cls labels. This is not possible unless we have the containers in the same privileged namespace. 
-	ro.Privileged = true
-	
 	log.Infof("Start Container: %s", name)
 	_, err = dk.Run(ro)
+	return err
+}
+
+// getImage returns the image the container specified by name should run, taking
+// into account the overrides configured for the minion.
+func getImage(name string, minion db.Minion) string {
+	if image := minion.SystemImages[imageKeys[name]]; image != "" {
+		return image
+	}
+	return imageMap[name]
+}
+
+// getRunningImage returns the image of the running container with the given name,
+// or "" if there is no such container.
+func getRunningImage(name string) (string, error) {
+	container, err := getRunning(name)
+	return container.Image, err
+}
+
+// getRunning returns the running container with the given name, or an empty
+// Container if there is no such container.
+func getRunning(name string) (docker.Container, error) {
+	containers, err := dk.List(map[string][]string{"name": {name}})
 	if err != nil {
-		log.WithError(err).Warnf("Failed to run %s.", name)
+		return docker.Container{}, err
 	}
+
+	// Docker matches any container whose name contains `name`.
+	for _, container := range containers {
+		if strings.TrimPrefix(container.Name, "/") == name {
+			return container, nil
+		}
+	}
+	return docker.Container{}, nil
 }
 
 // Remove removes the docker container specified by name.
 func Remove(name string) {
 	log.WithField("name", name).Info("Removing container")
+	clearDesired(name)
 	err := dk.Remove(name)
 	if err != nil && err != docker.ErrNoSuchContainer {
 		log.WithError(err).Warnf("Failed to remove %s.", name)
 	}
 }
 
+// setDesired records that the container specified by name should be running with
+// the given arguments.
+func setDesired(name string, args []string) {
+	desiredLock.Lock()
+	desired[name] = args
+	desiredLock.Unlock()
+}
+
+// clearDesired records that the container specified by name should not be running.
+func clearDesired(name string) {
+	desiredLock.Lock()
+	delete(desired, name)
+	desiredLock.Unlock()
+}
+
+func getDesired(name string) (args []string, ok bool) {
+	desiredLock.Lock()
+	defer desiredLock.Unlock()
+
+	args, ok = desired[name]
+	return args, ok
+}
+
+// checkDocker returns an error if the Docker daemon isn't responding.
+func checkDocker() error {
+	_, err := dk.List(nil)
+	return err
+}
+
+// checkContainers returns an error listing the containers the supervisor started
+// that aren't running.
+func checkContainers() error {
+	desiredLock.Lock()
+	var names []string
+	for name := range desired {
+		names = append(names, name)
+	}
+	desiredLock.Unlock()
+	sort.Strings(names)
+
+	var stopped []string
+	for _, name := range names {
+		running, err := dk.IsRunning(name)
+		if err != nil {
+			return fmt.Errorf("check %s: %s", name, err)
+		}
+
+		if !running {
+			stopped = append(stopped, name)
+		}
+	}
+
+	if len(stopped) != 0 {
+		return fmt.Errorf("not running: %s", strings.Join(stopped, ", "))
+	}
+	return nil
+}
+
 func initialClusterString(etcdIPs []string) string {
 	var initialCluster []string
 	for _, ip := range etcdIPs {
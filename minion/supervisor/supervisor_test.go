@@ -3,9 +3,13 @@ package supervisor
 import (
 	"fmt"
 	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
 
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/minion/docker"
+	"github.com/quilt/quilt/minion/supervisor/images"
 )
 
 type testCtx struct {
@@ -23,6 +27,7 @@ func initTest(r db.Role) *testCtx {
 		conn.Trigger(db.MinionTable, db.EtcdTable)}
 	role = r
 	dk = ctx.fd.Client
+	desired = map[string][]string{}
 
 	ctx.conn.Txn(db.AllTables...).Run(func(view db.Database) error {
 		m := view.InsertMinion()
@@ -75,6 +80,39 @@ func (f fakeDocker) running() map[string][]string {
 	return res
 }
 
+// image returns the image of the running container with the given name.
+func (f fakeDocker) image(name string) string {
+	image, _ := getRunningImage(name)
+	return image
+}
+
+func (f fakeDocker) ids() []string {
+	f.md.Lock()
+	defer f.md.Unlock()
+
+	var ids []string
+	for id := range f.md.Containers {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// stop stops the container with the given name, as if it had crashed.
+func (f fakeDocker) stop(name string) {
+	f.md.Lock()
+	var ids []string
+	for id, c := range f.md.Containers {
+		if c.Name == name {
+			ids = append(ids, id)
+		}
+	}
+	f.md.Unlock()
+
+	for _, id := range ids {
+		f.md.StopContainer(id)
+	}
+}
+
 func etcdArgsMaster(ip string, etcdIPs []string) []string {
 	return []string{
 		fmt.Sprintf("--name=master-%s", ip),
@@ -88,3 +126,36 @@ func etcdArgsMaster(ip string, etcdIPs []string) []string {
 		"--election-timeout=5000",
 	}
 }
+
+func TestUpgrade(t *testing.T) {
+	ctx := initTest(db.Master)
+
+	run(images.Registry)
+	assert.Equal(t, "registry:2", ctx.fd.image(images.Registry))
+
+	ctx.conn.Txn(db.MinionTable).Run(func(view db.Database) error {
+		m := view.MinionSelf()
+		m.SystemImages = map[string]string{"registry": "mirror/registry:2.6"}
+		view.Commit(m)
+		return nil
+	})
+
+	run(images.Registry)
+	assert.Equal(t, "mirror/registry:2.6", ctx.fd.image(images.Registry))
+	assert.Len(t, ctx.fd.md.Containers, 1)
+
+	// Containers that run the configured image are left alone.
+	ids := ctx.fd.ids()
+	run(images.Registry)
+	assert.Equal(t, ids, ctx.fd.ids())
+}
+
+func TestGetImage(t *testing.T) {
+	t.Parallel()
+
+	minion := db.Minion{SystemImages: map[string]string{"ovs": "mirror/ovs"}}
+	assert.Equal(t, "mirror/ovs", getImage(images.Ovnnorthd, minion))
+	assert.Equal(t, "mirror/ovs", getImage(images.Ovsvswitchd, minion))
+	assert.Equal(t, imageMap[images.Etcd], getImage(images.Etcd, minion))
+	assert.Equal(t, imageMap[images.Etcd], getImage(images.Etcd, db.Minion{}))
+}
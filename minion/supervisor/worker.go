@@ -17,6 +17,7 @@ import (
 func runWorker() {
 	setupWorker()
 	go runWorkerSystem()
+	go runRouter()
 }
 
 func setupWorker() {
@@ -77,11 +78,15 @@ func runWorkerOnce() {
 		"--election-timeout="+etcdElectionTimeout,
 		"--proxy=on")
 
-	run(images.Monitor,
-		"--storage_duration=5m0s",
-		"--allow_dynamic_housekeeping=true",
-		"--global_housekeeping_interval=3m0s",
-		"--housekeeping_interval=1s")
+	if minion.DisableCadvisor {
+		Remove(images.Monitor)
+	} else {
+		run(images.Monitor,
+			"--storage_duration=5m0s",
+			"--allow_dynamic_housekeeping=true",
+			"--global_housekeeping_interval=3m0s",
+			"--housekeeping_interval=1s")
+	}
 
 	run(images.Ovsdb, "ovsdb-server")
 	run(images.Ovsvswitchd, "ovs-vswitchd")
@@ -94,7 +99,8 @@ func runWorkerOnce() {
 	err := execRun("ovs-vsctl", "set", "Open_vSwitch", ".",
 		fmt.Sprintf("external_ids:ovn-remote=\"tcp:%s:6640\"", leaderIP),
 		fmt.Sprintf("external_ids:ovn-encap-ip=%s", IP),
-		fmt.Sprintf("external_ids:ovn-encap-type=\"%s\"", tunnelingProtocol),
+		fmt.Sprintf("external_ids:ovn-encap-type=\"%s\"",
+			minion.Network().Encapsulation),
 		fmt.Sprintf("external_ids:api_server=\"http://%s:9000\"", leaderIP),
 		fmt.Sprintf("external_ids:system-id=\"%s\"", IP))
 	if err != nil {
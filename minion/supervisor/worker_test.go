@@ -169,3 +169,44 @@ func etcdArgsWorker(etcdIPs []string) []string {
 		"--proxy=on",
 	}
 }
+
+func TestDisableCadvisor(t *testing.T) {
+	ctx := initTest(db.Worker)
+	ctx.conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		e := view.SelectFromEtcd(nil)[0]
+		e.EtcdIPs = []string{"1.2.3.4"}
+		view.Commit(e)
+		return nil
+	})
+	ctx.run()
+	assert.Contains(t, ctx.fd.running(), images.Monitor)
+
+	ctx.conn.Txn(db.MinionTable).Run(func(view db.Database) error {
+		m := view.MinionSelf()
+		m.DisableCadvisor = true
+		view.Commit(m)
+		return nil
+	})
+	ctx.run()
+	assert.NotContains(t, ctx.fd.running(), images.Monitor)
+}
+
+func TestEncapsulation(t *testing.T) {
+	ctx := initTest(db.Worker)
+	ctx.conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.MinionSelf()
+		m.PrivateIP = "1.2.3.4"
+		m.Encapsulation = "vxlan"
+		view.Commit(m)
+
+		e := view.SelectFromEtcd(nil)[0]
+		e.EtcdIPs = []string{"1.2.3.4"}
+		e.LeaderIP = "5.6.7.8"
+		view.Commit(e)
+		return nil
+	})
+	ctx.run()
+
+	assert.Len(t, ctx.execs, 1)
+	assert.Contains(t, ctx.execs[0], "external_ids:ovn-encap-type=\"vxlan\"")
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/quilt/quilt/api"
 	"github.com/quilt/quilt/api/client"
 	"github.com/quilt/quilt/connection"
+	"github.com/quilt/quilt/connection/auth"
 	"github.com/quilt/quilt/connection/credentials"
 	"github.com/quilt/quilt/connection/credentials/tls"
 	tlsIO "github.com/quilt/quilt/connection/credentials/tls/io"
@@ -97,7 +98,7 @@ func randomTLSCredentials() (tls.TLS, error) {
 		return tls.TLS{}, err
 	}
 
-	signed, err := rsa.NewSigned(ca)
+	signed, err := rsa.NewSigned(ca, auth.Admin)
 	if err != nil {
 		return tls.TLS{}, err
 	}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -16,6 +17,7 @@ import (
 	"time"
 
 	"github.com/spf13/afero"
+	"golang.org/x/crypto/ssh"
 )
 
 // Sleep stores time.Sleep so we can mock it out for unit tests.
@@ -244,3 +246,27 @@ func JoinNotifiers(a, b chan struct{}) chan struct{} {
 	}()
 	return c
 }
+
+// HostKeyCallback returns an SSH host key callback that only accepts keys whose
+// SHA256 fingerprint is in `fingerprints`. If no fingerprints are known, e.g.
+// because the machine's minion hasn't reported them yet, the key is trusted on
+// first use: `tofu` is called with the key's fingerprint, and should return an
+// error if a different key was trusted for the host before.
+func HostKeyCallback(fingerprints []string,
+	tofu func(fingerprint string) error) ssh.HostKeyCallback {
+
+	return func(hostname string, _ net.Addr, key ssh.PublicKey) error {
+		fingerprint := ssh.FingerprintSHA256(key)
+		if len(fingerprints) == 0 {
+			return tofu(fingerprint)
+		}
+
+		for _, trusted := range fingerprints {
+			if fingerprint == trusted {
+				return nil
+			}
+		}
+		return fmt.Errorf("host key %s for %s doesn't match the keys reported "+
+			"by its minion", fingerprint, hostname)
+	}
+}
@@ -3,12 +3,16 @@ package util
 import (
 	"archive/tar"
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"fmt"
 	"io"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
 )
 
 func TestToTar(t *testing.T) {
@@ -117,3 +121,35 @@ func TestJoinNotifiers(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestHostKeyCallback(t *testing.T) {
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	key, err := ssh.NewPublicKey(&privKey.PublicKey)
+	assert.NoError(t, err)
+	fingerprint := ssh.FingerprintSHA256(key)
+
+	var trusted []string
+	tofu := func(fingerprint string) error {
+		trusted = append(trusted, fingerprint)
+		return nil
+	}
+
+	// Keys are trusted on first use if the machine hasn't reported any.
+	callback := HostKeyCallback(nil, tofu)
+	assert.NoError(t, callback("host", nil, key))
+	assert.Equal(t, []string{fingerprint}, trusted)
+
+	trusted = nil
+	callback = HostKeyCallback([]string{"other", fingerprint}, tofu)
+	assert.NoError(t, callback("host", nil, key))
+	assert.Empty(t, trusted)
+
+	callback = HostKeyCallback([]string{"other"}, tofu)
+	assert.EqualError(t, callback("host", nil, key), "host key "+fingerprint+
+		" for host doesn't match the keys reported by its minion")
+	assert.Empty(t, trusted)
+
+	callback = HostKeyCallback(nil, func(string) error { return assert.AnError })
+	assert.Equal(t, assert.AnError, callback("host", nil, key))
+}